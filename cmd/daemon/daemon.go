@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"alfred-tool/services"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	retries int
+	backoff time.Duration
+	once    bool
+)
+
+var DaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "运行定时任务守护进程",
+	Long: `持续运行并按 cron 设置执行rsync定时任务
+
+- 同一配置不会重叠执行（手动执行中的配置也会跳过）
+- 守护进程停止期间错过的执行会在启动后补执行一次
+- 执行失败后按指数退避重试（--backoff、2×--backoff、4×--backoff…）
+- 同一时间只能运行一个守护进程
+
+使用 rsync schedule set 设置定时执行，Ctrl+C 停止时会等待执行中的任务结束。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scheduler := services.NewScheduler(services.SchedulerOptions{
+			Retries: retries,
+			Backoff: backoff,
+		})
+
+		if once {
+			// 只执行当前到期的任务，适合由系统的定时器调用
+			if _, err := scheduler.RunDue(time.Now()); err != nil {
+				fmt.Printf("错误: %v\n", err)
+				os.Exit(1)
+			}
+			scheduler.Wait()
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := scheduler.Run(ctx); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	DaemonCmd.Flags().IntVar(&retries, "retries", 3, "执行失败后最多重试次数")
+	DaemonCmd.Flags().DurationVar(&backoff, "backoff", time.Minute, "第一次重试前的等待时间，之后每次翻倍")
+	DaemonCmd.Flags().BoolVar(&once, "once", false, "只执行当前到期的任务后退出（不重试）")
+}
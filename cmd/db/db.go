@@ -0,0 +1,25 @@
+package db
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var DbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "数据库管理",
+	Long: `管理本地SQLite数据库
+
+数据库管理包含以下功能：
+- 生成一致性的在线备份，可选压缩
+- 列出已有备份
+- 从备份恢复，恢复前校验完整性并预览变化
+- 自动轮转，只保留最近的N个备份
+- 合并 iCloud 冲突副本`,
+}
+
+func init() {
+	DbCmd.AddCommand(backupCmd)
+	DbCmd.AddCommand(listCmd)
+	DbCmd.AddCommand(restoreCmd)
+	DbCmd.AddCommand(mergeCmd)
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"alfred-tool/services"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupCompress bool
+	backupKeep     int
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "备份数据库",
+	Long:  `将当前数据库备份到带时间戳的文件中，并自动清理超出保留数量的旧备份`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := services.BackupDatabase(services.BackupOptions{
+			Compress: backupCompress,
+			Keep:     backupKeep,
+		})
+		if err != nil {
+			fmt.Printf("备份失败: %v\n", err)
+			return
+		}
+		fmt.Printf("数据库已备份到: %s\n", file)
+	},
+}
+
+func init() {
+	backupCmd.Flags().BoolVarP(&backupCompress, "compress", "z", false, "使用gzip压缩备份文件")
+	backupCmd.Flags().IntVarP(&backupKeep, "keep", "k", 10, "保留的备份数量，0表示不清理")
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出所有备份",
+	Long:  `显示备份目录中的所有数据库备份，从新到旧排列`,
+	Run: func(cmd *cobra.Command, args []string) {
+		backups, err := services.ListBackups()
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		if len(backups) == 0 {
+			fmt.Println("没有找到任何备份")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "文件名\t大小\t时间")
+		fmt.Fprintln(w, "------\t----\t----")
+		for _, backup := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%s\n",
+				backup.Name,
+				models.FormatBytes(backup.Size),
+				backup.ModTime.Format("2006-01-02 15:04:05"),
+			)
+		}
+		w.Flush()
+	},
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"alfred-tool/services"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var mergeDryRun bool
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge [数据库文件]",
+	Short: "合并另一个数据库",
+	Long: `将另一个数据库（如 iCloud 产生的冲突副本）合并到当前数据库
+
+记录按名称匹配：
+- 只存在于另一个数据库的记录会被新增
+- 两边内容不同时保留更新时间较新的版本，并在冲突报告中列出
+- 只存在于当前数据库的记录保持不变`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !mergeDryRun {
+			if err := services.AutoBackup("merge"); err != nil {
+				fmt.Printf("合并失败: %v\n", err)
+				return
+			}
+		}
+
+		reports, err := services.MergeDatabase(args[0], mergeDryRun)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		printMergeReports(reports)
+
+		if mergeDryRun {
+			fmt.Println("预览模式，未写入任何修改")
+			return
+		}
+		fmt.Println("合并完成，确认无误后可删除冲突副本")
+	},
+}
+
+func init() {
+	mergeCmd.Flags().BoolVar(&mergeDryRun, "dry-run", false, "只生成合并报告，不写入数据库")
+}
+
+func printMergeReports(reports []services.MergeReport) {
+	entityNames := map[string]string{
+		"ssh":     "SSH连接",
+		"rsync":   "Rsync配置",
+		"service": "服务",
+	}
+
+	for _, report := range reports {
+		fmt.Printf("## %s: 新增 %d, 覆盖 %d, 冲突 %d\n",
+			entityNames[report.Entity], len(report.Added), len(report.Updated), len(report.Conflicts))
+		for _, name := range report.Added {
+			fmt.Printf("  + %s\n", name)
+		}
+		for _, conflict := range report.Conflicts {
+			winner := "保留本地"
+			if conflict.Winner == "other" {
+				winner = "采用对方"
+			}
+			fmt.Printf("  ! %s: 本地 %s / 对方 %s -> %s\n",
+				conflict.Name,
+				conflict.LocalUpdatedAt.Format("2006-01-02 15:04:05"),
+				conflict.OtherUpdatedAt.Format("2006-01-02 15:04:05"),
+				winner,
+			)
+		}
+	}
+}
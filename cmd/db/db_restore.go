@@ -0,0 +1,99 @@
+package db
+
+import (
+	"alfred-tool/services"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreYes     bool
+	restorePreview bool
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [备份文件]",
+	Short: "从备份恢复数据库",
+	Long: `从指定备份恢复数据库，支持完整路径或备份目录中的文件名
+
+恢复前会校验备份文件的完整性并显示将要发生的变化，
+确认后会先备份当前数据库再进行替换。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := args[0]
+
+		preview, err := services.PreviewRestore(file)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		printRestorePreview(preview)
+
+		if restorePreview {
+			return
+		}
+
+		if !restoreYes {
+			fmt.Print("确认恢复? (y/N): ")
+			reader := bufio.NewReader(os.Stdin)
+			response, _ := reader.ReadString('\n')
+			response = strings.ToLower(strings.TrimSpace(response))
+			if response != "y" && response != "yes" {
+				fmt.Println("取消恢复")
+				return
+			}
+		}
+
+		safetyBackup, err := services.RestoreDatabase(file)
+		if err != nil {
+			fmt.Printf("恢复失败: %v\n", err)
+			return
+		}
+		fmt.Printf("恢复前的数据库已备份到: %s\n", safetyBackup)
+		fmt.Println("数据库恢复成功!")
+	},
+}
+
+func init() {
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "跳过确认直接恢复")
+	restoreCmd.Flags().BoolVar(&restorePreview, "preview", false, "只预览变化，不执行恢复")
+}
+
+func printRestorePreview(preview *services.RestorePreview) {
+	entityNames := map[string]string{
+		"ssh":     "SSH连接",
+		"rsync":   "Rsync配置",
+		"service": "服务",
+	}
+
+	fmt.Printf("备份文件完整性检查通过: %s\n\n", preview.File)
+
+	changed := false
+	for _, diff := range preview.Diffs {
+		if !diff.HasChanges() {
+			continue
+		}
+		changed = true
+		fmt.Printf("## %s\n", entityNames[diff.Entity])
+		for _, name := range diff.Added {
+			fmt.Printf("  + %s\n", name)
+		}
+		for _, name := range diff.Removed {
+			fmt.Printf("  - %s\n", name)
+		}
+		for _, name := range diff.Changed {
+			fmt.Printf("  ~ %s\n", name)
+		}
+		fmt.Println()
+	}
+
+	if !changed {
+		fmt.Println("备份与当前数据库内容一致")
+		return
+	}
+	fmt.Println("(+ 将恢复  - 将删除  ~ 将回退到备份版本)")
+}
@@ -0,0 +1,52 @@
+package doctor
+
+import (
+	"alfred-tool/database"
+	"alfred-tool/services"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "检查数据一致性",
+	Long: `检查数据库中的数据一致性问题
+
+检查内容：
+- 引用了不存在SSH连接的rsync配置
+- 引用了不存在SSH连接的服务
+- 数据库目录中的 iCloud 冲突副本`,
+	Run: func(cmd *cobra.Command, args []string) {
+		problems := 0
+
+		references, err := services.FindDanglingReferences()
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		for _, ref := range references {
+			switch ref.Entity {
+			case "rsync":
+				fmt.Printf("✗ rsync配置 '%s' 引用的SSH连接 '%s' 不存在\n", ref.Name, ref.Reference)
+			case "service":
+				fmt.Printf("✗ 服务 '%s' (ID: %d) 引用的SSH连接 %s 不存在\n", ref.Name, ref.ID, ref.Reference)
+			}
+		}
+		problems += len(references)
+
+		for _, conflict := range database.FindConflictedCopies() {
+			fmt.Printf("✗ 发现数据库冲突副本: %s\n", conflict)
+			problems++
+		}
+
+		if problems == 0 {
+			fmt.Println("✓ 没有发现问题")
+			return
+		}
+		fmt.Printf("\n共发现 %d 个问题\n", problems)
+		if len(references) > 0 {
+			fmt.Println("可以通过 rsync update / service update 重新选择连接，或用 trash restore ssh <name> 恢复被删除的连接")
+		}
+	},
+}
@@ -0,0 +1,72 @@
+package history
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history [数据类型] [名称或ID]",
+	Short: "查看变更历史",
+	Long: `查看SSH连接、rsync配置或服务的变更历史
+
+每次新增、修改、删除都会记录字段级变更、时间、操作用户、主机名和执行的命令，
+密码等敏感字段只记录是否变更，不记录内容。
+
+数据类型: ssh, rsync, service`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		records, err := services.GetHistory(args[0], args[1])
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		for _, record := range records {
+			fmt.Printf("v%d  %s  %s  %s@%s\n",
+				record.Version,
+				record.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+				actionNames[record.Action],
+				record.User,
+				record.Hostname,
+			)
+			fmt.Printf("    命令: %s\n", record.Command)
+
+			var changes []models.FieldChange
+			if err := json.Unmarshal([]byte(record.Changes), &changes); err == nil {
+				printChanges(changes)
+			}
+			fmt.Println()
+		}
+	},
+}
+
+func init() {
+	HistoryCmd.AddCommand(diffCmd)
+}
+
+var actionNames = map[models.ChangeAction]string{
+	models.ChangeActionCreate:  "新增",
+	models.ChangeActionUpdate:  "修改",
+	models.ChangeActionDelete:  "删除",
+	models.ChangeActionRestore: "恢复",
+	models.ChangeActionPurge:   "永久删除",
+	models.ChangeActionRevert:  "回滚",
+}
+
+func printChanges(changes []models.FieldChange) {
+	for _, change := range changes {
+		switch {
+		case change.Old == "":
+			fmt.Printf("    + %s: %s\n", change.Field, change.New)
+		case change.New == "":
+			fmt.Printf("    - %s: %s\n", change.Field, change.Old)
+		default:
+			fmt.Printf("    ~ %s: %s → %s\n", change.Field, change.Old, change.New)
+		}
+	}
+}
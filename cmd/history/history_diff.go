@@ -0,0 +1,42 @@
+package history
+
+import (
+	"alfred-tool/services"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [数据类型] [名称或ID] [起始版本] [结束版本]",
+	Short: "比较两个历史版本",
+	Long:  `比较同一记录两个历史版本之间的差异，不指定结束版本时与最新版本比较`,
+	Args:  cobra.RangeArgs(3, 4),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, err := strconv.Atoi(args[2])
+		if err != nil {
+			fmt.Println("无效的版本号")
+			return
+		}
+		to := 0
+		if len(args) > 3 {
+			to, err = strconv.Atoi(args[3])
+			if err != nil {
+				fmt.Println("无效的版本号")
+				return
+			}
+		}
+
+		changes, err := services.DiffHistoryVersions(args[0], args[1], from, to)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		if len(changes) == 0 {
+			fmt.Println("两个版本内容相同")
+			return
+		}
+		printChanges(changes)
+	},
+}
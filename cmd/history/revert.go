@@ -0,0 +1,36 @@
+package history
+
+import (
+	"alfred-tool/services"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var revertVersion int
+
+var RevertCmd = &cobra.Command{
+	Use:   "revert [数据类型] [名称或ID]",
+	Short: "回滚到历史版本",
+	Long: `将SSH连接、rsync配置或服务恢复到指定历史版本的内容
+
+已删除的记录会同时恢复；密码等敏感字段在历史中不可见，回滚时保留当前值。`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if revertVersion <= 0 {
+			fmt.Println("请使用 --to 指定要回滚到的版本")
+			return
+		}
+
+		record, err := services.RevertToVersion(args[0], args[1], revertVersion)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("'%s' 已回滚到版本 v%d\n", record.EntityName, record.Version)
+	},
+}
+
+func init() {
+	RevertCmd.Flags().IntVar(&revertVersion, "to", 0, "要回滚到的版本号")
+}
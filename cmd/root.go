@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"os"
 
+	"alfred-tool/cmd/daemon"
+	"alfred-tool/cmd/db"
+	"alfred-tool/cmd/doctor"
+	"alfred-tool/cmd/history"
 	"alfred-tool/cmd/rsync"
 	"alfred-tool/cmd/service"
 	"alfred-tool/cmd/ssh"
+	"alfred-tool/cmd/trash"
 	"alfred-tool/database"
 
 	"github.com/spf13/cobra"
@@ -32,4 +37,10 @@ func init() {
 	rootCmd.AddCommand(ssh.SshCmd)
 	rootCmd.AddCommand(rsync.RsyncCmd)
 	rootCmd.AddCommand(service.ServiceCmd)
+	rootCmd.AddCommand(db.DbCmd)
+	rootCmd.AddCommand(trash.TrashCmd)
+	rootCmd.AddCommand(doctor.DoctorCmd)
+	rootCmd.AddCommand(daemon.DaemonCmd)
+	rootCmd.AddCommand(history.HistoryCmd)
+	rootCmd.AddCommand(history.RevertCmd)
 }
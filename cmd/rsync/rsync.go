@@ -13,8 +13,18 @@ rsync配置包含以下功能：
 - 添加、修改、删除rsync配置
 - 列出和搜索已保存的配置
 - 执行rsync同步操作
-- 支持上传和下载两种方向
-- 支持排除规则和自定义选项`,
+- 查看执行记录和日志
+- 定时执行
+- 监听本地文件变化自动上传
+- 快照备份与恢复
+- 分组顺序或并行执行多个配置
+- 支持上传、下载、双向同步和服务器之间传输
+- 支持有序的过滤规则、.gitignore 和自定义选项
+- 带宽、超时、续传和备份等传输限制，以及常见场景的选项预设
+- 路径、规则和选项中可以使用 {{变量}}
+- 本地没有 rsync 时可以使用 sftp 传输引擎
+- 校验本地和服务器上的文件是否一致，可在每次执行后自动校验
+- 复制配置、保存配置模板，并按SSH连接的标签批量生成配置`,
 }
 
 func init() {
@@ -24,4 +34,18 @@ func init() {
 	RsyncCmd.AddCommand(updateCmd)
 	RsyncCmd.AddCommand(deleteCmd)
 	RsyncCmd.AddCommand(runCmd)
+	RsyncCmd.AddCommand(renderCmd)
+	RsyncCmd.AddCommand(historyCmd)
+	RsyncCmd.AddCommand(logCmd)
+	RsyncCmd.AddCommand(scheduleCmd)
+	RsyncCmd.AddCommand(watchCmd)
+	RsyncCmd.AddCommand(snapshotsCmd)
+	RsyncCmd.AddCommand(restoreCmd)
+	RsyncCmd.AddCommand(groupCmd)
+	RsyncCmd.AddCommand(filterCmd)
+	RsyncCmd.AddCommand(presetCmd)
+	RsyncCmd.AddCommand(verifyCmd)
+	RsyncCmd.AddCommand(cloneCmd)
+	RsyncCmd.AddCommand(templateCmd)
+	RsyncCmd.AddCommand(generateCmd)
 }
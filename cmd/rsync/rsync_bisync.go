@@ -0,0 +1,126 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+var syncActionNames = map[models.SyncActionType]string{
+	models.SyncActionPush:         "↑ 上传",
+	models.SyncActionPull:         "↓ 下载",
+	models.SyncActionDeleteRemote: "✗ 删除服务器文件",
+	models.SyncActionDeleteLocal:  "✗ 删除本地文件",
+	models.SyncActionKeepBoth:     "⇅ 保留两个版本",
+}
+
+// conflictText 冲突的说明
+func conflictText(c models.SyncConflict) string {
+	switch {
+	case c.Local == nil:
+		return "本地已删除，服务器已修改"
+	case c.Remote == nil:
+		return "本地已修改，服务器已删除"
+	}
+	return fmt.Sprintf("两端都已修改（本地 %s，服务器 %s）",
+		time.Unix(c.Local.ModTime, 0).Format("01-02 15:04:05"), time.Unix(c.Remote.ModTime, 0).Format("01-02 15:04:05"))
+}
+
+// resolutionText 冲突的处理结果
+func resolutionText(c models.SyncConflict) string {
+	if c.Resolution == "" {
+		return "未解决，两端保持不变"
+	}
+	return syncActionNames[c.Resolution]
+}
+
+// printSyncPlan 按指定格式输出双向同步的计划
+func printSyncPlan(configName string, plan *models.SyncPlan, format string) error {
+	switch format {
+	case previewFormatTable:
+		printSyncPlanTable(plan)
+	case previewFormatJSON:
+		return printJSON(plan)
+	case previewFormatAlfred:
+		return printJSON(syncPlanAlfredData(configName, plan))
+	default:
+		return fmt.Errorf("未知的输出格式: %s（可选: table, json, alfred）", format)
+	}
+	return nil
+}
+
+func printSyncPlanTable(plan *models.SyncPlan) {
+	if len(plan.Actions) == 0 && len(plan.Conflicts) == 0 {
+		fmt.Println("两端一致，没有需要同步的变更")
+		return
+	}
+
+	if len(plan.Actions) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "操作\t大小\t路径")
+		fmt.Fprintln(w, "----\t----\t----")
+		for _, action := range plan.Actions {
+			size := ""
+			if action.Size > 0 {
+				size = models.FormatBytes(action.Size)
+			}
+			path := action.Path
+			if action.Conflict {
+				path += "（冲突）"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", syncActionNames[action.Type], size, path)
+		}
+		w.Flush()
+	}
+
+	if len(plan.Conflicts) > 0 {
+		fmt.Printf("\n冲突 %d 个:\n", len(plan.Conflicts))
+		for _, c := range plan.Conflicts {
+			fmt.Printf("  %s: %s → %s\n", c.Path, conflictText(c), resolutionText(c))
+		}
+	}
+
+	fmt.Printf("\n%s\n", syncPlanSummary(plan))
+}
+
+func syncPlanSummary(plan *models.SyncPlan) string {
+	counts := lo.CountValuesBy(plan.Actions, func(action models.SyncAction) models.SyncActionType { return action.Type })
+	return fmt.Sprintf("上传 %d，下载 %d，删除服务器 %d，删除本地 %d，保留两个版本 %d，未解决冲突 %d，未变化 %d",
+		counts[models.SyncActionPush], counts[models.SyncActionPull],
+		counts[models.SyncActionDeleteRemote], counts[models.SyncActionDeleteLocal],
+		counts[models.SyncActionKeepBoth], len(plan.Unresolved()), plan.Unchanged)
+}
+
+func syncPlanAlfredData(configName string, plan *models.SyncPlan) models.AlfredData {
+	summary := models.AlfredItem{
+		Uid:      configName,
+		Title:    fmt.Sprintf("同步 %s", configName),
+		Subtitle: syncPlanSummary(plan),
+		Arg:      []string{configName},
+	}
+
+	items := []models.AlfredItem{summary}
+	for _, action := range plan.Actions {
+		subtitle := syncActionNames[action.Type]
+		if action.Size > 0 {
+			subtitle += " · " + models.FormatBytes(action.Size)
+		}
+		if action.Conflict {
+			subtitle += " · 冲突"
+		}
+		items = append(items, models.AlfredItem{Uid: action.Path, Title: action.Path, Subtitle: subtitle, Arg: []string{configName}})
+	}
+	for _, c := range plan.Unresolved() {
+		items = append(items, models.AlfredItem{
+			Uid:      "conflict:" + c.Path,
+			Title:    "⚠️ " + c.Path,
+			Subtitle: conflictText(c) + " · " + resolutionText(c),
+			Arg:      []string{configName},
+		})
+	}
+	return models.AlfredData{Items: items}
+}
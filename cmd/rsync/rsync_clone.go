@@ -0,0 +1,35 @@
+package rsync
+
+import (
+	"alfred-tool/services"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var cloneSSH string
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone [配置名称] [新配置名称]",
+	Short: "复制rsync配置",
+	Long: `复制rsync配置的所有选项为新的配置，--ssh 指定新配置使用的SSH连接
+
+执行记录、快照、双向同步的状态和所属分组不会复制；原配置定时执行时，新配置的定时执行处于暂停状态，
+确认后用 rsync schedule resume 恢复。`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := services.CloneRsyncConfig(args[0], args[1], cloneSSH)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("已复制rsync配置 '%s' 为 '%s'（SSH连接: %s）\n", args[0], config.Name, config.SSHName)
+		if config.SchedulePaused {
+			fmt.Println("新配置的定时执行已暂停")
+		}
+	},
+}
+
+func init() {
+	cloneCmd.Flags().StringVar(&cloneSSH, "ssh", "", "新配置使用的SSH连接，默认与原配置相同")
+}
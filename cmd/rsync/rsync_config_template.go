@@ -0,0 +1,171 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templateFrom        string
+	templateFile        string
+	templateDescription string
+	templateNamePattern string
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "rsync配置模板",
+	Long: `配置模板是可复用的部分rsync配置，只保存模板中设置的选项，不包含配置名称和SSH连接，
+用 rsync generate 为多个SSH连接批量生成配置。
+
+模板内容为 JSON 对象，键与 rsync配置的 JSON 字段相同，如:
+{"direction": "upload", "local_path": "~/project/dist/", "remote_path": "/var/www/{{env}}", "archive": true, "delete": true}`,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出配置模板",
+	Long:  `以 Alfred JSON 列出配置模板，arg 为模板名称`,
+	Run: func(cmd *cobra.Command, args []string) {
+		templates, err := services.GetAllConfigTemplates()
+		if err != nil {
+			fmt.Printf("获取配置模板失败: %v\n", err)
+			return
+		}
+		alfredData := models.AlfredData{
+			Items: lo.Map(templates, func(template models.RsyncConfigTemplate, index int) models.AlfredItem {
+				subtitle := strings.Join(template.Fields(), ", ")
+				if template.Description != "" {
+					subtitle = template.Description + " · " + subtitle
+				}
+				return models.AlfredItem{
+					Uid:      template.Name,
+					Title:    template.Name,
+					Subtitle: subtitle,
+					Arg:      []string{template.Name},
+				}
+			}),
+		}
+		if err := printJSON(alfredData); err != nil {
+			fmt.Println(err)
+		}
+	},
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:   "show [模板名称]",
+	Short: "显示配置模板的内容",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		template, err := services.GetConfigTemplateByName(args[0])
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("模板: %s\n", template.Name)
+		if template.Description != "" {
+			fmt.Printf("描述: %s\n", template.Description)
+		}
+		namePattern := template.NamePattern
+		if namePattern == "" {
+			namePattern = models.DefaultGenerateNamePattern + "（默认）"
+		}
+		fmt.Printf("生成的配置名称: %s\n", namePattern)
+		fmt.Printf("使用次数: %d\n\n", template.UsageCount)
+
+		var content bytes.Buffer
+		if err := json.Indent(&content, []byte(template.Config), "", "  "); err != nil {
+			fmt.Println(template.Config)
+			return
+		}
+		fmt.Println(content.String())
+	},
+}
+
+var templateSaveCmd = &cobra.Command{
+	Use:   "save [模板名称]",
+	Short: "保存配置模板",
+	Long: `保存配置模板，同名的模板已存在时覆盖
+
+--from 以已有的rsync配置为基础，保存其中设置的选项（不含名称和SSH连接）；
+--file 从 JSON 文件读取模板内容，- 表示从标准输入读取。
+--name 设置生成的配置名称，可以使用 {{template}}、{{ssh.name}}、{{ssh.address}}、{{ssh.port}}、{{ssh.username}}。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		template := &models.RsyncConfigTemplate{
+			Name:        strings.TrimSpace(args[0]),
+			Description: strings.TrimSpace(templateDescription),
+			NamePattern: strings.TrimSpace(templateNamePattern),
+		}
+
+		var err error
+		switch {
+		case templateFrom != "" && templateFile != "":
+			err = fmt.Errorf("--from 和 --file 只能使用一个")
+		case templateFrom != "":
+			err = services.CreateConfigTemplateFromConfig(templateFrom, template)
+		case templateFile != "":
+			template.Config, err = readTemplateFile(templateFile)
+			if err == nil {
+				err = services.SaveConfigTemplate(template)
+			}
+		default:
+			err = fmt.Errorf("请使用 --from 指定rsync配置，或 --file 指定模板文件")
+		}
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("已保存配置模板 '%s'（%s）\n", template.Name, strings.Join(template.Fields(), ", "))
+	},
+}
+
+// readTemplateFile 读取模板内容，- 表示标准输入
+func readTemplateFile(name string) (string, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取模板文件失败: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+var templateDeleteCmd = &cobra.Command{
+	Use:   "delete [模板名称]",
+	Short: "删除配置模板",
+	Long:  `删除配置模板，已生成的配置不受影响`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := services.DeleteConfigTemplate(args[0]); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("已删除配置模板 '%s'\n", args[0])
+	},
+}
+
+func init() {
+	templateSaveCmd.Flags().StringVar(&templateFrom, "from", "", "以已有的rsync配置为基础")
+	templateSaveCmd.Flags().StringVar(&templateFile, "file", "", "从 JSON 文件读取模板内容，- 表示标准输入")
+	templateSaveCmd.Flags().StringVar(&templateDescription, "description", "", "模板描述")
+	templateSaveCmd.Flags().StringVar(&templateNamePattern, "name", "", "生成的配置名称，默认为 "+models.DefaultGenerateNamePattern)
+
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateShowCmd)
+	templateCmd.AddCommand(templateSaveCmd)
+	templateCmd.AddCommand(templateDeleteCmd)
+}
@@ -22,6 +22,11 @@ var deleteCmd = &cobra.Command{
 			return
 		}
 
+		if err := services.AutoBackup("delete"); err != nil {
+			fmt.Printf("删除失败: %v\n", err)
+			return
+		}
+
 		err = services.DeleteRsyncConfig(configName)
 		if err != nil {
 			fmt.Printf("删除失败: %v\n", err)
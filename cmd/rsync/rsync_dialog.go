@@ -0,0 +1,74 @@
+package rsync
+
+import (
+	"alfred-tool/dialog"
+	"alfred-tool/dialog/field"
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// showPathsDialog 用对话框修改rsync配置的路径，远程路径和目标路径可以浏览所选SSH连接上的目录
+func showPathsDialog(configName string) error {
+	config, err := services.GetRsyncConfigByName(configName)
+	if err != nil {
+		return fmt.Errorf("未找到rsync配置 '%s'", configName)
+	}
+
+	remote := config.Direction == models.RsyncDirectionRemote
+	var fields []field.Field
+	remoteLabel := "远程路径"
+	if remote {
+		remoteLabel = "源路径"
+	} else {
+		fields = append(fields, field.NewFolderField("localPath", "本地路径", field.WithDefaultValue(config.LocalPath)))
+	}
+	fields = append(fields, field.NewRemoteFolderField("remotePath", remoteLabel, services.RemoteBrowseCommand(config.SSHName),
+		field.WithDefaultValue(config.RemotePath), field.WithNote("SSH连接: "+config.SSHName)))
+	if remote {
+		fields = append(fields, field.NewRemoteFolderField("targetPath", "目标路径", services.RemoteBrowseCommand(config.TargetSSHName),
+			field.WithDefaultValue(config.TargetPath), field.WithNote("SSH连接: "+config.TargetSSHName)))
+	}
+
+	d := dialog.NewDialog(
+		dialog.WithTitle("修改 "+config.Name+" 的路径"),
+		dialog.WithSize(600, 300),
+		dialog.WithOkLabel("保存"),
+		dialog.WithCancelLabel("取消"),
+		dialog.WithAlwaysOnTop(true),
+		dialog.WithFields(fields...),
+	)
+	result, err := d.Open()
+	if err != nil {
+		return fmt.Errorf("打开对话框失败: %v", err)
+	}
+
+	if !remote {
+		config.LocalPath = strings.TrimSpace(getStringValue(result, "localPath"))
+	}
+	config.RemotePath = strings.TrimSpace(getStringValue(result, "remotePath"))
+	if remote {
+		config.TargetPath = strings.TrimSpace(getStringValue(result, "targetPath"))
+	}
+	if config.RemotePath == "" || (!remote && config.LocalPath == "") {
+		return errors.New("路径不能为空")
+	}
+	if err := config.ValidateRemoteTransfer(); err != nil {
+		return err
+	}
+	if err := services.UpdateRsyncConfig(config); err != nil {
+		return fmt.Errorf("保存rsync配置失败: %v", err)
+	}
+	fmt.Printf("rsync配置 '%s' 的路径已更新\n", config.Name)
+	return nil
+}
+
+// getStringValue 对话框结果中的字符串值
+func getStringValue(result map[string]any, key string) string {
+	if val, ok := result[key].(string); ok {
+		return val
+	}
+	return ""
+}
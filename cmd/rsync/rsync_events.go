@@ -0,0 +1,103 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// jsonEventHandler 每个事件输出一行 JSON，供 Alfred 或其他程序读取
+func jsonEventHandler() services.RsyncEventHandler {
+	encoder := json.NewEncoder(os.Stdout)
+	return func(event models.RsyncEvent) {
+		encoder.Encode(event)
+	}
+}
+
+// printEngineInfo 输出使用的传输引擎和被忽略的选项
+func printEngineInfo(info models.TransferEngineInfo) {
+	fmt.Printf("传输引擎: %s\n", info)
+	if len(info.Ignored) > 0 {
+		fmt.Printf("sftp 引擎忽略的选项: %s\n", strings.Join(info.Ignored, ", "))
+	}
+}
+
+// terminalEventHandler 在终端中显示逐个文件和单行刷新的进度
+func terminalEventHandler() services.RsyncEventHandler {
+	progressShown := false
+	clearProgress := func() {
+		if progressShown {
+			fmt.Print("\r\033[K")
+			progressShown = false
+		}
+	}
+
+	return func(event models.RsyncEvent) {
+		switch event.Type {
+		case models.RsyncEventStart:
+			if event.Engine != nil {
+				printEngineInfo(*event.Engine)
+			}
+			fmt.Printf("执行命令: %s\n", models.ShellJoin(event.Command))
+		case models.RsyncEventFile:
+			clearProgress()
+			fmt.Printf("%-6s %s\n", changeKindNames[event.File.Kind], changeDisplayPath(*event.File))
+		case models.RsyncEventProgress:
+			p := event.Progress
+			fmt.Printf("\r\033[K%3d%%  %s  %s/s  剩余 %s",
+				p.Percent,
+				models.FormatBytes(p.Bytes),
+				models.FormatBytes(int64(p.Rate)),
+				time.Duration(p.ETASeconds)*time.Second,
+			)
+			progressShown = true
+		case models.RsyncEventHook:
+			clearProgress()
+			h := event.Hook
+			icon := "✅"
+			if h.Error != "" {
+				icon = "❌"
+			}
+			fmt.Printf("%s 钩子 %s（%s）\n", icon, h.RsyncHook, time.Duration(h.DurationSeconds*float64(time.Second)).Round(time.Millisecond))
+		case models.RsyncEventConflict:
+			clearProgress()
+			c := *event.Conflict
+			fmt.Printf("⚠️  冲突 %s: %s → %s\n", c.Path, conflictText(c), resolutionText(c))
+		case models.RsyncEventDeleteGuard:
+			clearProgress()
+			d := *event.DeleteGuard
+			icon := "🛡️ "
+			if !d.Allowed || d.Forced {
+				icon = "⚠️ "
+			}
+			fmt.Printf("%s删除保护: %s\n", icon, d)
+		case models.RsyncEventResult:
+			clearProgress()
+			r := event.Result
+			fmt.Printf("传输 %d 个文件（%s），源文件共 %s，加速比 %.2f，用时 %s\n",
+				r.FilesTransferred,
+				models.FormatBytes(r.TransferredSize),
+				models.FormatBytes(r.TotalSize),
+				r.Speedup,
+				r.Duration.Round(time.Millisecond),
+			)
+			if r.Conflicts > 0 {
+				fmt.Printf("⚠️  %d 个冲突未解决，两端保持不变，请手动处理后再同步\n", r.Conflicts)
+			}
+			if v := r.Verify; v != nil {
+				icon := "✅"
+				if !v.Passed || v.Error != "" {
+					icon = "❌"
+				}
+				fmt.Printf("%s %s\n", icon, v)
+				if v.ReportPath != "" && !v.Passed {
+					fmt.Printf("校验报告: %s\n", v.ReportPath)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var filterCmd = &cobra.Command{
+	Use:   "filter",
+	Short: "查看和测试rsync配置的过滤规则",
+	Long: `查看和测试rsync配置的过滤规则
+
+规则按以下顺序检查，第一条匹配的规则生效：
+1. 过滤规则（+ 包含、- 排除、P 保护、. 合并文件）
+2. 排除规则
+3. 开启忽略文件时，本地路径各级目录中的 .gitignore 和 .rsyncignore（深层目录优先）`,
+}
+
+var filterListCmd = &cobra.Command{
+	Use:   "list [配置名称]",
+	Short: "按生效顺序列出配置的全部规则",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := services.GetRsyncConfigByName(args[0])
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		ignoreRules, err := services.RsyncIgnoreRules(config)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		rules := config.OrderedFilterRules(ignoreRules)
+		if len(rules) == 0 {
+			fmt.Println("没有过滤规则，所有文件都会被传输")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\t规则\t来源")
+		fmt.Fprintln(w, "-\t----\t----")
+		for i, rule := range rules {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, rule, rule.Source)
+		}
+		w.Flush()
+	},
+}
+
+var filterTestCmd = &cobra.Command{
+	Use:   "test [配置名称] [路径]",
+	Short: "测试路径是否会被传输",
+	Long: `测试路径是否会被传输，并显示决定结果的规则
+
+路径可以是本地路径中的文件，也可以是相对于本地路径的路径（以 / 结尾表示目录），
+如: rsync filter test "my-app" logs/debug.log`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		check, err := services.TestRsyncFilter(args[0], args[1])
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		display := check.Path
+		if check.IsDir {
+			display += "/"
+		}
+		if check.Transfer.Excluded {
+			fmt.Printf("❌ '%s' 不会被传输\n", display)
+		} else {
+			fmt.Printf("✅ '%s' 会被传输\n", display)
+		}
+		printFilterResult(check.Transfer)
+
+		if check.Protected.Excluded && check.Protected.Rule.Action == models.FilterProtect {
+			fmt.Printf("🛡️  目标中的 '%s' 受保护，不会被 --delete 删除\n", display)
+			printFilterResult(check.Protected)
+		}
+	},
+}
+
+// printFilterResult 显示决定结果的规则
+func printFilterResult(result models.FilterResult) {
+	if result.Rule == nil {
+		fmt.Println("   没有匹配的规则")
+		return
+	}
+	fmt.Printf("   匹配规则: %s（%s）\n", result.Rule, result.Rule.Source)
+	if result.Excluded {
+		fmt.Printf("   匹配路径: %s\n", result.Path)
+	}
+}
+
+func init() {
+	filterCmd.AddCommand(filterListCmd)
+	filterCmd.AddCommand(filterTestCmd)
+}
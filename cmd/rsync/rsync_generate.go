@@ -0,0 +1,111 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	generateTemplate    string
+	generateHosts       string
+	generateNamePattern string
+	generateDryRun      bool
+	generateYes         bool
+	generateFormat      string
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "按配置模板为多个SSH连接批量生成rsync配置",
+	Long: `为每个匹配 --hosts 的SSH连接按模板生成一个rsync配置，先显示预览，确认后再写入
+
+--hosts 以逗号分隔，满足任一条件即可：tag:标签 按SSH连接的标签匹配，name:模式 或直接写模式按名称通配符匹配，
+如 tag:web、web-*、tag:web,db-01。
+--name 设置生成的配置名称，默认使用模板中的设置或 {{template}}-{{ssh.name}}。
+同名配置已存在或配置无效时跳过，其余配置在同一事务中创建。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		plan, err := services.PlanRsyncGenerate(generateTemplate, generateHosts, generateNamePattern)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		switch generateFormat {
+		case previewFormatTable:
+			printGeneratePlan(plan)
+		case previewFormatJSON:
+			if err := printJSON(plan); err != nil {
+				fmt.Printf("错误: %v\n", err)
+			}
+		default:
+			fmt.Printf("错误: 未知的输出格式: %s（可选: table, json）\n", generateFormat)
+			return
+		}
+		if generateDryRun || plan.Creatable() == 0 {
+			return
+		}
+
+		if !generateYes {
+			fmt.Printf("\n创建 %d 个rsync配置? (y/N): ", plan.Creatable())
+			reader := bufio.NewReader(os.Stdin)
+			response, _ := reader.ReadString('\n')
+			response = strings.ToLower(strings.TrimSpace(response))
+			if response != "y" && response != "yes" {
+				fmt.Println("取消创建")
+				return
+			}
+		}
+
+		created, err := services.ApplyRsyncGenerate(plan)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("已创建 %d 个rsync配置\n", created)
+	},
+}
+
+func printGeneratePlan(plan *services.GeneratePlan) {
+	fmt.Printf("模板: %s，主机: %s，名称: %s\n\n", plan.Template, plan.Hosts, plan.NamePattern)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "状态\t名称\tSSH连接\t路径")
+	fmt.Fprintln(w, "----\t----\t-------\t----")
+	for _, generated := range plan.Configs {
+		config := generated.Config
+		status := "新建"
+		switch {
+		case generated.Exists:
+			status = "已存在，跳过"
+		case generated.Error != "":
+			status = "错误: " + generated.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status, config.Name, config.SSHName, generatePaths(config))
+	}
+	w.Flush()
+}
+
+// generatePaths 返回预览中显示的路径
+func generatePaths(config *models.RsyncConfig) string {
+	if config.Direction == models.RsyncDirectionRemote {
+		return fmt.Sprintf("%s → %s:%s", config.RemotePath, config.TargetSSHName, config.TargetPath)
+	}
+	return fmt.Sprintf("%s <-> %s", config.LocalPath, config.RemotePath)
+}
+
+func init() {
+	generateCmd.Flags().StringVarP(&generateTemplate, "template", "t", "", "配置模板名称")
+	generateCmd.Flags().StringVar(&generateHosts, "hosts", "", "匹配的SSH连接，如 tag:web 或 web-*")
+	generateCmd.Flags().StringVar(&generateNamePattern, "name", "", "生成的配置名称，可以使用 {{template}} 和 {{ssh.name}} 等变量")
+	generateCmd.Flags().BoolVar(&generateDryRun, "dry-run", false, "只显示预览，不创建配置")
+	generateCmd.Flags().BoolVarP(&generateYes, "yes", "y", false, "不确认直接创建")
+	generateCmd.Flags().StringVarP(&generateFormat, "format", "f", previewFormatTable, "预览输出格式: table, json")
+	generateCmd.MarkFlagRequired("template")
+	generateCmd.MarkFlagRequired("hosts")
+}
@@ -0,0 +1,185 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	groupMode          string
+	groupConcurrency   int
+	groupStopOnFailure bool
+	groupDescription   string
+)
+
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "管理rsync分组",
+	Long: `把多个rsync配置组成分组，一次执行整个分组
+
+- 顺序执行（sequential）：按添加的顺序逐个执行
+- 并行执行（parallel）：同时执行，--concurrency 限制同时执行的数量
+- --stop-on-failure：有配置失败后不再开始执行其余配置`,
+}
+
+var groupListCmd = &cobra.Command{
+	Use:   "list [搜索关键词]",
+	Short: "列出rsync分组",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var query string
+		if len(args) > 0 {
+			query = args[0]
+		}
+		groups, err := services.SearchRsyncGroups(query)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		alfredData := models.AlfredData{
+			Items: lo.Map(groups, func(group models.RsyncGroup, index int) models.AlfredItem {
+				title := fmt.Sprintf("⇶ %s", group.Name)
+				configs, missing, err := services.GetRsyncGroupConfigs(&group)
+				var subtitle string
+				if err != nil {
+					subtitle = err.Error()
+				} else {
+					names := lo.Map(configs, func(config models.RsyncConfig, index int) string { return config.Name })
+					subtitle = fmt.Sprintf("%s: %s", group.ModeText(), truncateString(strings.Join(names, ", "), 60))
+					if missing > 0 {
+						title = "⚠️ " + title
+						subtitle += fmt.Sprintf(" | %d 个配置已被删除", missing)
+					}
+				}
+				if group.Description != "" {
+					subtitle += fmt.Sprintf(" - %s", truncateString(group.Description, 30))
+				}
+
+				return models.AlfredItem{
+					Uid:       group.Name,
+					Title:     title,
+					Subtitle:  subtitle,
+					Arg:       group.GetArg(),
+					Variables: group.GetVariables(),
+				}
+			}),
+		}
+		marshal, err := json.Marshal(alfredData)
+		if err != nil {
+			fmt.Printf("JSON序列化失败: %v\n", err)
+			return
+		}
+		fmt.Println(string(marshal))
+	},
+}
+
+var groupAddCmd = &cobra.Command{
+	Use:   "add [分组名称] [配置名称...]",
+	Short: "添加rsync分组",
+	Long:  `添加rsync分组，配置按给出的顺序执行，如: rsync group add "pull-db" db1 db2 db3 --mode parallel --concurrency 2`,
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, err := models.ParseRsyncGroupMode(groupMode)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		group := &models.RsyncGroup{
+			Name:          args[0],
+			Mode:          mode,
+			Concurrency:   groupConcurrency,
+			StopOnFailure: groupStopOnFailure,
+			Description:   groupDescription,
+		}
+		if err := services.SaveRsyncGroup(group, args[1:]); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("rsync分组 '%s' 已添加\n", group.Name)
+	},
+}
+
+var groupUpdateCmd = &cobra.Command{
+	Use:   "update [分组名称] [配置名称...]",
+	Short: "修改rsync分组",
+	Long: `修改rsync分组，给出配置名称时按新的顺序替换分组中的配置，只修改指定的选项
+
+如: rsync group update "pull-db" --mode sequential --stop-on-failure`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		group, err := services.GetRsyncGroupByName(args[0])
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		flags := cmd.Flags()
+		if flags.Changed("mode") {
+			mode, err := models.ParseRsyncGroupMode(groupMode)
+			if err != nil {
+				fmt.Printf("错误: %v\n", err)
+				return
+			}
+			group.Mode = mode
+		}
+		if flags.Changed("concurrency") {
+			group.Concurrency = groupConcurrency
+		}
+		if flags.Changed("stop-on-failure") {
+			group.StopOnFailure = groupStopOnFailure
+		}
+		if flags.Changed("description") {
+			group.Description = groupDescription
+		}
+		if flags.Changed("name") {
+			group.Name, _ = flags.GetString("name")
+		}
+
+		var configNames []string
+		if len(args) > 1 {
+			configNames = args[1:]
+		}
+		if err := services.SaveRsyncGroup(group, configNames); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("rsync分组 '%s' 已更新\n", group.Name)
+	},
+}
+
+var groupDeleteCmd = &cobra.Command{
+	Use:   "delete [分组名称]",
+	Short: "删除rsync分组",
+	Long:  `删除rsync分组，分组中的rsync配置不会被删除`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := services.DeleteRsyncGroup(args[0]); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("rsync分组 '%s' 已删除\n", args[0])
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{groupAddCmd, groupUpdateCmd} {
+		c.Flags().StringVarP(&groupMode, "mode", "m", string(models.RsyncGroupSequential), "执行方式: sequential, parallel")
+		c.Flags().IntVarP(&groupConcurrency, "concurrency", "c", 0, "并行执行时最多同时执行的配置数，0 表示不限制")
+		c.Flags().BoolVar(&groupStopOnFailure, "stop-on-failure", false, "有配置失败后不再开始执行其余配置")
+		c.Flags().StringVarP(&groupDescription, "description", "d", "", "分组描述")
+	}
+	groupUpdateCmd.Flags().String("name", "", "新的分组名称")
+
+	groupCmd.AddCommand(groupListCmd)
+	groupCmd.AddCommand(groupAddCmd)
+	groupCmd.AddCommand(groupUpdateCmd)
+	groupCmd.AddCommand(groupDeleteCmd)
+	groupCmd.AddCommand(groupRunCmd)
+}
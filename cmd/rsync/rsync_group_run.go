@@ -0,0 +1,145 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var groupJSONEvents bool
+
+var groupRunCmd = &cobra.Command{
+	Use:   "run [分组名称]",
+	Short: "执行rsync分组",
+	Long: `按分组的执行方式执行其中的所有rsync配置，结束后显示汇总
+
+每个配置都会记录在执行记录中（触发方式为 group）。
+--json-events 把执行过程输出为逐行 JSON 事件，每行带有配置名称和分组的整体进度，
+最后一行为 type 为 group_result 的汇总。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		groupName := args[0]
+
+		if groupJSONEvents {
+			encoder := json.NewEncoder(os.Stdout)
+			result, err := services.RunRsyncGroup(groupName, func(event services.RsyncGroupEvent) {
+				encoder.Encode(event)
+			})
+			summary := struct {
+				Type   string                   `json:"type"`
+				Result *models.RsyncGroupResult `json:"result,omitempty"`
+				Error  string                   `json:"error,omitempty"`
+			}{Type: "group_result", Result: result}
+			if err != nil {
+				summary.Error = err.Error()
+			}
+			encoder.Encode(summary)
+			if err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+
+		fmt.Printf("开始执行rsync分组: %s\n", groupName)
+		status := &groupStatus{}
+		result, err := services.RunRsyncGroup(groupName, status.handle)
+		status.clear()
+		if result != nil {
+			printGroupSummary(result)
+		}
+		if err != nil {
+			fmt.Printf("执行失败: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// groupStatus 每个配置开始和结束时单独输出一行，底部维护一行刷新的整体进度
+type groupStatus struct {
+	shown bool
+}
+
+func (s *groupStatus) handle(event services.RsyncGroupEvent) {
+	switch event.Event.Type {
+	case models.RsyncEventStart:
+		s.clear()
+		fmt.Printf("▶️  %s 开始执行\n", event.Config)
+	case models.RsyncEventHook:
+		s.clear()
+		h := event.Event.Hook
+		if h.Error != "" {
+			fmt.Printf("❌ %s 钩子 %s 失败\n", event.Config, h.RsyncHook)
+		}
+	case models.RsyncEventResult:
+		s.clear()
+		r := event.Event.Result
+		if r.Error != "" {
+			fmt.Printf("❌ %s 执行失败: %s\n", event.Config, r.Error)
+		} else {
+			fmt.Printf("✅ %s 传输 %d 个文件（%s），用时 %s\n",
+				event.Config, r.FilesTransferred, models.FormatBytes(r.TransferredSize), r.Duration.Round(time.Millisecond))
+		}
+	}
+	s.show(event.Progress)
+}
+
+// show 刷新整体进度行
+func (s *groupStatus) show(p services.RsyncGroupProgress) {
+	line := fmt.Sprintf("%3d%%  完成 %d/%d", p.Percent, p.Done, p.Total)
+	if len(p.Running) > 0 {
+		line += "  执行中: " + truncateString(strings.Join(p.Running, ", "), 50)
+	}
+	fmt.Printf("\r\033[K%s", line)
+	s.shown = true
+}
+
+// clear 清除进度行，之后的输出从行首开始
+func (s *groupStatus) clear() {
+	if s.shown {
+		fmt.Print("\r\033[K")
+		s.shown = false
+	}
+}
+
+var groupItemStatusNames = map[models.RsyncGroupItemStatus]string{
+	models.RsyncGroupItemSuccess: "✅ 成功",
+	models.RsyncGroupItemFailed:  "❌ 失败",
+	models.RsyncGroupItemSkipped: "⏭️  跳过",
+}
+
+// printGroupSummary 以表格显示分组中各配置的结果和汇总
+func printGroupSummary(result *models.RsyncGroupResult) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "配置\t状态\t文件数\t传输大小\t用时\t错误")
+	fmt.Fprintln(w, "----\t----\t------\t--------\t----\t----")
+	for _, item := range result.Items {
+		files, size, duration := "", "", ""
+		if item.Result != nil {
+			files = fmt.Sprintf("%d", item.Result.FilesTransferred)
+			size = models.FormatBytes(item.Result.TransferredSize)
+			duration = item.Result.Duration.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			item.ConfigName, groupItemStatusNames[item.Status], files, size, duration, truncateString(item.Error, 60))
+	}
+	w.Flush()
+
+	fmt.Printf("\n分组 '%s': 成功 %d，失败 %d，跳过 %d，共传输 %d 个文件（%s），用时 %s\n",
+		result.GroupName, result.Succeeded, result.Failed, result.Skipped,
+		result.FilesTransferred, models.FormatBytes(result.TransferredSize), result.Duration.Round(time.Millisecond))
+	if result.Missing > 0 {
+		fmt.Printf("⚠️  分组中有 %d 个配置已被删除，请用 rsync group update 更新分组\n", result.Missing)
+	}
+}
+
+func init() {
+	groupRunCmd.Flags().BoolVar(&groupJSONEvents, "json-events", false, "以逐行 JSON 输出执行事件")
+}
@@ -0,0 +1,79 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var historyLimit int
+
+var historyCmd = &cobra.Command{
+	Use:   "history [配置名称]",
+	Short: "查看rsync执行记录",
+	Long: `列出rsync配置的执行记录，包括开始时间、用时、是否成功和传输的文件，从新到旧排列
+
+不指定配置名称时列出所有配置的执行记录。`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var configName string
+		if len(args) > 0 {
+			configName = args[0]
+		}
+
+		runs, err := services.GetRsyncRuns(configName, historyLimit)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		alfredData := models.AlfredData{
+			Items: lo.Map(runs, func(run models.RsyncRun, index int) models.AlfredItem {
+				id := strconv.FormatUint(uint64(run.ID), 10)
+				return models.AlfredItem{
+					Uid:      id,
+					Title:    fmt.Sprintf("%s %s  %s", run.StatusIcon(), run.ConfigName, run.StartedAt.Local().Format("2006-01-02 15:04:05")),
+					Subtitle: runSummary(run),
+					Arg:      []string{id},
+					Variables: map[string]string{
+						"rsync_run_id":   id,
+						"rsync_name":     run.ConfigName,
+						"rsync_log_path": run.LogPath,
+					},
+				}
+			}),
+		}
+		marshal, err := json.Marshal(alfredData)
+		if err != nil {
+			fmt.Printf("JSON序列化失败: %v\n", err)
+			return
+		}
+		fmt.Println(string(marshal))
+	},
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 50, "最多显示的记录数，0 表示全部")
+}
+
+// runSummary 执行记录的一行摘要
+func runSummary(run models.RsyncRun) string {
+	if !run.Finished() {
+		return fmt.Sprintf("#%d 未结束（仍在执行或被中断）", run.ID)
+	}
+	summary := fmt.Sprintf("#%d 传输 %d 个文件（%s），用时 %s",
+		run.ID, run.FilesTransferred, models.FormatBytes(run.TransferredSize), run.Duration().Round(time.Second))
+	switch {
+	case run.ExitCode == models.RsyncRunExitCodeHookFailed, run.ExitCode == models.RsyncRunExitCodeDeleteGuard:
+		summary = fmt.Sprintf("#%d %s", run.ID, truncateString(run.Error, 80))
+	case !run.Succeeded():
+		summary = fmt.Sprintf("#%d 退出码 %d: %s", run.ID, run.ExitCode, truncateString(run.Error, 60))
+	}
+	return summary
+}
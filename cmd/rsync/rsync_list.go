@@ -25,22 +25,54 @@ var listCmd = &cobra.Command{
 }
 
 func displayRsyncConfigs(configs []models.RsyncConfig) {
+	connectionNames, err := services.GetConnectionNameSet()
+	if err != nil {
+		fmt.Printf("错误: %v\n", err)
+		return
+	}
+	lastRuns, err := services.GetLastRsyncRuns()
+	if err != nil {
+		fmt.Printf("错误: %v\n", err)
+		return
+	}
+
 	alfredData := models.AlfredData{
 		Items: lo.Map(configs, func(item models.RsyncConfig, index int) models.AlfredItem {
 			direction := "↑"
 			directionText := "上传"
-			if item.Direction == models.RsyncDirectionDownload {
+			switch item.Direction {
+			case models.RsyncDirectionDownload:
 				direction = "↓"
 				directionText = "下载"
+			case models.RsyncDirectionBidirectional:
+				direction = "⇅"
+				directionText = "双向"
 			}
 
 			title := fmt.Sprintf("%s %s [%s]", direction, item.Name, item.SSHName)
 			subtitle := fmt.Sprintf("%s: %s ↔ %s", directionText, truncateString(item.LocalPath, 25), truncateString(item.RemotePath, 25))
+			if item.Direction == models.RsyncDirectionRemote {
+				title = fmt.Sprintf("⇒ %s [%s → %s]", item.Name, item.SSHName, item.TargetSSHName)
+				subtitle = fmt.Sprintf("服务器之间: %s → %s", truncateString(item.RemotePath, 25), truncateString(item.TargetPath, 25))
+			}
 
 			if item.Description != "" {
 				subtitle += fmt.Sprintf(" - %s", truncateString(item.Description, 30))
 			}
 
+			if run, ok := lastRuns[item.ID]; ok {
+				subtitle += fmt.Sprintf(" | 上次运行: %s %s", run.StatusIcon(), run.StartedAt.Local().Format("01-02 15:04"))
+			}
+
+			// 关联的SSH连接已不存在
+			if !connectionNames[item.SSHName] {
+				title = "⚠️ " + title
+				subtitle = fmt.Sprintf("连接已失效: SSH连接 '%s' 不存在", item.SSHName)
+			} else if item.TargetSSHName != "" && !connectionNames[item.TargetSSHName] {
+				title = "⚠️ " + title
+				subtitle = fmt.Sprintf("连接已失效: 目标SSH连接 '%s' 不存在", item.TargetSSHName)
+			}
+
 			return models.AlfredItem{
 				Uid:       item.Name,
 				Title:     title,
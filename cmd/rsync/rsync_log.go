@@ -0,0 +1,74 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log [执行记录ID]",
+	Short: "查看rsync执行日志",
+	Long:  `显示某次rsync执行的命令、结果和完整输出，执行记录ID可通过 rsync history 查看`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			fmt.Printf("错误: 无效的执行记录ID: %s\n", args[0])
+			return
+		}
+
+		run, err := services.GetRsyncRun(uint(id))
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		fmt.Printf("配置: %s\n", run.ConfigName)
+		fmt.Printf("开始: %s\n", run.StartedAt.Local().Format("2006-01-02 15:04:05"))
+		if run.Finished() {
+			fmt.Printf("结束: %s（用时 %s）\n", run.EndedAt.Local().Format("2006-01-02 15:04:05"), run.Duration().Round(time.Millisecond))
+			fmt.Printf("退出码: %d\n", run.ExitCode)
+		} else {
+			fmt.Println("状态: 未结束（仍在执行或被中断）")
+		}
+		if run.Trigger != "" {
+			fmt.Printf("触发方式: %s\n", run.Trigger)
+		}
+		fmt.Printf("命令: %s\n", run.Command)
+		if run.Engine == models.TransferEngineSFTP {
+			fmt.Println("传输引擎: sftp")
+		}
+		if run.Snapshot != "" {
+			fmt.Printf("快照: %s\n", run.Snapshot)
+		}
+		if run.DeleteGuard != "" {
+			fmt.Printf("删除保护: %s\n", run.DeleteGuard)
+		}
+		fmt.Printf("结果: %s\n", runSummary(*run))
+		if run.Speedup > 0 {
+			fmt.Printf("源文件共 %d 字节，加速比 %.2f\n", run.TotalSize, run.Speedup)
+		}
+		if run.Verify != "" {
+			fmt.Printf("校验: %s\n", run.Verify)
+		}
+		if run.VerifyReportPath != "" {
+			fmt.Printf("校验报告: %s\n", run.VerifyReportPath)
+		}
+
+		if run.LogPath == "" {
+			return
+		}
+		content, err := os.ReadFile(run.LogPath)
+		if err != nil {
+			fmt.Printf("\n读取日志失败: %v\n", err)
+			return
+		}
+		fmt.Printf("\n日志 (%s):\n%s", run.LogPath, content)
+	},
+}
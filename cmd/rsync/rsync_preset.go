@@ -0,0 +1,75 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var presetCmd = &cobra.Command{
+	Use:   "preset",
+	Short: "rsync选项预设",
+	Long: `常见场景的一组选项，应用到配置时只修改预设涉及的选项
+
+- slow-link（慢速网络）：最高压缩级别，中断后从中断处继续，5 分钟没有数据时超时
+- background（后台传输）：限速 1M/s，保留部分传输的文件，10 分钟没有数据时超时
+- mirror（镜像）：删除多余的文件，被覆盖或删除的文件备份到目标路径下的 .rsync-backup/日期
+- deploy（部署）：按内容比较，删除旧文件，不保留部分传输的文件，1 分钟没有响应时失败`,
+}
+
+var presetListCmd = &cobra.Command{
+	Use:   "list [配置名称]",
+	Short: "列出rsync选项预设",
+	Long:  `以 Alfred JSON 列出预设，给出配置名称时 arg 为应用预设所需的 配置名称 和 预设名称`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		alfredData := models.AlfredData{
+			Items: lo.Map(models.RsyncPresets, func(preset models.RsyncPreset, index int) models.AlfredItem {
+				arg := []string{preset.Name}
+				if len(args) > 0 {
+					arg = []string{args[0], preset.Name}
+				}
+				return models.AlfredItem{
+					Uid:      preset.Name,
+					Title:    fmt.Sprintf("%s (%s)", preset.Label, preset.Name),
+					Subtitle: preset.Description,
+					Arg:      arg,
+				}
+			}),
+		}
+		marshal, err := json.Marshal(alfredData)
+		if err != nil {
+			fmt.Printf("JSON序列化失败: %v\n", err)
+			return
+		}
+		fmt.Println(string(marshal))
+	},
+}
+
+var presetApplyCmd = &cobra.Command{
+	Use:   "apply [配置名称] [预设名称]",
+	Short: "把预设应用到rsync配置",
+	Long:  `把预设的选项写入已保存的rsync配置，如: rsync preset apply "vpn-backup" slow-link`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := services.ApplyRsyncPreset(args[0], args[1])
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		preset, _ := models.GetRsyncPreset(args[1])
+		fmt.Printf("已把预设 '%s' 应用到rsync配置 '%s'\n", preset.Label, config.Name)
+		if limits := config.LimitsText(); limits != "" {
+			fmt.Printf("传输限制: %s\n", limits)
+		}
+	},
+}
+
+func init() {
+	presetCmd.AddCommand(presetListCmd)
+	presetCmd.AddCommand(presetApplyCmd)
+}
@@ -0,0 +1,120 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/samber/lo"
+)
+
+// 预览输出格式
+const (
+	previewFormatTable  = "table"
+	previewFormatJSON   = "json"
+	previewFormatAlfred = "alfred"
+)
+
+var changeKindNames = map[models.ItemizedChangeKind]string{
+	models.ItemizedChangeNew:       "新增",
+	models.ItemizedChangeUpdated:   "更新",
+	models.ItemizedChangeDeleted:   "删除",
+	models.ItemizedChangeAttribute: "仅属性",
+}
+
+// printPreview 按指定格式输出预览结果
+func printPreview(preview *services.RsyncPreview, format string) error {
+	switch format {
+	case previewFormatTable:
+		printPreviewTable(preview)
+	case previewFormatJSON:
+		return printJSON(preview)
+	case previewFormatAlfred:
+		return printJSON(previewAlfredData(preview))
+	default:
+		return fmt.Errorf("未知的输出格式: %s（可选: table, json, alfred）", format)
+	}
+	return nil
+}
+
+func printJSON(v any) error {
+	marshal, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	fmt.Println(string(marshal))
+	return nil
+}
+
+func printPreviewTable(preview *services.RsyncPreview) {
+	if preview.Engine.Engine == models.TransferEngineSFTP {
+		printEngineInfo(preview.Engine)
+	}
+	fmt.Printf("预览命令: %s\n\n", models.ShellJoin(preview.Command))
+
+	if len(preview.Changes) == 0 {
+		fmt.Println("没有需要同步的变更")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "类型\t大小\t路径")
+	fmt.Fprintln(w, "----\t----\t----")
+	for _, change := range preview.Changes {
+		size := ""
+		if change.FileType == "f" && change.Kind != models.ItemizedChangeDeleted {
+			size = models.FormatBytes(change.Size)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", changeKindNames[change.Kind], size, changeDisplayPath(change))
+	}
+	w.Flush()
+
+	fmt.Printf("\n%s\n", summaryText(preview.Summary))
+}
+
+func previewAlfredData(preview *services.RsyncPreview) models.AlfredData {
+	summary := models.AlfredItem{
+		Uid:      preview.ConfigName,
+		Title:    fmt.Sprintf("执行 %s", preview.ConfigName),
+		Subtitle: summaryText(preview.Summary),
+		Arg:      []string{preview.ConfigName},
+	}
+	if len(preview.Changes) == 0 {
+		summary.Subtitle = "没有需要同步的变更"
+	}
+
+	items := lo.Map(preview.Changes, func(change models.ItemizedChange, index int) models.AlfredItem {
+		subtitle := changeKindNames[change.Kind]
+		if change.FileType == "f" && change.Kind != models.ItemizedChangeDeleted {
+			subtitle += " · " + models.FormatBytes(change.Size)
+		}
+		return models.AlfredItem{
+			Uid:      change.Path,
+			Title:    changeDisplayPath(change),
+			Subtitle: subtitle,
+			Arg:      []string{preview.ConfigName},
+		}
+	})
+	return models.AlfredData{Items: append([]models.AlfredItem{summary}, items...)}
+}
+
+func changeDisplayPath(change models.ItemizedChange) string {
+	if change.FileType == "L" && change.Target != "" {
+		return change.Path + " -> " + change.Target
+	}
+	return change.Path
+}
+
+func summaryText(summary models.ItemizedSummary) string {
+	parts := []string{
+		fmt.Sprintf("新增 %d", summary.New),
+		fmt.Sprintf("更新 %d", summary.Updated),
+		fmt.Sprintf("删除 %d", summary.Deleted),
+		fmt.Sprintf("仅属性 %d", summary.Attribute),
+	}
+	return fmt.Sprintf("%s，需传输 %s", strings.Join(parts, "，"), models.FormatBytes(summary.Bytes))
+}
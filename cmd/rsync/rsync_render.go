@@ -0,0 +1,111 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	renderVars   []string
+	renderFormat string
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render [配置名称]",
+	Short: "显示替换变量后的配置和rsync命令",
+	Long: `替换配置中的变量，显示用到的变量及其来源、替换后的路径和将要执行的rsync命令，不执行任何命令
+
+本地路径、远程路径、目标路径、排除规则、过滤规则和额外选项中可以使用 {{名称}}，按以下顺序取值：
+1. 内置变量：name、date、time、datetime、year、month、day、timestamp、home、
+   ssh.name、ssh.address、ssh.port、ssh.username（服务器之间传输时还有 target.*）
+2. --var 名称=值
+3. 同名的环境变量（Alfred 工作流变量以环境变量传入）
+4. 配置中设置的默认值`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vars, err := parseVarFlags(renderVars)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		render, err := services.RenderRsyncConfig(args[0], vars)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		switch renderFormat {
+		case previewFormatTable:
+			printRender(render)
+		case previewFormatJSON:
+			err = printJSON(render)
+		default:
+			err = fmt.Errorf("未知的输出格式: %s（可选: table, json）", renderFormat)
+		}
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+		}
+	},
+}
+
+func printRender(render *services.RsyncRender) {
+	if len(render.Variables) == 0 {
+		fmt.Println("配置中没有使用变量")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "变量\t值\t来源")
+		fmt.Fprintln(w, "----\t--\t----")
+		for _, v := range render.Variables {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.Name, v.Value, v.Source)
+		}
+		w.Flush()
+	}
+	fmt.Println()
+
+	config := render.Config
+	if config.Direction == models.RsyncDirectionRemote {
+		fmt.Printf("源路径: %s\n", config.RemotePath)
+		fmt.Printf("目标: %s:%s\n", config.TargetSSHName, config.TargetPath)
+	} else {
+		fmt.Printf("本地路径: %s\n", config.LocalPath)
+		fmt.Printf("远程路径: %s\n", config.RemotePath)
+	}
+	if config.ExcludeRules != "" {
+		fmt.Printf("排除规则: %s\n", strings.ReplaceAll(config.ExcludeRules, "\n", ", "))
+	}
+	if config.FilterRules != "" {
+		fmt.Printf("过滤规则: %s\n", strings.ReplaceAll(config.FilterRules, "\n", ", "))
+	}
+	if config.Options != "" {
+		fmt.Printf("额外选项: %s\n", config.Options)
+	}
+
+	fmt.Println("\n命令:")
+	for _, command := range render.Commands {
+		fmt.Printf("  %s\n", models.ShellJoin(command))
+	}
+}
+
+// parseVarFlags 解析 --var 名称=值
+func parseVarFlags(values []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, value := range values {
+		name, v, err := models.ParseTemplateAssignment(value)
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = v
+	}
+	return vars, nil
+}
+
+func init() {
+	renderCmd.Flags().StringArrayVar(&renderVars, "var", nil, "设置配置中的变量，格式为 名称=值，可重复使用")
+	renderCmd.Flags().StringVarP(&renderFormat, "format", "f", previewFormatTable, "输出格式: table, json")
+}
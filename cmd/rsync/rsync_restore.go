@@ -0,0 +1,71 @@
+package rsync
+
+import (
+	"alfred-tool/services"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreDryRun bool
+	restoreYes    bool
+	restoreFormat string
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [配置名称] [快照名称]",
+	Short: "把快照恢复到服务器",
+	Long: `把快照备份中的一个快照上传回配置的远程路径，快照名称可通过 rsync snapshots 查看，latest 表示最新的快照
+
+恢复会覆盖服务器上的文件（配置开启了 --delete 时还会删除快照中没有的文件），
+执行前会先预览将要发生的变更并要求确认，--yes 跳过确认，--dry-run 只预览不执行。`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		configName, snapshotName := args[0], args[1]
+
+		if restoreDryRun || !restoreYes {
+			preview, err := services.PreviewRsyncRestore(configName, snapshotName)
+			if err != nil {
+				fmt.Printf("错误: %v\n", err)
+				return
+			}
+			if restoreDryRun {
+				if err := printPreview(preview, restoreFormat); err != nil {
+					fmt.Printf("错误: %v\n", err)
+				}
+				return
+			}
+
+			printPreviewTable(preview)
+			if len(preview.Changes) == 0 {
+				return
+			}
+			fmt.Printf("\n确认把快照 %s 恢复到服务器? (y/N): ", snapshotName)
+			reader := bufio.NewReader(os.Stdin)
+			response, _ := reader.ReadString('\n')
+			response = strings.ToLower(strings.TrimSpace(response))
+			if response != "y" && response != "yes" {
+				fmt.Println("取消恢复")
+				return
+			}
+		}
+
+		fmt.Printf("开始恢复快照: %s\n", snapshotName)
+		if _, err := services.RestoreRsyncSnapshot(configName, snapshotName, services.RsyncRunOptions{Handler: terminalEventHandler()}); err != nil {
+			fmt.Printf("恢复失败: %v\n", err)
+			return
+		}
+		fmt.Printf("快照 '%s' 已恢复到服务器\n", snapshotName)
+	},
+}
+
+func init() {
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "只预览将要发生的变更，不执行")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "不预览确认，直接恢复")
+	restoreCmd.Flags().StringVarP(&restoreFormat, "format", "f", previewFormatTable, "预览输出格式: table, json, alfred")
+	restoreCmd.MarkFlagsMutuallyExclusive("dry-run", "yes")
+}
@@ -1,26 +1,60 @@
 package rsync
 
 import (
+	"alfred-tool/models"
 	"alfred-tool/services"
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	dryRun bool
+	dryRun      bool
+	confirmRun  bool
+	showCommand bool
+	jsonEvents  bool
+	format      string
+	trigger     string
+	runVars     []string
+	runEngine   string
+	forceRun    bool
 )
 
 var runCmd = &cobra.Command{
 	Use:   "run [配置名称]",
 	Short: "执行rsync配置",
-	Long:  `执行指定的rsync配置进行文件同步`,
-	Args:  cobra.ExactArgs(1),
+	Long: `执行指定的rsync配置进行文件同步
+
+--dry-run 会实际以 rsync --dry-run 执行一次，列出将要新增、更新、删除的文件和需要传输的大小，
+不会修改任何文件；--confirm 先显示同样的预览，确认后再执行。
+双向同步的配置预览的是比较两端文件后得到的上传、下载、删除和冲突。
+--var 名称=值 设置路径、规则和选项中 {{名称}} 的值，优先于 Alfred 工作流变量（环境变量）和配置中的默认值。
+--json-events 把执行过程输出为逐行 JSON 事件（start、file、progress、conflict、delete_guard、result）。
+启用删除时执行前会先试运行，源目录为空或不存在时拒绝执行，删除超过配置的上限时中止，--force 在超过上限时仍然执行。
+--engine 指定本次使用的传输引擎：rsync，或在本地没有 rsync 时使用的 sftp（整个文件传输，不需要服务器上的 rsync），
+默认使用配置中的设置，auto 在本地有 rsync 时使用 rsync，否则使用 sftp。`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		configName := args[0]
-		if dryRun {
-			// 预览模式，只显示命令不执行
-			cmdStr, err := services.DryRunRsyncConfig(configName)
+		vars, err := parseVarFlags(runVars)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		engine := models.TransferEngine(runEngine)
+		if runEngine != "" {
+			if engine, err = models.ParseTransferEngine(runEngine); err != nil {
+				fmt.Printf("错误: %v\n", err)
+				return
+			}
+		}
+
+		if showCommand {
+			// 只显示命令不执行
+			cmdStr, err := services.DryRunRsyncConfig(configName, vars)
 			if err != nil {
 				fmt.Printf("错误: %v\n", err)
 				return
@@ -29,13 +63,84 @@ var runCmd = &cobra.Command{
 			return
 		}
 
+		if dryRun || confirmRun {
+			previewFormat := format
+			if confirmRun {
+				previewFormat = previewFormatTable
+			}
+			hasChanges, err := previewRun(configName, vars, engine, previewFormat)
+			if err != nil {
+				fmt.Printf("错误: %v\n", err)
+				return
+			}
+			if dryRun || !hasChanges {
+				return
+			}
+			fmt.Print("\n确认执行? (y/N): ")
+			reader := bufio.NewReader(os.Stdin)
+			response, _ := reader.ReadString('\n')
+			response = strings.ToLower(strings.TrimSpace(response))
+			if response != "y" && response != "yes" {
+				fmt.Println("取消执行")
+				return
+			}
+		}
+
 		// 实际执行
+		if jsonEvents {
+			handler := jsonEventHandler()
+			result, err := services.RunRsyncConfig(configName, services.RsyncRunOptions{Trigger: trigger, Handler: handler, Variables: vars, Engine: engine, Force: forceRun})
+			if err != nil {
+				// 没能启动rsync时不会有结果事件，补发一个
+				if result == nil {
+					handler(models.RsyncEvent{
+						Type:   models.RsyncEventResult,
+						Result: &models.RsyncResult{Error: err.Error()},
+					})
+				}
+				os.Exit(1)
+			}
+			return
+		}
+
 		fmt.Printf("开始执行rsync配置: %s\n", configName)
-		err := services.ExecuteRsyncConfig(configName)
-		if err != nil {
+		if _, err := services.RunRsyncConfig(configName, services.RsyncRunOptions{Trigger: trigger, Handler: terminalEventHandler(), Variables: vars, Engine: engine, Force: forceRun}); err != nil {
 			fmt.Printf("执行失败: %v\n", err)
-			return
+			os.Exit(1)
 		}
 		fmt.Printf("rsync配置 '%s' 执行完成\n", configName)
 	},
 }
+
+// previewRun 按格式输出执行前的预览，返回是否有需要同步的变更
+// 双向同步由多条rsync命令完成，预览的是比较两端文件得到的同步计划
+func previewRun(configName string, vars map[string]string, engine models.TransferEngine, previewFormat string) (bool, error) {
+	config, err := services.GetRsyncConfigByName(configName)
+	if err == nil && config.Direction == models.RsyncDirectionBidirectional {
+		plan, err := services.PreviewBidirectionalSync(configName, vars)
+		if err != nil {
+			return false, err
+		}
+		return len(plan.Actions) > 0, printSyncPlan(configName, plan, previewFormat)
+	}
+
+	preview, err := services.PreviewRsyncConfig(configName, vars, engine)
+	if err != nil {
+		return false, err
+	}
+	return len(preview.Changes) > 0, printPreview(preview, previewFormat)
+}
+
+func init() {
+	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "以 rsync --dry-run 预览将要发生的变更，不修改文件")
+	runCmd.Flags().BoolVar(&confirmRun, "confirm", false, "先预览变更，确认后再执行")
+	runCmd.Flags().BoolVar(&showCommand, "command", false, "只输出带 --dry-run 的rsync命令，不执行")
+	runCmd.Flags().BoolVar(&jsonEvents, "json-events", false, "以逐行 JSON 输出执行事件")
+	runCmd.Flags().StringVarP(&format, "format", "f", previewFormatTable, "预览输出格式: table, json, alfred")
+	runCmd.Flags().StringArrayVar(&runVars, "var", nil, "设置配置中的变量，格式为 名称=值，可重复使用")
+	runCmd.Flags().StringVar(&runEngine, "engine", "", "本次使用的传输引擎: auto, rsync, sftp")
+	runCmd.Flags().BoolVar(&forceRun, "force", false, "将要删除的文件超过删除保护的上限时仍然执行")
+	runCmd.Flags().StringVar(&trigger, "trigger", models.RsyncTriggerManual, "记录在执行记录中的触发方式")
+	runCmd.Flags().MarkHidden("trigger")
+	runCmd.MarkFlagsMutuallyExclusive("dry-run", "confirm", "command")
+}
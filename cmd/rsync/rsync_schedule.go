@@ -0,0 +1,131 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "管理rsync定时执行",
+	Long: `管理rsync配置的定时执行，定时任务由 daemon 命令执行
+
+定时设置使用标准的 5 段 cron 表达式（分 时 日 月 周），也支持 @hourly、@daily、@weekly、@monthly 等简写。
+也可以用 schedule export 导出为 crontab 条目或 systemd timer，交给系统执行。`,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出所有定时执行的rsync配置",
+	Run: func(cmd *cobra.Command, args []string) {
+		scheduled, err := services.GetScheduledRsyncConfigs()
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		now := time.Now()
+		alfredData := models.AlfredData{
+			Items: lo.Map(scheduled, func(item services.ScheduledRsyncConfig, index int) models.AlfredItem {
+				title := fmt.Sprintf("⏰ %s  %s", item.Config.Name, item.Schedule.Expr)
+				var subtitle string
+				switch {
+				case item.Config.SchedulePaused:
+					title = fmt.Sprintf("⏸ %s  %s", item.Config.Name, item.Schedule.Expr)
+					subtitle = "已暂停"
+				case item.Due(now):
+					subtitle = "待执行（守护进程未运行或错过了执行）"
+				default:
+					subtitle = "下次运行: " + item.NextRun.Format("2006-01-02 15:04")
+				}
+				if item.LastRun != nil {
+					subtitle += fmt.Sprintf(" | 上次运行: %s %s", item.LastRun.StatusIcon(), item.LastRun.StartedAt.Local().Format("01-02 15:04"))
+				}
+
+				return models.AlfredItem{
+					Uid:       item.Config.Name,
+					Title:     title,
+					Subtitle:  subtitle,
+					Arg:       item.Config.GetArg(),
+					Variables: item.Config.GetVariables(),
+				}
+			}),
+		}
+		marshal, err := json.Marshal(alfredData)
+		if err != nil {
+			fmt.Printf("JSON序列化失败: %v\n", err)
+			return
+		}
+		fmt.Println(string(marshal))
+	},
+}
+
+var scheduleSetCmd = &cobra.Command{
+	Use:   "set [配置名称] [cron表达式]",
+	Short: "设置定时执行",
+	Long:  `设置rsync配置的定时执行，如: rsync schedule set "my-backup" "0 3 * * *"`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := services.SetRsyncSchedule(args[0], args[1]); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		schedule, _ := models.ParseCron(args[1])
+		fmt.Printf("rsync配置 '%s' 已设置定时执行，下次运行: %s\n", args[0], schedule.Next(time.Now()).Format("2006-01-02 15:04"))
+	},
+}
+
+var scheduleClearCmd = &cobra.Command{
+	Use:   "clear [配置名称]",
+	Short: "取消定时执行",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := services.SetRsyncSchedule(args[0], ""); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("rsync配置 '%s' 已取消定时执行\n", args[0])
+	},
+}
+
+var schedulePauseCmd = &cobra.Command{
+	Use:   "pause [配置名称]",
+	Short: "暂停定时执行",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := services.PauseRsyncSchedule(args[0]); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("rsync配置 '%s' 的定时执行已暂停\n", args[0])
+	},
+}
+
+var scheduleResumeCmd = &cobra.Command{
+	Use:   "resume [配置名称]",
+	Short: "恢复定时执行",
+	Long:  `恢复rsync配置的定时执行，暂停期间错过的执行不会补执行`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := services.ResumeRsyncSchedule(args[0]); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("rsync配置 '%s' 的定时执行已恢复\n", args[0])
+	},
+}
+
+func init() {
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleSetCmd)
+	scheduleCmd.AddCommand(scheduleClearCmd)
+	scheduleCmd.AddCommand(schedulePauseCmd)
+	scheduleCmd.AddCommand(scheduleResumeCmd)
+	scheduleCmd.AddCommand(scheduleExportCmd)
+}
@@ -0,0 +1,79 @@
+package rsync
+
+import (
+	"alfred-tool/services"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+)
+
+var scheduleExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "导出定时设置为 crontab 或 systemd timer",
+	Long: `把rsync定时设置导出为系统定时器，作为 daemon 之外的另一种执行方式
+
+crontab: 输出 crontab 条目，可追加到 crontab -e 中，暂停的配置会被注释掉
+systemd: 生成 service 和 timer 单元，指定 --output 时写入目录（如 ~/.config/systemd/user），
+         否则输出到终端；timer 设置了 Persistent=true，错过的执行会在开机后补执行`,
+	Run: func(cmd *cobra.Command, args []string) {
+		switch exportFormat {
+		case "crontab":
+			content, err := services.ExportCrontab()
+			if err != nil {
+				fmt.Printf("错误: %v\n", err)
+				return
+			}
+			if exportOutput != "" {
+				if err := os.WriteFile(exportOutput, []byte(content), 0644); err != nil {
+					fmt.Printf("写入失败: %v\n", err)
+					return
+				}
+				fmt.Printf("已写入 %s\n", exportOutput)
+				return
+			}
+			fmt.Print(content)
+
+		case "systemd":
+			units, err := services.ExportSystemdUnits()
+			if err != nil {
+				fmt.Printf("错误: %v\n", err)
+				return
+			}
+			if exportOutput == "" {
+				for _, unit := range units {
+					fmt.Printf("# %s\n%s\n", unit.Name, unit.Content)
+				}
+				return
+			}
+
+			if err := os.MkdirAll(exportOutput, 0755); err != nil {
+				fmt.Printf("创建目录失败: %v\n", err)
+				return
+			}
+			for _, unit := range units {
+				path := filepath.Join(exportOutput, unit.Name)
+				if err := os.WriteFile(path, []byte(unit.Content), 0644); err != nil {
+					fmt.Printf("写入失败: %v\n", err)
+					return
+				}
+				fmt.Printf("已写入 %s\n", path)
+			}
+			fmt.Println("执行 systemctl --user daemon-reload 后，用 systemctl --user enable --now <名称>.timer 启用")
+
+		default:
+			fmt.Printf("错误: 未知的导出格式: %s（可选: crontab, systemd）\n", exportFormat)
+		}
+	},
+}
+
+func init() {
+	scheduleExportCmd.Flags().StringVarP(&exportFormat, "format", "f", "crontab", "导出格式: crontab, systemd")
+	scheduleExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "写入的文件（crontab）或目录（systemd），默认输出到终端")
+}
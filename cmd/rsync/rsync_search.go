@@ -1,6 +1,7 @@
 package rsync
 
 import (
+	"alfred-tool/models"
 	"alfred-tool/services"
 	"fmt"
 	"strings"
@@ -33,21 +34,59 @@ var searchCmd = &cobra.Command{
 			fmt.Printf("SSH连接: %s\n", config.SSHName)
 
 			direction := "上传 (本地→服务器)"
-			if config.Direction == "download" {
+			switch config.Direction {
+			case models.RsyncDirectionDownload:
 				direction = "下载 (服务器→本地)"
+			case models.RsyncDirectionBidirectional:
+				direction = "双向同步 (本地↔服务器)"
+			case models.RsyncDirectionRemote:
+				direction = "服务器之间 (服务器→服务器)"
 			}
 			fmt.Printf("方向: %s\n", direction)
+			if config.Direction == models.RsyncDirectionBidirectional {
+				policy, _ := models.ParseConflictPolicy(string(config.ConflictPolicy))
+				fmt.Printf("冲突处理: %s\n", policy)
+			}
 
-			fmt.Printf("本地路径: %s\n", config.LocalPath)
-			fmt.Printf("远程路径: %s\n", config.RemotePath)
+			if config.Direction == models.RsyncDirectionRemote {
+				mode, _ := models.ParseRemoteTransferMode(string(config.RemoteTransferMode))
+				fmt.Printf("传输方式: %s\n", mode)
+				fmt.Printf("源路径: %s\n", config.RemotePath)
+				fmt.Printf("目标: %s:%s\n", config.TargetSSHName, config.TargetPath)
+			} else {
+				fmt.Printf("本地路径: %s\n", config.LocalPath)
+				fmt.Printf("远程路径: %s\n", config.RemotePath)
+			}
 
 			if config.ExcludeRules != "" {
 				fmt.Printf("排除规则: %s\n", strings.ReplaceAll(config.ExcludeRules, "\n", ", "))
 			}
+			if config.FilterRules != "" {
+				fmt.Printf("过滤规则: %s\n", strings.ReplaceAll(config.FilterRules, "\n", ", "))
+			}
+			if config.UseIgnoreFiles {
+				fmt.Println("忽略文件: .gitignore, .rsyncignore")
+			}
 
 			if config.Options != "" {
 				fmt.Printf("选项: %s\n", config.Options)
 			}
+			if limits := config.LimitsText(); limits != "" {
+				fmt.Printf("传输限制: %s\n", limits)
+			}
+			if config.Engine != "" && config.Engine != models.TransferEngineAuto {
+				fmt.Printf("传输引擎: %s\n", config.Engine)
+			}
+			if config.VerifyAfterRun {
+				verify := "执行后校验大小和修改时间"
+				if config.VerifyChecksum {
+					verify = "执行后校验内容 (SHA-256)"
+				}
+				fmt.Println(verify)
+			}
+			if config.Variables != "" {
+				fmt.Printf("变量: %s\n", strings.ReplaceAll(config.Variables, "\n", ", "))
+			}
 
 			if config.Description != "" {
 				fmt.Printf("描述: %s\n", config.Description)
@@ -0,0 +1,86 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotsPrune  bool
+	snapshotsDryRun bool
+)
+
+var snapshotsCmd = &cobra.Command{
+	Use:   "snapshots [配置名称]",
+	Short: "列出快照备份",
+	Long: `列出开启了快照备份的下载配置在本地路径下保存的快照，从新到旧排列
+
+每次执行会在本地路径下创建以时间命名的快照目录，未变化的文件硬链接到上一个快照，
+latest 链接指向最新的快照。执行成功后会按保留策略自动清理旧快照，
+--prune 可以在修改保留策略后手动清理（--dry-run 只列出将要删除的快照）。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configName := args[0]
+
+		if snapshotsPrune {
+			pruned, err := services.PruneRsyncSnapshots(configName, snapshotsDryRun)
+			for _, snapshot := range pruned {
+				if snapshotsDryRun {
+					fmt.Printf("将删除: %s\n", snapshot.Name)
+				} else {
+					fmt.Printf("已删除: %s\n", snapshot.Name)
+				}
+			}
+			if err != nil {
+				fmt.Printf("错误: %v\n", err)
+				return
+			}
+			if len(pruned) == 0 {
+				fmt.Println("没有需要清理的快照")
+			}
+			return
+		}
+
+		config, snapshots, err := services.GetRsyncSnapshots(configName)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		alfredData := models.AlfredData{
+			Items: lo.Map(snapshots, func(snapshot models.RsyncSnapshot, index int) models.AlfredItem {
+				title := snapshot.Time.Format("2006-01-02 15:04:05")
+				if snapshot.Latest {
+					title += " (latest)"
+				}
+				return models.AlfredItem{
+					Uid:      snapshot.Name,
+					Title:    title,
+					Subtitle: fmt.Sprintf("%s | %s", snapshot.Path, config.SnapshotRetention()),
+					Arg:      []string{snapshot.Name},
+					Variables: map[string]string{
+						"rsync_name":          config.Name,
+						"rsync_snapshot":      snapshot.Name,
+						"rsync_snapshot_path": snapshot.Path,
+					},
+				}
+			}),
+		}
+		marshal, err := json.Marshal(alfredData)
+		if err != nil {
+			fmt.Printf("JSON序列化失败: %v\n", err)
+			return
+		}
+		fmt.Println(string(marshal))
+	},
+}
+
+func init() {
+	snapshotsCmd.Flags().BoolVar(&snapshotsPrune, "prune", false, "按保留策略删除旧快照")
+	snapshotsCmd.Flags().BoolVar(&snapshotsDryRun, "dry-run", false, "与 --prune 一起使用，只列出将要删除的快照")
+}
@@ -7,17 +7,30 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var updatePaths bool
+
 var updateCmd = &cobra.Command{
 	Use:   "update [配置名称]",
 	Short: "修改rsync配置",
-	Long:  `打开图形界面修改指定的rsync配置`,
-	Args:  cobra.ExactArgs(1),
+	Long: `打开图形界面修改指定的rsync配置
+
+--paths 只修改路径，使用对话框（可在 Alfred 中调用），远程路径和目标路径可以浏览SSH连接服务器上的目录`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		configName := args[0]
 
-		err := ui.ShowUpdateRsyncDialog(configName)
+		var err error
+		if updatePaths {
+			err = showPathsDialog(configName)
+		} else {
+			err = ui.ShowUpdateRsyncDialog(configName)
+		}
 		if err != nil {
 			fmt.Printf("错误: %v\n", err)
 		}
 	},
 }
+
+func init() {
+	updateCmd.Flags().BoolVar(&updatePaths, "paths", false, "只修改路径，可以浏览服务器上的目录")
+}
@@ -0,0 +1,135 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyChecksum bool
+	verifyFormat   string
+	verifyVars     []string
+)
+
+var verifyDiffNames = map[models.VerifyDiffKind]string{
+	models.VerifyOnlyLocal:  "仅本地",
+	models.VerifyOnlyRemote: "仅服务器",
+	models.VerifyType:       "类型不同",
+	models.VerifySize:       "大小不同",
+	models.VerifyModTime:    "时间不同",
+	models.VerifyChecksum:   "内容不同",
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [配置名称]",
+	Short: "校验本地和服务器上的文件是否一致",
+	Long: `比较配置的本地路径和远程路径中的文件，遵循配置的排除和过滤规则，不会修改任何文件
+
+默认比较文件大小，配置保持修改时间（-a 或 -t）时还比较修改时间；--checksum 在本地和服务器上
+分别计算大小和时间一致的文件的 SHA-256 比较内容（服务器上需要 sha256sum）。
+没有启用删除多余文件时，目标中多余的文件列出但不影响结果。校验未通过时退出码为 1。
+配置中启用“执行后校验”时，每次执行成功后自动校验，结果保存在执行记录中（rsync log 查看）。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vars, err := parseVarFlags(verifyVars)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		report, err := services.VerifyRsyncConfig(args[0], vars, verifyChecksum)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+
+		switch verifyFormat {
+		case previewFormatTable:
+			printVerifyTable(report)
+		case previewFormatJSON:
+			err = printJSON(report)
+		case previewFormatAlfred:
+			err = printJSON(verifyAlfredData(report))
+		default:
+			err = fmt.Errorf("未知的输出格式: %s（可选: table, json, alfred）", verifyFormat)
+		}
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		if !report.Passed && verifyFormat != previewFormatAlfred {
+			os.Exit(1)
+		}
+	},
+}
+
+func printVerifyTable(report *models.VerifyReport) {
+	fmt.Printf("本地: %s\n服务器: %s\n\n", report.LocalPath, report.RemotePath)
+	if len(report.Diffs) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "差异\t本地\t服务器\t路径")
+		fmt.Fprintln(w, "----\t----\t------\t----")
+		for _, diff := range report.Diffs {
+			name := verifyDiffNames[diff.Kind]
+			if diff.Ignored {
+				name += "（忽略）"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, verifyFileText(diff.Kind, diff.Local), verifyFileText(diff.Kind, diff.Remote), diff.Path)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+	fmt.Println(report.Summary())
+}
+
+// verifyFileText 差异中一端的说明，按差异类型显示大小、修改时间或校验和
+func verifyFileText(kind models.VerifyDiffKind, info *models.VerifyFileInfo) string {
+	switch {
+	case info == nil:
+		return "-"
+	case info.IsDir:
+		return "目录"
+	case kind == models.VerifyChecksum:
+		if info.Checksum == "" {
+			return "-"
+		}
+		return info.Checksum[:12]
+	case kind == models.VerifyModTime:
+		return time.Unix(info.ModTime, 0).Format("2006-01-02 15:04:05")
+	}
+	return models.FormatBytes(info.Size)
+}
+
+func verifyAlfredData(report *models.VerifyReport) models.AlfredData {
+	summary := models.AlfredItem{
+		Uid:      report.ConfigName,
+		Title:    report.Summary().String(),
+		Subtitle: fmt.Sprintf("%s ⇄ %s", report.LocalPath, report.RemotePath),
+		Arg:      []string{report.ConfigName},
+	}
+	items := lo.Map(report.Diffs, func(diff models.VerifyDiff, _ int) models.AlfredItem {
+		subtitle := verifyDiffNames[diff.Kind]
+		if diff.Ignored {
+			subtitle += "（忽略）"
+		}
+		return models.AlfredItem{
+			Uid:      string(diff.Kind) + ":" + diff.Path,
+			Title:    diff.Path,
+			Subtitle: fmt.Sprintf("%s · 本地 %s · 服务器 %s", subtitle, verifyFileText(diff.Kind, diff.Local), verifyFileText(diff.Kind, diff.Remote)),
+			Arg:      []string{report.ConfigName},
+		}
+	})
+	return models.AlfredData{Items: append([]models.AlfredItem{summary}, items...)}
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifyChecksum, "checksum", false, "在两端计算 SHA-256 比较文件内容")
+	verifyCmd.Flags().StringVarP(&verifyFormat, "format", "f", previewFormatTable, "输出格式: table, json, alfred")
+	verifyCmd.Flags().StringArrayVar(&verifyVars, "var", nil, "设置配置中的变量，格式为 名称=值，可重复使用")
+}
@@ -0,0 +1,135 @@
+package rsync
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchDebounce time.Duration
+	watchNoInit   bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [配置名称]",
+	Short: "监听本地文件变化并自动上传",
+	Long: `递归监听上传配置的本地路径，文件变化后自动执行同步
+
+- 只支持上传方向的配置
+- 匹配排除规则的文件和目录不会触发同步
+- 连续的变化（如编辑器保存、git checkout）会合并，最后一次变化后等待 --debounce 再同步
+- 同步期间发生的变化会在同步结束后再同步一次
+- 每次同步都会记录在执行记录中（触发方式为 watch）
+
+按 Ctrl+C 停止监听。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configName := args[0]
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		status := &watchStatus{}
+		err := services.WatchRsyncConfig(ctx, configName, services.WatchOptions{
+			Debounce:    watchDebounce,
+			InitialSync: !watchNoInit,
+			Handler:     status.handle,
+		})
+		status.clear()
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("已停止监听")
+	},
+}
+
+// watchStatus 在终端底部维护一行刷新的状态，每次同步的结果单独输出一行
+type watchStatus struct {
+	dirs     int
+	pending  int
+	lastSync string
+	shown    bool
+}
+
+func (s *watchStatus) handle(event services.WatchEvent) {
+	now := time.Now().Format("15:04:05")
+
+	switch event.Type {
+	case services.WatchEventReady:
+		s.dirs = event.Dirs
+	case services.WatchEventChange:
+		s.pending++
+	case services.WatchEventSyncStart:
+		s.clear()
+		if len(event.Paths) == 0 {
+			fmt.Printf("%s 🔄 初始同步...\n", now)
+		} else {
+			fmt.Printf("%s 🔄 同步 %d 个变化: %s\n", now, len(event.Paths), watchPathsSummary(event.Paths))
+		}
+	case services.WatchEventSyncDone:
+		s.clear()
+		switch {
+		case event.Skipped:
+			fmt.Printf("%s ⏳ 配置正在其他进程中执行，稍后重试\n", now)
+		case event.Err != nil:
+			fmt.Printf("%s ❌ 同步失败: %v\n", now, event.Err)
+			s.lastSync = now + " ❌"
+			s.pending = 0
+		default:
+			r := event.Result
+			fmt.Printf("%s ✅ 传输 %d 个文件（%s），用时 %s\n",
+				now, r.FilesTransferred, models.FormatBytes(r.TransferredSize), r.Duration.Round(time.Millisecond))
+			s.lastSync = now + " ✅"
+			s.pending = 0
+		}
+	case services.WatchEventError:
+		s.clear()
+		fmt.Printf("%s ⚠️  %v\n", now, event.Err)
+	}
+	s.show()
+}
+
+// show 刷新状态行
+func (s *watchStatus) show() {
+	line := fmt.Sprintf("👀 监听 %d 个目录", s.dirs)
+	if s.pending > 0 {
+		line += fmt.Sprintf(" | 待同步 %d 个变化", s.pending)
+	}
+	if s.lastSync != "" {
+		line += " | 上次同步 " + s.lastSync
+	}
+	fmt.Printf("\r\033[K%s（Ctrl+C 退出）", line)
+	s.shown = true
+}
+
+// clear 清除状态行，之后的输出从行首开始
+func (s *watchStatus) clear() {
+	if s.shown {
+		fmt.Print("\r\033[K")
+		s.shown = false
+	}
+}
+
+// watchPathsSummary 最多显示前 3 个变化的路径
+func watchPathsSummary(paths []string) string {
+	const max = 3
+	if len(paths) <= max {
+		return strings.Join(paths, ", ")
+	}
+	return strings.Join(paths[:max], ", ") + " 等"
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 500*time.Millisecond, "最后一次变化后等待多久再同步")
+	watchCmd.Flags().BoolVar(&watchNoInit, "no-initial-sync", false, "开始监听前不先同步一次")
+}
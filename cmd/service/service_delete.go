@@ -46,6 +46,11 @@ func deleteService(id uint) {
 		return
 	}
 
+	if err := services.AutoBackup("delete"); err != nil {
+		fmt.Printf("删除服务失败: %v\n", err)
+		return
+	}
+
 	if err := serviceService.DeleteService(id); err != nil {
 		fmt.Printf("删除服务失败: %v\n", err)
 		return
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"alfred-tool/dialog"
+	"alfred-tool/dialog/field"
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// showPathsDialog 用对话框修改服务详情，可以浏览关联SSH连接服务器上的目录，选中的路径追加到服务详情
+func showPathsDialog(id uint) error {
+	serviceService := services.NewServiceService()
+	service, err := serviceService.GetServiceByID(id)
+	if err != nil {
+		return fmt.Errorf("未找到服务 %d", id)
+	}
+	if service.SSHConnectionID == 0 || service.SSHConnection.ID == 0 {
+		return errors.New("服务没有关联SSH连接，无法浏览服务器")
+	}
+	connName := service.SSHConnection.Name
+
+	d := dialog.NewDialog(
+		dialog.WithTitle("修改 "+service.Name+" 的服务详情"),
+		dialog.WithSize(600, 400),
+		dialog.WithOkLabel("保存"),
+		dialog.WithCancelLabel("取消"),
+		dialog.WithAlwaysOnTop(true),
+		dialog.WithFields(
+			field.NewTextEditorField("details", "服务详情", field.WithDefaultValue(service.Details)),
+			field.NewRemoteFolderField("path", "追加服务器路径", services.RemoteBrowseCommand(connName),
+				field.WithNote("SSH连接: "+connName+"，选择的路径追加到服务详情的最后一行")),
+		),
+	)
+	result, err := d.Open()
+	if err != nil {
+		return fmt.Errorf("打开对话框失败: %v", err)
+	}
+
+	details := strings.TrimRight(getStringValue(result, "details"), "\n")
+	if path := strings.TrimSpace(getStringValue(result, "path")); path != "" {
+		if details != "" {
+			details += "\n"
+		}
+		details += path
+	}
+	service.Details = strings.TrimSpace(details)
+	// 只保存服务本身，不改动预加载的SSH连接
+	service.SSHConnection = models.SSHConnection{}
+	if err := serviceService.UpdateService(service); err != nil {
+		return fmt.Errorf("保存服务失败: %v", err)
+	}
+	fmt.Printf("服务 '%s' 的服务详情已更新\n", service.Name)
+	return nil
+}
+
+// getStringValue 对话框结果中的字符串值
+func getStringValue(result map[string]any, key string) string {
+	if val, ok := result[key].(string); ok {
+		return val
+	}
+	return ""
+}
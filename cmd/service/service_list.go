@@ -40,6 +40,8 @@ func listServices() {
 		sshConnection := "无"
 		if service.SSHConnectionID > 0 && service.SSHConnection.Name != "" {
 			sshConnection = service.SSHConnection.Name
+		} else if service.SSHConnectionID > 0 {
+			sshConnection = "⚠️ 已失效"
 		}
 
 		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n",
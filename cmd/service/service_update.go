@@ -8,21 +8,35 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var servicePaths bool
+
 var serviceUpdateCmd = &cobra.Command{
 	Use:   "update [服务ID]",
 	Short: "更新服务信息",
-	Long:  `更新指定ID的服务信息`,
-	Args:  cobra.ExactArgs(1),
+	Long: `更新指定ID的服务信息
+
+--paths 只修改服务详情，使用对话框（可在 Alfred 中调用），可以浏览关联SSH连接服务器上的目录并追加到服务详情`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
 			fmt.Println("无效的服务ID")
 			return
 		}
+		if servicePaths {
+			if err := showPathsDialog(uint(id)); err != nil {
+				fmt.Printf("错误: %v\n", err)
+			}
+			return
+		}
 		updateService(uint(id))
 	},
 }
 
+func init() {
+	serviceUpdateCmd.Flags().BoolVar(&servicePaths, "paths", false, "只修改服务详情，可以浏览服务器上的目录")
+}
+
 func updateService(id uint) {
 	err := ui.ShowUpdateServiceDialog(id)
 	if err != nil {
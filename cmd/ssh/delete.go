@@ -8,18 +8,63 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	deleteCascade  bool
+	deleteReassign string
+)
+
 var DeleteCmd = &cobra.Command{
 	Use:   "delete <name>",
 	Short: "删除SSH连接",
-	Long:  `删除指定名称的SSH连接配置。`,
-	Args:  cobra.ExactArgs(1),
+	Long: `删除指定名称的SSH连接配置。
+
+如果连接仍被rsync配置或服务引用，需要使用 --cascade 将它们一并移入回收站，
+或使用 --reassign <其他连接> 将它们转移到另一个连接。
+删除的记录可以通过 trash restore 恢复。`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		connectionName := args[0]
-		if err := services.DeleteConnection(connectionName); err != nil {
+
+		dependents, err := services.GetConnectionDependents(connectionName)
+		if err != nil {
 			fmt.Printf("删除连接失败: %v\n", err)
 			return
 		}
+		if !dependents.IsEmpty() {
+			fmt.Printf("连接 '%s' 仍被以下配置引用:\n", connectionName)
+			for _, config := range dependents.RsyncConfigs {
+				fmt.Printf("  rsync: %s\n", config.Name)
+			}
+			for _, service := range dependents.Services {
+				fmt.Printf("  service: %s (ID: %d)\n", service.Name, service.ID)
+			}
+			if !deleteCascade && deleteReassign == "" {
+				fmt.Println("使用 --cascade 一并删除这些配置，或使用 --reassign <其他连接> 转移它们")
+				return
+			}
+		}
 
+		if err := services.AutoBackup("delete"); err != nil {
+			fmt.Printf("删除连接失败: %v\n", err)
+			return
+		}
+		if err := services.DeleteConnection(connectionName, services.DeleteConnectionOptions{
+			Cascade:    deleteCascade,
+			ReassignTo: deleteReassign,
+		}); err != nil {
+			fmt.Printf("删除连接失败: %v\n", err)
+			return
+		}
+
+		if deleteReassign != "" && !dependents.IsEmpty() {
+			fmt.Printf("引用已转移到连接 '%s'\n", deleteReassign)
+		}
 		fmt.Printf("连接 '%s' 已成功删除\n", connectionName)
 	},
 }
+
+func init() {
+	DeleteCmd.Flags().BoolVar(&deleteCascade, "cascade", false, "一并删除引用该连接的rsync配置和服务")
+	DeleteCmd.Flags().StringVar(&deleteReassign, "reassign", "", "将引用该连接的rsync配置和服务转移到指定连接")
+	DeleteCmd.MarkFlagsMutuallyExclusive("cascade", "reassign")
+}
@@ -29,6 +29,7 @@ func ShowAddDialogV2() error {
 			field.NewSegmentedField("passwordType", "密码类型", []string{"私钥", "密码"}, field.WithDefaultValue("私钥")),
 			field.NewFileField("keyPath", "私钥文件", field.WithDefaultValue("/Users/luca/.ssh/key"), field.WithVisibleWhen("passwordType", "私钥")),
 			field.NewTextField("password", "密码", field.WithVisibleWhen("passwordType", "密码")),
+			field.NewTextField("tags", "标签", field.WithNote("可选，多个标签以逗号分隔")),
 			field.NewTextEditorField("description", "描述", field.WithNote("可选")),
 		),
 	)
@@ -47,10 +48,11 @@ func ShowAddDialogV2() error {
 	passwordType := getStringValue(result, "passwordType")
 	keyPath := getStringValue(result, "keyPath")
 	password := getStringValue(result, "password")
+	tags := getStringValue(result, "tags")
 	description := getStringValue(result, "description")
 
 	// 保存连接
-	err = saveConnection(name, address, port, username, localIP, passwordType, password, keyPath, description)
+	err = saveConnection(name, address, port, username, localIP, passwordType, password, keyPath, tags, description)
 	if err != nil {
 		return fmt.Errorf("保存连接失败: %v", err)
 	}
@@ -90,6 +92,7 @@ func ShowUpdateDialogV2(connectionName string) error {
 			field.NewSegmentedField("passwordType", "密码类型", []string{"私钥", "密码"}, field.WithDefaultValue(passwordTypeDefault)),
 			field.NewFileField("keyPath", "私钥文件", field.WithDefaultValue(conn.KeyPath), field.WithVisibleWhen("passwordType", "私钥")),
 			field.NewTextField("password", "密码", field.WithDefaultValue(conn.Password), field.WithVisibleWhen("passwordType", "密码")),
+			field.NewTextField("tags", "标签", field.WithDefaultValue(conn.Tags), field.WithNote("可选，多个标签以逗号分隔")),
 			field.NewTextEditorField("description", "描述", field.WithDefaultValue(conn.Description), field.WithNote("可选")),
 		),
 	)
@@ -108,10 +111,11 @@ func ShowUpdateDialogV2(connectionName string) error {
 	passwordType := getStringValue(result, "passwordType")
 	keyPath := getStringValue(result, "keyPath")
 	password := getStringValue(result, "password")
+	tags := getStringValue(result, "tags")
 	description := getStringValue(result, "description")
 
 	// 更新连接
-	err = updateConnection(conn.ID, name, address, port, username, localIP, passwordType, password, keyPath, description)
+	err = updateConnection(conn.ID, name, address, port, username, localIP, passwordType, password, keyPath, tags, description)
 	if err != nil {
 		return fmt.Errorf("更新连接失败: %v", err)
 	}
@@ -131,7 +135,7 @@ func getStringValue(result map[string]any, key string) string {
 }
 
 // saveConnection 保存SSH连接（复用原有逻辑）
-func saveConnection(name, address, port, username, localIP, passwordType, password, keyPath, description string) error {
+func saveConnection(name, address, port, username, localIP, passwordType, password, keyPath, tags, description string) error {
 	if strings.TrimSpace(name) == "" || strings.TrimSpace(address) == "" ||
 		strings.TrimSpace(username) == "" {
 		return errors.New("名称、地址和用户名不能为空")
@@ -162,6 +166,7 @@ func saveConnection(name, address, port, username, localIP, passwordType, passwo
 		Username:     strings.TrimSpace(username),
 		PasswordType: dbPasswordType,
 		LocalIP:      strings.TrimSpace(localIP),
+		Tags:         models.NormalizeTags(tags),
 		Description:  strings.TrimSpace(description),
 	}
 
@@ -176,7 +181,7 @@ func saveConnection(name, address, port, username, localIP, passwordType, passwo
 }
 
 // updateConnection 更新SSH连接（复用原有逻辑）
-func updateConnection(id uint, name, address, port, username, localIP, passwordType, password, keyPath, description string) error {
+func updateConnection(id uint, name, address, port, username, localIP, passwordType, password, keyPath, tags, description string) error {
 	if strings.TrimSpace(name) == "" || strings.TrimSpace(address) == "" ||
 		strings.TrimSpace(username) == "" {
 		return errors.New("名称、地址和用户名不能为空")
@@ -205,6 +210,7 @@ func updateConnection(id uint, name, address, port, username, localIP, passwordT
 		Username:     strings.TrimSpace(username),
 		PasswordType: dbPasswordType,
 		LocalIP:      strings.TrimSpace(localIP),
+		Tags:         models.NormalizeTags(tags),
 		Description:  strings.TrimSpace(description),
 	}
 	conn.ID = id
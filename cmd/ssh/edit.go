@@ -0,0 +1,185 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"alfred-tool/models"
+	"alfred-tool/services"
+
+	"github.com/spf13/cobra"
+)
+
+var editForce bool
+
+// errEditKept 放弃上传，本地副本保留在临时目录中
+var errEditKept = errors.New("已放弃上传")
+
+var EditCmd = &cobra.Command{
+	Use:   "edit <连接名称>:<路径>",
+	Short: "编辑服务器上的文件",
+	Long: `通过 sftp 把服务器上的文件下载到临时目录，用 $VISUAL 或 $EDITOR（默认 vi）打开，每次保存后上传回服务器，
+编辑器退出后再检查一次，上传最后一次保存之后的修改。
+
+上传前会重新下载服务器上的文件，检查编辑期间是否被其他人修改。编辑过程中发现冲突时不再上传，
+编辑器退出后可选择覆盖、查看差异或放弃上传（本地副本保留在临时目录中）；--force 跳过这项检查直接覆盖。
+编辑过程中的上传结果在编辑器退出后显示。使用 GUI 编辑器时需要让命令等待窗口关闭（如 EDITOR="code -w"），
+否则编辑器命令立即返回，之后的保存不会上传。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		remote, ok := models.ParseRemoteFileSpec(args[0])
+		if !ok {
+			fmt.Printf("错误: 服务器路径格式应为 连接名称:路径，当前为 '%s'\n", args[0])
+			os.Exit(1)
+		}
+
+		session, err := services.StartRemoteEdit(remote)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		err = editRemoteFile(session)
+		if errors.Is(err, errEditKept) {
+			fmt.Printf("%v，编辑后的文件保留在 %s\n", err, session.LocalPath)
+			os.Exit(1)
+		}
+		session.Cleanup()
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// editRemoteFile 打开编辑器，每次保存后上传，编辑器退出后检查冲突并上传剩余的修改
+func editRemoteFile(session *services.RemoteEditSession) error {
+	// 编辑器占用终端，编辑过程中的上传结果在退出后显示
+	ctx, cancel := context.WithCancel(context.Background())
+	ready, done := make(chan struct{}), make(chan struct{})
+	var events []services.RemoteEditEvent
+	go func() {
+		defer close(done)
+		err := session.WatchSaves(ctx, editForce, func(event services.RemoteEditEvent) {
+			if event.Ready {
+				close(ready)
+				return
+			}
+			events = append(events, event)
+		})
+		if err != nil {
+			events = append(events, services.RemoteEditEvent{Err: err})
+		}
+	}()
+	// 开始监听后再打开编辑器，避免漏掉很快的第一次保存；无法监听时只在编辑器退出后上传
+	select {
+	case <-ready:
+	case <-done:
+	}
+	editorErr := runEditor(session.LocalPath)
+	cancel()
+	<-done
+
+	uploads := 0
+	for _, event := range events {
+		switch {
+		case event.Uploaded:
+			uploads++
+		case event.Conflict:
+			fmt.Printf("保存时发现服务器上的 %s 已被修改，没有上传\n", session.Remote)
+		case event.Err != nil:
+			fmt.Printf("保存时上传失败: %v\n", event.Err)
+		}
+	}
+	if uploads > 0 {
+		fmt.Printf("编辑过程中已上传 %d 次\n", uploads)
+	}
+	if editorErr != nil {
+		return editorErr
+	}
+
+	changed, err := session.LocalChanged()
+	if err != nil {
+		return err
+	}
+	if !changed {
+		if uploads == 0 {
+			fmt.Println("文件没有修改，不上传")
+		}
+		return nil
+	}
+
+	if !editForce {
+		remoteCopy, conflict, err := session.RemoteChanged()
+		if err != nil {
+			fmt.Printf("错误: 检查服务器上的文件失败: %v\n", err)
+			return errEditKept
+		}
+		if conflict {
+			if err := resolveEditConflict(session, remoteCopy); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := session.Upload(); err != nil {
+		fmt.Printf("错误: %v\n", err)
+		return errEditKept
+	}
+	fmt.Printf("已上传 %s\n", session.Remote)
+	return nil
+}
+
+// runEditor 用 $VISUAL 或 $EDITOR 打开文件并等待编辑器退出
+func runEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	words, err := models.SplitShellWords(editor)
+	if err != nil || len(words) == 0 {
+		return fmt.Errorf("无法解析编辑器命令 '%s'", editor)
+	}
+
+	cmd := exec.Command(words[0], append(words[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("编辑器 '%s' 执行失败: %v", editor, err)
+	}
+	return nil
+}
+
+// resolveEditConflict 服务器上的文件在编辑期间被修改时询问是否覆盖
+func resolveEditConflict(session *services.RemoteEditSession, remoteCopy string) error {
+	fmt.Printf("服务器上的 %s 在编辑期间已被修改\n", session.Remote)
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("[o] 覆盖服务器上的文件  [d] 查看差异  [k] 放弃上传 (o/d/K): ")
+		response, _ := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "o":
+			return nil
+		case "d":
+			// diff 在有差异时退出码为 1
+			cmd := exec.Command("diff", "-u", remoteCopy, session.LocalPath)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			cmd.Run()
+		default:
+			return errEditKept
+		}
+	}
+}
+
+func init() {
+	EditCmd.Flags().BoolVar(&editForce, "force", false, "不检查服务器上的文件是否被修改，直接覆盖")
+}
@@ -0,0 +1,44 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+
+	"alfred-tool/models"
+	"alfred-tool/services"
+
+	"github.com/spf13/cobra"
+)
+
+var getOptions models.FileTransferOptions
+
+var GetCmd = &cobra.Command{
+	Use:   "get <连接名称>:<路径> [本地路径]",
+	Short: "从服务器下载文件",
+	Long: `通过 sftp 从SSH连接的服务器下载文件或目录，不指定本地路径时下载到当前目录。
+
+路径为空或 ~/ 开头时相对于用户目录；-r 递归下载目录，-a 从已有的部分文件继续传输，-p 保持修改时间和权限。`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		remote, ok := models.ParseRemoteFileSpec(args[0])
+		if !ok {
+			fmt.Printf("错误: 服务器路径格式应为 连接名称:路径，当前为 '%s'\n", args[0])
+			os.Exit(1)
+		}
+		local := ""
+		if len(args) > 1 {
+			local = args[1]
+		}
+		if err := services.GetRemoteFile(remote, local, getOptions, os.Stdout); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已下载 %s\n", remote)
+	},
+}
+
+func init() {
+	GetCmd.Flags().BoolVarP(&getOptions.Recursive, "recursive", "r", false, "递归下载目录")
+	GetCmd.Flags().BoolVarP(&getOptions.Resume, "resume", "a", false, "从已有的部分文件继续传输")
+	GetCmd.Flags().BoolVarP(&getOptions.Preserve, "preserve", "p", false, "保持修改时间和权限")
+}
@@ -0,0 +1,119 @@
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"alfred-tool/models"
+	"alfred-tool/services"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lsAll      bool
+	lsDirsOnly bool
+	lsFormat   string
+)
+
+var LsCmd = &cobra.Command{
+	Use:   "ls [连接名称] [路径]",
+	Short: "列出服务器上的目录",
+	Long: `通过 sftp 列出SSH连接服务器上的目录，不指定路径时列出用户目录，~/ 开头的路径相对于用户目录。使用密码的SSH连接在终端中询问密码，在 Alfred 等没有终端的地方执行时需要使用密钥。
+
+默认输出 Alfred JSON：第一项为当前目录，其次为上级目录，arg 为绝对路径，
+变量 remote_is_dir 表示能否继续进入，用于在 Alfred 中逐级浏览；-f json 输出目录内容，-f table 输出表格。`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := ""
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		listing, err := services.ListRemoteDir(args[0], dir, services.RemoteListOptions{All: lsAll, DirsOnly: lsDirsOnly})
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+
+		switch lsFormat {
+		case "alfred":
+			printJSON(remoteListingAlfredData(listing))
+		case "json":
+			printJSON(listing)
+		case "table":
+			printRemoteListing(listing)
+		default:
+			fmt.Printf("错误: 未知的输出格式: %s（可选: alfred, json, table）\n", lsFormat)
+		}
+	},
+}
+
+// remoteListingAlfredData 当前目录、上级目录和目录中的项，⌘ 复制路径
+func remoteListingAlfredData(listing *models.RemoteListing) models.AlfredData {
+	item := func(title, subtitle, path string, isDir bool) models.AlfredItem {
+		return models.AlfredItem{
+			Uid:      listing.Connection + ":" + path,
+			Title:    title,
+			Subtitle: subtitle,
+			Arg:      []string{path},
+			Mods: map[models.ModName]models.AlfredMod{
+				models.Mod_Cmd: models.NewAlfredMod("复制路径 "+path, path),
+			},
+			Variables: map[string]string{
+				"ssh_name":      listing.Connection,
+				"remote_path":   path,
+				"remote_is_dir": fmt.Sprintf("%t", isDir),
+			},
+		}
+	}
+
+	items := []models.AlfredItem{
+		item("📂 "+listing.Path, fmt.Sprintf("%s 上的当前目录，共 %d 项", listing.Connection, len(listing.Entries)), listing.Path, true),
+	}
+	if listing.Parent != "" {
+		items = append(items, item("..", "上级目录 "+listing.Parent, listing.Parent, true))
+	}
+	for _, entry := range listing.Entries {
+		title := entry.Name
+		subtitle := fmt.Sprintf("%s  %s  %s", entry.Mode, models.FormatBytes(entry.Size), entry.Modified)
+		switch {
+		case entry.IsDir:
+			title = "📁 " + entry.Name + "/"
+			subtitle = fmt.Sprintf("%s  %s", entry.Mode, entry.Modified)
+		case entry.IsLink:
+			title = "🔗 " + entry.Name
+		}
+		items = append(items, item(title, subtitle, entry.Path, entry.Browsable()))
+	}
+	return models.AlfredData{Items: items}
+}
+
+func printRemoteListing(listing *models.RemoteListing) {
+	fmt.Printf("%s:%s\n\n", listing.Connection, listing.Path)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, entry := range listing.Entries {
+		name := entry.Name
+		if entry.IsDir {
+			name += "/"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", entry.Mode, entry.Size, entry.Modified, name)
+	}
+	w.Flush()
+}
+
+func printJSON(v any) {
+	marshal, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("JSON序列化失败: %v\n", err)
+		return
+	}
+	fmt.Println(string(marshal))
+}
+
+func init() {
+	LsCmd.Flags().BoolVarP(&lsAll, "all", "a", false, "包括以 . 开头的隐藏文件")
+	LsCmd.Flags().BoolVar(&lsDirsOnly, "dirs", false, "只列出目录")
+	LsCmd.Flags().StringVarP(&lsFormat, "format", "f", "alfred", "输出格式: alfred, json, table")
+}
@@ -0,0 +1,40 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+
+	"alfred-tool/models"
+	"alfred-tool/services"
+
+	"github.com/spf13/cobra"
+)
+
+var putOptions models.FileTransferOptions
+
+var PutCmd = &cobra.Command{
+	Use:   "put <本地路径> <连接名称>:<路径>",
+	Short: "上传文件到服务器",
+	Long: `通过 sftp 把本地文件或目录上传到SSH连接的服务器，路径为空时上传到用户目录。
+
+-r 递归上传目录，-a 从服务器上已有的部分文件继续传输，-p 保持修改时间和权限。`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		remote, ok := models.ParseRemoteFileSpec(args[1])
+		if !ok {
+			fmt.Printf("错误: 服务器路径格式应为 连接名称:路径，当前为 '%s'\n", args[1])
+			os.Exit(1)
+		}
+		if err := services.PutRemoteFile(args[0], remote, putOptions, os.Stdout); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已上传 %s 到 %s\n", args[0], remote)
+	},
+}
+
+func init() {
+	PutCmd.Flags().BoolVarP(&putOptions.Recursive, "recursive", "r", false, "递归上传目录")
+	PutCmd.Flags().BoolVarP(&putOptions.Resume, "resume", "a", false, "从服务器上已有的部分文件继续传输")
+	PutCmd.Flags().BoolVarP(&putOptions.Preserve, "preserve", "p", false, "保持修改时间和权限")
+}
@@ -7,7 +7,7 @@ import (
 var SshCmd = &cobra.Command{
 	Use:   "ssh",
 	Short: "SSH连接管理",
-	Long:  `SSH连接管理命令，支持添加、列出、搜索、更新、删除和使用SSH连接。`,
+	Long:  `SSH连接管理命令，支持添加、列出、搜索、更新、删除和使用SSH连接，以及浏览服务器上的目录和下载、上传、编辑服务器上的文件。`,
 }
 
 func init() {
@@ -18,4 +18,8 @@ func init() {
 	SshCmd.AddCommand(DeleteCmd)
 	SshCmd.AddCommand(UseCmd)
 	SshCmd.AddCommand(SyncCmd)
+	SshCmd.AddCommand(LsCmd)
+	SshCmd.AddCommand(GetCmd)
+	SshCmd.AddCommand(PutCmd)
+	SshCmd.AddCommand(EditCmd)
 }
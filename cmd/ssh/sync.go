@@ -15,6 +15,10 @@ var SyncCmd = &cobra.Command{
 	Short: "同步配置到SSH配置文件",
 	Long:  `将数据库中的SSH连接配置同步到 ~/.ssh/config 文件中。`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := services.AutoBackup("sync"); err != nil {
+			fmt.Printf("同步失败: %v\n", err)
+			return
+		}
 		if err := syncToSSHConfig(); err != nil {
 			fmt.Printf("同步失败: %v\n", err)
 			return
@@ -0,0 +1,30 @@
+package trash
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var TrashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "回收站管理",
+	Long: `管理已删除的SSH连接、rsync配置和服务
+
+删除操作不会立即清除数据，而是移入回收站：
+- 列出回收站中的记录
+- 恢复误删的记录
+- 永久清除记录
+
+数据类型: ssh, rsync, service`,
+}
+
+func init() {
+	TrashCmd.AddCommand(listCmd)
+	TrashCmd.AddCommand(restoreCmd)
+	TrashCmd.AddCommand(purgeCmd)
+}
+
+var entityNames = map[string]string{
+	"ssh":     "SSH连接",
+	"rsync":   "Rsync配置",
+	"service": "服务",
+}
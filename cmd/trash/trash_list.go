@@ -0,0 +1,57 @@
+package trash
+
+import (
+	"alfred-tool/models"
+	"alfred-tool/services"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list [数据类型]",
+	Short: "列出回收站中的记录",
+	Long:  `列出回收站中的记录，可指定数据类型 ssh、rsync 或 service`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		entity := ""
+		if len(args) > 0 {
+			entity = args[0]
+		}
+
+		items, err := services.ListTrash(entity)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		displayTrashItems(items)
+	},
+}
+
+func displayTrashItems(items []services.TrashItem) {
+	alfredData := models.AlfredData{
+		Items: lo.Map(items, func(item services.TrashItem, index int) models.AlfredItem {
+			id := strconv.FormatUint(uint64(item.ID), 10)
+			return models.AlfredItem{
+				Uid:      item.Entity + "-" + id,
+				Title:    fmt.Sprintf("[%s] %s", entityNames[item.Entity], item.Name),
+				Subtitle: fmt.Sprintf("ID: %s  删除于 %s", id, item.DeletedAt.Local().Format("2006-01-02 15:04:05")),
+				Arg:      []string{item.Entity, id},
+				Variables: map[string]string{
+					"trash_entity": item.Entity,
+					"trash_id":     id,
+					"trash_name":   item.Name,
+				},
+			}
+		}),
+	}
+	marshal, err := json.Marshal(alfredData)
+	if err != nil {
+		fmt.Printf("JSON序列化失败: %v\n", err)
+		return
+	}
+	fmt.Println(string(marshal))
+}
@@ -0,0 +1,65 @@
+package trash
+
+import (
+	"alfred-tool/services"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var purgeYes bool
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge [数据类型] [名称或ID]",
+	Short: "永久删除回收站中的记录",
+	Long: `永久删除回收站中的记录，此操作不可撤销
+
+不指定参数时清空整个回收站，只指定数据类型时清空该类型的回收站。`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var entity, key string
+		if len(args) > 0 {
+			entity = args[0]
+		}
+		if len(args) > 1 {
+			key = args[1]
+		}
+
+		if key == "" && !purgeYes {
+			target := "整个回收站"
+			if entity != "" {
+				target = entityNames[entity] + "回收站"
+			}
+			fmt.Printf("确认清空%s? (y/N): ", target)
+			reader := bufio.NewReader(os.Stdin)
+			response, _ := reader.ReadString('\n')
+			response = strings.ToLower(strings.TrimSpace(response))
+			if response != "y" && response != "yes" {
+				fmt.Println("取消清理")
+				return
+			}
+		}
+
+		if err := services.AutoBackup("purge"); err != nil {
+			fmt.Printf("清理失败: %v\n", err)
+			return
+		}
+
+		items, err := services.PurgeTrash(entity, key)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		for _, item := range items {
+			fmt.Printf("已永久删除 %s '%s'\n", entityNames[item.Entity], item.Name)
+		}
+		fmt.Printf("共清理 %d 条记录\n", len(items))
+	},
+}
+
+func init() {
+	purgeCmd.Flags().BoolVarP(&purgeYes, "yes", "y", false, "跳过确认")
+}
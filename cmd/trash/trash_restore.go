@@ -0,0 +1,35 @@
+package trash
+
+import (
+	"alfred-tool/services"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [数据类型] [名称或ID]",
+	Short: "从回收站恢复记录",
+	Long:  `从回收站恢复指定的记录，同名记录有多条时恢复最近删除的一条`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		entity, key := args[0], args[1]
+
+		item, err := services.RestoreTrash(entity, key)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		fmt.Printf("%s '%s' 已恢复\n", entityNames[entity], item.Name)
+
+		// rsync配置依赖的SSH连接可能仍在回收站中
+		if entity == "rsync" {
+			config, err := services.GetRsyncConfigByName(item.Name)
+			if err == nil {
+				if _, err := services.GetConnectionByName(config.SSHName); err != nil {
+					fmt.Printf("警告: 关联的SSH连接 '%s' 不存在，可使用 trash restore ssh %s 恢复\n", config.SSHName, config.SSHName)
+				}
+			}
+		}
+	},
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"reflect"
+	"strings"
+	"time"
+
+	"alfred-tool/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// auditedTables 需要记录变更历史的表及其对应的数据类型
+var auditedTables = map[string]string{
+	"ssh_connections": "ssh",
+	"rsync_configs":   "rsync",
+	"services":        "service",
+}
+
+// auditIgnoredFields 不参与变更比较的字段
+var auditIgnoredFields = map[string]bool{
+	"ID":             true,
+	"CreatedAt":      true,
+	"UpdatedAt":      true,
+	"DeletedAt":      true,
+	"usage_count":    true,
+	"ssh_connection": true,
+}
+
+const (
+	auditOldRowsKey = "audit:old_rows"
+	// AuditActionKey 通过 db.Set(AuditActionKey, action) 覆盖自动判断的变更类型
+	AuditActionKey = "audit:action"
+)
+
+// registerAuditCallbacks 注册记录变更历史的回调，所有通过 GORM 的写操作都会被记录
+func registerAuditCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().After("gorm:create").Register("audit:after_create", auditAfterCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("audit:before_update", auditBeforeChange); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("audit:after_update", auditAfterChange); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("audit:before_delete", auditBeforeChange); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register("audit:after_delete", auditAfterChange)
+}
+
+func auditEntity(db *gorm.DB) (string, bool) {
+	if db.Statement.Schema == nil {
+		return "", false
+	}
+	entity, ok := auditedTables[db.Statement.Schema.Table]
+	return entity, ok
+}
+
+// auditSession 返回复用当前连接（包括事务）的新会话，每次查询都应重新获取
+func auditSession(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Unscoped()
+}
+
+// auditBeforeChange 在更新/删除前读取将被影响的记录
+func auditBeforeChange(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if _, ok := auditEntity(db); !ok {
+		return
+	}
+	stmt := db.Statement
+
+	query := auditSession(db).Model(reflect.New(stmt.Schema.ModelType).Interface())
+	conditions := 0
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			query = query.Clauses(where)
+			conditions++
+		}
+	}
+	if stmt.Model != nil {
+		modelValue := reflect.Indirect(reflect.ValueOf(stmt.Model))
+		if modelValue.Kind() == reflect.Struct && stmt.Schema.PrioritizedPrimaryField != nil {
+			if id, isZero := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, modelValue); !isZero {
+				query = query.Where("id = ?", id)
+				conditions++
+			}
+		}
+	}
+	if conditions == 0 {
+		return
+	}
+
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := query.Find(rows.Interface()).Error; err != nil {
+		return
+	}
+	db.InstanceSet(auditOldRowsKey, rows.Elem().Interface())
+}
+
+// auditAfterChange 对比更新/删除前后的记录并写入历史
+func auditAfterChange(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	entity, ok := auditEntity(db)
+	if !ok {
+		return
+	}
+	value, ok := db.InstanceGet(auditOldRowsKey)
+	if !ok {
+		return
+	}
+
+	oldRows := reflect.ValueOf(value)
+	for i := 0; i < oldRows.Len(); i++ {
+		oldRow := oldRows.Index(i)
+		id := oldRow.FieldByName("ID").Uint()
+
+		newRow := reflect.New(db.Statement.Schema.ModelType)
+		err := auditSession(db).Where("id = ?", id).Limit(1).Find(newRow.Interface()).Error
+		if err != nil {
+			continue
+		}
+
+		oldDeleted := isDeleted(oldRow)
+		var action models.ChangeAction
+		var newValue any
+		if newRow.Elem().FieldByName("ID").Uint() == 0 {
+			action = models.ChangeActionPurge
+		} else {
+			newValue = newRow.Interface()
+			newDeleted := isDeleted(newRow.Elem())
+			switch {
+			case !oldDeleted && newDeleted:
+				action = models.ChangeActionDelete
+			case oldDeleted && !newDeleted:
+				action = models.ChangeActionRestore
+			default:
+				action = models.ChangeActionUpdate
+			}
+		}
+
+		oldFields := AuditFields(oldRow.Addr().Interface())
+		newFields := AuditFields(newValue)
+		changes := models.DiffFields(oldFields, newFields)
+		if action == models.ChangeActionUpdate && len(changes) == 0 {
+			continue
+		}
+		if override, ok := db.Get(AuditActionKey); ok && action != models.ChangeActionPurge {
+			action = override.(models.ChangeAction)
+		}
+
+		name := fmt.Sprint(oldFields["name"])
+		if newFields != nil {
+			name = fmt.Sprint(newFields["name"])
+		}
+		snapshot := newFields
+		if snapshot == nil {
+			snapshot = oldFields
+		}
+		writeChangeRecord(db, entity, uint(id), name, action, changes, snapshot)
+	}
+}
+
+// auditAfterCreate 记录新建的记录
+func auditAfterCreate(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	entity, ok := auditEntity(db)
+	if !ok {
+		return
+	}
+
+	var created []reflect.Value
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+			created = append(created, reflect.Indirect(db.Statement.ReflectValue.Index(i)))
+		}
+	case reflect.Struct:
+		created = append(created, db.Statement.ReflectValue)
+	}
+
+	for _, row := range created {
+		if !row.CanAddr() {
+			continue
+		}
+		fields := AuditFields(row.Addr().Interface())
+		changes := models.DiffFields(nil, fields)
+		writeChangeRecord(db, entity, uint(row.FieldByName("ID").Uint()), fmt.Sprint(fields["name"]),
+			models.ChangeActionCreate, changes, fields)
+	}
+}
+
+func isDeleted(row reflect.Value) bool {
+	deletedAt, ok := row.FieldByName("DeletedAt").Interface().(gorm.DeletedAt)
+	return ok && deletedAt.Valid
+}
+
+// AuditFields 将记录转换为参与比较的字段（不含ID、时间和使用次数），敏感字段保留原值用于比较，写入时再隐藏
+func AuditFields(value any) map[string]any {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	fields := make(map[string]any)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	for key := range auditIgnoredFields {
+		delete(fields, key)
+	}
+	return fields
+}
+
+// writeChangeRecord 写入一条变更历史，版本号在同一条 INSERT 中由子查询得到，
+// 多个进程同时修改同一记录时不会得到相同的版本号（另有唯一索引保证）
+func writeChangeRecord(db *gorm.DB, entity string, id uint, name string, action models.ChangeAction,
+	changes []models.FieldChange, snapshot map[string]any) {
+	changesJSON, _ := json.Marshal(changes)
+	snapshotJSON, _ := json.Marshal(models.RedactFields(snapshot))
+
+	nextVersion := auditSession(db).Model(&models.ChangeRecord{}).
+		Select("COALESCE(MAX(version), 0) + 1").
+		Where("entity = ? AND entity_id = ?", entity, id)
+	record := map[string]any{
+		"CreatedAt":  time.Now(),
+		"Entity":     entity,
+		"EntityID":   id,
+		"EntityName": name,
+		"Version":    gorm.Expr("(?)", nextVersion),
+		"Action":     action,
+		"Changes":    string(changesJSON),
+		"Snapshot":   string(snapshotJSON),
+		"User":       currentUsername(),
+		"Hostname":   currentHostname(),
+		"Command":    strings.Join(os.Args, " "),
+	}
+	if err := auditSession(db).Model(&models.ChangeRecord{}).Create(record).Error; err != nil {
+		db.AddError(fmt.Errorf("记录变更历史失败: %v", err))
+	}
+}
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func currentHostname() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}
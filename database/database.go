@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"alfred-tool/models"
 
@@ -14,20 +16,36 @@ import (
 
 var DB *gorm.DB
 
+// DBPath 数据库文件路径
+var DBPath = filepath.Join("/Users/luca/Library/Mobile Documents/com~apple~CloudDocs/ssh/connections.db")
+
+// dsnParams 多个 Alfred 实例可能同时访问数据库：
+// WAL 允许读写并发，busy_timeout 让写锁冲突时等待而不是直接报 "database is locked"，
+// txlock=immediate 让事务开始时就获取写锁，避免事务中途升级锁失败
+const dsnParams = "?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+
 func InitDB() {
 	//homeDir, err := os.UserHomeDir()
 	//if err != nil {
 	//	log.Fatal("无法获取用户主目录:", err)
 	//}
 	var err error
-	dbPath := filepath.Join("/Users/luca/Library/Mobile Documents/com~apple~CloudDocs/ssh/connections.db")
+	dbPath := DBPath
 
 	// 创建目录
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
 		log.Fatal("无法创建数据库目录:", err)
 	}
 
-	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	// 检查 iCloud 冲突副本
+	if conflicts := FindConflictedCopies(); len(conflicts) > 0 {
+		log.Printf("警告: 发现 %d 个数据库冲突副本，可使用 db merge 合并后删除:", len(conflicts))
+		for _, conflict := range conflicts {
+			log.Printf("  %s", conflict)
+		}
+	}
+
+	DB, err = gorm.Open(sqlite.Open(dbPath+dsnParams), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
@@ -35,9 +53,17 @@ func InitDB() {
 	}
 
 	// 自动迁移
-	if err := DB.AutoMigrate(&models.SSHConnection{}, &models.RsyncConfig{}, &models.Service{}); err != nil {
+	if err := DB.AutoMigrate(&models.SSHConnection{}, &models.RsyncConfig{}, &models.Service{}, &models.ChangeRecord{}, &models.RsyncRun{},
+		&models.RsyncGroup{}, &models.RsyncGroupMember{}, &models.RsyncConfigTemplate{}); err != nil {
 		log.Fatal("数据库迁移失败:", err)
 	}
+	if err := dropLegacyNameIndexes(); err != nil {
+		log.Fatal("数据库迁移失败:", err)
+	}
+
+	if err := registerAuditCallbacks(DB); err != nil {
+		log.Fatal("注册变更历史回调失败:", err)
+	}
 
 	log.Println("数据库初始化成功")
 }
@@ -45,3 +71,79 @@ func InitDB() {
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// dropLegacyNameIndexes 删除旧版本的名称唯一索引
+// 旧索引包含已软删除的记录，会导致删除后无法再添加同名记录，已由只约束未删除记录的部分索引替代
+func dropLegacyNameIndexes() error {
+	legacyIndexes := []struct {
+		model any
+		name  string
+	}{
+		{&models.SSHConnection{}, "idx_ssh_connections_name"},
+		{&models.RsyncConfig{}, "idx_rsync_configs_name"},
+	}
+	for _, index := range legacyIndexes {
+		if DB.Migrator().HasIndex(index.model, index.name) {
+			if err := DB.Migrator().DropIndex(index.model, index.name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// conflictedCopyPattern 匹配 iCloud（"connections 2.db"）和 Dropbox（"connections (xxx's conflicted copy).db"）产生的冲突副本
+var conflictedCopyPattern = regexp.MustCompile(`^(.+?)( \d+| \(.*conflicted copy.*\))$`)
+
+// FindConflictedCopies 查找与数据库文件同目录下的冲突副本
+func FindConflictedCopies() []string {
+	dir := filepath.Dir(DBPath)
+	ext := filepath.Ext(DBPath)
+	base := strings.TrimSuffix(filepath.Base(DBPath), ext)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var conflicts []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ext {
+			continue
+		}
+		match := conflictedCopyPattern.FindStringSubmatch(strings.TrimSuffix(name, ext))
+		if match != nil && match[1] == base {
+			conflicts = append(conflicts, filepath.Join(dir, name))
+		}
+	}
+	return conflicts
+}
+
+// GetBackupDir 返回数据库备份目录（与数据库文件同级的 backups 目录）
+func GetBackupDir() string {
+	return filepath.Join(filepath.Dir(DBPath), "backups")
+}
+
+// GetLogDir 返回rsync执行日志目录（与数据库文件同级的 logs 目录）
+func GetLogDir() string {
+	return filepath.Join(filepath.Dir(DBPath), "logs")
+}
+
+// GetStateDir 返回双向同步状态目录（与数据库文件同级的 state 目录）
+func GetStateDir() string {
+	return filepath.Join(filepath.Dir(DBPath), "state")
+}
+
+// CloseDB 关闭数据库连接
+func CloseDB() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	DB = nil
+	return sqlDB.Close()
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFindConflictedCopies(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := DBPath
+	DBPath = filepath.Join(dir, "connections.db")
+	t.Cleanup(func() { DBPath = oldPath })
+
+	files := []string{
+		"connections.db",
+		"connections 2.db",
+		"connections 13.db",
+		"connections (luca's conflicted copy 2024-01-01).db",
+		"connections2.db",
+		"connections 2.db-wal",
+		"connections-backup.db",
+		"other 2.db",
+	}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "connections 3.db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := FindConflictedCopies()
+	sort.Strings(got)
+	want := []string{
+		filepath.Join(dir, "connections (luca's conflicted copy 2024-01-01).db"),
+		filepath.Join(dir, "connections 13.db"),
+		filepath.Join(dir, "connections 2.db"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindConflictedCopies()\n得到 %v\n期望 %v", got, want)
+	}
+}
@@ -68,7 +68,7 @@ struct VisibleWhen: Codable, Equatable {
 
 struct Field: Identifiable, Codable, Comparable {
     enum FieldType: String, Codable {
-        case text, checkbox, dropdown, texteditor, segmented, filepicker
+        case text, checkbox, dropdown, texteditor, segmented, filepicker, remotefolderpicker
     }
     let id = UUID()
     let type: FieldType
@@ -81,9 +81,10 @@ struct Field: Identifiable, Codable, Comparable {
     let note: String? // note text shown below field in red
     let order: Int // display order
     let visibleWhen: VisibleWhen? // conditional visibility
+    let browseCommand: [String]? // command listing a remote folder, the folder path is appended
 
     private enum CodingKeys: String, CodingKey {
-        case type, label, bindingKey, options, defaultValue, filePickerType, copy, note, order, visibleWhen
+        case type, label, bindingKey, options, defaultValue, filePickerType, copy, note, order, visibleWhen, browseCommand
     }
 
     // Implement Comparable for sorting
@@ -239,6 +240,167 @@ struct FilePickerField: View {
     }
 }
 
+// MARK: - RemoteFolderPickerField (Remote Folder Picker)
+struct RemoteListing: Decodable {
+    struct Entry: Decodable, Identifiable {
+        let name: String
+        let path: String
+        let isDir: Bool
+        let isLink: Bool
+        var id: String { path }
+
+        private enum CodingKeys: String, CodingKey {
+            case name, path
+            case isDir = "is_dir"
+            case isLink = "is_link"
+        }
+    }
+    let path: String
+    let parent: String
+    let entries: [Entry]
+}
+
+struct BrowseError: LocalizedError {
+    let message: String
+    var errorDescription: String? { message }
+}
+
+// Runs the browse command with the folder path appended and decodes its JSON output
+func listRemoteFolder(command: [String], path: String) -> Result<RemoteListing, Error> {
+    let process = Process()
+    process.executableURL = URL(fileURLWithPath: "/usr/bin/env")
+    process.arguments = command + [path]
+    let pipe = Pipe()
+    process.standardOutput = pipe
+    do {
+        try process.run()
+    } catch {
+        return .failure(error)
+    }
+    let data = pipe.fileHandleForReading.readDataToEndOfFile()
+    process.waitUntilExit()
+
+    let output = String(data: data, encoding: .utf8)?.trimmingCharacters(in: .whitespacesAndNewlines) ?? ""
+    if process.terminationStatus != 0 {
+        return .failure(BrowseError(message: output.isEmpty ? "列出目录失败" : output))
+    }
+    do {
+        return .success(try JSONDecoder().decode(RemoteListing.self, from: data))
+    } catch {
+        return .failure(BrowseError(message: "无法解析目录列表: \(error.localizedDescription)"))
+    }
+}
+
+struct RemoteFolderPickerField: View {
+    @Binding var path: String
+    let browseCommand: [String]
+    @State private var browsing = false
+
+    var body: some View {
+        HStack(spacing: 8) {
+            TextField("", text: $path)
+                .textFieldStyle(.roundedBorder)
+
+            Button("浏览...") {
+                browsing = true
+            }
+            .frame(width: 80)
+            .disabled(browseCommand.isEmpty)
+        }
+        .sheet(isPresented: $browsing) {
+            RemoteFolderBrowser(command: browseCommand, startPath: path) { selected in
+                if let selected = selected {
+                    path = selected
+                }
+                browsing = false
+            }
+        }
+    }
+}
+
+// Click a folder to open it, "选择" picks the folder currently shown
+struct RemoteFolderBrowser: View {
+    let command: [String]
+    let startPath: String
+    let onFinish: (String?) -> Void
+    @State private var listing: RemoteListing?
+    @State private var errorMessage: String?
+    @State private var loading = false
+
+    var body: some View {
+        VStack(alignment: .leading, spacing: 8) {
+            HStack(spacing: 8) {
+                Button(action: {
+                    if let parent = listing?.parent, !parent.isEmpty {
+                        load(parent)
+                    }
+                }) {
+                    Label("", systemImage: "chevron.up")
+                }
+                .labelStyle(.iconOnly)
+                .disabled(loading || (listing?.parent.isEmpty ?? true))
+
+                Text(listing?.path ?? startPath)
+                    .lineLimit(1)
+                    .truncationMode(.head)
+                Spacer()
+                if loading {
+                    ProgressView().controlSize(.small)
+                }
+            }
+            if let errorMessage = errorMessage {
+                Text(errorMessage)
+                    .font(.caption)
+                    .foregroundColor(.red)
+            }
+            List(listing?.entries.filter { $0.isDir || $0.isLink } ?? []) { entry in
+                Button(action: { load(entry.path) }) {
+                    Label(entry.name, systemImage: entry.isLink ? "link" : "folder")
+                        .frame(maxWidth: .infinity, alignment: .leading)
+                }
+                .buttonStyle(.plain)
+                .disabled(loading)
+            }
+            .frame(minHeight: 240)
+
+            HStack {
+                Spacer()
+                Button("取消") {
+                    onFinish(nil)
+                }
+                .keyboardShortcut(.cancelAction)
+                Button("选择") {
+                    onFinish(listing?.path)
+                }
+                .keyboardShortcut(.defaultAction)
+                .disabled(listing == nil || loading)
+            }
+        }
+        .padding(12)
+        .frame(width: 460, height: 380)
+        .onAppear {
+            load(startPath)
+        }
+    }
+
+    private func load(_ path: String) {
+        loading = true
+        errorMessage = nil
+        DispatchQueue.global(qos: .userInitiated).async {
+            let result = listRemoteFolder(command: command, path: path)
+            DispatchQueue.main.async {
+                loading = false
+                switch result {
+                case .success(let newListing):
+                    listing = newListing
+                case .failure(let error):
+                    errorMessage = error.localizedDescription
+                }
+            }
+        }
+    }
+}
+
 // MARK: - DynamicDialogView
 struct DynamicDialogView: View {
     let fields: [Field]
@@ -479,6 +641,27 @@ struct DynamicDialogView: View {
                                 .padding(.leading, maxLabelWidth + 10)
                         }
                     }
+                case .remotefolderpicker:
+                    VStack(alignment: .leading, spacing: 4) {
+                        HStack(alignment: .center, spacing: 10) {
+                            Text(field.label + ":")
+                                .lineLimit(1)
+                                .frame(width: maxLabelWidth, alignment: .leading)
+                            RemoteFolderPickerField(
+                                path: Binding(
+                                    get: { self.values[field.bindingKey] as? String ?? "" },
+                                    set: { self.values[field.bindingKey] = $0 }
+                                ),
+                                browseCommand: field.browseCommand ?? []
+                            )
+                        }
+                        if let note = field.note, !note.isEmpty {
+                            Text(note)
+                                .font(.caption)
+                                .foregroundColor(.red)
+                                .padding(.leading, maxLabelWidth + 10)
+                        }
+                    }
                     }
                 }
             }
@@ -500,7 +683,7 @@ struct DynamicDialogView: View {
                     var output: [String: Any] = [:]
                     for field in fields {
                         switch field.type {
-                        case .text, .texteditor, .filepicker:
+                        case .text, .texteditor, .filepicker, .remotefolderpicker:
                             output[field.bindingKey] = values[field.bindingKey] as? String ?? ""
                         case .checkbox:
                             output[field.bindingKey] = values[field.bindingKey] as? Bool ?? false
@@ -2,6 +2,8 @@ package dialog
 
 import (
 	"alfred-tool/dialog/field"
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -62,6 +64,14 @@ func TestDialog(t *testing.T) {
 				field.WithOrder(7),
 				field.WithVisibleWhen("theme", "自动"),
 			),
+
+			// 服务器目录选择器
+			field.NewRemoteFolderField("remotePath", "服务器目录",
+				[]string{"alfred-tool", "ssh", "ls", "web-01", "--dirs", "-f", "json"},
+				field.WithDefaultValue("/var/www"),
+				field.WithNote("点击浏览逐级选择服务器上的目录"),
+				field.WithOrder(8),
+			),
 		),
 	).Open()
 	if err != nil {
@@ -70,3 +80,28 @@ func TestDialog(t *testing.T) {
 	}
 	t.Log("对话框返回结果:", open)
 }
+
+func TestRemoteFolderFieldJSON(t *testing.T) {
+	command := []string{"/usr/local/bin/alfred-tool", "ssh", "ls", "web-01", "--dirs", "-f", "json"}
+	d := NewDialog(WithFields(field.NewRemoteFolderField("remotePath", "远程路径", command, field.WithDefaultValue("/var/www"))))
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Fields map[string]struct {
+			Type          string   `json:"type"`
+			BindingKey    string   `json:"bindingKey"`
+			DefaultValue  string   `json:"defaultValue"`
+			BrowseCommand []string `json:"browseCommand"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	f := decoded.Fields["remotePath"]
+	if f.Type != "remotefolderpicker" || f.BindingKey != "remotePath" || f.DefaultValue != "/var/www" || !reflect.DeepEqual(f.BrowseCommand, command) {
+		t.Errorf("服务器目录选择器字段序列化错误: %s", data)
+	}
+}
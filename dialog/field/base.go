@@ -3,12 +3,13 @@ package field
 type FieldType string
 
 const (
-	Text       FieldType = "text"       //文本
-	TextEditor FieldType = "texteditor" //文本编辑器
-	CheckBox   FieldType = "checkbox"   //多选框
-	Dropdown   FieldType = "dropdown"   //下拉框
-	Segmented  FieldType = "segmented"  // 分段器
-	FilePicker FieldType = "filepicker" // 文件选择器
+	Text               FieldType = "text"               //文本
+	TextEditor         FieldType = "texteditor"         //文本编辑器
+	CheckBox           FieldType = "checkbox"           //多选框
+	Dropdown           FieldType = "dropdown"           //下拉框
+	Segmented          FieldType = "segmented"          // 分段器
+	FilePicker         FieldType = "filepicker"         // 文件选择器
+	RemoteFolderPicker FieldType = "remotefolderpicker" // 服务器目录选择器
 )
 
 type FilePickerType string
@@ -26,9 +27,10 @@ type Field struct {
 	Copy           bool           `json:"copy,omitempty"`
 	FilePickerType FilePickerType `json:"filePickerType,omitempty"`
 	Options        []string       `json:"options,omitempty"`
-	Note           string         `json:"note,omitempty"`        // 字段备注，显示在控件下方的红色小字
-	Order          int            `json:"order"`                 // 字段显示顺序，数字越小越靠前
-	VisibleWhen    *VisibleWhen   `json:"visibleWhen,omitempty"` // 条件显示配置
+	Note           string         `json:"note,omitempty"`          // 字段备注，显示在控件下方的红色小字
+	Order          int            `json:"order"`                   // 字段显示顺序，数字越小越靠前
+	VisibleWhen    *VisibleWhen   `json:"visibleWhen,omitempty"`   // 条件显示配置
+	BrowseCommand  []string       `json:"browseCommand,omitempty"` // 服务器目录选择器列出目录的命令，目录路径作为最后一个参数传入
 }
 
 // VisibleWhen 定义字段的可见性条件
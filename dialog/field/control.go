@@ -285,3 +285,37 @@ func NewFolderField(key, name string, opts ...FieldOption) Field {
 	}
 	return NewFilePickerField(key, name, Folder, opts...)
 }
+
+// WithBrowseCommand 设置服务器目录选择器列出目录的命令
+// 参数:
+//   - command: 命令及其参数，对话框把要列出的目录作为最后一个参数追加后执行，
+//     命令应输出 ssh ls -f json 格式的 JSON（path、parent、entries）
+//
+// 注意: 仅对 RemoteFolderPicker 类型字段有效
+//
+// 示例:
+//
+//	WithBrowseCommand([]string{"/usr/local/bin/alfred-tool", "ssh", "ls", "web-01", "--dirs", "-f", "json"})
+func WithBrowseCommand(command []string) FieldOption {
+	return func(f *Field) {
+		f.BrowseCommand = command
+	}
+}
+
+// NewRemoteFolderField 创建一个服务器目录选择器字段，可以直接输入路径，或点击浏览逐级选择服务器上的目录
+// 参数:
+//   - key: 字段绑定的键名（bindingKey）
+//   - name: 字段显示的标签名称（label）
+//   - browseCommand: 列出目录的命令，见 WithBrowseCommand
+//   - opts: 可选的字段配置项，如 WithDefaultValue、WithNote、WithOrder 等
+//
+// 示例:
+//
+//	field.NewRemoteFolderField("remotePath", "远程路径", []string{exe, "ssh", "ls", "web-01", "--dirs", "-f", "json"}, WithDefaultValue("/var/www"))
+func NewRemoteFolderField(key, name string, browseCommand []string, opts ...FieldOption) Field {
+	if opts == nil {
+		opts = make([]FieldOption, 0)
+	}
+	opts = append(opts, WithBindingKey(key), WithLabel(name), WithBrowseCommand(browseCommand))
+	return NewField(RemoteFolderPicker, opts...)
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type ChangeAction string
+
+const (
+	ChangeActionCreate  ChangeAction = "create"
+	ChangeActionUpdate  ChangeAction = "update"
+	ChangeActionDelete  ChangeAction = "delete"  // 移入回收站
+	ChangeActionRestore ChangeAction = "restore" // 从回收站恢复
+	ChangeActionPurge   ChangeAction = "purge"   // 永久删除
+	ChangeActionRevert  ChangeAction = "revert"  // 回滚到历史版本
+)
+
+// RedactedValue 敏感字段在历史记录中的占位值
+const RedactedValue = "******"
+
+// changeSecretFields 敏感字段，历史记录中只记录是否变更，不记录内容
+var changeSecretFields = map[string]bool{
+	"password": true,
+}
+
+// ChangeRecord 数据变更历史，只追加不修改
+// 同一记录的版本号由唯一索引保证不重复
+type ChangeRecord struct {
+	ID         uint         `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time    `json:"created_at"`
+	Entity     string       `gorm:"index:idx_change_records_entity;uniqueIndex:idx_change_records_version;not null" json:"entity"` // ssh、rsync 或 service
+	EntityID   uint         `gorm:"index:idx_change_records_entity;uniqueIndex:idx_change_records_version;not null" json:"entity_id"`
+	EntityName string       `gorm:"index" json:"entity_name"`
+	Version    int          `gorm:"uniqueIndex:idx_change_records_version;not null" json:"version"` // 同一记录的版本号，从1开始递增
+	Action     ChangeAction `gorm:"not null" json:"action"`
+	Changes    string       `json:"changes"`  // 字段级变更，JSON 格式的 []FieldChange
+	Snapshot   string       `json:"snapshot"` // 变更后的完整数据，JSON 格式，敏感字段已隐藏
+	User       string       `json:"user"`
+	Hostname   string       `json:"hostname"`
+	Command    string       `json:"command"`
+}
+
+// FieldChange 单个字段的变更
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// SnapshotFields 解析版本的快照
+func (r *ChangeRecord) SnapshotFields() (map[string]any, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(r.Snapshot), &fields); err != nil {
+		return nil, fmt.Errorf("解析版本 %d 失败: %v", r.Version, err)
+	}
+	return fields, nil
+}
+
+// DiffFields 比较两组字段，返回按字段名排序的有变化的字段，敏感字段的值会被隐藏
+// oldFields 为 nil 时为新建，newFields 为 nil 时为永久删除
+func DiffFields(oldFields, newFields map[string]any) []FieldChange {
+	keys := make(map[string]bool)
+	for key := range oldFields {
+		keys[key] = true
+	}
+	for key := range newFields {
+		keys[key] = true
+	}
+
+	var changes []FieldChange
+	for key := range keys {
+		oldValue := formatFieldValue(oldFields[key])
+		newValue := formatFieldValue(newFields[key])
+		if oldValue == newValue {
+			continue
+		}
+		if changeSecretFields[key] {
+			oldValue, newValue = redact(oldValue), redact(newValue)
+		}
+		changes = append(changes, FieldChange{Field: key, Old: oldValue, New: newValue})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// RedactFields 隐藏敏感字段，用于写入快照
+func RedactFields(fields map[string]any) map[string]any {
+	redacted := make(map[string]any, len(fields))
+	for key, value := range fields {
+		if changeSecretFields[key] {
+			value = redact(formatFieldValue(value))
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// RevertFields 回滚时用快照覆盖当前字段，快照中已隐藏的敏感字段和快照中没有的字段保留当前值
+func RevertFields(current, snapshot map[string]any) map[string]any {
+	reverted := make(map[string]any, len(current))
+	for field, value := range current {
+		reverted[field] = value
+	}
+	for field, value := range snapshot {
+		if value == RedactedValue {
+			continue
+		}
+		reverted[field] = value
+	}
+	return reverted
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+func formatFieldValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
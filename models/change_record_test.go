@@ -0,0 +1,69 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffFields(t *testing.T) {
+	oldFields := map[string]any{"name": "web", "port": float64(22), "password": "old", "tags": "prod"}
+	newFields := map[string]any{"name": "web", "port": float64(2222), "password": "new", "description": "主站"}
+
+	got := DiffFields(oldFields, newFields)
+	want := []FieldChange{
+		{Field: "description", Old: "", New: "主站"},
+		{Field: "password", Old: RedactedValue, New: RedactedValue},
+		{Field: "port", Old: "22", New: "2222"},
+		{Field: "tags", Old: "prod", New: ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffFields\n得到 %+v\n期望 %+v", got, want)
+	}
+
+	// 新建时所有非空字段都是变更，清空的密码不显示占位值
+	created := DiffFields(nil, map[string]any{"name": "web", "password": "", "enabled": true})
+	want = []FieldChange{{Field: "enabled", Old: "", New: "true"}, {Field: "name", Old: "", New: "web"}}
+	if !reflect.DeepEqual(created, want) {
+		t.Errorf("新建记录的变更\n得到 %+v\n期望 %+v", created, want)
+	}
+
+	if changes := DiffFields(oldFields, oldFields); len(changes) != 0 {
+		t.Errorf("相同的字段不应有变更: %+v", changes)
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	fields := map[string]any{"name": "web", "password": "secret"}
+	redacted := RedactFields(fields)
+	if redacted["password"] != RedactedValue || redacted["name"] != "web" {
+		t.Errorf("敏感字段应被隐藏: %v", redacted)
+	}
+	if fields["password"] != "secret" {
+		t.Error("RedactFields 不应修改传入的字段")
+	}
+}
+
+func TestRevertFields(t *testing.T) {
+	current := map[string]any{"name": "web-new", "port": float64(2222), "password": "current", "usage_count": float64(5)}
+	snapshot := map[string]any{"name": "web", "port": float64(22), "password": RedactedValue}
+
+	got := RevertFields(current, snapshot)
+	want := map[string]any{"name": "web", "port": float64(22), "password": "current", "usage_count": float64(5)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RevertFields\n得到 %v\n期望 %v", got, want)
+	}
+	if current["name"] != "web-new" {
+		t.Error("RevertFields 不应修改当前字段")
+	}
+}
+
+func TestChangeRecordSnapshotFields(t *testing.T) {
+	record := ChangeRecord{Version: 3, Snapshot: `{"name":"web","port":22}`}
+	fields, err := record.SnapshotFields()
+	if err != nil || fields["name"] != "web" || fields["port"] != float64(22) {
+		t.Errorf("SnapshotFields = %v, %v", fields, err)
+	}
+	if _, err := (&ChangeRecord{Version: 4, Snapshot: "{"}).SnapshotFields(); err == nil {
+		t.Error("无效的快照应该返回错误")
+	}
+}
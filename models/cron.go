@@ -0,0 +1,228 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros 常用的简写
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField 一个字段的取值范围和可用的名称
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // 从 min 开始的名称，如月份 jan、星期 sun
+}
+
+var cronFields = []cronField{
+	{name: "分钟", min: 0, max: 59},
+	{name: "小时", min: 0, max: 23},
+	{name: "日期", min: 1, max: 31},
+	{name: "月份", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "星期", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// CronSchedule 解析后的 cron 表达式（分 时 日 月 周）
+type CronSchedule struct {
+	Expr string
+	// 每个字段允许的取值，按位存储
+	minute, hour, dom, month, dow uint64
+	// 日期或星期为 * 时两者取交集，否则取并集（与 cron 的行为一致）
+	domStar, dowStar bool
+}
+
+// ParseCron 解析标准的 5 段 cron 表达式，支持列表、范围、步长、月份和星期名称以及 @daily 等简写
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron 表达式 '%s' 应包含 5 个字段（分 时 日 月 周）", expr)
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron 表达式 '%s' 的%s字段错误: %v", expr, cronFields[i].name, err)
+		}
+		bits[i] = b
+	}
+
+	// 星期的 7 与 0 都表示周日
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &CronSchedule{
+		Expr:    expr,
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("无效的步长 '%s'", item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = spec.min, spec.max
+			if spec.max == 7 {
+				end = 6
+			}
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = parseCronValue(bounds[0], spec); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(bounds[1], spec); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("无效的范围 '%s'", rangePart)
+			}
+		default:
+			value, err := parseCronValue(rangePart, spec)
+			if err != nil {
+				return 0, err
+			}
+			start, end = value, value
+			// "5/10" 表示从 5 开始每 10 个
+			if step > 1 {
+				end = spec.max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, spec cronField) (int, error) {
+	for i, name := range spec.names {
+		if strings.EqualFold(s, name) {
+			return spec.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("无效的值 '%s'", s)
+	}
+	if v < spec.min || v > spec.max {
+		return 0, fmt.Errorf("值 %d 超出范围 %d-%d", v, spec.min, spec.max)
+	}
+	return v, nil
+}
+
+// Next 返回 after 之后（不含）的下一次执行时间，5 年内没有匹配时返回零值
+func (c *CronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// SystemdOnCalendar 转换为 systemd timer 的 OnCalendar 表达式
+// 日期和星期都有限制时 cron 取并集，systemd 需要两条 OnCalendar 表示
+func (c *CronSchedule) SystemdOnCalendar() []string {
+	minute := systemdValues(c.minute, 0, 59, nil)
+	hour := systemdValues(c.hour, 0, 23, nil)
+	month := systemdValues(c.month, 1, 12, nil)
+	day := systemdValues(c.dom, 1, 31, nil)
+	weekday := systemdValues(c.dow, 0, 6, []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"})
+
+	clock := fmt.Sprintf("%s:%s:00", hour, minute)
+	switch {
+	case c.domStar && c.dowStar:
+		return []string{fmt.Sprintf("*-%s-* %s", month, clock)}
+	case c.dowStar:
+		return []string{fmt.Sprintf("*-%s-%s %s", month, day, clock)}
+	case c.domStar:
+		return []string{fmt.Sprintf("%s *-%s-* %s", weekday, month, clock)}
+	default:
+		return []string{
+			fmt.Sprintf("*-%s-%s %s", month, day, clock),
+			fmt.Sprintf("%s *-%s-* %s", weekday, month, clock),
+		}
+	}
+}
+
+// systemdValues 取值覆盖整个范围时返回 *，否则返回逗号分隔的列表
+func systemdValues(bits uint64, min, max int, names []string) string {
+	var values []string
+	for v := min; v <= max; v++ {
+		if bits&(1<<uint(v)) == 0 {
+			continue
+		}
+		if names != nil {
+			values = append(values, names[v-min])
+		} else {
+			values = append(values, fmt.Sprintf("%02d", v))
+		}
+	}
+	if len(values) == max-min+1 {
+		return "*"
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC) // 周三
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 10, 31, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 31, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * mon,fri", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		// 日期和星期都有限制时取并集：每月 15 号或每周一
+		{"0 0 15 * 1", time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 10 31 1 *", time.Date(2025, 1, 31, 10, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) 错误: %v", tt.expr, err)
+			continue
+		}
+		if got := schedule.Next(base); !got.Equal(tt.want) {
+			t.Errorf("ParseCron(%q).Next = %s, 期望 %s", tt.expr, got, tt.want)
+		}
+	}
+
+	if schedule, _ := ParseCron("0 0 30 2 *"); !schedule.Next(base).IsZero() {
+		t.Error("2 月 30 日不存在，Next 应返回零值")
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) 应该返回错误", expr)
+		}
+	}
+}
+
+func TestCronSystemdOnCalendar(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"0 3 * * *", []string{"*-*-* 03:00:00"}},
+		{"*/30 * * * *", []string{"*-*-* *:00,30:00"}},
+		{"0 9 * * 1-5", []string{"Mon,Tue,Wed,Thu,Fri *-*-* 09:00:00"}},
+		{"0 0 1 jan,jul *", []string{"*-01,07-01 00:00:00"}},
+		{"0 0 15 * 1", []string{"*-*-15 00:00:00", "Mon *-*-* 00:00:00"}},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) 错误: %v", tt.expr, err)
+		}
+		if got := schedule.SystemdOnCalendar(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCron(%q).SystemdOnCalendar = %q, 期望 %q", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RemoteFileSpec 命令行中 连接名称:路径 形式的服务器路径
+type RemoteFileSpec struct {
+	Connection string
+	Path       string // 为空时为用户目录
+}
+
+func (s RemoteFileSpec) String() string {
+	return s.Connection + ":" + s.Path
+}
+
+// ParseRemoteFileSpec 解析 连接名称:路径，以第一个 : 分隔，不是这种形式时返回 false
+func ParseRemoteFileSpec(s string) (RemoteFileSpec, bool) {
+	name, p, ok := strings.Cut(s, ":")
+	if !ok || name == "" {
+		return RemoteFileSpec{}, false
+	}
+	return RemoteFileSpec{Connection: name, Path: p}, true
+}
+
+// FileTransferOptions ssh get、put 传输单个文件或目录的选项
+type FileTransferOptions struct {
+	Recursive bool // 递归传输目录
+	Resume    bool // 从已有的部分文件继续传输
+	Preserve  bool // 保持修改时间和权限
+}
+
+// BuildFileTransferCommand 生成以批处理模式执行 sftp 传输文件的命令，批处理命令从标准输入读取
+// 使用密码的连接由 ssh 在终端中询问密码
+func BuildFileTransferCommand(sshConnection *SSHConnection) []string {
+	cmd := append(sftpBatchArgs(sshConnection), "-o", "ConnectTimeout=10")
+	return append(cmd, sshConnection.Destination())
+}
+
+// BuildFileTransferBatch 下载（get）或上传（put）一个文件或目录的 sftp 批处理命令
+// 批处理模式默认不显示进度，先用 progress 打开；remote 为空或 ~ 时为用户目录，~/ 开头的路径相对于用户目录
+func BuildFileTransferBatch(upload bool, local, remote string, opts FileTransferOptions) (string, error) {
+	if strings.ContainsAny(local+remote, "\n\r") {
+		return "", fmt.Errorf("路径包含换行符，sftp 无法处理")
+	}
+
+	command := "get"
+	if upload {
+		command = "put"
+	}
+	if opts.Recursive {
+		command += " -r"
+	}
+	if opts.Resume {
+		command += " -a"
+	}
+	if opts.Preserve {
+		command += " -p"
+	}
+
+	local = SFTPQuote(localSFTPPath(local))
+	remote = SFTPQuote(sftpRemotePath(strings.TrimSpace(remote), ""))
+	if upload {
+		return fmt.Sprintf("progress\n%s %s %s\n", command, local, remote), nil
+	}
+	return fmt.Sprintf("progress\n%s %s %s\n", command, remote, local), nil
+}
+
+// localSFTPPath 本地路径，以 - 开头的参数会被 sftp 当作选项
+func localSFTPPath(p string) string {
+	if p == "" {
+		return "."
+	}
+	if strings.HasPrefix(p, "-") {
+		return "./" + p
+	}
+	return p
+}
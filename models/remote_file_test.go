@@ -0,0 +1,67 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRemoteFileSpec(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   RemoteFileSpec
+		wantOK bool
+	}{
+		{"web:/var/log/app.log", RemoteFileSpec{Connection: "web", Path: "/var/log/app.log"}, true},
+		{"web:", RemoteFileSpec{Connection: "web"}, true},
+		{"web:c:d", RemoteFileSpec{Connection: "web", Path: "c:d"}, true},
+		{"app.log", RemoteFileSpec{}, false},
+		{":/tmp", RemoteFileSpec{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRemoteFileSpec(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseRemoteFileSpec(%q) = %+v, %v, want %+v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBuildFileTransferBatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		upload bool
+		local  string
+		remote string
+		opts   FileTransferOptions
+		want   string
+	}{
+		{"下载到当前目录", false, "", "/var/log/app.log", FileTransferOptions{}, "progress\nget /var/log/app.log .\n"},
+		{"递归续传下载", false, "backup", "~/data dir", FileTransferOptions{Recursive: true, Resume: true}, "progress\nget -r -a data\\ dir backup\n"},
+		{"上传到用户目录", true, "-notes.txt", "", FileTransferOptions{Preserve: true}, "progress\nput -p ./-notes.txt .\n"},
+		{"上传目录", true, "site", "/srv/www", FileTransferOptions{Recursive: true}, "progress\nput -r site /srv/www\n"},
+	}
+	for _, tt := range tests {
+		got, err := BuildFileTransferBatch(tt.upload, tt.local, tt.remote, tt.opts)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: BuildFileTransferBatch() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := BuildFileTransferBatch(false, "a", "b\nrm c", FileTransferOptions{}); err == nil {
+		t.Error("BuildFileTransferBatch() 路径包含换行符时应返回错误")
+	}
+}
+
+func TestBuildFileTransferCommand(t *testing.T) {
+	password := &SSHConnection{Address: "example.com", Port: 2222, Username: "luca", PasswordType: PasswordTypePassword}
+	got := BuildFileTransferCommand(password)
+	want := []string{"sftp", "-o", "BatchMode=no", "-b", "-", "-P", "2222", "-o", "ConnectTimeout=10", "luca@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("使用密码的连接\n得到 %q\n期望 %q", got, want)
+	}
+
+	key := &SSHConnection{Address: "example.com", Port: 22, Username: "luca", PasswordType: PasswordTypeKeyPath, KeyPath: "/keys/id_rsa"}
+	got = BuildFileTransferCommand(key)
+	want = []string{"sftp", "-b", "-", "-P", "22", "-i", "/keys/id_rsa", "-o", "ConnectTimeout=10", "luca@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("使用密钥的连接\n得到 %q\n期望 %q", got, want)
+	}
+}
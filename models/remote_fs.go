@@ -0,0 +1,221 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RemoteEntry 服务器目录中的一项
+type RemoteEntry struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"` // 绝对路径
+	IsDir    bool   `json:"is_dir"`
+	IsLink   bool   `json:"is_link"` // 符号链接，指向的可能是目录
+	Size     int64  `json:"size"`
+	Mode     string `json:"mode"`     // 类型和权限，如 drwxr-xr-x
+	Modified string `json:"modified"` // 服务器输出的修改时间，如 Jan  5 10:22
+}
+
+// Browsable 是否可以进入，符号链接指向的可能是目录
+func (e RemoteEntry) Browsable() bool {
+	return e.IsDir || e.IsLink
+}
+
+// RemoteListing 服务器上一个目录的内容
+type RemoteListing struct {
+	Connection string        `json:"connection"`
+	Path       string        `json:"path"`   // 目录的绝对路径
+	Parent     string        `json:"parent"` // 上级目录，根目录时为空
+	Entries    []RemoteEntry `json:"entries"`
+}
+
+// BuildRemoteLsCommand 生成以批处理模式执行 sftp 列出目录的命令，批处理命令由 BuildRemoteLsBatch 生成并从标准输入读取
+// 使用密码的连接由 ssh 在终端中询问密码
+func BuildRemoteLsCommand(sshConnection *SSHConnection) []string {
+	cmd := append(sftpBatchArgs(sshConnection), "-q", "-o", "ConnectTimeout=10")
+	return append(cmd, sshConnection.Destination())
+}
+
+// BuildRemoteLsBatch 列出目录的 sftp 批处理命令：进入目录、输出绝对路径、列出所有文件
+// dir 为空或 ~ 时为用户目录，~/ 开头的路径相对于用户目录
+func BuildRemoteLsBatch(dir string) string {
+	var b strings.Builder
+	if dir = strings.TrimSpace(dir); dir != "" && dir != "~" {
+		b.WriteString("cd " + SFTPQuote(sftpRemotePath(dir, "")) + "\n")
+	}
+	b.WriteString("pwd\nls -la\n")
+	return b.String()
+}
+
+// remoteLsLinePattern 匹配 sftp ls -l 输出的一行：类型和权限、链接数、所有者、组、大小、修改时间（三段）、名称
+var remoteLsLinePattern = regexp.MustCompile(`^([-dlcbps?])(\S{9})\S*\s+\d+\s+\S+\s+\S+\s+(\d+)\s+(\S+\s+\S+\s+\S+) (.+)$`)
+
+// ParseRemoteLs 解析 BuildRemoteLsBatch 的输出，返回目录的绝对路径和其中的项，目录在前，按名称排序
+func ParseRemoteLs(output string) (string, []RemoteEntry, error) {
+	var dir string
+	var entries []RemoteEntry
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "sftp> ") {
+			continue
+		}
+		if p, ok := strings.CutPrefix(line, "Remote working directory: "); ok {
+			dir = p
+			continue
+		}
+		if entry, ok := parseRemoteLsLine(line); ok && entry.Name != "." && entry.Name != ".." {
+			entries = append(entries, entry)
+		}
+	}
+	if dir == "" {
+		return "", nil, fmt.Errorf("无法解析 sftp 输出的目录")
+	}
+
+	for i := range entries {
+		entries[i].Path = path.Join(dir, entries[i].Name)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Browsable() != entries[j].Browsable() {
+			return entries[i].Browsable()
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return dir, entries, nil
+}
+
+// parseRemoteLsLine 解析 sftp ls -l 输出的一行，名称只保留最后一段路径
+func parseRemoteLsLine(line string) (RemoteEntry, bool) {
+	match := remoteLsLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return RemoteEntry{}, false
+	}
+	name := match[5]
+	// 部分服务器会在符号链接后附加 -> 目标
+	if before, _, ok := strings.Cut(name, " -> "); ok && match[1] == "l" {
+		name = before
+	}
+	// 列出指定的目录时名称带有目录的路径
+	if name != "/" {
+		name = path.Base(name)
+	}
+	size, _ := strconv.ParseInt(match[3], 10, 64)
+	return RemoteEntry{
+		Name:     name,
+		IsDir:    match[1] == "d",
+		IsLink:   match[1] == "l",
+		Size:     size,
+		Mode:     match[1] + match[2],
+		Modified: strings.Join(strings.Fields(match[4]), " "),
+	}, true
+}
+
+// ParseRemoteLsTime 解析 sftp ls -l 输出的修改时间，返回 Unix 秒和精度（秒）
+// sftp 只显示到分钟，半年以前的文件只显示日期；以 -n 列出时由本地的 sftp 按本地时区格式化，时间按 now 的时区解析
+func ParseRemoteLsTime(modified string, now time.Time) (int64, int64, error) {
+	fields := strings.Fields(modified)
+	if len(fields) != 3 {
+		return 0, 0, fmt.Errorf("无法解析修改时间: %q", modified)
+	}
+	month, err := time.Parse("Jan", fields[0])
+	day, err2 := strconv.Atoi(fields[1])
+	if err != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("无法解析修改时间: %q", modified)
+	}
+
+	if clock, err := time.Parse("15:04", fields[2]); err == nil {
+		// 没有年份时为最近半年内，晚于现在的属于上一年
+		t := time.Date(now.Year(), month.Month(), day, clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t.Unix(), 60, nil
+	}
+	year, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, 0, fmt.Errorf("无法解析修改时间: %q", modified)
+	}
+	return time.Date(year, month.Month(), day, 0, 0, 0, 0, now.Location()).Unix(), 24 * 60 * 60, nil
+}
+
+// parseRemoteLsPerm 解析 rwxr-xr-x 形式的权限位，不含 setuid、setgid 和 sticky 位
+func parseRemoteLsPerm(mode string) uint32 {
+	var perm uint32
+	for i, c := range mode {
+		if i >= 9 {
+			break
+		}
+		perm <<= 1
+		if c != '-' && c != 'S' && c != 'T' {
+			perm |= 1
+		}
+	}
+	return perm
+}
+
+// BuildRemoteTreeBatch 列出 root 下 dirs 中各目录的 sftp 批处理命令，dirs 为相对路径，空字符串为 root 本身
+// 以 -n 列出使修改时间由本地的 sftp 格式化；命令以 - 开头，目录不存在或不能读取时继续执行后面的命令
+func BuildRemoteTreeBatch(root string, dirs []string) (string, error) {
+	var b strings.Builder
+	for _, dir := range dirs {
+		if strings.ContainsAny(dir, "\n\r") {
+			return "", fmt.Errorf("文件名 %q 包含换行符，无法通过 sftp 列出", dir)
+		}
+		b.WriteString("-ls -lan " + SFTPQuote(sftpRemotePath(root, dir)) + "\n")
+	}
+	return b.String(), nil
+}
+
+// RemoteTreeListing BuildRemoteTreeBatch 中一个目录的列表
+type RemoteTreeListing struct {
+	Self    *RemoteEntry  // 目录本身（. 项），列出的是文件时为文件本身，不存在时为空
+	Entries []RemoteEntry // 目录中的项，不含 . 和 ..
+}
+
+// ParseRemoteTreeBatch 解析 BuildRemoteTreeBatch 的输出，按 sftp 回显的命令分段，返回与 dirs 顺序相同的列表
+func ParseRemoteTreeBatch(output string, count int) []RemoteTreeListing {
+	listings := make([]RemoteTreeListing, count)
+	current := -1
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "sftp> ") {
+			current++
+			continue
+		}
+		if current < 0 || current >= count {
+			continue
+		}
+		entry, ok := parseRemoteLsLine(line)
+		if !ok {
+			continue
+		}
+		listing := &listings[current]
+		switch entry.Name {
+		case ".":
+			listing.Self = &entry
+		case "..":
+		default:
+			listing.Entries = append(listing.Entries, entry)
+		}
+	}
+	// 列出的是文件而不是目录时，输出只有文件本身一行
+	for i := range listings {
+		if listings[i].Self == nil && len(listings[i].Entries) == 1 && !listings[i].Entries[0].IsDir {
+			listings[i].Self = &listings[i].Entries[0]
+			listings[i].Entries = nil
+		}
+	}
+	return listings
+}
+
+// RemoteParent 上级目录，根目录时为空
+func RemoteParent(dir string) string {
+	if dir == "/" || dir == "" {
+		return ""
+	}
+	return path.Dir(dir)
+}
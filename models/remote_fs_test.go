@@ -0,0 +1,116 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildRemoteLsBatch(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"", "pwd\nls -la\n"},
+		{"~", "pwd\nls -la\n"},
+		{"~/logs", "cd logs\npwd\nls -la\n"},
+		{"/var/my app", "cd /var/my\\ app\npwd\nls -la\n"},
+	}
+	for _, tt := range tests {
+		if got := BuildRemoteLsBatch(tt.dir); got != tt.want {
+			t.Errorf("BuildRemoteLsBatch(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestParseRemoteLs(t *testing.T) {
+	output := strings.Join([]string{
+		"sftp> pwd",
+		"Remote working directory: /srv/app",
+		"sftp> ls -la",
+		"drwxr-xr-x    5 deploy   deploy       4096 Jan  5 10:22 .",
+		"drwxr-xr-x    3 root     root         4096 Jan  1  2024 ..",
+		"-rw-r--r--    1 deploy   deploy        120 Jan  5 10:22 README.md",
+		"drwxr-xr-x    2 deploy   deploy       4096 Jan  5 10:22 logs",
+		"lrwxrwxrwx    1 deploy   deploy         18 Jan  5 10:22 current",
+		"lrwxrwxrwx    1 deploy   deploy          4 Jan  5 10:22 data -> /data",
+		"-rw-r--r--    1 deploy   deploy          7 Jan  5 10:22 my notes.txt",
+		"",
+	}, "\n")
+	dir, entries, err := ParseRemoteLs(output)
+	if err != nil {
+		t.Fatalf("ParseRemoteLs() error = %v", err)
+	}
+	if dir != "/srv/app" {
+		t.Errorf("dir = %q, want /srv/app", dir)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if got, want := strings.Join(names, ","), "current,data,logs,README.md,my notes.txt"; got != want {
+		t.Errorf("names = %s, want %s", got, want)
+	}
+	if e := entries[2]; !e.IsDir || e.Path != "/srv/app/logs" || e.Mode != "drwxr-xr-x" {
+		t.Errorf("logs = %+v", e)
+	}
+	if e := entries[0]; !e.IsLink || !e.Browsable() {
+		t.Errorf("current = %+v", e)
+	}
+	if e := entries[1]; e.Path != "/srv/app/data" {
+		t.Errorf("data = %+v", e)
+	}
+	if e := entries[4]; e.Size != 7 || e.Modified != "Jan 5 10:22" || e.Path != "/srv/app/my notes.txt" {
+		t.Errorf("my notes.txt = %+v", e)
+	}
+
+	if _, _, err := ParseRemoteLs("Couldn't canonicalize: No such file or directory\n"); err == nil {
+		t.Error("ParseRemoteLs() 缺少目录时应返回错误")
+	}
+}
+
+func TestRemoteParent(t *testing.T) {
+	tests := map[string]string{"/": "", "": "", "/srv": "/", "/srv/app": "/srv"}
+	for dir, want := range tests {
+		if got := RemoteParent(dir); got != want {
+			t.Errorf("RemoteParent(%q) = %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestBuildRemoteTreeBatch(t *testing.T) {
+	got, err := BuildRemoteTreeBatch("~/site", []string{"", "css", "my docs"})
+	want := "-ls -lan site\n-ls -lan site/css\n-ls -lan site/my\\ docs\n"
+	if err != nil || got != want {
+		t.Errorf("BuildRemoteTreeBatch() = %q, %v, want %q", got, err, want)
+	}
+	if _, err := BuildRemoteTreeBatch("/srv", []string{"a\nb"}); err == nil {
+		t.Error("BuildRemoteTreeBatch() 路径包含换行符时应返回错误")
+	}
+}
+
+func TestParseRemoteTreeBatch(t *testing.T) {
+	output := strings.Join([]string{
+		"sftp> -ls -lan /srv/site",
+		"drwxr-xr-x    4 1000     1000         4096 Jan  5 10:22 /srv/site/.",
+		"drwxr-xr-x    3 0        0            4096 Jan  1  2024 /srv/site/..",
+		"-rw-r--r--    1 1000     1000          120 Jan  5 10:22 /srv/site/index.html",
+		"drwxr-xr-x    2 1000     1000         4096 Jan  5 10:22 /srv/site/css",
+		"sftp> -ls -lan /srv/missing",
+		"sftp> -ls -lan /srv/site.tar",
+		"-rw-r--r--    1 1000     1000         2048 Jan  5 10:22 /srv/site.tar",
+		"",
+	}, "\n")
+	listings := ParseRemoteTreeBatch(output, 3)
+	if len(listings) != 3 {
+		t.Fatalf("len = %d, want 3", len(listings))
+	}
+	if l := listings[0]; l.Self == nil || !l.Self.IsDir || len(l.Entries) != 2 || l.Entries[0].Name != "index.html" || l.Entries[1].Name != "css" {
+		t.Errorf("listings[0] = %+v", l)
+	}
+	if l := listings[1]; l.Self != nil || len(l.Entries) != 0 {
+		t.Errorf("不存在的目录 = %+v", l)
+	}
+	if l := listings[2]; l.Self == nil || l.Self.IsDir || l.Self.Size != 2048 || len(l.Entries) != 0 {
+		t.Errorf("文件 = %+v", l)
+	}
+}
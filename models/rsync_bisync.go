@@ -0,0 +1,347 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ConflictPolicy string
+
+const (
+	ConflictPolicyReport   ConflictPolicy = "report"    // 只报告冲突，两边都不修改
+	ConflictPolicyNewer    ConflictPolicy = "newer"     // 修改时间较新的一方为准，删除与修改冲突时保留修改
+	ConflictPolicyKeepBoth ConflictPolicy = "keep-both" // 保留两个版本，本地版本改名为冲突副本
+	ConflictPolicyLocal    ConflictPolicy = "local"     // 本地为准
+	ConflictPolicyRemote   ConflictPolicy = "remote"    // 服务器为准
+)
+
+// ParseConflictPolicy 解析冲突处理方式，为空时只报告冲突
+func ParseConflictPolicy(policy string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(policy); p {
+	case "":
+		return ConflictPolicyReport, nil
+	case ConflictPolicyReport, ConflictPolicyNewer, ConflictPolicyKeepBoth, ConflictPolicyLocal, ConflictPolicyRemote:
+		return p, nil
+	}
+	return "", fmt.Errorf("无效的冲突处理方式 '%s'，可选: report, newer, keep-both, local, remote", policy)
+}
+
+// ValidateBidirectional 检查双向同步的设置
+func (r *RsyncConfig) ValidateBidirectional() error {
+	if r.ConflictPolicy != "" && r.Direction != RsyncDirectionBidirectional {
+		return fmt.Errorf("冲突处理方式只能用于双向同步配置")
+	}
+	if _, err := ParseConflictPolicy(string(r.ConflictPolicy)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SyncFileState 文件在一端的状态
+type SyncFileState struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`          // Unix 秒，rsync --list-only 只精确到秒
+	Hash    string `json:"hash,omitempty"` // 内容的 sha256，只在需要时计算
+}
+
+// SyncManifestEntry 上一次同步后两端一致的文件
+type SyncManifestEntry struct {
+	Size          int64  `json:"size"`
+	LocalModTime  int64  `json:"local_mtime"`
+	RemoteModTime int64  `json:"remote_mtime"`
+	Hash          string `json:"hash,omitempty"` // 本地文件内容的 sha256
+}
+
+// SyncManifest 双向同步的状态，记录上一次成功同步后两端的文件
+// 本地路径、服务器或远程路径变化后原来的状态不再适用
+type SyncManifest struct {
+	ConfigID   uint                         `json:"config_id"`
+	SSHName    string                       `json:"ssh_name"`
+	LocalPath  string                       `json:"local_path"`
+	RemotePath string                       `json:"remote_path"`
+	SyncedAt   time.Time                    `json:"synced_at"`
+	Files      map[string]SyncManifestEntry `json:"files"`
+}
+
+// Matches 判断状态是否属于配置当前的两端路径
+func (m *SyncManifest) Matches(config *RsyncConfig) bool {
+	return m.ConfigID == config.ID && m.SSHName == config.SSHName &&
+		m.LocalPath == config.LocalPath && m.RemotePath == config.RemotePath
+}
+
+type SyncActionType string
+
+const (
+	SyncActionPush         SyncActionType = "push"          // 上传本地文件
+	SyncActionPull         SyncActionType = "pull"          // 下载服务器文件
+	SyncActionDeleteRemote SyncActionType = "delete_remote" // 删除服务器文件
+	SyncActionDeleteLocal  SyncActionType = "delete_local"  // 删除本地文件
+	SyncActionKeepBoth     SyncActionType = "keep_both"     // 本地版本改名为冲突副本并上传，再下载服务器版本
+)
+
+// SyncAction 双向同步中对一个文件的操作
+type SyncAction struct {
+	Type     SyncActionType `json:"type"`
+	Path     string         `json:"path"`
+	Size     int64          `json:"size"`               // 需要传输的大小
+	Conflict bool           `json:"conflict,omitempty"` // 按冲突处理方式解决的冲突
+}
+
+// SyncConflict 两端都有变化的文件，Local 或 Remote 为空表示该端已删除
+type SyncConflict struct {
+	Path       string         `json:"path"`
+	Local      *SyncFileState `json:"local,omitempty"`
+	Remote     *SyncFileState `json:"remote,omitempty"`
+	Resolution SyncActionType `json:"resolution,omitempty"` // 为空表示没有解决，两端都不修改
+}
+
+// SyncPlan 双向同步的计划
+type SyncPlan struct {
+	Actions   []SyncAction   `json:"actions"`
+	Conflicts []SyncConflict `json:"conflicts"`
+	Unchanged int            `json:"unchanged"`
+}
+
+// Unresolved 没有解决的冲突
+func (p *SyncPlan) Unresolved() []SyncConflict {
+	var conflicts []SyncConflict
+	for _, c := range p.Conflicts {
+		if c.Resolution == "" {
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts
+}
+
+// ValidateSyncSides 已有上一次同步的状态时，任何一端没有文件都视为异常（磁盘未挂载、路径写错或被改名），
+// 否则计划会把基准中的所有文件当作已删除，在另一端全部删除
+func ValidateSyncSides(base map[string]SyncManifestEntry, local, remote map[string]SyncFileState) error {
+	if len(base) == 0 {
+		return nil
+	}
+	switch {
+	case len(local) == 0:
+		return fmt.Errorf("本地路径为空或不存在，而上一次同步时有 %d 个文件，为避免删除服务器上的文件拒绝执行", len(base))
+	case len(remote) == 0:
+		return fmt.Errorf("远程路径为空或不存在，而上一次同步时有 %d 个文件，为避免删除本地文件拒绝执行", len(base))
+	}
+	return nil
+}
+
+// DeleteGuardCounts 计划在两端删除的文件数，返回删除比例较高的一端：Dest 为该端的文件数，Source 为另一端的文件数
+func (p *SyncPlan) DeleteGuardCounts(local, remote map[string]SyncFileState) DeleteGuardCounts {
+	localSide := DeleteGuardCounts{Source: len(remote), Dest: len(local)}
+	remoteSide := DeleteGuardCounts{Source: len(local), Dest: len(remote)}
+	for _, action := range p.Actions {
+		switch action.Type {
+		case SyncActionDeleteLocal:
+			localSide.Deleted++
+		case SyncActionDeleteRemote:
+			remoteSide.Deleted++
+		}
+	}
+	// 按比例比较：localSide.Deleted/localSide.Dest 与 remoteSide.Deleted/remoteSide.Dest
+	if localSide.Deleted*max(remoteSide.Dest, 1) >= remoteSide.Deleted*max(localSide.Dest, 1) {
+		return localSide
+	}
+	return remoteSide
+}
+
+// PlanBidirectionalSync 比较两端与上一次同步的状态，只有一端变化的文件同步到另一端，两端都变化的按 policy 处理
+// 两端内容相同（大小和修改时间相同，或哈希相同）的文件不需要同步
+func PlanBidirectionalSync(base map[string]SyncManifestEntry, local, remote map[string]SyncFileState, policy ConflictPolicy) SyncPlan {
+	paths := make(map[string]bool)
+	for p := range base {
+		paths[p] = true
+	}
+	for p := range local {
+		paths[p] = true
+	}
+	for p := range remote {
+		paths[p] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var plan SyncPlan
+	for _, p := range sorted {
+		entry, hasBase := base[p]
+		l, hasLocal := local[p]
+		r, hasRemote := remote[p]
+		localChanged := changedSince(hasBase, entry.Size, entry.LocalModTime, entry.Hash, hasLocal, l)
+		remoteChanged := changedSince(hasBase, entry.Size, entry.RemoteModTime, "", hasRemote, r)
+
+		switch {
+		case !localChanged && !remoteChanged:
+			if hasLocal || hasRemote {
+				plan.Unchanged++
+			}
+		case localChanged && !remoteChanged:
+			if hasLocal {
+				plan.Actions = append(plan.Actions, SyncAction{Type: SyncActionPush, Path: p, Size: l.Size})
+			} else if hasRemote {
+				plan.Actions = append(plan.Actions, SyncAction{Type: SyncActionDeleteRemote, Path: p})
+			}
+		case remoteChanged && !localChanged:
+			if hasRemote {
+				plan.Actions = append(plan.Actions, SyncAction{Type: SyncActionPull, Path: p, Size: r.Size})
+			} else if hasLocal {
+				plan.Actions = append(plan.Actions, SyncAction{Type: SyncActionDeleteLocal, Path: p})
+			}
+		case !hasLocal && !hasRemote:
+			// 两端都已删除
+		case hasLocal && hasRemote && sameContent(l, r):
+			plan.Unchanged++
+		default:
+			conflict := SyncConflict{Path: p}
+			if hasLocal {
+				conflict.Local = &l
+			}
+			if hasRemote {
+				conflict.Remote = &r
+			}
+			conflict.Resolution = resolveConflict(conflict, policy)
+			if conflict.Resolution != "" {
+				action := SyncAction{Type: conflict.Resolution, Path: p, Conflict: true}
+				switch conflict.Resolution {
+				case SyncActionPush:
+					action.Size = l.Size
+				case SyncActionPull, SyncActionKeepBoth:
+					action.Size = r.Size
+				}
+				plan.Actions = append(plan.Actions, action)
+			}
+			plan.Conflicts = append(plan.Conflicts, conflict)
+		}
+	}
+	return plan
+}
+
+// changedSince 判断一端的文件相对上一次同步是否有变化，修改时间变化但哈希相同时视为没有变化
+func changedSince(hasBase bool, size, modTime int64, hash string, exists bool, state SyncFileState) bool {
+	if !hasBase || !exists {
+		return hasBase != exists
+	}
+	if state.Size != size {
+		return true
+	}
+	if state.ModTime == modTime {
+		return false
+	}
+	return hash == "" || state.Hash != hash
+}
+
+// sameContent 判断两端的文件内容是否相同
+func sameContent(l, r SyncFileState) bool {
+	if l.Size != r.Size {
+		return false
+	}
+	if l.ModTime == r.ModTime {
+		return true
+	}
+	return l.Hash != "" && l.Hash == r.Hash
+}
+
+// resolveConflict 按冲突处理方式决定操作，返回空表示不处理
+func resolveConflict(c SyncConflict, policy ConflictPolicy) SyncActionType {
+	switch {
+	case c.Local == nil: // 本地删除，服务器修改
+		switch policy {
+		case ConflictPolicyLocal:
+			return SyncActionDeleteRemote
+		case ConflictPolicyRemote, ConflictPolicyNewer, ConflictPolicyKeepBoth:
+			return SyncActionPull
+		}
+	case c.Remote == nil: // 本地修改，服务器删除
+		switch policy {
+		case ConflictPolicyRemote:
+			return SyncActionDeleteLocal
+		case ConflictPolicyLocal, ConflictPolicyNewer, ConflictPolicyKeepBoth:
+			return SyncActionPush
+		}
+	default:
+		switch policy {
+		case ConflictPolicyLocal:
+			return SyncActionPush
+		case ConflictPolicyRemote:
+			return SyncActionPull
+		case ConflictPolicyKeepBoth:
+			return SyncActionKeepBoth
+		case ConflictPolicyNewer:
+			if c.Local.ModTime > c.Remote.ModTime {
+				return SyncActionPush
+			}
+			if c.Remote.ModTime > c.Local.ModTime {
+				return SyncActionPull
+			}
+		}
+	}
+	return ""
+}
+
+// ConflictCopyName 冲突副本的文件名，如 notes/todo.md -> notes/todo.conflict-20240301-150405.md
+func ConflictCopyName(file string, t time.Time) string {
+	dir, name := path.Split(file)
+	ext := path.Ext(name)
+	if ext == name {
+		ext = ""
+	}
+	return dir + strings.TrimSuffix(name, ext) + ".conflict-" + t.Format("20060102-150405") + ext
+}
+
+// listOnlyLinePattern 匹配 rsync --list-only 的一行，如 "-rw-r--r--          2,048 2024/03/01 12:00:00 notes/todo.md"
+var listOnlyLinePattern = regexp.MustCompile(`^([-dlcbps])\S*\s+([\d,.]+)\s+(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2})\s(.+)$`)
+
+// ParseListOnly 解析 rsync --list-only 的输出，只返回普通文件，时间按 loc 解析
+func ParseListOnly(output string, loc *time.Location) map[string]SyncFileState {
+	files := make(map[string]SyncFileState)
+	for _, line := range strings.Split(output, "\n") {
+		match := listOnlyLinePattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil || match[1] != "-" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006/01/02 15:04:05", match[3], loc)
+		if err != nil {
+			continue
+		}
+		files[match[4]] = SyncFileState{Size: parseRsyncSize(match[2]), ModTime: t.Unix()}
+	}
+	return files
+}
+
+// BuildBisyncCommand 生成双向同步中一个方向的rsync命令，只传输 filesFrom 中列出的文件（NUL 分隔），
+// 列表中在源端不存在的文件会在目标端删除
+func (r *RsyncConfig) BuildBisyncCommand(sshConnection *SSHConnection, direction RsyncDirection, filesFrom string, ignoreRules []FilterRule) []string {
+	config := *r
+	config.Direction = direction
+	config.Delete = false
+	config.LocalPath = strings.TrimRight(r.LocalPath, "/") + "/"
+	config.RemotePath = strings.TrimRight(r.RemotePath, "/") + "/"
+	cmd := config.buildCommand(sshConnection, config.LocalPath, ignoreRules)
+
+	// 修改时间用于判断下一次同步时哪一端有变化，必须保持
+	options := []string{"--files-from=" + filesFrom, "--from0", "--delete-missing-args"}
+	if !r.Archive && !r.Times {
+		options = append(options, "--times")
+	}
+	return append(cmd[:1], append(options, cmd[1:]...)...)
+}
+
+// BuildListCommand 生成列出远程路径中所有文件的rsync命令，过滤规则与同步时相同
+func (r *RsyncConfig) BuildListCommand(sshConnection *SSHConnection, ignoreRules []FilterRule) []string {
+	cmd := []string{"rsync", "--list-only", "-r"}
+	cmd = append(cmd, r.GetOptionsSlice()...)
+	cmd = append(cmd, r.filterArgs(ignoreRules)...)
+	if needsProtectArgs(r.RemotePath) {
+		cmd = append(cmd, "--protect-args")
+	}
+	cmd = append(cmd, remoteShellArgs(sshConnection)...)
+	return append(cmd, sshConnection.Destination()+":"+strings.TrimRight(r.RemotePath, "/")+"/")
+}
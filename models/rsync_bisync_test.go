@@ -0,0 +1,219 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanBidirectionalSync(t *testing.T) {
+	base := map[string]SyncManifestEntry{
+		"same.txt":        {Size: 1, LocalModTime: 100, RemoteModTime: 200},
+		"local-edit.txt":  {Size: 1, LocalModTime: 100, RemoteModTime: 200},
+		"remote-edit.txt": {Size: 1, LocalModTime: 100, RemoteModTime: 200},
+		"local-del.txt":   {Size: 1, LocalModTime: 100, RemoteModTime: 200},
+		"remote-del.txt":  {Size: 1, LocalModTime: 100, RemoteModTime: 200},
+		"both-edit.txt":   {Size: 1, LocalModTime: 100, RemoteModTime: 200},
+		"both-del.txt":    {Size: 1, LocalModTime: 100, RemoteModTime: 200},
+		"touched.txt":     {Size: 1, LocalModTime: 100, RemoteModTime: 200, Hash: "h"},
+	}
+	local := map[string]SyncFileState{
+		"same.txt":        {Size: 1, ModTime: 100},
+		"local-edit.txt":  {Size: 2, ModTime: 300},
+		"remote-edit.txt": {Size: 1, ModTime: 100},
+		"remote-del.txt":  {Size: 1, ModTime: 100},
+		"both-edit.txt":   {Size: 2, ModTime: 300},
+		"touched.txt":     {Size: 1, ModTime: 300, Hash: "h"},
+		"new-local.txt":   {Size: 5, ModTime: 300},
+	}
+	remote := map[string]SyncFileState{
+		"same.txt":        {Size: 1, ModTime: 200},
+		"local-edit.txt":  {Size: 1, ModTime: 200},
+		"remote-edit.txt": {Size: 3, ModTime: 400},
+		"local-del.txt":   {Size: 1, ModTime: 200},
+		"both-edit.txt":   {Size: 3, ModTime: 400},
+		"touched.txt":     {Size: 1, ModTime: 200},
+		"new-remote.txt":  {Size: 6, ModTime: 400},
+	}
+
+	plan := PlanBidirectionalSync(base, local, remote, ConflictPolicyReport)
+	want := []SyncAction{
+		{Type: SyncActionDeleteRemote, Path: "local-del.txt"},
+		{Type: SyncActionPush, Path: "local-edit.txt", Size: 2},
+		{Type: SyncActionPush, Path: "new-local.txt", Size: 5},
+		{Type: SyncActionPull, Path: "new-remote.txt", Size: 6},
+		{Type: SyncActionDeleteLocal, Path: "remote-del.txt"},
+		{Type: SyncActionPull, Path: "remote-edit.txt", Size: 3},
+	}
+	if !reflect.DeepEqual(plan.Actions, want) {
+		t.Errorf("Actions = %+v\n期望 %+v", plan.Actions, want)
+	}
+	if len(plan.Conflicts) != 1 || plan.Conflicts[0].Path != "both-edit.txt" || len(plan.Unresolved()) != 1 {
+		t.Errorf("Conflicts = %+v", plan.Conflicts)
+	}
+	if plan.Unchanged != 2 {
+		t.Errorf("Unchanged = %d, 期望 2", plan.Unchanged)
+	}
+}
+
+func TestPlanBidirectionalSyncFirstRun(t *testing.T) {
+	local := map[string]SyncFileState{
+		"a.txt": {Size: 1, ModTime: 100},
+		"b.txt": {Size: 2, ModTime: 100},
+		"c.txt": {Size: 2, ModTime: 100, Hash: "x"},
+	}
+	remote := map[string]SyncFileState{
+		"a.txt": {Size: 1, ModTime: 100},
+		"b.txt": {Size: 3, ModTime: 200},
+		"c.txt": {Size: 2, ModTime: 200, Hash: "x"},
+	}
+	plan := PlanBidirectionalSync(nil, local, remote, ConflictPolicyReport)
+	if len(plan.Actions) != 0 || plan.Unchanged != 2 {
+		t.Errorf("首次同步内容相同的文件不应同步: %+v", plan)
+	}
+	if len(plan.Conflicts) != 1 || plan.Conflicts[0].Path != "b.txt" {
+		t.Errorf("首次同步两端不同的文件应该是冲突: %+v", plan.Conflicts)
+	}
+}
+
+func TestPlanBidirectionalSyncPolicies(t *testing.T) {
+	base := map[string]SyncManifestEntry{
+		"edit.txt":       {Size: 1, LocalModTime: 100, RemoteModTime: 100},
+		"local-del.txt":  {Size: 1, LocalModTime: 100, RemoteModTime: 100},
+		"remote-del.txt": {Size: 1, LocalModTime: 100, RemoteModTime: 100},
+	}
+	local := map[string]SyncFileState{
+		"edit.txt":       {Size: 2, ModTime: 300},
+		"remote-del.txt": {Size: 2, ModTime: 300},
+	}
+	remote := map[string]SyncFileState{
+		"edit.txt":      {Size: 3, ModTime: 200},
+		"local-del.txt": {Size: 3, ModTime: 200},
+	}
+
+	tests := []struct {
+		policy ConflictPolicy
+		want   []SyncActionType // edit.txt、local-del.txt、remote-del.txt
+	}{
+		{ConflictPolicyReport, []SyncActionType{"", "", ""}},
+		{ConflictPolicyNewer, []SyncActionType{SyncActionPush, SyncActionPull, SyncActionPush}},
+		{ConflictPolicyKeepBoth, []SyncActionType{SyncActionKeepBoth, SyncActionPull, SyncActionPush}},
+		{ConflictPolicyLocal, []SyncActionType{SyncActionPush, SyncActionDeleteRemote, SyncActionPush}},
+		{ConflictPolicyRemote, []SyncActionType{SyncActionPull, SyncActionPull, SyncActionDeleteLocal}},
+	}
+	for _, tt := range tests {
+		plan := PlanBidirectionalSync(base, local, remote, tt.policy)
+		var got []SyncActionType
+		for _, c := range plan.Conflicts {
+			got = append(got, c.Resolution)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 冲突处理 = %v, 期望 %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSyncSides(t *testing.T) {
+	base := map[string]SyncManifestEntry{"a.txt": {Size: 1}, "b.txt": {Size: 1}}
+	files := map[string]SyncFileState{"a.txt": {Size: 1}}
+	empty := map[string]SyncFileState{}
+
+	if err := ValidateSyncSides(base, files, files); err != nil {
+		t.Errorf("两端都有文件时不应返回错误: %v", err)
+	}
+	if err := ValidateSyncSides(nil, empty, files); err != nil {
+		t.Errorf("第一次同步时本地为空不应返回错误: %v", err)
+	}
+	if err := ValidateSyncSides(base, empty, files); err == nil || !strings.Contains(err.Error(), "本地路径") {
+		t.Errorf("本地为空时应拒绝执行，得到 %v", err)
+	}
+	if err := ValidateSyncSides(base, files, empty); err == nil || !strings.Contains(err.Error(), "远程路径") {
+		t.Errorf("远程为空时应拒绝执行，得到 %v", err)
+	}
+
+	// 只剩一个文件的一端会让基准中的其余文件在另一端被删除
+	remote := make(map[string]SyncFileState)
+	manifest := make(map[string]SyncManifestEntry)
+	for i := 0; i < 20; i++ {
+		name := string(rune('a'+i)) + ".txt"
+		remote[name] = SyncFileState{Size: 1, ModTime: 200}
+		manifest[name] = SyncManifestEntry{Size: 1, LocalModTime: 100, RemoteModTime: 200}
+	}
+	local := map[string]SyncFileState{"a.txt": {Size: 1, ModTime: 100}}
+	plan := PlanBidirectionalSync(manifest, local, remote, ConflictPolicyReport)
+	got := plan.DeleteGuardCounts(local, remote)
+	if want := (DeleteGuardCounts{Source: 1, Dest: 20, Deleted: 19}); got != want {
+		t.Errorf("DeleteGuardCounts() = %+v, want %+v", got, want)
+	}
+	if decision := EvaluateDeleteGuard(got, 0, DefaultDeleteMaxPercent, deleteGuardPercentMinDeletes, false); decision.Allowed {
+		t.Errorf("删除 95%% 的文件时应拒绝执行: %+v", decision)
+	}
+}
+
+func TestValidateBidirectional(t *testing.T) {
+	config := RsyncConfig{Direction: RsyncDirectionUpload, ConflictPolicy: ConflictPolicyNewer}
+	if err := config.ValidateBidirectional(); err == nil {
+		t.Error("上传配置设置冲突处理方式应该返回错误")
+	}
+	config = RsyncConfig{Direction: RsyncDirectionBidirectional, ConflictPolicy: "merge"}
+	if err := config.ValidateBidirectional(); err == nil {
+		t.Error("无效的冲突处理方式应该返回错误")
+	}
+	if policy, _ := ParseConflictPolicy(""); policy != ConflictPolicyReport {
+		t.Errorf("默认冲突处理方式 = %s, 期望 report", policy)
+	}
+}
+
+func TestConflictCopyName(t *testing.T) {
+	at := time.Date(2024, 3, 1, 15, 4, 5, 0, time.UTC)
+	tests := map[string]string{
+		"notes/todo.md": "notes/todo.conflict-20240301-150405.md",
+		"Makefile":      "Makefile.conflict-20240301-150405",
+		".env":          ".env.conflict-20240301-150405",
+	}
+	for file, want := range tests {
+		if got := ConflictCopyName(file, at); got != want {
+			t.Errorf("ConflictCopyName(%q) = %q, 期望 %q", file, got, want)
+		}
+	}
+}
+
+func TestParseListOnly(t *testing.T) {
+	output := strings.Join([]string{
+		"drwxr-xr-x          4,096 2024/03/01 12:00:00 .",
+		"drwxr-xr-x          4,096 2024/03/01 12:00:00 notes",
+		"-rw-r--r--          2,048 2024/03/01 12:00:01 notes/todo list.md",
+		"lrwxrwxrwx              7 2024/03/01 12:00:00 link",
+		"-rw-r--r--             12 2024/03/02 08:30:00 a.txt",
+	}, "\n")
+	files := ParseListOnly(output, time.UTC)
+	want := map[string]SyncFileState{
+		"notes/todo list.md": {Size: 2048, ModTime: time.Date(2024, 3, 1, 12, 0, 1, 0, time.UTC).Unix()},
+		"a.txt":              {Size: 12, ModTime: time.Date(2024, 3, 2, 8, 30, 0, 0, time.UTC).Unix()},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("ParseListOnly = %+v, 期望 %+v", files, want)
+	}
+}
+
+func TestBuildBisyncCommand(t *testing.T) {
+	config := RsyncConfig{
+		Direction:  RsyncDirectionBidirectional,
+		LocalPath:  "/local/notes",
+		RemotePath: "/srv/notes",
+		Verbose:    true,
+		Delete:     true,
+	}
+	conn := &SSHConnection{Username: "u", Address: "h", Port: 22}
+	cmd := config.BuildBisyncCommand(conn, RsyncDirectionDownload, "/tmp/pull", nil)
+	joined := ShellJoin(cmd)
+	for _, want := range []string{"--files-from=/tmp/pull", "--from0", "--delete-missing-args", "--times", "/local/notes/"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("命令 %s 缺少 %s", joined, want)
+		}
+	}
+	if strings.Contains(joined, "--delete ") || !strings.HasSuffix(joined, ":/srv/notes/ /local/notes/") {
+		t.Errorf("下载命令错误: %s", joined)
+	}
+}
@@ -9,22 +9,73 @@ import (
 type RsyncDirection string
 
 const (
-	RsyncDirectionUpload   RsyncDirection = "upload"   // 本地 -> 服务器
-	RsyncDirectionDownload RsyncDirection = "download" // 服务器 -> 本地
+	RsyncDirectionUpload        RsyncDirection = "upload"        // 本地 -> 服务器
+	RsyncDirectionDownload      RsyncDirection = "download"      // 服务器 -> 本地
+	RsyncDirectionBidirectional RsyncDirection = "bidirectional" // 本地 <-> 服务器，两端的变化互相同步
+	RsyncDirectionRemote        RsyncDirection = "remote"        // 服务器 -> 服务器，RemotePath 为源路径
 )
 
 type RsyncConfig struct {
 	gorm.Model
-	Name         string         `gorm:"uniqueIndex;not null" json:"name"`
+	Name         string         `gorm:"uniqueIndex:idx_rsync_configs_active_name,where:deleted_at IS NULL;not null" json:"name"`
 	SSHName      string         `gorm:"not null" json:"ssh_name"` // 关联的SSH连接名称
 	Direction    RsyncDirection `gorm:"not null" json:"direction"`
 	LocalPath    string         `gorm:"not null" json:"local_path"`
 	RemotePath   string         `gorm:"not null" json:"remote_path"`
 	ExcludeRules string         `json:"exclude_rules"` // 排除规则，换行分隔
+	FilterRules  string         `json:"filter_rules"`  // 有序的过滤规则，换行分隔，在排除规则之前生效
 	Options      string         `json:"options"`       // 额外的rsync选项
 	Description  string         `json:"description"`
 	UsageCount   int            `gorm:"default:0" json:"usage_count"`
 
+	// 读取本地路径各级目录中的 .gitignore 和 .rsyncignore，转换为排除规则
+	UseIgnoreFiles bool `json:"use_ignore_files"`
+
+	// 双向同步时两端都有变化的文件的处理方式，为空表示只报告
+	ConflictPolicy ConflictPolicy `json:"conflict_policy"`
+
+	// 服务器之间传输：从 SSHName 的 RemotePath 传输到 TargetSSHName 的 TargetPath，不使用 LocalPath
+	TargetSSHName      string             `json:"target_ssh_name"`
+	TargetPath         string             `json:"target_path"`
+	RemoteTransferMode RemoteTransferMode `json:"remote_transfer_mode"` // 为空表示源服务器直接连接目标服务器
+
+	// 传输引擎，为空或 auto 时本地有 rsync 则使用 rsync，否则使用 sftp
+	Engine TransferEngine `json:"engine"`
+
+	// 执行成功后比较本地和服务器上的文件，报告保存在执行记录中
+	VerifyAfterRun bool `json:"verify_after_run"`
+	VerifyChecksum bool `json:"verify_checksum"` // 同时在两端计算 SHA-256 比较内容
+
+	// 删除保护，启用删除时执行前先试运行，删除超过上限时中止执行，都为 0 时比例上限为 DefaultDeleteMaxPercent
+	DeleteMaxCount   int `json:"delete_max_count"`   // 删除数量上限
+	DeleteMaxPercent int `json:"delete_max_percent"` // 删除数占目标中文件的比例上限（%）
+
+	// 用户定义变量的默认值，每行一个 名称=值，路径、规则和选项中的 {{名称}} 在执行时替换
+	Variables string `json:"variables"`
+
+	// 定时执行
+	Schedule       string `json:"schedule"`        // cron 表达式，为空表示不定时执行
+	SchedulePaused bool   `json:"schedule_paused"` // 暂停定时执行
+
+	// 执行前后的钩子，每行一个命令，以 remote: 开头的命令通过SSH连接在服务器上执行
+	PreHooks  string `json:"pre_hooks"`
+	PostHooks string `json:"post_hooks"`
+
+	// 快照备份，仅用于下载配置：每次执行写入 LocalPath 下新的时间戳目录，未变化的文件硬链接到上一个快照
+	SnapshotMode        bool `json:"snapshot_mode"`
+	SnapshotKeepDaily   int  `json:"snapshot_keep_daily"`   // 保留最近 N 天每天最新的快照
+	SnapshotKeepWeekly  int  `json:"snapshot_keep_weekly"`  // 保留最近 N 周每周最新的快照
+	SnapshotKeepMonthly int  `json:"snapshot_keep_monthly"` // 保留最近 N 个月每月最新的快照
+
+	// 传输限制，0 或空表示不限制
+	BandwidthLimit int        `json:"bandwidth_limit"` // --bwlimit 带宽限制，KiB/s
+	Timeout        int        `json:"timeout"`         // --timeout 超过 N 秒没有数据传输时失败
+	ResumeMode     ResumeMode `json:"resume_mode"`     // 中断后继续传输的方式
+	MaxSize        int64      `json:"max_size"`        // --max-size 跳过大于 N 字节的文件
+	CompressLevel  int        `json:"compress_level"`  // --compress-level 压缩级别 1-9，需要启用压缩
+	Backup         bool       `json:"backup"`          // --backup 备份目标中被覆盖或删除的文件
+	BackupDir      string     `json:"backup_dir"`      // --backup-dir 备份目录，相对路径在目标路径下，为空时在原位置加 ~ 后缀
+
 	// 常用rsync选项
 	Verbose   bool `json:"verbose"`   // -v 详细输出
 	Recursive bool `json:"recursive"` // -r 递归
@@ -57,16 +108,85 @@ func (r *RsyncConfig) GetExcludeRulesSlice() []string {
 	return rules
 }
 
+// ParseOptions 按 shell 规则解析额外选项，支持 --rsync-path="sudo rsync" 这样带引号的参数
+func (r *RsyncConfig) ParseOptions() ([]string, error) {
+	words, err := SplitShellWords(r.Options)
+	if err != nil {
+		return nil, fmt.Errorf("额外选项格式错误: %v", err)
+	}
+	return words, nil
+}
+
+// GetOptionsSlice 返回额外选项，格式错误时退化为按空白拆分（保存前由 ParseOptions 校验）
 func (r *RsyncConfig) GetOptionsSlice() []string {
 	if r.Options == "" {
 		return []string{}
 	}
-	return strings.Fields(r.Options)
+	words, err := r.ParseOptions()
+	if err != nil {
+		return strings.Fields(r.Options)
+	}
+	return words
+}
+
+// BuildRsyncCommand 生成rsync命令，ignoreRules 为从忽略文件得到的规则
+func (r *RsyncConfig) BuildRsyncCommand(sshConnection *SSHConnection, ignoreRules []FilterRule) []string {
+	return r.buildCommand(sshConnection, r.LocalPath, ignoreRules)
 }
 
-func (r *RsyncConfig) BuildRsyncCommand(sshConnection *SSHConnection) []string {
+// buildCommand 生成rsync命令，本地一端使用 localPath
+func (r *RsyncConfig) buildCommand(sshConnection *SSHConnection, localPath string, ignoreRules []FilterRule) []string {
+	cmd := append([]string{"rsync"}, r.optionArgs(ignoreRules)...)
+
+	// 远程路径包含空格、引号等字符时，让 rsync 直接传递参数而不经过远程 shell 拆分
+	if needsProtectArgs(r.RemotePath) {
+		cmd = append(cmd, "--protect-args")
+	}
+
+	cmd = append(cmd, remoteShellArgs(sshConnection)...)
+
+	// 源路径和目标路径
+	remote := sshConnection.Destination() + ":" + r.RemotePath
+	local := safeLocalPath(localPath)
+	if r.Direction == RsyncDirectionUpload {
+		// 本地 -> 服务器
+		cmd = append(cmd, local, remote)
+	} else {
+		// 服务器 -> 本地
+		cmd = append(cmd, remote, local)
+	}
+
+	return cmd
+}
+
+// QuoteRemotePathArgs 本地rsync不支持 --protect-args 时去掉该选项，改为在远程路径中加上远程 shell 的引号，
+// 由远程 shell 去掉引号后得到原路径，开头的 ~/ 仍由远程展开；没有 --protect-args 的命令原样返回
+func QuoteRemotePathArgs(cmdArgs []string, sshConnection *SSHConnection) []string {
+	if cmdArgs[0] != "rsync" {
+		return cmdArgs
+	}
+	prefix := sshConnection.Destination() + ":"
+	quoted := make([]string, 0, len(cmdArgs))
+	protected := false
+	for _, arg := range cmdArgs {
+		switch {
+		case arg == "--protect-args":
+			protected = true
+			continue
+		case strings.HasPrefix(arg, prefix):
+			arg = prefix + remoteShellPath(strings.TrimPrefix(arg, prefix))
+		}
+		quoted = append(quoted, arg)
+	}
+	if !protected {
+		return cmdArgs
+	}
+	return quoted
+}
+
+// optionArgs 生成rsync的选项和过滤规则参数，不含程序名、-e 和路径
+func (r *RsyncConfig) optionArgs(ignoreRules []FilterRule) []string {
 	var cmd []string
-	cmd = append(cmd, "rsync")
 
 	// 构建选项字符串
 	var options []string
@@ -123,42 +243,38 @@ func (r *RsyncConfig) BuildRsyncCommand(sshConnection *SSHConnection) []string {
 		cmd = append(cmd, "--dry-run")
 	}
 
+	// 传输限制，放在自定义选项之前，自定义选项可以覆盖
+	cmd = append(cmd, r.limitArgs()...)
+
 	// 添加用户自定义选项
 	if r.Options != "" {
 		cmd = append(cmd, r.GetOptionsSlice()...)
 	}
 
-	// 添加排除规则
-	excludeRules := r.GetExcludeRulesSlice()
-	for _, rule := range excludeRules {
-		cmd = append(cmd, "--exclude", rule)
-	}
+	// 添加过滤和排除规则，按顺序第一条匹配的规则生效
+	cmd = append(cmd, r.filterArgs(ignoreRules)...)
 
-	// SSH 选项
-	sshOptions := fmt.Sprintf("-p %d", sshConnection.Port)
-	if sshConnection.PasswordType == PasswordTypeKeyPath && sshConnection.KeyPath != "" {
-		sshOptions += fmt.Sprintf(" -i %s", sshConnection.KeyPath)
-	}
-	cmd = append(cmd, "-e", fmt.Sprintf("ssh %s", sshOptions))
+	return cmd
+}
 
-	// 源路径和目标路径
-	if r.Direction == RsyncDirectionUpload {
-		// 本地 -> 服务器
-		cmd = append(cmd, r.LocalPath)
-		cmd = append(cmd, fmt.Sprintf("%s@%s:%s", sshConnection.Username, sshConnection.Address, r.RemotePath))
-	} else {
-		// 服务器 -> 本地
-		cmd = append(cmd, fmt.Sprintf("%s@%s:%s", sshConnection.Username, sshConnection.Address, r.RemotePath))
-		cmd = append(cmd, r.LocalPath)
+// remoteShellArgs 生成 -e 参数，rsync 会自己拆分 -e 的命令字符串，参数需要按 rsync 的规则引用
+func remoteShellArgs(sshConnection *SSHConnection) []string {
+	sshOptions := append([]string{"ssh"}, sshConnection.SSHArgs()...)
+	for i, option := range sshOptions {
+		sshOptions[i] = RemoteShellQuote(option)
 	}
-
-	return cmd
+	return []string{"-e", strings.Join(sshOptions, " ")}
 }
 
 func (r *RsyncConfig) GetDisplayInfo() string {
 	direction := "↑"
-	if r.Direction == RsyncDirectionDownload {
+	switch r.Direction {
+	case RsyncDirectionDownload:
 		direction = "↓"
+	case RsyncDirectionBidirectional:
+		direction = "⇅"
+	case RsyncDirectionRemote:
+		return fmt.Sprintf("⇒ %s [%s → %s] %s -> %s", r.Name, r.SSHName, r.TargetSSHName, r.RemotePath, r.TargetPath)
 	}
 	return fmt.Sprintf("%s %s [%s] %s <-> %s", direction, r.Name, r.SSHName, r.LocalPath, r.RemotePath)
 }
@@ -168,9 +284,11 @@ func (r *RsyncConfig) GetArg() []string {
 }
 
 func (r *RsyncConfig) GetVariables() map[string]string {
-	direction := "upload"
-	if r.Direction == RsyncDirectionDownload {
-		direction = "download"
+	direction := string(r.Direction)
+	switch r.Direction {
+	case RsyncDirectionDownload, RsyncDirectionBidirectional, RsyncDirectionRemote:
+	default:
+		direction = "upload"
 	}
 
 	return map[string]string{
@@ -179,7 +297,10 @@ func (r *RsyncConfig) GetVariables() map[string]string {
 		"rsync_direction":   direction,
 		"rsync_local_path":  r.LocalPath,
 		"rsync_remote_path": r.RemotePath,
+		"rsync_target_ssh":  r.TargetSSHName,
+		"rsync_target_path": r.TargetPath,
 		"rsync_exclude":     r.ExcludeRules,
+		"rsync_filter":      r.FilterRules,
 		"rsync_options":     r.Options,
 		"rsync_description": r.Description,
 		"rsync_usage_count": fmt.Sprintf("%d", r.UsageCount),
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// RsyncConfigTemplate 可复用的部分rsync配置，只保存模板中设置的选项，用于复制和批量生成配置
+type RsyncConfigTemplate struct {
+	gorm.Model
+	Name        string `gorm:"uniqueIndex:idx_rsync_config_templates_active_name,where:deleted_at IS NULL;not null" json:"name"`
+	Description string `json:"description"`
+	Config      string `gorm:"not null" json:"config"` // 部分rsync配置的 JSON，键与rsync配置的 JSON 字段相同
+	NamePattern string `json:"name_pattern"`           // 生成配置的名称，为空时使用 DefaultGenerateNamePattern
+	UsageCount  int    `gorm:"default:0" json:"usage_count"`
+}
+
+// DefaultGenerateNamePattern 批量生成配置时默认的名称
+const DefaultGenerateNamePattern = "{{template}}-{{ssh.name}}"
+
+// templateIdentityKeys 每个配置各自的字段，不能保存在模板中
+var templateIdentityKeys = []string{"ID", "CreatedAt", "UpdatedAt", "DeletedAt", "name", "ssh_name", "usage_count", "schedule_paused"}
+
+// NewRsyncConfigTemplateJSON 从已有的配置生成模板内容，去掉名称、SSH连接等每个配置各自的字段和未设置的选项
+func NewRsyncConfigTemplateJSON(config *RsyncConfig) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("JSON解析失败: %v", err)
+	}
+	for _, key := range templateIdentityKeys {
+		delete(fields, key)
+	}
+	for key, value := range fields {
+		if value == nil || value == false || value == "" || value == float64(0) {
+			delete(fields, key)
+		}
+	}
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	return string(data), nil
+}
+
+// ValidateConfigTemplate 检查模板内容：必须是 JSON 对象，键为rsync配置的字段且不包含名称、SSH连接等字段
+func ValidateConfigTemplate(content string) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &fields); err != nil {
+		return fmt.Errorf("模板内容不是有效的 JSON 对象: %v", err)
+	}
+	for _, key := range templateIdentityKeys {
+		if _, ok := fields[key]; ok {
+			return fmt.Errorf("模板中不能设置 %s，名称和SSH连接在复制或生成配置时指定", key)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(content)))
+	decoder.DisallowUnknownFields()
+	var config RsyncConfig
+	if err := decoder.Decode(&config); err != nil {
+		return fmt.Errorf("模板内容错误: %v", err)
+	}
+	return nil
+}
+
+// Fields 模板中设置的字段，按名称排序
+func (t *RsyncConfigTemplate) Fields() []string {
+	var fields map[string]json.RawMessage
+	json.Unmarshal([]byte(t.Config), &fields)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Apply 把模板中设置的选项写入配置，模板中没有的字段保持不变
+func (t *RsyncConfigTemplate) Apply(config *RsyncConfig) error {
+	if err := ValidateConfigTemplate(t.Config); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(t.Config), config)
+}
+
+// RenderNamePattern 替换名称中的 {{变量}}，有未定义的变量时返回错误
+func RenderNamePattern(pattern string, vars map[string]string) (string, error) {
+	var missing []string
+	name := templateVariablePattern.ReplaceAllStringFunc(pattern, func(s string) string {
+		key := templateVariablePattern.FindStringSubmatch(s)[1]
+		value, ok := vars[key]
+		if !ok {
+			missing = append(missing, key)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("名称中有未定义的变量: %s（可用: template, ssh.name, ssh.address, ssh.port, ssh.username）", strings.Join(missing, ", "))
+	}
+	return strings.TrimSpace(name), nil
+}
+
+// GenerateNameVariables 批量生成配置时名称中可以使用的变量
+func GenerateNameVariables(templateName string, sshConnection *SSHConnection) map[string]string {
+	return map[string]string{
+		"template":     templateName,
+		"ssh.name":     sshConnection.Name,
+		"ssh.address":  sshConnection.Address,
+		"ssh.port":     strconv.Itoa(sshConnection.Port),
+		"ssh.username": sshConnection.Username,
+	}
+}
+
+// MatchHosts 按选择条件筛选SSH连接，条件以逗号分隔，满足任一条件即可：
+// tag:标签 按标签匹配，name:模式 或直接写模式按名称通配符匹配（如 web-*）
+func MatchHosts(connections []SSHConnection, selector string) ([]SSHConnection, error) {
+	var terms []string
+	for _, term := range strings.Split(selector, ",") {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("请指定主机，如 tag:web 或 web-*")
+	}
+	for _, term := range terms {
+		if pattern, ok := strings.CutPrefix(term, "name:"); ok || !strings.HasPrefix(term, "tag:") {
+			if !ok {
+				pattern = term
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("无效的名称模式 '%s': %v", pattern, err)
+			}
+		}
+	}
+
+	var matched []SSHConnection
+	for _, conn := range connections {
+		for _, term := range terms {
+			var ok bool
+			if tag, isTag := strings.CutPrefix(term, "tag:"); isTag {
+				ok = conn.HasTag(tag)
+			} else {
+				ok, _ = path.Match(strings.TrimPrefix(term, "name:"), conn.Name)
+			}
+			if ok {
+				matched = append(matched, conn)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
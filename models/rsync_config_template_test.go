@@ -0,0 +1,132 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewRsyncConfigTemplateJSON(t *testing.T) {
+	config := &RsyncConfig{
+		Name:           "deploy-web",
+		SSHName:        "web-01",
+		Direction:      RsyncDirectionUpload,
+		LocalPath:      "~/project/dist/",
+		RemotePath:     "/var/www",
+		Timeout:        30,
+		Schedule:       "0 3 * * *",
+		SchedulePaused: true,
+		UsageCount:     12,
+	}
+	content, err := NewRsyncConfigTemplateJSON(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &RsyncConfigTemplate{Config: content}
+	want := []string{"direction", "local_path", "remote_path", "schedule", "timeout"}
+	if got := template.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() = %v, want %v", got, want)
+	}
+	if err := ValidateConfigTemplate(content); err != nil {
+		t.Errorf("ValidateConfigTemplate() = %v", err)
+	}
+}
+
+func TestValidateConfigTemplate(t *testing.T) {
+	tests := []struct {
+		content string
+		wantErr bool
+	}{
+		{`{"direction": "upload", "delete": true}`, false},
+		{`{}`, false},
+		{`{"name": "deploy"}`, true},
+		{`{"ssh_name": "web-01"}`, true},
+		{`{"unknown_field": 1}`, true},
+		{`{"timeout": "30"}`, true},
+		{`["upload"]`, true},
+		{`not json`, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateConfigTemplate(tt.content); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateConfigTemplate(%s) error = %v, wantErr %v", tt.content, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRsyncConfigTemplateApply(t *testing.T) {
+	template := &RsyncConfigTemplate{Config: `{"remote_path": "/srv/app", "timeout": 60}`}
+	config := &RsyncConfig{Name: "app", SSHName: "db-01", Direction: RsyncDirectionDownload, RemotePath: "/tmp", Timeout: 10}
+	if err := template.Apply(config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "app" || config.SSHName != "db-01" || config.Direction != RsyncDirectionDownload {
+		t.Errorf("字段不在模板中时应保持不变: %+v", config)
+	}
+	if config.RemotePath != "/srv/app" || config.Timeout != 60 {
+		t.Errorf("模板中的字段应写入配置: remote_path=%s timeout=%d", config.RemotePath, config.Timeout)
+	}
+}
+
+func TestRenderNamePattern(t *testing.T) {
+	vars := GenerateNameVariables("deploy", &SSHConnection{Name: "web-01", Address: "10.0.0.1", Port: 2222, Username: "root"})
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{DefaultGenerateNamePattern, "deploy-web-01", false},
+		{"{{ ssh.name }}:{{ssh.port}}", "web-01:2222", false},
+		{"{{ssh.username}}@{{ssh.address}}", "root@10.0.0.1", false},
+		{"fixed", "fixed", false},
+		{"{{env}}-{{ssh.name}}", "", true},
+	}
+	for _, tt := range tests {
+		got, err := RenderNamePattern(tt.pattern, vars)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("RenderNamePattern(%q) = %q, %v, want %q, wantErr %v", tt.pattern, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMatchHosts(t *testing.T) {
+	connections := []SSHConnection{
+		{Name: "web-01", Tags: "web, prod"},
+		{Name: "web-02", Tags: "web,staging"},
+		{Name: "db-01", Tags: "DB prod"},
+		{Name: "cache"},
+	}
+	tests := []struct {
+		selector string
+		want     []string
+		wantErr  bool
+	}{
+		{"tag:web", []string{"web-01", "web-02"}, false},
+		{"tag:db", []string{"db-01"}, false},
+		{"web-*", []string{"web-01", "web-02"}, false},
+		{"name:cache", []string{"cache"}, false},
+		{"tag:prod, cache", []string{"web-01", "db-01", "cache"}, false},
+		{"tag:web,web-01", []string{"web-01", "web-02"}, false},
+		{"tag:none", nil, false},
+		{" , ", nil, true},
+		{"web-[", nil, true},
+	}
+	for _, tt := range tests {
+		matched, err := MatchHosts(connections, tt.selector)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("MatchHosts(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+			continue
+		}
+		var got []string
+		for _, conn := range matched {
+			got = append(got, conn.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MatchHosts(%q) = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	if got := NormalizeTags(" web，prod  web,, staging "); got != "web, prod, staging" {
+		t.Errorf("NormalizeTags() = %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// looksLikeOption rsync 会把以 - 开头的参数当作选项
+func looksLikeOption(arg string) bool {
+	return strings.HasPrefix(arg, "-")
+}
+
+// looksLikeRemote rsync 会把第一个 / 之前带 : 的参数当作远程路径
+func looksLikeRemote(arg string) bool {
+	colon := strings.Index(arg, ":")
+	slash := strings.Index(arg, "/")
+	return colon >= 0 && (slash < 0 || colon < slash)
+}
+
+func indexOf(args []string, target string) int {
+	for i, arg := range args {
+		if arg == target {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestBuildRsyncCommandNastyPaths(t *testing.T) {
+	property := func(localPath, remotePath, keyPath nastyString, options nastyArgs, download bool) bool {
+		config := &RsyncConfig{
+			Direction:  RsyncDirectionUpload,
+			LocalPath:  string(localPath),
+			RemotePath: string(remotePath),
+			Options:    ShellJoin(options),
+			Archive:    true,
+		}
+		if download {
+			config.Direction = RsyncDirectionDownload
+		}
+		conn := &SSHConnection{
+			Address:      "example.com",
+			Port:         2222,
+			Username:     "luca",
+			PasswordType: PasswordTypeKeyPath,
+			KeyPath:      string(keyPath),
+		}
+
+		cmd := config.BuildRsyncCommand(conn, nil)
+		if cmd[0] != "rsync" {
+			t.Logf("命令不是 rsync: %q", cmd)
+			return false
+		}
+
+		// 额外选项原样保留
+		if !reflect.DeepEqual(config.GetOptionsSlice(), []string(options)) {
+			t.Logf("额外选项解析错误: %q", config.GetOptionsSlice())
+			return false
+		}
+
+		// -e 的命令字符串按 rsync 的规则拆分后与原参数一致
+		e := indexOf(cmd, "-e")
+		want := []string{"ssh", "-p", "2222"}
+		if keyPath != "" {
+			want = append(want, "-i", string(keyPath))
+		}
+		if e < 0 || !reflect.DeepEqual(splitRsyncRemoteShell(cmd[e+1]), want) {
+			t.Logf("-e 参数错误: %q", cmd)
+			return false
+		}
+
+		// 本地路径不会被当作选项或远程路径，且指向同一个位置
+		local, remote := cmd[len(cmd)-2], cmd[len(cmd)-1]
+		if download {
+			local, remote = remote, local
+		}
+		if looksLikeOption(local) || looksLikeRemote(local) {
+			t.Logf("本地路径有歧义: %q", local)
+			return false
+		}
+		if local != string(localPath) && local != "./"+string(localPath) {
+			t.Logf("本地路径被修改: %q", local)
+			return false
+		}
+		if remote != "luca@example.com:"+string(remotePath) {
+			t.Logf("远程路径错误: %q", remote)
+			return false
+		}
+		if needsProtectArgs(string(remotePath)) && indexOf(cmd, "--protect-args") < 0 {
+			t.Logf("缺少 --protect-args: %q", cmd)
+			return false
+		}
+
+		// 预览命令可以被 shell 还原为同样的参数
+		words, err := SplitShellWords(ShellJoin(cmd))
+		if err != nil || !reflect.DeepEqual(words, cmd) {
+			t.Logf("预览命令无法还原: %s", ShellJoin(cmd))
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBuildRsyncCommandICloudKeyPath(t *testing.T) {
+	config := &RsyncConfig{
+		Direction:  RsyncDirectionUpload,
+		LocalPath:  "/Users/luca/Library/Mobile Documents/com~apple~CloudDocs/project/",
+		RemotePath: "/data/my project/",
+		Options:    `--rsync-path="sudo rsync"`,
+	}
+	conn := &SSHConnection{
+		Address:      "example.com",
+		Port:         22,
+		Username:     "luca",
+		PasswordType: PasswordTypeKeyPath,
+		KeyPath:      "/Users/luca/Library/Mobile Documents/com~apple~CloudDocs/ssh/id_rsa",
+	}
+
+	got := ShellJoin(config.BuildRsyncCommand(conn, nil))
+	want := `rsync '--rsync-path=sudo rsync' --protect-args -e 'ssh -p 22 -i '\''/Users/luca/Library/Mobile Documents/com~apple~CloudDocs/ssh/id_rsa'\''' '/Users/luca/Library/Mobile Documents/com~apple~CloudDocs/project/' 'luca@example.com:/data/my project/'`
+	if got != want {
+		t.Errorf("预览命令\n得到 %s\n期望 %s", got, want)
+	}
+}
+
+func TestQuoteRemotePathArgs(t *testing.T) {
+	conn := &SSHConnection{Address: "example.com", Port: 22, Username: "luca"}
+	config := &RsyncConfig{Direction: RsyncDirectionDownload, LocalPath: "./backup/", RemotePath: "~/my project/it's/"}
+
+	got := QuoteRemotePathArgs(config.BuildRsyncCommand(conn, nil), conn)
+	if indexOf(got, "--protect-args") >= 0 {
+		t.Errorf("旧版本rsync不应使用 --protect-args: %q", got)
+	}
+	want := "luca@example.com:~/" + ShellQuote("my project/it's/")
+	if got[len(got)-2] != want || got[len(got)-1] != "./backup/" {
+		t.Errorf("远程路径应该由远程 shell 引用\n得到 %q\n期望 %q", got[len(got)-2:], want)
+	}
+
+	// 不需要 --protect-args 的命令保持不变
+	config.RemotePath = "/srv/app/"
+	plain := config.BuildRsyncCommand(conn, nil)
+	if !reflect.DeepEqual(QuoteRemotePathArgs(plain, conn), plain) {
+		t.Errorf("没有 --protect-args 的命令不应修改: %q", plain)
+	}
+}
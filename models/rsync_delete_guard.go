@@ -0,0 +1,136 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultDeleteMaxPercent 没有设置删除上限时，删除超过目标中文件的百分之多少就中止执行
+const DefaultDeleteMaxPercent = 50
+
+// deleteGuardPercentMinDeletes 目标中文件很少时删除几个文件比例就很高，
+// 使用默认比例上限时删除少于此数量不检查比例，用户设置的比例上限始终检查
+const deleteGuardPercentMinDeletes = 10
+
+// DeleteGuardCounts 试运行得到的源和目标中的文件数（不含根目录）和将要删除的数量
+type DeleteGuardCounts struct {
+	Source  int `json:"source"`  // 源中的文件和目录数
+	Dest    int `json:"dest"`    // 目标中已有的文件和目录数，包括将要删除的
+	Deleted int `json:"deleted"` // 将要删除的文件和目录数
+}
+
+// DeleteGuardDecision 删除保护的检查结果
+type DeleteGuardDecision struct {
+	DeleteGuardCounts
+	Percent    float64 `json:"percent"`     // 删除数占目标中文件的百分比
+	MaxCount   int     `json:"max_count"`   // 删除数量上限，0 表示不限制
+	MaxPercent int     `json:"max_percent"` // 删除比例上限，0 表示不限制
+	Allowed    bool    `json:"allowed"`     // 是否继续执行
+	Forced     bool    `json:"forced"`      // 超过上限但使用了 --force
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// HasDeleteOption 执行时是否会删除目标中多余的文件：启用了删除，或额外选项中有 --delete、--del 等选项
+func (r *RsyncConfig) HasDeleteOption() bool {
+	if r.Delete {
+		return true
+	}
+	options, _ := r.ParseOptions()
+	for _, option := range options {
+		if option == "--del" || strings.HasPrefix(option, "--delete") && option != "--delete-missing-args" {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteGuardEnabled 执行前是否需要删除保护检查
+// 试运行不会删除文件；快照备份每次写入新的目录；双向同步不使用 --delete，但会把一端的删除同步到另一端
+func (r *RsyncConfig) DeleteGuardEnabled() bool {
+	if r.DryRun || r.SnapshotMode {
+		return false
+	}
+	return r.Direction == RsyncDirectionBidirectional || r.HasDeleteOption()
+}
+
+// DeleteGuardLimits 删除数量和比例上限，以及删除多少项以上才检查比例
+// 都没有设置时比例上限为 DefaultDeleteMaxPercent，删除少于 deleteGuardPercentMinDeletes 项时不检查比例
+func (r *RsyncConfig) DeleteGuardLimits() (maxCount, maxPercent, percentMinDeletes int) {
+	if r.DeleteMaxCount == 0 && r.DeleteMaxPercent == 0 {
+		return 0, DefaultDeleteMaxPercent, deleteGuardPercentMinDeletes
+	}
+	return r.DeleteMaxCount, r.DeleteMaxPercent, 0
+}
+
+// ValidateDeleteGuard 检查删除上限
+func (r *RsyncConfig) ValidateDeleteGuard() error {
+	if r.DeleteMaxCount < 0 {
+		return fmt.Errorf("删除数量上限不能为负数")
+	}
+	if r.DeleteMaxPercent < 0 || r.DeleteMaxPercent > 100 {
+		return fmt.Errorf("删除比例上限应为 1-100，0 表示不限制")
+	}
+	return nil
+}
+
+// CountDeleteGuardItems 统计 rsync 以 -ii 和 ItemizeOutFormat 试运行的输出，-ii 时没有变化的项也会输出
+func CountDeleteGuardItems(output string) DeleteGuardCounts {
+	var counts DeleteGuardCounts
+	for _, line := range strings.Split(output, "\n") {
+		match := itemizeLinePattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil || match[3] == "./" {
+			continue
+		}
+		flags := match[1]
+		if strings.HasPrefix(flags, "*deleting") {
+			counts.Dest++
+			counts.Deleted++
+			continue
+		}
+		counts.Source++
+		// 属性标记全部为 + 的是新建的项，其余都已在目标中存在（完全没有变化的项只有两位标记）
+		if attributes := flags[min(2, len(flags)):]; attributes == "" || strings.Trim(attributes, "+") != "" {
+			counts.Dest++
+		}
+	}
+	return counts
+}
+
+// EvaluateDeleteGuard 按上限判断是否继续执行，删除少于 percentMinDeletes 项时不检查比例，源为空时无论 force 都拒绝执行
+func EvaluateDeleteGuard(counts DeleteGuardCounts, maxCount, maxPercent, percentMinDeletes int, force bool) DeleteGuardDecision {
+	decision := DeleteGuardDecision{DeleteGuardCounts: counts, MaxCount: maxCount, MaxPercent: maxPercent, Allowed: true}
+	if counts.Dest > 0 {
+		decision.Percent = float64(counts.Deleted) * 100 / float64(counts.Dest)
+	}
+
+	switch {
+	case counts.Source == 0:
+		decision.Allowed = false
+		decision.Reason = "源目录为空，为避免清空目标拒绝执行"
+		return decision
+	case maxCount > 0 && counts.Deleted > maxCount:
+		decision.Reason = fmt.Sprintf("将删除 %d 项，超过上限 %d 项", counts.Deleted, maxCount)
+	case maxPercent > 0 && counts.Deleted >= percentMinDeletes && decision.Percent > float64(maxPercent):
+		decision.Reason = fmt.Sprintf("将删除目标中 %.1f%% 的文件，超过上限 %d%%", decision.Percent, maxPercent)
+	default:
+		return decision
+	}
+	if force {
+		decision.Forced = true
+	} else {
+		decision.Allowed = false
+	}
+	return decision
+}
+
+// String 记录在执行历史中的一行说明
+func (d DeleteGuardDecision) String() string {
+	counts := fmt.Sprintf("删除 %d/%d 项（%.1f%%）", d.Deleted, d.Dest, d.Percent)
+	switch {
+	case d.Forced:
+		return fmt.Sprintf("%s，%s，已使用 --force 继续执行", counts, d.Reason)
+	case !d.Allowed:
+		return fmt.Sprintf("%s，已中止: %s", counts, d.Reason)
+	}
+	return fmt.Sprintf("%s，未超过上限", counts)
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountDeleteGuardItems(t *testing.T) {
+	output := strings.Join([]string{
+		".d          4,096 ./",
+		".f            120 same.txt",
+		">f.st......   300 changed.txt",
+		".f..t......   120 touched.txt",
+		">f+++++++++   500 new.txt",
+		"cd+++++++++ 4,096 newdir/",
+		"*deleting       0 old.txt",
+		"*deleting       0 olddir/",
+		"sending incremental file list",
+		"",
+	}, "\n")
+	got := CountDeleteGuardItems(output)
+	want := DeleteGuardCounts{Source: 5, Dest: 5, Deleted: 2}
+	if got != want {
+		t.Errorf("CountDeleteGuardItems() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEvaluateDeleteGuard(t *testing.T) {
+	tests := []struct {
+		name              string
+		counts            DeleteGuardCounts
+		maxCount          int
+		maxPercent        int
+		percentMinDeletes int
+		force             bool
+		wantAllowed       bool
+		wantForced        bool
+	}{
+		{"未超过上限", DeleteGuardCounts{Source: 100, Dest: 100, Deleted: 20}, 0, 50, 10, false, true, false},
+		{"超过比例", DeleteGuardCounts{Source: 10, Dest: 100, Deleted: 90}, 0, 50, 10, false, false, false},
+		{"超过默认比例但删除很少", DeleteGuardCounts{Source: 1, Dest: 4, Deleted: 3}, 0, 50, 10, false, true, false},
+		{"超过设置的比例且删除很少", DeleteGuardCounts{Source: 1, Dest: 4, Deleted: 3}, 0, 50, 0, false, false, false},
+		{"超过数量", DeleteGuardCounts{Source: 1000, Dest: 1000, Deleted: 11}, 10, 0, 0, false, false, false},
+		{"超过数量时强制执行", DeleteGuardCounts{Source: 1000, Dest: 1000, Deleted: 11}, 10, 0, 0, true, true, true},
+		{"不限制", DeleteGuardCounts{Source: 1, Dest: 1000, Deleted: 999}, 0, 0, 0, false, true, false},
+		{"源为空", DeleteGuardCounts{Source: 0, Dest: 10, Deleted: 10}, 0, 50, 10, false, false, false},
+		{"源为空时不能强制执行", DeleteGuardCounts{Source: 0, Dest: 10, Deleted: 10}, 0, 0, 0, true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := EvaluateDeleteGuard(tt.counts, tt.maxCount, tt.maxPercent, tt.percentMinDeletes, tt.force)
+			if d.Allowed != tt.wantAllowed || d.Forced != tt.wantForced {
+				t.Errorf("EvaluateDeleteGuard() = allowed %v forced %v (%s), want allowed %v forced %v", d.Allowed, d.Forced, d, tt.wantAllowed, tt.wantForced)
+			}
+			if !d.Allowed && d.Reason == "" {
+				t.Error("中止时应有原因")
+			}
+		})
+	}
+}
+
+func TestDeleteGuardEnabled(t *testing.T) {
+	tests := []struct {
+		config RsyncConfig
+		want   bool
+	}{
+		{RsyncConfig{Direction: RsyncDirectionUpload, Delete: true}, true},
+		{RsyncConfig{Direction: RsyncDirectionUpload}, false},
+		{RsyncConfig{Direction: RsyncDirectionDownload, Options: "--delete-after"}, true},
+		{RsyncConfig{Direction: RsyncDirectionDownload, Options: "--del"}, true},
+		{RsyncConfig{Direction: RsyncDirectionUpload, Delete: true, DryRun: true}, false},
+		{RsyncConfig{Direction: RsyncDirectionDownload, Delete: true, SnapshotMode: true}, false},
+		{RsyncConfig{Direction: RsyncDirectionBidirectional}, true},
+		{RsyncConfig{Direction: RsyncDirectionBidirectional, DryRun: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.config.DeleteGuardEnabled(); got != tt.want {
+			t.Errorf("DeleteGuardEnabled(%+v) = %v, want %v", tt.config, got, tt.want)
+		}
+	}
+
+	config := RsyncConfig{}
+	if count, percent, minDeletes := config.DeleteGuardLimits(); count != 0 || percent != DefaultDeleteMaxPercent || minDeletes != deleteGuardPercentMinDeletes {
+		t.Errorf("DeleteGuardLimits() = %d, %d, %d", count, percent, minDeletes)
+	}
+	config.DeleteMaxCount = 100
+	if count, percent, minDeletes := config.DeleteGuardLimits(); count != 100 || percent != 0 || minDeletes != 0 {
+		t.Errorf("DeleteGuardLimits() = %d, %d, %d", count, percent, minDeletes)
+	}
+	config.DeleteMaxCount, config.DeleteMaxPercent = 0, 20
+	if count, percent, minDeletes := config.DeleteGuardLimits(); count != 0 || percent != 20 || minDeletes != 0 {
+		t.Errorf("设置比例上限时应始终检查比例: DeleteGuardLimits() = %d, %d, %d", count, percent, minDeletes)
+	}
+	config.DeleteMaxPercent = 101
+	if err := config.ValidateDeleteGuard(); err == nil {
+		t.Error("ValidateDeleteGuard() 应拒绝超过 100 的比例")
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TransferEngine 执行传输的方式
+type TransferEngine string
+
+const (
+	TransferEngineAuto  TransferEngine = "auto"  // 本地有 rsync 时使用 rsync，否则使用 sftp
+	TransferEngineRsync TransferEngine = "rsync" // 外部的 rsync 程序，本地和服务器上都需要安装
+	TransferEngineSFTP  TransferEngine = "sftp"  // 调用系统的 sftp 命令列出服务器上的文件并传输整个文件，服务器上不需要 rsync
+)
+
+// ParseTransferEngine 解析传输引擎，为空时自动选择
+func ParseTransferEngine(engine string) (TransferEngine, error) {
+	switch e := TransferEngine(engine); e {
+	case "":
+		return TransferEngineAuto, nil
+	case TransferEngineAuto, TransferEngineRsync, TransferEngineSFTP:
+		return e, nil
+	}
+	return "", fmt.Errorf("无效的传输引擎 '%s'，可选: auto, rsync, sftp", engine)
+}
+
+// RsyncVersion rsync 的版本号
+type RsyncVersion struct {
+	Major, Minor, Patch int
+}
+
+var rsyncVersionPattern = regexp.MustCompile(`rsync\s+version\s+v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseRsyncVersion 解析 rsync --version 的输出
+func ParseRsyncVersion(output string) (RsyncVersion, bool) {
+	match := rsyncVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return RsyncVersion{}, false
+	}
+	var v RsyncVersion
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	v.Patch, _ = strconv.Atoi(match[3])
+	return v, true
+}
+
+// AtLeast 版本是否不低于 major.minor
+func (v RsyncVersion) AtLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// SupportsInfoProgress rsync 3.1 开始支持整体进度 --info=progress2，
+// 更早的版本（如 macOS 自带的 2.6.9）只能使用逐文件的 --progress
+func (v RsyncVersion) SupportsInfoProgress() bool {
+	return v.AtLeast(3, 1)
+}
+
+// SupportsProtectArgs rsync 3.0 开始支持 --protect-args（-s），更早的版本只能由远程 shell 拆分路径
+func (v RsyncVersion) SupportsProtectArgs() bool {
+	return v.AtLeast(3, 0)
+}
+
+func (v RsyncVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// TransferEngineInfo 本次执行使用的传输引擎
+type TransferEngineInfo struct {
+	Engine  TransferEngine `json:"engine"`
+	Version string         `json:"version,omitempty"` // 本地 rsync 的版本
+	Reason  string         `json:"reason,omitempty"`  // 选择该引擎的原因
+	Ignored []string       `json:"ignored,omitempty"` // sftp 引擎不支持、被忽略的选项
+}
+
+func (i TransferEngineInfo) String() string {
+	text := string(i.Engine)
+	if i.Version != "" {
+		text += " " + i.Version
+	}
+	if i.Reason != "" {
+		text += "（" + i.Reason + "）"
+	}
+	return text
+}
+
+// ValidateEngine 检查配置指定的传输引擎
+func (r *RsyncConfig) ValidateEngine() error {
+	engine, err := ParseTransferEngine(string(r.Engine))
+	if err != nil {
+		return err
+	}
+	if engine == TransferEngineSFTP {
+		return r.ValidateSFTPEngine()
+	}
+	return nil
+}
+
+// ValidateSFTPEngine 检查配置能否使用 sftp 引擎，sftp 引擎只能在本地和服务器之间同步目录
+func (r *RsyncConfig) ValidateSFTPEngine() error {
+	switch {
+	case r.Direction == RsyncDirectionBidirectional:
+		return fmt.Errorf("sftp 引擎不支持双向同步")
+	case r.Direction == RsyncDirectionRemote:
+		return fmt.Errorf("服务器之间传输在源服务器上执行 rsync，不能使用 sftp 引擎")
+	case r.SnapshotMode:
+		return fmt.Errorf("sftp 引擎不支持快照备份")
+	case r.Backup:
+		return fmt.Errorf("sftp 引擎不支持 --backup")
+	}
+	return nil
+}
+
+// SFTPIgnoredOptions sftp 引擎不支持、执行时被忽略的选项
+func (r *RsyncConfig) SFTPIgnoredOptions() []string {
+	var ignored []string
+	if r.Checksum {
+		ignored = append(ignored, "-c（按大小和修改时间比较）")
+	}
+	if r.Archive || r.Links {
+		ignored = append(ignored, "-l（跳过符号链接）")
+	}
+	if r.Archive || r.Owner || r.Group {
+		ignored = append(ignored, "-o -g（不保持所有者和组）")
+	}
+	if r.ResumeMode != ResumeNone {
+		ignored = append(ignored, "--"+string(r.ResumeMode))
+	}
+	if r.CompressLevel > 0 {
+		ignored = append(ignored, "--compress-level")
+	}
+	if options := strings.TrimSpace(r.Options); options != "" {
+		ignored = append(ignored, "额外选项 "+options)
+	}
+	return ignored
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRsyncVersion(t *testing.T) {
+	cases := map[string]RsyncVersion{
+		"rsync  version 3.2.7  protocol version 31":         {3, 2, 7},
+		"rsync  version 2.6.9  protocol version 29":         {2, 6, 9},
+		"rsync  version v3.3.0  protocol version 32":        {3, 3, 0},
+		"openrsync: protocol version 29\nrsync version 3.1": {3, 1, 0},
+	}
+	for output, want := range cases {
+		got, ok := ParseRsyncVersion(output)
+		if !ok || got != want {
+			t.Errorf("ParseRsyncVersion(%q) = %v, %v, 期望 %v", output, got, ok, want)
+		}
+	}
+	if _, ok := ParseRsyncVersion("command not found"); ok {
+		t.Error("无法识别的输出应该返回 false")
+	}
+	if (RsyncVersion{2, 6, 9}).SupportsInfoProgress() || !(RsyncVersion{3, 1, 0}).SupportsInfoProgress() {
+		t.Error("SupportsInfoProgress 判断错误")
+	}
+	if (RsyncVersion{2, 6, 9}).SupportsProtectArgs() || !(RsyncVersion{3, 0, 0}).SupportsProtectArgs() {
+		t.Error("SupportsProtectArgs 判断错误")
+	}
+}
+
+func TestValidateSFTPEngine(t *testing.T) {
+	ok := RsyncConfig{Direction: RsyncDirectionUpload, Engine: TransferEngineSFTP}
+	if err := ok.ValidateEngine(); err != nil {
+		t.Errorf("上传配置应该可以使用 sftp 引擎: %v", err)
+	}
+	invalid := []RsyncConfig{
+		{Direction: RsyncDirectionBidirectional, Engine: TransferEngineSFTP},
+		{Direction: RsyncDirectionRemote, Engine: TransferEngineSFTP},
+		{Direction: RsyncDirectionDownload, Engine: TransferEngineSFTP, SnapshotMode: true},
+		{Direction: RsyncDirectionUpload, Engine: TransferEngineSFTP, Backup: true},
+		{Direction: RsyncDirectionUpload, Engine: "scp"},
+	}
+	for _, config := range invalid {
+		if err := config.ValidateEngine(); err == nil {
+			t.Errorf("%+v 应该返回错误", config)
+		}
+	}
+}
+
+func TestSFTPTransferRoots(t *testing.T) {
+	cases := []struct {
+		config                RsyncConfig
+		local, remote, prefix string
+	}{
+		{RsyncConfig{Direction: RsyncDirectionUpload, LocalPath: "/src/app", RemotePath: "/var/www/"}, "/src/app", "/var/www/app", "app"},
+		{RsyncConfig{Direction: RsyncDirectionUpload, LocalPath: "/src/app/", RemotePath: "/var/www"}, "/src/app", "/var/www", ""},
+		{RsyncConfig{Direction: RsyncDirectionDownload, LocalPath: "/backup", RemotePath: "~/logs"}, "/backup/logs", "~/logs", "logs"},
+		{RsyncConfig{Direction: RsyncDirectionDownload, LocalPath: "/backup", RemotePath: "~"}, "/backup", "~", ""},
+	}
+	for _, c := range cases {
+		local, remote, prefix := c.config.SFTPTransferRoots()
+		if local != c.local || remote != c.remote || prefix != c.prefix {
+			t.Errorf("%s -> %s: %s, %s, %s, 期望 %s, %s, %s", c.config.LocalPath, c.config.RemotePath, local, remote, prefix, c.local, c.remote, c.prefix)
+		}
+	}
+}
+
+func TestRemoteEntryTransferEntry(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	entry, err := RemoteEntry{Name: "a b.txt", Size: 12, Mode: "-rw-r--r--", Modified: "Feb 28 09:15"}.TransferEntry(now)
+	want := TransferEntry{Size: 12, ModTime: time.Date(2024, 2, 28, 9, 15, 0, 0, time.UTC).Unix(), Precision: 60, Mode: 0644}
+	if err != nil || entry != want {
+		t.Errorf("TransferEntry() = %+v, %v, want %+v", entry, err, want)
+	}
+
+	entry, err = RemoteEntry{Name: "sub", IsDir: true, Size: 4096, Mode: "drwxr-s---", Modified: "Jun 1 2023"}.TransferEntry(now)
+	want = TransferEntry{IsDir: true, Size: 4096, ModTime: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC).Unix(), Precision: 24 * 60 * 60, Mode: 0750}
+	if err != nil || entry != want {
+		t.Errorf("TransferEntry() = %+v, %v, want %+v", entry, err, want)
+	}
+
+	if _, err := (RemoteEntry{Name: "x", Mode: "-rw-r--r--", Modified: "soon"}).TransferEntry(now); err == nil {
+		t.Error("无法解析的修改时间应该返回错误")
+	}
+}
+
+func TestTransferEntrySameModTime(t *testing.T) {
+	local := TransferEntry{ModTime: 1700000125}
+	tests := []struct {
+		other TransferEntry
+		want  bool
+	}{
+		{TransferEntry{ModTime: 1700000125}, true},
+		{TransferEntry{ModTime: 1700000126}, false},
+		{TransferEntry{ModTime: 1700000100, Precision: 60}, true},
+		{TransferEntry{ModTime: 1700000160, Precision: 60}, false},
+		{TransferEntry{ModTime: 1699920000, Precision: 24 * 60 * 60}, true},
+	}
+	for _, tt := range tests {
+		if got := local.SameModTime(tt.other); got != tt.want {
+			t.Errorf("SameModTime(%+v) = %v, want %v", tt.other, got, tt.want)
+		}
+		if got := tt.other.SameModTime(local); got != tt.want {
+			t.Errorf("%+v.SameModTime() = %v, want %v", tt.other, got, tt.want)
+		}
+	}
+}
+
+func TestPlanFileTransfer(t *testing.T) {
+	dir := TransferEntry{IsDir: true}
+	source := map[string]TransferEntry{
+		"":               dir,
+		"same.txt":       {Size: 10, ModTime: 100},
+		"changed.txt":    {Size: 20, ModTime: 200},
+		"new":            dir,
+		"new/file.txt":   {Size: 30, ModTime: 300},
+		"big.iso":        {Size: 1000, ModTime: 100},
+		"node_modules":   dir,
+		"node_modules/x": {Size: 1, ModTime: 1},
+		"conflict":       dir,
+		"conflict/a":     {Size: 5, ModTime: 5},
+	}
+	dest := map[string]TransferEntry{
+		"":              dir,
+		"same.txt":      {Size: 10, ModTime: 100},
+		"changed.txt":   {Size: 20, ModTime: 150},
+		"old":           dir,
+		"old/a.txt":     {Size: 1, ModTime: 1},
+		"logs":          dir,
+		"logs/keep.log": {Size: 1, ModTime: 1},
+		"logs/tmp":      {Size: 1, ModTime: 1},
+		"conflict":      {Size: 3, ModTime: 3},
+	}
+	changes := PlanFileTransfer(source, dest, TransferPlanOptions{
+		Upload:  true,
+		Delete:  true,
+		MaxSize: 500,
+		Excluded: func(rel string, isDir bool) bool {
+			return rel == "node_modules" || strings.HasPrefix(rel, "node_modules/")
+		},
+		Protected: func(rel string, isDir bool) bool { return strings.HasSuffix(rel, ".log") },
+	})
+
+	got := make([]string, 0, len(changes))
+	for _, change := range changes {
+		got = append(got, change.Flags+" "+change.Path)
+	}
+	want := []string{
+		"*deleting old/a.txt",
+		"*deleting old/",
+		"*deleting logs/tmp",
+		"*deleting conflict",
+		"<f.st...... changed.txt",
+		"cd+++++++++ conflict/",
+		"<f+++++++++ conflict/a",
+		"cd+++++++++ new/",
+		"<f+++++++++ new/file.txt",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanFileTransfer =\n%s\n期望\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// 不删除时类型不同的路径无法传输，其下的内容也跳过
+	changes = PlanFileTransfer(source, dest, TransferPlanOptions{Upload: true, MaxSize: 500})
+	for _, change := range changes {
+		if change.Kind == ItemizedChangeDeleted || strings.HasPrefix(change.Path, "conflict") {
+			t.Errorf("不应该有变更 %s %s", change.Flags, change.Path)
+		}
+	}
+
+	// 源路径不以 / 结尾时变更路径带目录名，目标不存在时新建该目录
+	changes = PlanFileTransfer(map[string]TransferEntry{"": dir, "a": {Size: 1}}, nil, TransferPlanOptions{Prefix: "app"})
+	if len(changes) != 2 || changes[0].Path != "app/" || changes[1].Path != "app/a" || changes[1].Flags != ">f+++++++++" {
+		t.Errorf("带前缀的计划 = %+v", changes)
+	}
+}
+
+func TestBuildSFTPBatch(t *testing.T) {
+	changes := []ItemizedChange{
+		{Kind: ItemizedChangeDeleted, Path: "old/", FileType: "d"},
+		{Kind: ItemizedChangeDeleted, Path: "app/x y", FileType: "f"},
+		{Kind: ItemizedChangeNew, Path: "app/dir/", FileType: "d"},
+		{Kind: ItemizedChangeNew, Path: "app/dir/*.txt", FileType: "f"},
+	}
+	lines, err := BuildSFTPBatch(changes, "/src/app", "~/www/app", "app", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(lines))
+	for _, line := range lines {
+		got = append(got, line.Command)
+	}
+	want := []string{
+		"rmdir www/app/old",
+		`rm www/app/x\ y`,
+		"mkdir www/app/dir",
+		`put -p /src/app/dir/\*.txt www/app/dir/\*.txt`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("上传批处理 = %q", got)
+	}
+
+	lines, err = BuildSFTPBatch(changes, "/backup", "/logs", "", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].Command != `get /logs/app/dir/\*.txt /backup/app/dir/\*.txt` {
+		t.Errorf("下载批处理 = %+v", lines)
+	}
+
+	if _, err := BuildSFTPBatch([]ItemizedChange{{Kind: ItemizedChangeNew, Path: "a\nb", FileType: "f"}}, "/a", "/b", "", true, false); err == nil {
+		t.Error("包含换行符的文件名应该返回错误")
+	}
+	if got := sftpRemotePath("", "-rf"); got != "./-rf" {
+		t.Errorf("sftpRemotePath = %s", got)
+	}
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// filterPattern 一条编译后的过滤规则
+type filterPattern struct {
+	rule    FilterRule
+	re      *regexp.Regexp
+	dirOnly bool // 以 / 结尾的规则只匹配目录
+}
+
+// FilterMatcher 按 rsync 的规则判断路径是否被排除
+//
+//   - 以 / 开头的规则从传输根目录开始匹配，否则匹配路径的末尾部分
+//   - 不含 / 的规则只匹配文件名
+//   - * 不跨越 /，** 可以跨越 /，? 匹配单个非 / 字符
+//   - 规则按顺序检查，第一条匹配的规则生效
+//   - 目录被排除时其中的所有文件也被排除
+type FilterMatcher struct {
+	patterns []filterPattern
+}
+
+// FilterResult 过滤规则的判断结果
+type FilterResult struct {
+	Excluded bool
+	Rule     *FilterRule // 决定结果的规则，为空表示没有规则匹配
+	Path     string      // Rule 匹配的路径，父目录被排除时为父目录
+}
+
+// NewExcludeMatcher 编译排除规则
+func NewExcludeMatcher(rules []string) (*FilterMatcher, error) {
+	filters := make([]FilterRule, len(rules))
+	for i, rule := range rules {
+		filters[i] = FilterRule{Action: FilterExclude, Pattern: rule}
+	}
+	return NewFilterMatcher(filters)
+}
+
+// NewFilterMatcher 按顺序编译过滤规则，合并规则需要先展开，这里会忽略
+func NewFilterMatcher(rules []FilterRule) (*FilterMatcher, error) {
+	m := &FilterMatcher{}
+	for _, rule := range rules {
+		if rule.Action == FilterMerge {
+			continue
+		}
+		p, err := compileExcludePattern(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("规则 '%s' 无效: %v", rule, err)
+		}
+		p.rule = rule
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+// Excluded 判断相对传输根目录的路径是否被排除，isDir 表示 rel 本身是否为目录
+func (m *FilterMatcher) Excluded(rel string, isDir bool) bool {
+	return m.Check(rel, isDir).Excluded
+}
+
+// Check 判断发送端是否会传输该路径，保护规则不影响传输
+func (m *FilterMatcher) Check(rel string, isDir bool) FilterResult {
+	return m.check(rel, isDir, false)
+}
+
+// Protected 判断接收端的该路径是否受保护，被排除或保护的文件不会被 --delete 删除
+func (m *FilterMatcher) Protected(rel string, isDir bool) FilterResult {
+	return m.check(rel, isDir, true)
+}
+
+func (m *FilterMatcher) check(rel string, isDir, receiver bool) FilterResult {
+	rel = strings.Trim(path.Clean("/"+rel), "/")
+	var result FilterResult
+	if rel == "" || len(m.patterns) == 0 {
+		return result
+	}
+
+	// 依次检查每一级父目录，父目录被排除时 rsync 不会进入其中
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		current := strings.Join(parts[:i+1], "/")
+		currentIsDir := isDir || i < len(parts)-1
+		result = FilterResult{}
+		for j := range m.patterns {
+			p := &m.patterns[j]
+			if p.rule.Action == FilterProtect && !receiver {
+				continue
+			}
+			if p.dirOnly && !currentIsDir {
+				continue
+			}
+			if p.re.MatchString(current) {
+				result = FilterResult{Excluded: p.rule.Action != FilterInclude, Rule: &p.rule, Path: current}
+				break
+			}
+		}
+		if result.Excluded {
+			return result
+		}
+	}
+	return result
+}
+
+func compileExcludePattern(rule string) (filterPattern, error) {
+	var p filterPattern
+	pattern := rule
+	if strings.HasSuffix(pattern, "/") {
+		p.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimLeft(pattern, "/")
+	if pattern == "" {
+		return p, fmt.Errorf("规则为空")
+	}
+
+	var b strings.Builder
+	switch {
+	case anchored:
+		b.WriteString("^")
+	case strings.HasPrefix(pattern, "**"):
+		// 以 ** 开头时本身就可以匹配任意前缀
+		b.WriteString("^")
+	default:
+		b.WriteString("(^|/)")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return p, fmt.Errorf("缺少 ]")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return p, err
+	}
+	p.re = re
+	return p, nil
+}
+
+// TransferPath 返回本地文件相对传输根目录的路径，用于匹配排除规则
+// LocalPath 不以 / 结尾时 rsync 传输的是目录本身，传输根目录是其上一级；双向同步总是同步目录中的内容
+func (r *RsyncConfig) TransferPath(localFile string) (string, bool) {
+	root := filepath.Clean(r.LocalPath)
+	rel, err := filepath.Rel(root, filepath.Clean(localFile))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasSuffix(r.LocalPath, "/") && r.Direction != RsyncDirectionBidirectional {
+		if rel == "." {
+			rel = filepath.Base(root)
+		} else {
+			rel = filepath.Base(root) + "/" + rel
+		}
+	} else if rel == "." {
+		rel = ""
+	}
+	return rel, true
+}
@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestExcludeMatcher(t *testing.T) {
+	matcher, err := NewExcludeMatcher([]string{"*.log", "node_modules/", "/build", "docs/*.tmp", "**/cache/**", "[Tt]humbs.db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"src/debug.log", false, true},
+		{"src/main.go", false, false},
+		{"node_modules", true, true},
+		{"web/node_modules/react/index.js", false, true},
+		{"node_modules", false, false}, // 以 / 结尾的规则只匹配目录
+		{"build", true, true},
+		{"build/out.bin", false, true},
+		{"src/build", true, false}, // 以 / 开头的规则只从根目录匹配
+		{"docs/a.tmp", false, true},
+		{"src/docs/a.tmp", false, true},
+		{"docs/sub/a.tmp", false, false}, // * 不跨越 /
+		{"a/cache/b/c.txt", false, true},
+		{"thumbs.db", false, true},
+		{"Thumbs.db", false, true},
+	}
+	for _, tt := range tests {
+		if got := matcher.Excluded(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Excluded(%q, %v) = %v, 期望 %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestRsyncConfigTransferPath(t *testing.T) {
+	tests := []struct {
+		localPath, file, want string
+		ok                    bool
+	}{
+		{"/home/me/project/", "/home/me/project/src/a.go", "src/a.go", true},
+		{"/home/me/project", "/home/me/project/src/a.go", "project/src/a.go", true},
+		{"/home/me/project/", "/home/me/project", "", true},
+		{"/home/me/project", "/home/me/other/a.go", "", false},
+	}
+	for _, tt := range tests {
+		config := RsyncConfig{LocalPath: tt.localPath}
+		got, ok := config.TransferPath(tt.file)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("TransferPath(%q, %q) = %q, %v，期望 %q, %v", tt.localPath, tt.file, got, ok, tt.want, tt.ok)
+		}
+	}
+}
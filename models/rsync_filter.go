@@ -0,0 +1,199 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type FilterAction string
+
+const (
+	FilterInclude FilterAction = "+"
+	FilterExclude FilterAction = "-"
+	FilterProtect FilterAction = "P" // 只作用于接收端，匹配的文件不会被 --delete 删除
+	FilterMerge   FilterAction = "." // 从文件中读取更多规则
+)
+
+// 规则来源
+const (
+	FilterSourceRules   = "过滤规则"
+	FilterSourceExclude = "排除规则"
+)
+
+// IgnoreFileNames 开启忽略文件时在本地路径各级目录中读取的文件，同一目录中后面的文件优先
+var IgnoreFileNames = []string{".gitignore", ".rsyncignore"}
+
+// FilterRule 一条有序的过滤规则，按顺序第一条匹配的规则生效
+type FilterRule struct {
+	Action  FilterAction `json:"action"`
+	Pattern string       `json:"pattern"`          // 合并规则时为文件路径
+	Source  string       `json:"source,omitempty"` // 规则来源，如 过滤规则、排除规则、src/.gitignore
+}
+
+func (f FilterRule) String() string {
+	return string(f.Action) + " " + f.Pattern
+}
+
+var filterActionNames = map[string]FilterAction{
+	"+": FilterInclude, "include": FilterInclude,
+	"-": FilterExclude, "exclude": FilterExclude,
+	"P": FilterProtect, "protect": FilterProtect,
+	".": FilterMerge, "merge": FilterMerge,
+}
+
+// ParseFilterRules 解析过滤规则，每行一条，格式与 rsync --filter 相同：
+// "+ 模式" 包含，"- 模式" 排除，"P 模式" 保护，". 文件" 从文件读取规则，
+// 也可以写作 include、exclude、protect、merge；# 开头的行为注释
+func ParseFilterRules(text, source string) ([]FilterRule, error) {
+	var rules []FilterRule
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, pattern := line, ""
+		if index := strings.IndexAny(line, " \t"); index > 0 {
+			name, pattern = line[:index], strings.TrimSpace(line[index+1:])
+		}
+		action, ok := filterActionNames[name]
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("第 %d 行过滤规则 '%s' 无效，格式为 \"+ 模式\"、\"- 模式\"、\"P 模式\" 或 \". 文件\"", i+1, line)
+		}
+		if action != FilterMerge {
+			if _, err := compileExcludePattern(pattern); err != nil {
+				return nil, fmt.Errorf("第 %d 行过滤规则 '%s' 无效: %v", i+1, line, err)
+			}
+		}
+		rules = append(rules, FilterRule{Action: action, Pattern: pattern, Source: source})
+	}
+	return rules, nil
+}
+
+// ConvertIgnoreFile 把 .gitignore 格式的内容转换为过滤规则，prefix 为文件所在目录相对传输根目录的路径
+// gitignore 中后面的规则优先，而 rsync 中第一条匹配的规则生效，因此返回的规则与文件中的顺序相反
+func ConvertIgnoreFile(content, prefix, source string) []FilterRule {
+	prefix = escapeFilterPattern(strings.Trim(prefix, "/"))
+
+	var lines [][]FilterRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		action := FilterExclude
+		if strings.HasPrefix(line, "!") {
+			action = FilterInclude
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		dirOnly := strings.HasSuffix(line, "/")
+		pattern := strings.TrimRight(line, "/")
+		// 不含 / 的规则（或以 **/ 开头）匹配任意层级，否则相对忽略文件所在目录
+		floating := !strings.Contains(pattern, "/")
+		if strings.HasPrefix(pattern, "**/") {
+			pattern = strings.TrimPrefix(pattern, "**/")
+			floating = true
+		}
+		pattern = strings.TrimLeft(pattern, "/")
+		if pattern == "" {
+			continue
+		}
+
+		var patterns []string
+		switch {
+		case floating && prefix == "":
+			patterns = []string{pattern}
+		case floating:
+			patterns = []string{"/" + prefix + "/" + pattern, "/" + prefix + "/**/" + pattern}
+		case prefix == "":
+			patterns = []string{"/" + pattern}
+		default:
+			patterns = []string{"/" + prefix + "/" + pattern}
+		}
+
+		var rules []FilterRule
+		for _, p := range patterns {
+			if dirOnly {
+				p += "/"
+			}
+			rules = append(rules, FilterRule{Action: action, Pattern: p, Source: source})
+		}
+		lines = append(lines, rules)
+	}
+
+	var rules []FilterRule
+	for i := len(lines) - 1; i >= 0; i-- {
+		rules = append(rules, lines[i]...)
+	}
+	return rules
+}
+
+// escapeFilterPattern 转义目录名中的通配符
+func escapeFilterPattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// GetFilterRules 解析配置的过滤规则
+func (r *RsyncConfig) GetFilterRules() ([]FilterRule, error) {
+	return ParseFilterRules(r.FilterRules, FilterSourceRules)
+}
+
+// OrderedFilterRules 按生效顺序排列配置的全部规则：过滤规则、排除规则、从忽略文件得到的规则
+func (r *RsyncConfig) OrderedFilterRules(ignoreRules []FilterRule) []FilterRule {
+	// 格式错误由保存前的校验处理
+	rules, _ := r.GetFilterRules()
+	for _, rule := range r.GetExcludeRulesSlice() {
+		rules = append(rules, FilterRule{Action: FilterExclude, Pattern: rule, Source: FilterSourceExclude})
+	}
+	return append(rules, ignoreRules...)
+}
+
+// MergeFilePath 合并规则的文件路径，相对路径相对于本地路径
+func (r *RsyncConfig) MergeFilePath(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(r.LocalPath, file)
+}
+
+// filterArgs 生成过滤规则的rsync参数，排除规则保持使用 --exclude
+func (r *RsyncConfig) filterArgs(ignoreRules []FilterRule) []string {
+	var args []string
+	for _, rule := range r.OrderedFilterRules(ignoreRules) {
+		switch {
+		case rule.Source == FilterSourceExclude:
+			args = append(args, "--exclude", rule.Pattern)
+		case rule.Action == FilterMerge:
+			args = append(args, "--filter", string(FilterMerge)+" "+r.MergeFilePath(rule.Pattern))
+		default:
+			args = append(args, "--filter", rule.String())
+		}
+	}
+	return args
+}
+
+// ValidateFilters 检查过滤规则的格式
+func (r *RsyncConfig) ValidateFilters() error {
+	if _, err := r.GetFilterRules(); err != nil {
+		return err
+	}
+	if r.UseIgnoreFiles && r.SnapshotMode {
+		return fmt.Errorf("快照备份的本地路径中保存的是快照，不能读取忽略文件")
+	}
+	return nil
+}
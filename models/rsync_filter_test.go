@@ -0,0 +1,86 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilterRules(t *testing.T) {
+	rules, err := ParseFilterRules("# 注释\n+ keep.log\nexclude *.log\n\nP /uploads/\n. .rsync-filter", FilterSourceRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FilterRule{
+		{Action: FilterInclude, Pattern: "keep.log", Source: FilterSourceRules},
+		{Action: FilterExclude, Pattern: "*.log", Source: FilterSourceRules},
+		{Action: FilterProtect, Pattern: "/uploads/", Source: FilterSourceRules},
+		{Action: FilterMerge, Pattern: ".rsync-filter", Source: FilterSourceRules},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("ParseFilterRules = %+v, 期望 %+v", rules, want)
+	}
+
+	for _, text := range []string{"*.log", "+", "x *.log", "- [abc"} {
+		if _, err := ParseFilterRules(text, ""); err == nil {
+			t.Errorf("ParseFilterRules(%q) 应该返回错误", text)
+		}
+	}
+}
+
+func TestConvertIgnoreFile(t *testing.T) {
+	content := "# 构建产物\n*.log\n!keep.log\n/dist\nbuild/\ndocs/*.tmp\n**/cache\n"
+	got := ConvertIgnoreFile(content, "web", "web/.gitignore")
+
+	var patterns []string
+	for _, rule := range got {
+		patterns = append(patterns, rule.String())
+	}
+	want := []string{
+		"- /web/cache", "- /web/**/cache",
+		"- /web/docs/*.tmp",
+		"- /web/build/", "- /web/**/build/",
+		"- /web/dist",
+		"+ /web/keep.log", "+ /web/**/keep.log",
+		"- /web/*.log", "- /web/**/*.log",
+	}
+	if !reflect.DeepEqual(patterns, want) {
+		t.Errorf("ConvertIgnoreFile = %q, 期望 %q", patterns, want)
+	}
+}
+
+func TestFilterMatcherOrder(t *testing.T) {
+	rules, _ := ParseFilterRules("+ keep.log\n- *.log\nP /uploads/", FilterSourceRules)
+	rules = append(rules, ConvertIgnoreFile("node_modules/\n*.tmp\n!important.tmp", "", ".gitignore")...)
+	matcher, err := NewFilterMatcher(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		excluded bool
+		rule     string
+	}{
+		{"app/keep.log", false, false, "+ keep.log"},
+		{"app/debug.log", false, true, "- *.log"},
+		{"uploads/a.png", false, false, ""},
+		{"node_modules/x/index.js", false, true, "- node_modules/"},
+		{"a/important.tmp", false, false, "+ important.tmp"},
+		{"a/other.tmp", false, true, "- *.tmp"},
+	}
+	for _, tt := range tests {
+		result := matcher.Check(tt.path, tt.isDir)
+		rule := ""
+		if result.Rule != nil {
+			rule = result.Rule.String()
+		}
+		if result.Excluded != tt.excluded || rule != tt.rule {
+			t.Errorf("Check(%q) = %v %q, 期望 %v %q", tt.path, result.Excluded, rule, tt.excluded, tt.rule)
+		}
+	}
+
+	if result := matcher.Protected("uploads/a.png", false); !result.Excluded || result.Path != "uploads" {
+		t.Errorf("Protected(uploads/a.png) = %+v，应该受保护", result)
+	}
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RsyncGroupMode string
+
+const (
+	RsyncGroupSequential RsyncGroupMode = "sequential" // 按顺序逐个执行
+	RsyncGroupParallel   RsyncGroupMode = "parallel"   // 同时执行
+)
+
+// RsyncGroup 一组按顺序或并行执行的rsync配置
+type RsyncGroup struct {
+	gorm.Model
+	Name          string         `gorm:"uniqueIndex:idx_rsync_groups_active_name,where:deleted_at IS NULL;not null" json:"name"`
+	Mode          RsyncGroupMode `gorm:"not null;default:sequential" json:"mode"`
+	Concurrency   int            `gorm:"default:0" json:"concurrency"` // 并行时最多同时执行的配置数，0 表示不限制
+	StopOnFailure bool           `json:"stop_on_failure"`              // 有配置失败后不再开始执行其余配置
+	Description   string         `json:"description"`
+	UsageCount    int            `gorm:"default:0" json:"usage_count"`
+}
+
+// RsyncGroupMember 分组中的一个rsync配置，按 Position 排序
+// 通过ID引用，配置改名不影响分组
+type RsyncGroupMember struct {
+	ID            uint `gorm:"primarykey" json:"id"`
+	GroupID       uint `gorm:"index;not null" json:"group_id"`
+	RsyncConfigID uint `gorm:"index;not null" json:"rsync_config_id"`
+	Position      int  `json:"position"`
+}
+
+// ParseRsyncGroupMode 解析执行方式，为空时按顺序执行
+func ParseRsyncGroupMode(mode string) (RsyncGroupMode, error) {
+	switch RsyncGroupMode(mode) {
+	case "", RsyncGroupSequential:
+		return RsyncGroupSequential, nil
+	case RsyncGroupParallel:
+		return RsyncGroupParallel, nil
+	}
+	return "", fmt.Errorf("无效的执行方式 '%s'，可选: sequential, parallel", mode)
+}
+
+// Limit 同时执行的配置数，0 表示不限制
+func (g *RsyncGroup) Limit() int {
+	if g.Mode != RsyncGroupParallel {
+		return 1
+	}
+	if g.Concurrency < 0 {
+		return 0
+	}
+	return g.Concurrency
+}
+
+// ModeText 执行方式的描述
+func (g *RsyncGroup) ModeText() string {
+	if g.Mode != RsyncGroupParallel {
+		return "顺序执行"
+	}
+	if g.Concurrency > 0 {
+		return fmt.Sprintf("并行执行（最多 %d 个）", g.Concurrency)
+	}
+	return "并行执行"
+}
+
+func (g *RsyncGroup) GetArg() []string {
+	return []string{g.Name}
+}
+
+func (g *RsyncGroup) GetVariables() map[string]string {
+	return map[string]string{
+		"rsync_group_name":        g.Name,
+		"rsync_group_mode":        string(g.Mode),
+		"rsync_group_description": g.Description,
+		"rsync_group_usage_count": fmt.Sprintf("%d", g.UsageCount),
+	}
+}
+
+type RsyncGroupItemStatus string
+
+const (
+	RsyncGroupItemSuccess RsyncGroupItemStatus = "success"
+	RsyncGroupItemFailed  RsyncGroupItemStatus = "failed"
+	RsyncGroupItemSkipped RsyncGroupItemStatus = "skipped" // 因前面的配置失败而没有执行
+)
+
+// RsyncGroupItemResult 分组中一个配置的执行结果
+type RsyncGroupItemResult struct {
+	ConfigName string               `json:"config_name"`
+	Status     RsyncGroupItemStatus `json:"status"`
+	Result     *RsyncResult         `json:"result,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// RsyncGroupResult 分组执行的汇总结果，Items 与分组中配置的顺序一致
+type RsyncGroupResult struct {
+	GroupName        string                 `json:"group_name"`
+	Items            []RsyncGroupItemResult `json:"items"`
+	Succeeded        int                    `json:"succeeded"`
+	Failed           int                    `json:"failed"`
+	Skipped          int                    `json:"skipped"`
+	Missing          int                    `json:"missing"` // 分组中已被删除的配置数
+	FilesTransferred int                    `json:"files_transferred"`
+	TransferredSize  int64                  `json:"transferred_size"`
+	Duration         time.Duration          `json:"-"`
+	DurationSeconds  float64                `json:"duration_seconds"`
+}
+
+// Summarize 根据各配置的结果计算汇总
+func (r *RsyncGroupResult) Summarize(duration time.Duration) {
+	r.Succeeded, r.Failed, r.Skipped = 0, 0, 0
+	r.FilesTransferred, r.TransferredSize = 0, 0
+	for _, item := range r.Items {
+		switch item.Status {
+		case RsyncGroupItemSuccess:
+			r.Succeeded++
+		case RsyncGroupItemFailed:
+			r.Failed++
+		case RsyncGroupItemSkipped:
+			r.Skipped++
+		}
+		if item.Result != nil {
+			r.FilesTransferred += item.Result.FilesTransferred
+			r.TransferredSize += item.Result.TransferredSize
+		}
+	}
+	r.Duration = duration
+	r.DurationSeconds = duration.Seconds()
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRsyncGroupLimit(t *testing.T) {
+	tests := []struct {
+		group RsyncGroup
+		want  int
+	}{
+		{RsyncGroup{Mode: RsyncGroupSequential, Concurrency: 3}, 1},
+		{RsyncGroup{Mode: RsyncGroupParallel}, 0},
+		{RsyncGroup{Mode: RsyncGroupParallel, Concurrency: 2}, 2},
+	}
+	for _, tt := range tests {
+		if got := tt.group.Limit(); got != tt.want {
+			t.Errorf("Limit(%+v) = %d, 期望 %d", tt.group, got, tt.want)
+		}
+	}
+
+	if _, err := ParseRsyncGroupMode("random"); err == nil {
+		t.Error("无效的执行方式应该返回错误")
+	}
+}
+
+func TestRsyncGroupResultSummarize(t *testing.T) {
+	result := RsyncGroupResult{Items: []RsyncGroupItemResult{
+		{Status: RsyncGroupItemSuccess, Result: &RsyncResult{FilesTransferred: 2, TransferredSize: 100}},
+		{Status: RsyncGroupItemFailed, Result: &RsyncResult{FilesTransferred: 1, TransferredSize: 10}},
+		{Status: RsyncGroupItemSkipped},
+	}}
+	result.Summarize(time.Second)
+	if result.Succeeded != 1 || result.Failed != 1 || result.Skipped != 1 {
+		t.Errorf("状态统计错误: %+v", result)
+	}
+	if result.FilesTransferred != 3 || result.TransferredSize != 110 {
+		t.Errorf("传输统计错误: %d 个文件, %d 字节", result.FilesTransferred, result.TransferredSize)
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"strings"
+)
+
+// RsyncHookStage 钩子执行的阶段
+type RsyncHookStage string
+
+const (
+	RsyncHookPre  RsyncHookStage = "pre"  // rsync 执行前，失败时不再执行 rsync
+	RsyncHookPost RsyncHookStage = "post" // rsync 成功后
+)
+
+// 钩子命令的前缀，没有前缀的命令在本地执行
+const (
+	rsyncHookLocalPrefix  = "local:"
+	rsyncHookRemotePrefix = "remote:"
+)
+
+// RsyncHook 执行前后运行的一条命令
+type RsyncHook struct {
+	Stage   RsyncHookStage `json:"stage"`
+	Remote  bool           `json:"remote"` // 通过SSH连接在服务器上执行
+	Command string         `json:"command"`
+}
+
+// RsyncHookResult 一个钩子的执行结果
+type RsyncHookResult struct {
+	RsyncHook
+	ExitCode        int     `json:"exit_code"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Location 执行位置的名称
+func (h RsyncHook) Location() string {
+	if h.Remote {
+		return "remote"
+	}
+	return "local"
+}
+
+// String 用于日志的描述，如 "[pre/local] make build"
+func (h RsyncHook) String() string {
+	return "[" + string(h.Stage) + "/" + h.Location() + "] " + h.Command
+}
+
+// ParseRsyncHooks 解析钩子列表：每行一个命令，按顺序执行，# 开头的行是注释，
+// 以 remote: 开头的命令在服务器上执行，local: 或没有前缀的命令在本地执行
+func ParseRsyncHooks(text string, stage RsyncHookStage) []RsyncHook {
+	var hooks []RsyncHook
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hook := RsyncHook{Stage: stage, Command: line}
+		switch {
+		case hasPrefixFold(line, rsyncHookRemotePrefix):
+			hook.Remote = true
+			hook.Command = strings.TrimSpace(line[len(rsyncHookRemotePrefix):])
+		case hasPrefixFold(line, rsyncHookLocalPrefix):
+			hook.Command = strings.TrimSpace(line[len(rsyncHookLocalPrefix):])
+		}
+		if hook.Command != "" {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// GetHooks 返回指定阶段的钩子
+func (r *RsyncConfig) GetHooks(stage RsyncHookStage) []RsyncHook {
+	if stage == RsyncHookPre {
+		return ParseRsyncHooks(r.PreHooks, stage)
+	}
+	return ParseRsyncHooks(r.PostHooks, stage)
+}
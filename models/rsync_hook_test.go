@@ -0,0 +1,27 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRsyncHooks(t *testing.T) {
+	text := `
+# 构建
+npm run build
+local:  make dist
+Remote: systemctl restart app
+remote:
+`
+	want := []RsyncHook{
+		{Stage: RsyncHookPre, Command: "npm run build"},
+		{Stage: RsyncHookPre, Command: "make dist"},
+		{Stage: RsyncHookPre, Remote: true, Command: "systemctl restart app"},
+	}
+	if got := ParseRsyncHooks(text, RsyncHookPre); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRsyncHooks = %+v, 期望 %+v", got, want)
+	}
+	if got := want[2].String(); got != "[pre/remote] systemctl restart app" {
+		t.Errorf("String = %q", got)
+	}
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ItemizedChangeKind 预览中一项变更的类型
+type ItemizedChangeKind string
+
+const (
+	ItemizedChangeNew       ItemizedChangeKind = "new"       // 目标中不存在，将新建
+	ItemizedChangeUpdated   ItemizedChangeKind = "updated"   // 内容有变化，将重新传输
+	ItemizedChangeDeleted   ItemizedChangeKind = "deleted"   // 目标中多余，将删除（--delete）
+	ItemizedChangeAttribute ItemizedChangeKind = "attribute" // 只有时间、权限等属性变化
+)
+
+// ItemizedChange rsync --itemize-changes 输出中的一项
+type ItemizedChange struct {
+	Kind     ItemizedChangeKind `json:"kind"`
+	Path     string             `json:"path"`
+	FileType string             `json:"file_type"`        // f 文件, d 目录, L 符号链接, D 设备, S 特殊文件
+	Flags    string             `json:"flags"`            // 原始的变更标记，如 >f.st......
+	Size     int64              `json:"size"`             // 文件大小（字节）
+	Target   string             `json:"target,omitempty"` // 符号链接指向或硬链接的已有文件
+}
+
+// ItemizedSummary 预览结果统计
+type ItemizedSummary struct {
+	New       int   `json:"new"`
+	Updated   int   `json:"updated"`
+	Deleted   int   `json:"deleted"`
+	Attribute int   `json:"attribute"`
+	Bytes     int64 `json:"bytes"` // 需要传输的文件总大小
+}
+
+// Total 变更总数
+func (s ItemizedSummary) Total() int {
+	return s.New + s.Updated + s.Deleted + s.Attribute
+}
+
+// ItemizeOutFormat 预览时使用的输出格式：变更标记、文件大小、路径（符号链接带指向）
+// 与 --itemize-changes 相同，只是多了文件大小，用于统计需要传输的字节数
+const ItemizeOutFormat = "--out-format=%i %l %n%L"
+
+// itemizeLinePattern 匹配 ItemizeOutFormat 的一行，大小可能带千位分隔符或 K/M/G 单位（-h）
+var itemizeLinePattern = regexp.MustCompile(`^([<>ch.*][a-zA-Z.+?]+)\s+([\d,.]+[KMGTP]?)\s(.+)$`)
+
+// ParseItemizedChanges 解析 rsync 按 ItemizeOutFormat 输出的内容，忽略无法识别的行和没有变化的项
+func ParseItemizedChanges(output string) []ItemizedChange {
+	var changes []ItemizedChange
+	for _, line := range strings.Split(output, "\n") {
+		if change, ok := ParseItemizedLine(line); ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// ParseItemizedLine 解析单行输出
+func ParseItemizedLine(line string) (ItemizedChange, bool) {
+	match := itemizeLinePattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if match == nil {
+		return ItemizedChange{}, false
+	}
+	flags, path := match[1], match[3]
+
+	change := ItemizedChange{
+		Path:  path,
+		Flags: flags,
+		Size:  parseRsyncSize(match[2]),
+	}
+
+	if strings.HasPrefix(flags, "*deleting") {
+		change.Kind = ItemizedChangeDeleted
+		change.Size = 0
+		return change, true
+	}
+	if len(flags) < 3 {
+		return ItemizedChange{}, false
+	}
+
+	change.FileType = string(flags[1])
+	// %L 对符号链接输出 " -> 指向"，对硬链接输出 " => 已有文件"
+	arrow := ""
+	if change.FileType == "L" {
+		arrow = " -> "
+	} else if flags[0] == 'h' {
+		arrow = " => "
+	}
+	if i := strings.Index(path, arrow); arrow != "" && i >= 0 {
+		change.Path, change.Target = path[:i], path[i+len(arrow):]
+	}
+
+	attributes := flags[2:]
+	switch {
+	case strings.Trim(attributes, "+") == "":
+		change.Kind = ItemizedChangeNew
+	case flags[0] == '<' || flags[0] == '>':
+		change.Kind = ItemizedChangeUpdated
+	case strings.Trim(attributes, ". ") == "":
+		// 所有标记都是 .，表示没有任何变化（-ii 时才会输出）
+		return ItemizedChange{}, false
+	default:
+		change.Kind = ItemizedChangeAttribute
+	}
+	return change, true
+}
+
+// SummarizeItemizedChanges 统计各类变更数量和需要传输的字节数
+func SummarizeItemizedChanges(changes []ItemizedChange) ItemizedSummary {
+	var summary ItemizedSummary
+	for _, change := range changes {
+		switch change.Kind {
+		case ItemizedChangeNew:
+			summary.New++
+		case ItemizedChangeUpdated:
+			summary.Updated++
+		case ItemizedChangeDeleted:
+			summary.Deleted++
+		case ItemizedChangeAttribute:
+			summary.Attribute++
+		}
+		if (change.Kind == ItemizedChangeNew || change.Kind == ItemizedChangeUpdated) && change.FileType == "f" {
+			summary.Bytes += change.Size
+		}
+	}
+	return summary
+}
+
+// parseRsyncSize 解析 rsync 输出的大小，支持 1,234 这样的千位分隔和 1.23K 这样的单位
+func parseRsyncSize(s string) int64 {
+	if unit := strings.IndexAny(s, "KMGTP"); unit >= 0 {
+		multiplier := 1.0
+		for _, u := range "KMGTP" {
+			multiplier *= 1024
+			if byte(u) == s[unit] {
+				break
+			}
+		}
+		value, err := strconv.ParseFloat(strings.ReplaceAll(s[:unit], ",", ""), 64)
+		if err != nil {
+			return 0
+		}
+		return int64(value * multiplier)
+	}
+
+	digits := strings.NewReplacer(",", "", ".", "").Replace(s)
+	value, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// FormatBytes 把字节数格式化为易读的形式
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
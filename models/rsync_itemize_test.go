@@ -0,0 +1,46 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseItemizedChanges(t *testing.T) {
+	output := `sending incremental file list
+*deleting   0 old/report.pdf
+cd+++++++++ 4,096 new dir/
+>f+++++++++ 1,234 new dir/a b.txt
+<f.st...... 2,048 changed.go
+.d..t...... 4,096 ./
+.f...p..... 10 mode-only.sh
+cL+++++++++ 7 latest -> v1.2.3
+hf+++++++++ 10 hard => mode-only.sh
+.f          10 unchanged.txt
+>f.s....... 1.50K human.bin
+
+sent 123 bytes  received 45 bytes  336.00 bytes/sec
+total size is 9,999  speedup is 59.52 (DRY RUN)
+`
+	want := []ItemizedChange{
+		{Kind: ItemizedChangeDeleted, Path: "old/report.pdf", Flags: "*deleting"},
+		{Kind: ItemizedChangeNew, Path: "new dir/", FileType: "d", Flags: "cd+++++++++", Size: 4096},
+		{Kind: ItemizedChangeNew, Path: "new dir/a b.txt", FileType: "f", Flags: ">f+++++++++", Size: 1234},
+		{Kind: ItemizedChangeUpdated, Path: "changed.go", FileType: "f", Flags: "<f.st......", Size: 2048},
+		{Kind: ItemizedChangeAttribute, Path: "./", FileType: "d", Flags: ".d..t......", Size: 4096},
+		{Kind: ItemizedChangeAttribute, Path: "mode-only.sh", FileType: "f", Flags: ".f...p.....", Size: 10},
+		{Kind: ItemizedChangeNew, Path: "latest", FileType: "L", Flags: "cL+++++++++", Size: 7, Target: "v1.2.3"},
+		{Kind: ItemizedChangeNew, Path: "hard", FileType: "f", Flags: "hf+++++++++", Size: 10, Target: "mode-only.sh"},
+		{Kind: ItemizedChangeUpdated, Path: "human.bin", FileType: "f", Flags: ">f.s.......", Size: 1536},
+	}
+
+	got := ParseItemizedChanges(output)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseItemizedChanges\n得到 %+v\n期望 %+v", got, want)
+	}
+
+	summary := SummarizeItemizedChanges(got)
+	wantSummary := ItemizedSummary{New: 4, Updated: 2, Deleted: 1, Attribute: 2, Bytes: 1234 + 2048 + 10 + 1536}
+	if summary != wantSummary {
+		t.Errorf("SummarizeItemizedChanges = %+v, 期望 %+v", summary, wantSummary)
+	}
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ResumeMode 传输中断后继续传输的方式
+type ResumeMode string
+
+const (
+	ResumeNone         ResumeMode = ""              // 不保留部分传输的文件，下次重新传输
+	ResumePartial      ResumeMode = "partial"       // --partial 保留部分传输的文件，下次以它为基础增量传输
+	ResumeAppendVerify ResumeMode = "append-verify" // --partial --append-verify 从中断处追加，传输后校验整个文件
+)
+
+// ParseResumeMode 解析续传方式，为空表示不续传
+func ParseResumeMode(mode string) (ResumeMode, error) {
+	switch m := ResumeMode(mode); m {
+	case ResumeNone, ResumePartial, ResumeAppendVerify:
+		return m, nil
+	}
+	return "", fmt.Errorf("无效的续传方式 '%s'，可选: partial, append-verify", mode)
+}
+
+func (m ResumeMode) String() string {
+	switch m {
+	case ResumePartial:
+		return "保留部分传输的文件 (--partial)"
+	case ResumeAppendVerify:
+		return "从中断处追加并校验 (--append-verify)"
+	}
+	return "不续传"
+}
+
+// MaxCompressLevel rsync 使用 zlib 压缩时的最高压缩级别
+const MaxCompressLevel = 9
+
+// ValidateLimits 检查带宽、超时、续传、文件大小、压缩级别和备份设置
+func (r *RsyncConfig) ValidateLimits() error {
+	if r.BandwidthLimit < 0 || r.Timeout < 0 || r.MaxSize < 0 {
+		return fmt.Errorf("带宽限制、超时和最大文件大小不能为负数")
+	}
+	if _, err := ParseResumeMode(string(r.ResumeMode)); err != nil {
+		return err
+	}
+	if r.CompressLevel < 0 || r.CompressLevel > MaxCompressLevel {
+		return fmt.Errorf("压缩级别应为 1-%d，0 表示使用默认值", MaxCompressLevel)
+	}
+	if r.CompressLevel > 0 && !r.Compress {
+		return fmt.Errorf("设置压缩级别需要启用压缩 (-z)")
+	}
+	if r.BackupDir != "" && !r.Backup {
+		return fmt.Errorf("备份目录需要启用备份 (--backup)")
+	}
+	if r.Backup && r.SnapshotMode {
+		return fmt.Errorf("快照备份已保留每次执行的版本，不能同时使用 --backup")
+	}
+	if r.Direction == RsyncDirectionBidirectional && backupDirTop(r.BackupDir) != "" {
+		return fmt.Errorf("双向同步的备份目录不能在同步的路径中，否则备份会被当作新文件同步到另一端")
+	}
+	if strings.HasPrefix(r.BackupDir, "-") {
+		return fmt.Errorf("备份目录不能以 - 开头")
+	}
+	return nil
+}
+
+// limitArgs 生成带宽、超时、续传、文件大小、压缩级别和备份的rsync选项
+func (r *RsyncConfig) limitArgs() []string {
+	var args []string
+	if r.BandwidthLimit > 0 {
+		args = append(args, fmt.Sprintf("--bwlimit=%d", r.BandwidthLimit))
+	}
+	if r.Timeout > 0 {
+		args = append(args, fmt.Sprintf("--timeout=%d", r.Timeout))
+	}
+	switch r.ResumeMode {
+	case ResumePartial:
+		args = append(args, "--partial")
+	case ResumeAppendVerify:
+		args = append(args, "--partial", "--append-verify")
+	}
+	if r.MaxSize > 0 {
+		args = append(args, fmt.Sprintf("--max-size=%d", r.MaxSize))
+	}
+	if r.Compress && r.CompressLevel > 0 {
+		args = append(args, fmt.Sprintf("--compress-level=%d", r.CompressLevel))
+	}
+	if r.Backup {
+		args = append(args, "--backup")
+		if r.BackupDir != "" {
+			args = append(args, "--backup-dir="+r.BackupDir)
+			// 备份目录在目标路径中时，防止 --delete 删除以前的备份
+			if top := backupDirTop(r.BackupDir); top != "" && r.Delete {
+				args = append(args, "--filter=P /"+top+"/")
+			}
+		}
+	}
+	return args
+}
+
+// backupDirTop 相对于目标路径的备份目录的第一级目录，备份目录不在目标路径中时返回空
+func backupDirTop(dir string) string {
+	if strings.HasPrefix(dir, "/") || strings.HasPrefix(dir, "~") {
+		return ""
+	}
+	top, _, _ := strings.Cut(path.Clean(dir), "/")
+	if top == "." || top == ".." {
+		return ""
+	}
+	return top
+}
+
+// LimitsText 已设置的传输限制的简短说明，没有设置时返回空
+func (r *RsyncConfig) LimitsText() string {
+	var parts []string
+	if r.BandwidthLimit > 0 {
+		parts = append(parts, "限速 "+FormatBandwidth(r.BandwidthLimit)+"/s")
+	}
+	if r.Timeout > 0 {
+		parts = append(parts, fmt.Sprintf("超时 %d 秒", r.Timeout))
+	}
+	if r.ResumeMode != ResumeNone {
+		parts = append(parts, "续传 "+string(r.ResumeMode))
+	}
+	if r.MaxSize > 0 {
+		parts = append(parts, "最大 "+FormatByteSize(r.MaxSize))
+	}
+	if r.Compress && r.CompressLevel > 0 {
+		parts = append(parts, fmt.Sprintf("压缩级别 %d", r.CompressLevel))
+	}
+	if r.Backup {
+		backup := "备份"
+		if r.BackupDir != "" {
+			backup += "到 " + r.BackupDir
+		}
+		parts = append(parts, backup)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseUnitSize 解析带 K、M、G、T 单位（1024 进制）的数值，可以带小数和 B、iB、/s 后缀，
+// 没有单位时乘以 unit，单位为 B 时为字节，返回值为最小单位的整数
+func parseUnitSize(text string, unit float64) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(text))
+	if s == "" {
+		return 0, nil
+	}
+	s = strings.TrimSuffix(s, "/S")
+	multiplier := unit
+	if trimmed := strings.TrimSuffix(s, "IB"); trimmed != s {
+		s = trimmed
+	} else if strings.HasSuffix(s, "B") {
+		// 只有 B 时单位为字节
+		s = strings.TrimSuffix(s, "B")
+		multiplier = 1
+	}
+	if i := strings.IndexAny(s, "KMGT"); i >= 0 && i == len(s)-1 {
+		multiplier = math.Pow(1024, float64(strings.IndexByte("KMGT", s[i])+1))
+		s = s[:i]
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("'%s' 不是有效的大小，如: 500K、1.5M、2G", text)
+	}
+	return int64(math.Round(value * multiplier)), nil
+}
+
+// ParseBandwidth 解析带宽限制，返回 KiB/s；没有单位时按 rsync 的规则为 KiB/s，如 500、500K、1.5M
+func ParseBandwidth(text string) (int, error) {
+	bytes, err := parseUnitSize(text, 1024)
+	if err != nil {
+		return 0, err
+	}
+	kib := (bytes + 1023) / 1024
+	if kib > math.MaxInt32 {
+		return 0, fmt.Errorf("带宽限制 '%s' 太大", text)
+	}
+	return int(kib), nil
+}
+
+// ParseByteSize 解析文件大小，返回字节数；没有单位时为字节，如 100M、1.5G
+func ParseByteSize(text string) (int64, error) {
+	return parseUnitSize(text, 1)
+}
+
+// FormatBandwidth 把 KiB/s 格式化为 ParseBandwidth 可以解析的形式，0 返回空
+func FormatBandwidth(kib int) string {
+	if kib == 0 {
+		return ""
+	}
+	return FormatByteSize(int64(kib) * 1024)
+}
+
+// FormatByteSize 把字节数格式化为 ParseByteSize 可以解析的形式，只使用能整除的单位，0 返回空
+func FormatByteSize(size int64) string {
+	if size == 0 {
+		return ""
+	}
+	for i := len("KMGT"); i > 0; i-- {
+		unit := int64(1) << (10 * i)
+		if size%unit == 0 {
+			return fmt.Sprintf("%d%c", size/unit, "KMGT"[i-1])
+		}
+	}
+	return strconv.FormatInt(size, 10)
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLimitSizes(t *testing.T) {
+	bandwidths := map[string]int{"": 0, "500": 500, "500K": 500, "1.5M": 1536, "2mb/s": 2048, "1G": 1048576, "100B": 1}
+	for text, want := range bandwidths {
+		if got, err := ParseBandwidth(text); err != nil || got != want {
+			t.Errorf("ParseBandwidth(%q) = %d, %v, 期望 %d", text, got, err, want)
+		}
+	}
+	sizes := map[string]int64{"": 0, "100": 100, "100M": 100 << 20, "1.5G": 3 << 29, "4KiB": 4096}
+	for text, want := range sizes {
+		if got, err := ParseByteSize(text); err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v, 期望 %d", text, got, err, want)
+		}
+	}
+	for _, text := range []string{"abc", "-1M", "1X", "M"} {
+		if _, err := ParseByteSize(text); err == nil {
+			t.Errorf("ParseByteSize(%q) 应该返回错误", text)
+		}
+	}
+
+	for _, size := range []int64{100, 4096, 3 << 29, 100 << 20} {
+		if got, _ := ParseByteSize(FormatByteSize(size)); got != size {
+			t.Errorf("FormatByteSize(%d) = %s 解析后不一致", size, FormatByteSize(size))
+		}
+	}
+	if got := FormatBandwidth(1536); got != "1536K" {
+		t.Errorf("FormatBandwidth(1536) = %s", got)
+	}
+}
+
+func TestLimitArgs(t *testing.T) {
+	config := RsyncConfig{
+		Archive:        true,
+		Compress:       true,
+		Delete:         true,
+		BandwidthLimit: 2048,
+		Timeout:        300,
+		ResumeMode:     ResumeAppendVerify,
+		MaxSize:        100 << 20,
+		CompressLevel:  9,
+		Backup:         true,
+		BackupDir:      ".rsync-backup/2024-03-01",
+		Options:        "--bwlimit=100",
+	}
+	got := config.optionArgs(nil)
+	want := []string{"-a", "-z", "--delete", "--bwlimit=2048", "--timeout=300", "--partial", "--append-verify",
+		"--max-size=104857600", "--compress-level=9", "--backup", "--backup-dir=.rsync-backup/2024-03-01",
+		"--filter=P /.rsync-backup/", "--bwlimit=100"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("optionArgs:\n得到 %q\n期望 %q", got, want)
+	}
+
+	// 备份目录不在目标路径中时不需要保护
+	config.BackupDir = "../backup"
+	config.Options = ""
+	if got := config.optionArgs(nil); got[len(got)-1] != "--backup-dir=../backup" {
+		t.Errorf("optionArgs = %q", got)
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	invalid := []RsyncConfig{
+		{Timeout: -1},
+		{ResumeMode: "resume"},
+		{CompressLevel: 5},
+		{Compress: true, CompressLevel: 10},
+		{BackupDir: "old"},
+		{Direction: RsyncDirectionDownload, SnapshotMode: true, Backup: true},
+		{Direction: RsyncDirectionBidirectional, Backup: true, BackupDir: ".backup"},
+	}
+	for _, config := range invalid {
+		if err := config.ValidateLimits(); err == nil {
+			t.Errorf("%+v 应该返回错误", config)
+		}
+	}
+
+	bisync := RsyncConfig{Direction: RsyncDirectionBidirectional, Backup: true, BackupDir: "/var/backups/app"}
+	if err := bisync.ValidateLimits(); err != nil {
+		t.Errorf("绝对路径的备份目录: %v", err)
+	}
+}
+
+func TestRsyncPresets(t *testing.T) {
+	for _, preset := range RsyncPresets {
+		config := RsyncConfig{Direction: RsyncDirectionUpload, BandwidthLimit: 512}
+		preset.Apply(&config)
+		if err := config.ValidateLimits(); err != nil {
+			t.Errorf("预设 %s: %v", preset.Name, err)
+		}
+		if err := config.ValidateTemplates(); err != nil {
+			t.Errorf("预设 %s: %v", preset.Name, err)
+		}
+		if found, err := GetRsyncPreset(preset.Label); err != nil || found.Name != preset.Name {
+			t.Errorf("GetRsyncPreset(%s) = %v, %v", preset.Label, found.Name, err)
+		}
+	}
+
+	config := RsyncConfig{BandwidthLimit: 512}
+	preset, _ := GetRsyncPreset("slow-link")
+	preset.Apply(&config)
+	if config.BandwidthLimit != 512 || !config.Compress || config.ResumeMode != ResumeAppendVerify {
+		t.Errorf("预设只应修改涉及的选项: %+v", config)
+	}
+	if _, err := GetRsyncPreset("fast"); err == nil {
+		t.Error("不存在的预设应该返回错误")
+	}
+}
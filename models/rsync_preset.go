@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RsyncPreset 常见场景的一组选项，应用到配置时只修改预设涉及的选项
+type RsyncPreset struct {
+	Name        string
+	Label       string
+	Description string
+	apply       func(config *RsyncConfig)
+}
+
+// Apply 把预设的选项写入配置
+func (p RsyncPreset) Apply(config *RsyncConfig) {
+	p.apply(config)
+}
+
+// RsyncPresets 内置的预设
+var RsyncPresets = []RsyncPreset{
+	{
+		Name:        "slow-link",
+		Label:       "慢速网络",
+		Description: "经 VPN 等慢速网络传输大文件：最高压缩级别，中断后从中断处继续，5 分钟没有数据时超时",
+		apply: func(config *RsyncConfig) {
+			config.Compress = true
+			config.CompressLevel = MaxCompressLevel
+			config.ResumeMode = ResumeAppendVerify
+			config.Timeout = 300
+		},
+	},
+	{
+		Name:        "background",
+		Label:       "后台传输",
+		Description: "定时或长时间的传输：限速 1M/s 以免占满带宽，保留部分传输的文件，10 分钟没有数据时超时",
+		apply: func(config *RsyncConfig) {
+			config.BandwidthLimit = 1024
+			config.ResumeMode = ResumePartial
+			config.Timeout = 600
+		},
+	},
+	{
+		Name:        "mirror",
+		Label:       "镜像",
+		Description: "目标与源保持一致：删除多余的文件，被覆盖或删除的文件备份到目标路径下的 .rsync-backup/日期",
+		apply: func(config *RsyncConfig) {
+			config.Archive = true
+			config.Delete = true
+			config.Backup = true
+			config.BackupDir = ".rsync-backup/{{date}}"
+		},
+	},
+	{
+		Name:        "deploy",
+		Label:       "部署",
+		Description: "发布代码：按内容比较，删除旧文件，不保留部分传输的文件，1 分钟没有响应时失败",
+		apply: func(config *RsyncConfig) {
+			config.Archive = true
+			config.Delete = true
+			config.Checksum = true
+			config.ResumeMode = ResumeNone
+			config.Timeout = 60
+		},
+	},
+}
+
+// GetRsyncPreset 按名称或显示名称查找预设
+func GetRsyncPreset(name string) (RsyncPreset, error) {
+	for _, preset := range RsyncPresets {
+		if preset.Name == name || preset.Label == name {
+			return preset, nil
+		}
+	}
+	names := make([]string, 0, len(RsyncPresets))
+	for _, preset := range RsyncPresets {
+		names = append(names, preset.Name)
+	}
+	return RsyncPreset{}, fmt.Errorf("预设 '%s' 不存在，可选: %s", name, strings.Join(names, ", "))
+}
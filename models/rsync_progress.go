@@ -0,0 +1,209 @@
+package models
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RsyncEventType rsync 执行过程中的事件类型
+type RsyncEventType string
+
+const (
+	RsyncEventStart       RsyncEventType = "start"        // 开始执行
+	RsyncEventFile        RsyncEventType = "file"         // 开始处理一个文件
+	RsyncEventProgress    RsyncEventType = "progress"     // 传输进度
+	RsyncEventHook        RsyncEventType = "hook"         // 一个执行前或执行后的钩子结束
+	RsyncEventConflict    RsyncEventType = "conflict"     // 双向同步中两端都有变化的文件
+	RsyncEventDeleteGuard RsyncEventType = "delete_guard" // 删除保护的检查结果
+	RsyncEventResult      RsyncEventType = "result"       // 执行结束
+)
+
+// RsyncProgress 一次进度更新
+type RsyncProgress struct {
+	Bytes       int64   `json:"bytes"`       // 已传输字节数
+	Percent     int     `json:"percent"`     // 完成百分比
+	Rate        float64 `json:"rate"`        // 传输速率（字节/秒）
+	ETASeconds  int64   `json:"eta_seconds"` // 预计剩余时间（秒）
+	Transferred int     `json:"transferred"` // 已传输文件数
+	ToCheck     int     `json:"to_check"`    // 待检查文件数
+	TotalFiles  int     `json:"total_files"` // 文件总数（rsync 仍在扫描时会增长）
+}
+
+// RsyncResult 一次执行的最终结果
+type RsyncResult struct {
+	RunID            uint           `json:"run_id,omitempty"` // 对应的执行记录
+	FilesTransferred int            `json:"files_transferred"`
+	TotalSize        int64          `json:"total_size"`       // 源文件总大小
+	TransferredSize  int64          `json:"transferred_size"` // 传输的文件总大小
+	BytesSent        int64          `json:"bytes_sent"`
+	BytesReceived    int64          `json:"bytes_received"`
+	Speedup          float64        `json:"speedup"`
+	Duration         time.Duration  `json:"-"`
+	DurationSeconds  float64        `json:"duration_seconds"`
+	Success          bool           `json:"success"`
+	Error            string         `json:"error,omitempty"`
+	Conflicts        int            `json:"conflicts,omitempty"` // 双向同步中没有解决的冲突数
+	Verify           *VerifySummary `json:"verify,omitempty"`    // 执行后校验的结果
+}
+
+// Add 累加另一次rsync的统计，用于一次执行包含多条rsync命令的情况
+func (r *RsyncResult) Add(other RsyncResult) {
+	r.FilesTransferred += other.FilesTransferred
+	r.TotalSize += other.TotalSize
+	r.TransferredSize += other.TransferredSize
+	r.BytesSent += other.BytesSent
+	r.BytesReceived += other.BytesReceived
+	if r.BytesSent+r.BytesReceived > 0 {
+		r.Speedup = float64(r.TotalSize) / float64(r.BytesSent+r.BytesReceived)
+	}
+}
+
+// RsyncEvent rsync 执行过程中的一个事件，Type 决定哪个字段有值
+type RsyncEvent struct {
+	Type        RsyncEventType       `json:"type"`
+	Command     []string             `json:"command,omitempty"`
+	File        *ItemizedChange      `json:"file,omitempty"`
+	Progress    *RsyncProgress       `json:"progress,omitempty"`
+	Result      *RsyncResult         `json:"result,omitempty"`
+	Hook        *RsyncHookResult     `json:"hook,omitempty"`
+	Conflict    *SyncConflict        `json:"conflict,omitempty"`
+	Engine      *TransferEngineInfo  `json:"engine,omitempty"` // 开始事件中本次使用的传输引擎
+	DeleteGuard *DeleteGuardDecision `json:"delete_guard,omitempty"`
+}
+
+// progressLinePattern 匹配进度行，如 "  1,234,567  45%  1.23MB/s  0:00:12 (xfr#3, to-chk=10/20)"
+// 旧版本 rsync 使用 xfer# 和 to-check=
+var progressLinePattern = regexp.MustCompile(`^\s*([\d,.]+[KMGTP]?)\s+(\d+)%\s+([\d,.]+)([kKMGT]?B)/s\s+(\d+:\d{2}(?::\d{2})?)(?:\s+\(xf(?:e)?r#(\d+),\s*(?:ir-chk|to-chk|to-check)=(\d+)/(\d+)\))?`)
+
+// statsPatterns --stats 输出中需要的字段
+var statsPatterns = []struct {
+	pattern *regexp.Regexp
+	apply   func(result *RsyncResult, value string)
+}{
+	{regexp.MustCompile(`^Number of (?:regular )?files transferred: ([\d,.]+)`), func(r *RsyncResult, v string) { r.FilesTransferred = int(parseRsyncSize(v)) }},
+	{regexp.MustCompile(`^Total file size: ([\d,.]+[KMGTP]?) bytes`), func(r *RsyncResult, v string) { r.TotalSize = parseRsyncSize(v) }},
+	{regexp.MustCompile(`^Total transferred file size: ([\d,.]+[KMGTP]?) bytes`), func(r *RsyncResult, v string) { r.TransferredSize = parseRsyncSize(v) }},
+	{regexp.MustCompile(`^Total bytes sent: ([\d,.]+[KMGTP]?)`), func(r *RsyncResult, v string) { r.BytesSent = parseRsyncSize(v) }},
+	{regexp.MustCompile(`^Total bytes received: ([\d,.]+[KMGTP]?)`), func(r *RsyncResult, v string) { r.BytesReceived = parseRsyncSize(v) }},
+	{regexp.MustCompile(`speedup is ([\d,.]+)`), func(r *RsyncResult, v string) {
+		r.Speedup, _ = strconv.ParseFloat(strings.ReplaceAll(v, ",", ""), 64)
+	}},
+}
+
+// RsyncOutputParser 逐行解析 rsync 的输出（ItemizeOutFormat、进度和 --stats），生成事件并累计最终结果
+type RsyncOutputParser struct {
+	result       RsyncResult
+	sawStats     bool
+	fileCount    int
+	transferSize int64
+}
+
+// ParseLine 解析一行输出，返回对应的事件；统计信息和无法识别的行返回 nil
+func (p *RsyncOutputParser) ParseLine(line string) *RsyncEvent {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	if progress, ok := ParseProgressLine(line); ok {
+		return &RsyncEvent{Type: RsyncEventProgress, Progress: &progress}
+	}
+
+	for _, stat := range statsPatterns {
+		if match := stat.pattern.FindStringSubmatch(line); match != nil {
+			stat.apply(&p.result, match[1])
+			p.sawStats = true
+			return nil
+		}
+	}
+
+	if change, ok := ParseItemizedLine(line); ok {
+		if (change.Kind == ItemizedChangeNew || change.Kind == ItemizedChangeUpdated) && change.FileType == "f" {
+			p.fileCount++
+			p.transferSize += change.Size
+		}
+		return &RsyncEvent{Type: RsyncEventFile, File: &change}
+	}
+	return nil
+}
+
+// Result 返回累计的执行结果，没有 --stats 输出时用逐项输出统计
+func (p *RsyncOutputParser) Result() RsyncResult {
+	result := p.result
+	if !p.sawStats {
+		result.FilesTransferred = p.fileCount
+		result.TransferredSize = p.transferSize
+	}
+	return result
+}
+
+// ParseProgressLine 解析进度行
+func ParseProgressLine(line string) (RsyncProgress, bool) {
+	match := progressLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return RsyncProgress{}, false
+	}
+
+	progress := RsyncProgress{
+		Bytes:      parseRsyncSize(match[1]),
+		ETASeconds: parseRsyncDuration(match[5]),
+	}
+	progress.Percent, _ = strconv.Atoi(match[2])
+	progress.Rate = parseRsyncRate(match[3], match[4])
+	if match[6] != "" {
+		progress.Transferred, _ = strconv.Atoi(match[6])
+		toCheck, _ := strconv.Atoi(match[7])
+		total, _ := strconv.Atoi(match[8])
+		progress.ToCheck, progress.TotalFiles = toCheck, total
+	}
+	return progress, true
+}
+
+// parseRsyncRate 解析速率，rsync 的 kB/MB/GB 以 1024 为进制
+func parseRsyncRate(value, unit string) float64 {
+	rate, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToUpper(unit) {
+	case "KB":
+		rate *= 1 << 10
+	case "MB":
+		rate *= 1 << 20
+	case "GB":
+		rate *= 1 << 30
+	case "TB":
+		rate *= 1 << 40
+	}
+	return rate
+}
+
+// parseRsyncDuration 解析 h:mm:ss 或 m:ss 格式的时间，返回秒数
+func parseRsyncDuration(s string) int64 {
+	var seconds int64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
+
+// ScanRsyncLines 用于 bufio.Scanner 的分割函数，进度行以 \r 结尾，普通输出以 \n 结尾
+func ScanRsyncLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRsyncOutputParser(t *testing.T) {
+	output := ">f+++++++++ 2,048 a.txt\n" +
+		"          1,024  50%  512.00kB/s    0:00:01\r" +
+		"          2,048 100%    1.00MB/s    0:00:00 (xfr#1, to-chk=1/3)\n" +
+		"<f.st...... 4,096 dir/b.bin\n" +
+		"      6,144 100%    2.50MB/s    1:02:03 (xfer#2, to-check=0/3)\n" +
+		"\n" +
+		"Number of files: 3 (reg: 2, dir: 1)\n" +
+		"Number of regular files transferred: 2\n" +
+		"Total file size: 1,234,567 bytes\n" +
+		"Total transferred file size: 6,144 bytes\n" +
+		"Total bytes sent: 6,500\n" +
+		"Total bytes received: 80\n" +
+		"\n" +
+		"sent 6,500 bytes  received 80 bytes  13,160.00 bytes/sec\n" +
+		"total size is 1,234,567  speedup is 187.62\n"
+
+	var parser RsyncOutputParser
+	var events []RsyncEvent
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Split(ScanRsyncLines)
+	for scanner.Scan() {
+		if event := parser.ParseLine(scanner.Text()); event != nil {
+			events = append(events, *event)
+		}
+	}
+
+	var types []RsyncEventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	wantTypes := []RsyncEventType{RsyncEventFile, RsyncEventProgress, RsyncEventProgress, RsyncEventFile, RsyncEventProgress}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Fatalf("事件类型 = %v, 期望 %v", types, wantTypes)
+	}
+
+	wantProgress := RsyncProgress{Bytes: 2048, Percent: 100, Rate: 1 << 20, Transferred: 1, ToCheck: 1, TotalFiles: 3}
+	if *events[2].Progress != wantProgress {
+		t.Errorf("进度 = %+v, 期望 %+v", *events[2].Progress, wantProgress)
+	}
+	if got := events[1].Progress; got.Rate != 512*1024 || got.ETASeconds != 1 || got.TotalFiles != 0 {
+		t.Errorf("不带文件计数的进度 = %+v", *got)
+	}
+	if got := events[4].Progress; got.ETASeconds != 3723 || got.Transferred != 2 {
+		t.Errorf("旧版本格式的进度 = %+v", *got)
+	}
+
+	wantResult := RsyncResult{
+		FilesTransferred: 2,
+		TotalSize:        1234567,
+		TransferredSize:  6144,
+		BytesSent:        6500,
+		BytesReceived:    80,
+		Speedup:          187.62,
+	}
+	if result := parser.Result(); result != wantResult {
+		t.Errorf("结果 = %+v, 期望 %+v", result, wantResult)
+	}
+}
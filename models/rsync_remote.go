@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RemoteTransferMode 服务器之间传输时rsync连接目标服务器的方式
+type RemoteTransferMode string
+
+const (
+	RemoteTransferDirect RemoteTransferMode = "direct" // 源服务器直接连接目标服务器
+	RemoteTransferRelay  RemoteTransferMode = "relay"  // 经本机的 ssh 反向隧道连接目标服务器，数据流经本机
+)
+
+// 中转时源服务器上反向隧道监听的地址和端口范围，端口在执行时随机选择
+const (
+	RemoteRelayHost    = "127.0.0.1"
+	RemoteRelayPortMin = 20000
+	RemoteRelayPortMax = 60000
+)
+
+// ParseRemoteTransferMode 解析服务器之间的传输方式，为空时源服务器直接连接目标服务器
+func ParseRemoteTransferMode(mode string) (RemoteTransferMode, error) {
+	switch m := RemoteTransferMode(mode); m {
+	case "":
+		return RemoteTransferDirect, nil
+	case RemoteTransferDirect, RemoteTransferRelay:
+		return m, nil
+	}
+	return "", fmt.Errorf("无效的传输方式 '%s'，可选: direct, relay", mode)
+}
+
+// ValidateRemoteTransfer 检查服务器之间传输的设置
+// rsync 在源服务器上执行，因此不能使用需要读取本地文件的忽略文件和合并规则
+func (r *RsyncConfig) ValidateRemoteTransfer() error {
+	if r.Direction != RsyncDirectionRemote {
+		if r.TargetSSHName != "" || r.TargetPath != "" || r.RemoteTransferMode != "" {
+			return fmt.Errorf("目标SSH连接、目标路径和传输方式只能用于服务器之间传输的配置")
+		}
+		return nil
+	}
+
+	if strings.TrimSpace(r.TargetSSHName) == "" || strings.TrimSpace(r.TargetPath) == "" {
+		return fmt.Errorf("服务器之间传输需要目标SSH连接和目标路径")
+	}
+	if _, err := ParseRemoteTransferMode(string(r.RemoteTransferMode)); err != nil {
+		return err
+	}
+	if r.UseIgnoreFiles {
+		return fmt.Errorf("服务器之间传输没有本地路径，不能读取忽略文件")
+	}
+	rules, _ := r.GetFilterRules()
+	for _, rule := range rules {
+		if rule.Action == FilterMerge {
+			return fmt.Errorf("服务器之间传输时rsync在源服务器上执行，不能读取合并文件 '%s'", rule.Pattern)
+		}
+	}
+	return nil
+}
+
+// BuildRemoteTransferCommand 生成服务器之间传输的命令：以 ssh -A 登录源服务器执行rsync，
+// 转发本机的 ssh-agent 用于登录目标服务器。relayPort 不为 0 时在源服务器上监听该端口，
+// 经本机的反向隧道连接目标服务器，用于两台服务器之间无法直接连接的情况
+func (r *RsyncConfig) BuildRemoteTransferCommand(source, target *SSHConnection, relayPort int) []string {
+	inner := append([]string{"rsync"}, r.optionArgs(nil)...)
+	if needsProtectArgs(r.RemotePath) || needsProtectArgs(r.TargetPath) {
+		inner = append(inner, "--protect-args")
+	}
+
+	// 目标服务器的私钥在本机上，源服务器只能通过转发的 ssh-agent 登录，因此不带 -i
+	cmd := []string{"ssh", "-A"}
+	targetShell := []string{"ssh", "-p", fmt.Sprintf("%d", target.Port)}
+	targetHost := target.Address
+	if relayPort > 0 {
+		cmd = append(cmd, "-o", "ExitOnForwardFailure=yes", "-R", fmt.Sprintf("%s:%d:%s:%d", RemoteRelayHost, relayPort, target.Address, target.Port))
+		// 按目标服务器的地址校验主机密钥，而不是隧道的地址
+		targetShell = []string{"ssh", "-p", fmt.Sprintf("%d", relayPort), "-o", "HostKeyAlias=" + target.Address}
+		targetHost = RemoteRelayHost
+	}
+	for i, option := range targetShell {
+		targetShell[i] = RemoteShellQuote(option)
+	}
+	inner = append(inner, "-e", strings.Join(targetShell, " "))
+
+	// 源路径由源服务器的 shell 解释，保留开头的 ~/ 以便展开到用户目录
+	command := ShellJoin(inner) + " " + remoteShellPath(safeLocalPath(r.RemotePath)) + " " +
+		ShellQuote(target.Username+"@"+targetHost+":"+r.TargetPath)
+
+	cmd = append(cmd, source.SSHArgs()...)
+	return append(cmd, source.Destination(), command)
+}
+
+// InsertRsyncOptions 在rsync程序名之后插入选项，服务器之间传输时插入到在源服务器上执行的rsync命令中
+func InsertRsyncOptions(cmdArgs []string, options ...string) []string {
+	if cmdArgs[0] == "rsync" {
+		return append(append([]string{"rsync"}, options...), cmdArgs[1:]...)
+	}
+	inserted := append([]string{}, cmdArgs...)
+	last := len(inserted) - 1
+	inserted[last] = "rsync " + ShellJoin(options) + strings.TrimPrefix(inserted[last], "rsync")
+	return inserted
+}
+
+// remoteShellPath 为远程 shell 引用路径，开头的 ~ 或 ~/ 不加引号
+func remoteShellPath(path string) string {
+	if path == "~" {
+		return path
+	}
+	if strings.HasPrefix(path, "~/") {
+		return "~/" + ShellQuote(path[2:])
+	}
+	return ShellQuote(path)
+}
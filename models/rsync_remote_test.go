@@ -0,0 +1,72 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildRemoteTransferCommand(t *testing.T) {
+	source := &SSHConnection{Username: "deploy", Address: "src.example.com", Port: 22, PasswordType: PasswordTypeKeyPath, KeyPath: "/keys/src"}
+	target := &SSHConnection{Username: "backup", Address: "dst.example.com", Port: 2222, PasswordType: PasswordTypeKeyPath, KeyPath: "/keys/dst"}
+	config := RsyncConfig{
+		Direction:    RsyncDirectionRemote,
+		RemotePath:   "~/app data/",
+		TargetPath:   "/srv/app",
+		Archive:      true,
+		Delete:       true,
+		ExcludeRules: "*.log",
+	}
+
+	got := config.BuildRemoteTransferCommand(source, target, 0)
+	want := []string{"ssh", "-A", "-p", "22", "-i", "/keys/src", "deploy@src.example.com",
+		"rsync -a --delete --exclude '*.log' --protect-args -e 'ssh -p 2222' ~/'app data/' backup@dst.example.com:/srv/app"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("直接连接:\n得到 %q\n期望 %q", got, want)
+	}
+
+	config.RemoteTransferMode = RemoteTransferRelay
+	got = config.BuildRemoteTransferCommand(source, target, 20022)
+	want = []string{"ssh", "-A", "-o", "ExitOnForwardFailure=yes", "-R", "127.0.0.1:20022:dst.example.com:2222",
+		"-p", "22", "-i", "/keys/src", "deploy@src.example.com",
+		"rsync -a --delete --exclude '*.log' --protect-args -e 'ssh -p 20022 -o HostKeyAlias=dst.example.com' ~/'app data/' backup@127.0.0.1:/srv/app"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("经本机中转:\n得到 %q\n期望 %q", got, want)
+	}
+}
+
+func TestInsertRsyncOptions(t *testing.T) {
+	local := InsertRsyncOptions([]string{"rsync", "-a", "src", "dst"}, "--dry-run", ItemizeOutFormat)
+	if want := []string{"rsync", "--dry-run", ItemizeOutFormat, "-a", "src", "dst"}; !reflect.DeepEqual(local, want) {
+		t.Errorf("InsertRsyncOptions = %q, 期望 %q", local, want)
+	}
+
+	cmdArgs := []string{"ssh", "-A", "u@h", "rsync -a src u@t:/dst"}
+	remote := InsertRsyncOptions(cmdArgs, "--dry-run", ItemizeOutFormat)
+	if want := "rsync --dry-run '" + ItemizeOutFormat + "' -a src u@t:/dst"; remote[3] != want {
+		t.Errorf("InsertRsyncOptions = %q, 期望 %q", remote[3], want)
+	}
+	if cmdArgs[3] != "rsync -a src u@t:/dst" {
+		t.Error("InsertRsyncOptions 不应修改原命令")
+	}
+}
+
+func TestValidateRemoteTransfer(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RsyncConfig
+		wantErr bool
+	}{
+		{"上传配置", RsyncConfig{Direction: RsyncDirectionUpload}, false},
+		{"上传配置带目标", RsyncConfig{Direction: RsyncDirectionUpload, TargetSSHName: "b"}, true},
+		{"缺少目标路径", RsyncConfig{Direction: RsyncDirectionRemote, TargetSSHName: "b"}, true},
+		{"有效", RsyncConfig{Direction: RsyncDirectionRemote, TargetSSHName: "b", TargetPath: "/x", RemoteTransferMode: RemoteTransferRelay}, false},
+		{"无效传输方式", RsyncConfig{Direction: RsyncDirectionRemote, TargetSSHName: "b", TargetPath: "/x", RemoteTransferMode: "tar"}, true},
+		{"忽略文件", RsyncConfig{Direction: RsyncDirectionRemote, TargetSSHName: "b", TargetPath: "/x", UseIgnoreFiles: true}, true},
+		{"合并规则", RsyncConfig{Direction: RsyncDirectionRemote, TargetSSHName: "b", TargetPath: "/x", FilterRules: ". rules.txt"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.config.ValidateRemoteTransfer(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, 期望错误 %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
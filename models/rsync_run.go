@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+)
+
+// 执行的触发方式
+const (
+	RsyncTriggerManual   = "manual"   // 手动执行
+	RsyncTriggerSchedule = "schedule" // 定时执行
+	RsyncTriggerRetry    = "retry"    // 定时执行失败后重试
+	RsyncTriggerWatch    = "watch"    // 监听到文件变化后执行
+	RsyncTriggerRestore  = "restore"  // 把快照恢复到服务器
+	RsyncTriggerGroup    = "group"    // 作为分组的一部分执行
+)
+
+// 没有 rsync 退出码时记录的退出码
+const (
+	RsyncRunExitCodeNotStarted  = -1 // rsync 没能启动
+	RsyncRunExitCodeHookFailed  = -2 // 执行前或执行后的钩子失败
+	RsyncRunExitCodeDeleteGuard = -3 // 删除保护中止了执行
+)
+
+// RsyncRun 一次rsync执行记录
+type RsyncRun struct {
+	ID               uint           `gorm:"primarykey" json:"id"`
+	RsyncConfigID    uint           `gorm:"index;not null" json:"rsync_config_id"`
+	ConfigName       string         `gorm:"index;not null" json:"config_name"` // 执行时的配置名称
+	Trigger          string         `json:"trigger"`                           // 触发方式
+	StartedAt        time.Time      `gorm:"index" json:"started_at"`
+	EndedAt          *time.Time     `json:"ended_at"` // 为空表示仍在执行或进程被中断
+	ExitCode         int            `json:"exit_code"`
+	Command          string         `json:"command"` // 可直接粘贴执行的完整命令
+	Engine           TransferEngine `json:"engine"`  // 使用的传输引擎，sftp 引擎时 Command 为 sftp 命令
+	FilesTransferred int            `json:"files_transferred"`
+	TotalSize        int64          `json:"total_size"`
+	TransferredSize  int64          `json:"transferred_size"`
+	BytesSent        int64          `json:"bytes_sent"`
+	BytesReceived    int64          `json:"bytes_received"`
+	Speedup          float64        `json:"speedup"`
+	Error            string         `json:"error"`
+	LogPath          string         `json:"log_path"`           // 本次执行的完整输出
+	Snapshot         string         `json:"snapshot"`           // 快照备份成功时保存的快照名称
+	Verify           string         `json:"verify"`             // 执行后校验的结果，为空表示没有校验
+	VerifyReportPath string         `json:"verify_report_path"` // 执行后校验的完整报告
+	DeleteGuard      string         `json:"delete_guard"`       // 删除保护的检查结果，为空表示没有检查
+}
+
+// Finished 是否已经结束
+func (r *RsyncRun) Finished() bool {
+	return r.EndedAt != nil
+}
+
+// Succeeded 是否执行成功，rsync 成功但保存快照等后续步骤失败时也算失败
+func (r *RsyncRun) Succeeded() bool {
+	return r.Finished() && r.ExitCode == 0 && r.Error == ""
+}
+
+// Duration 执行用时，未结束时为0
+func (r *RsyncRun) Duration() time.Duration {
+	if r.EndedAt == nil {
+		return 0
+	}
+	return r.EndedAt.Sub(r.StartedAt)
+}
+
+// StatusIcon 执行状态图标
+func (r *RsyncRun) StatusIcon() string {
+	switch {
+	case !r.Finished():
+		return "⏳"
+	case r.Succeeded():
+		return "✅"
+	default:
+		return "❌"
+	}
+}
+
+// ApplyResult 记录执行结果
+func (r *RsyncRun) ApplyResult(result RsyncResult) {
+	r.FilesTransferred = result.FilesTransferred
+	r.TotalSize = result.TotalSize
+	r.TransferredSize = result.TransferredSize
+	r.BytesSent = result.BytesSent
+	r.BytesReceived = result.BytesReceived
+	r.Speedup = result.Speedup
+	r.Error = result.Error
+	if result.Verify != nil {
+		r.Verify = result.Verify.String()
+		r.VerifyReportPath = result.Verify.ReportPath
+	}
+}
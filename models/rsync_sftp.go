@@ -0,0 +1,328 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// TransferEntry sftp 引擎列出的一个文件或目录
+type TransferEntry struct {
+	IsDir     bool
+	Size      int64
+	ModTime   int64  // Unix 秒，精度大于 1 秒时为所在分钟或日期的开始
+	Precision int64  // 修改时间的精度（秒），0 表示精确到秒；sftp 列出的服务器文件只精确到分钟或日期
+	Mode      uint32 // 权限位
+}
+
+// SameModTime 在两者中较低的精度下修改时间是否相同，sftp 只显示到分钟，同一分钟内的修改无法区分
+func (e TransferEntry) SameModTime(other TransferEntry) bool {
+	coarse, fine := e, other
+	if coarse.Precision < fine.Precision {
+		coarse, fine = fine, coarse
+	}
+	if coarse.Precision <= 1 {
+		return coarse.ModTime == fine.ModTime
+	}
+	return fine.ModTime >= coarse.ModTime && fine.ModTime < coarse.ModTime+coarse.Precision
+}
+
+// TransferEntry 转换为 sftp 引擎比较使用的文件信息，now 用于推断没有年份的修改时间
+func (e RemoteEntry) TransferEntry(now time.Time) (TransferEntry, error) {
+	modTime, precision, err := ParseRemoteLsTime(e.Modified, now)
+	if err != nil {
+		return TransferEntry{}, fmt.Errorf("'%s': %v", e.Name, err)
+	}
+	return TransferEntry{IsDir: e.IsDir, Size: e.Size, ModTime: modTime, Precision: precision, Mode: parseRemoteLsPerm(e.Mode[1:])}, nil
+}
+
+// IsRegular 是否为普通文件
+func (e RemoteEntry) IsRegular() bool {
+	return strings.HasPrefix(e.Mode, "-")
+}
+
+// TransferPlanOptions 生成 sftp 引擎传输计划的选项
+type TransferPlanOptions struct {
+	Upload    bool
+	Prefix    string // 逐项变更路径的前缀，源路径不以 / 结尾时为源目录名
+	Delete    bool
+	MaxSize   int64
+	Excluded  func(rel string, isDir bool) bool // 发送端被排除的路径，rel 包含 Prefix
+	Protected func(rel string, isDir bool) bool // 接收端被排除或保护、不能删除的路径，rel 包含 Prefix
+}
+
+// SFTPTransferRoots sftp 引擎的本地目录、服务器目录和逐项变更路径的前缀
+// 与 rsync 相同，源路径不以 / 结尾时传输到目标路径下的同名目录，变更路径以该目录名开头
+func (r *RsyncConfig) SFTPTransferRoots() (localRoot, remoteRoot, prefix string) {
+	localRoot = filepath.Clean(r.LocalPath)
+	remoteRoot = strings.TrimRight(r.RemotePath, "/")
+	if remoteRoot == "" {
+		remoteRoot = "/"
+	}
+
+	if r.Direction == RsyncDirectionUpload {
+		if !strings.HasSuffix(r.LocalPath, "/") {
+			prefix = filepath.Base(localRoot)
+			remoteRoot = path.Join(remoteRoot, prefix)
+		}
+		return localRoot, remoteRoot, prefix
+	}
+
+	if !strings.HasSuffix(r.RemotePath, "/") {
+		prefix = path.Base(remoteRoot)
+		// 用户目录本身没有可用的目录名，按同步其中的内容处理
+		if prefix == "~" || prefix == "/" || prefix == "." {
+			prefix = ""
+		}
+		localRoot = filepath.Join(localRoot, prefix)
+	}
+	return localRoot, remoteRoot, prefix
+}
+
+// BuildRemoteMkdirCommand 生成在服务器上创建目录（包括上级目录）的 ssh 命令
+func BuildRemoteMkdirCommand(sshConnection *SSHConnection, dir string) []string {
+	return sshConnection.RemoteCommand("mkdir -p " + remoteShellPath(dir))
+}
+
+// PlanFileTransfer 按 rsync 的快速检查（大小和修改时间）比较源和目标，生成传输计划
+// 先列出要删除的项（深层的在前），再按路径顺序列出要新建的目录和要传输的文件
+// 根目录（键为空字符串）在 Prefix 为空时由调用方创建，不在计划中
+func PlanFileTransfer(source, dest map[string]TransferEntry, opts TransferPlanOptions) []ItemizedChange {
+	item := func(rel string, isDir bool) string {
+		p := path.Join(opts.Prefix, rel)
+		if isDir {
+			p += "/"
+		}
+		return p
+	}
+	excluded := func(rel string, isDir bool) bool {
+		return opts.Excluded != nil && rel != "" && opts.Excluded(path.Join(opts.Prefix, rel), isDir)
+	}
+	protected := func(rel string, isDir bool) bool {
+		return opts.Protected != nil && opts.Protected(path.Join(opts.Prefix, rel), isDir)
+	}
+	flag := ">f"
+	if opts.Upload {
+		flag = "<f"
+	}
+
+	var paths []string
+	for rel := range source {
+		paths = append(paths, rel)
+	}
+	for rel := range dest {
+		if _, ok := source[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+
+	var transfers []ItemizedChange
+	deleting := make(map[string]bool)
+	replacing := make(map[string]bool) // 目标中类型不同、需要先删除的路径
+	var blocked []string               // 目标中类型不同又不能删除的路径，源中其下的内容无法传输
+	for _, rel := range paths {
+		if rel == "" && opts.Prefix == "" {
+			continue
+		}
+		if blockedBy(blocked, rel) {
+			continue
+		}
+		src, inSource := source[rel]
+		dst, inDest := dest[rel]
+		if inSource && excluded(rel, src.IsDir) {
+			inSource = false
+		}
+
+		switch {
+		case !inSource:
+			if inDest && opts.Delete && !protected(rel, dst.IsDir) {
+				deleting[rel] = true
+			}
+			continue
+		case inDest && src.IsDir && dst.IsDir:
+			continue
+		case inDest && src.IsDir != dst.IsDir:
+			if !opts.Delete || protected(rel, dst.IsDir) {
+				blocked = append(blocked, rel)
+				continue
+			}
+			deleting[rel] = true
+			replacing[rel] = true
+		case inDest && src.Size == dst.Size && src.SameModTime(dst):
+			continue
+		}
+
+		if src.IsDir {
+			transfers = append(transfers, ItemizedChange{Kind: ItemizedChangeNew, Path: item(rel, true), FileType: "d", Flags: "cd+++++++++"})
+			continue
+		}
+		if opts.MaxSize > 0 && src.Size > opts.MaxSize {
+			continue
+		}
+		change := ItemizedChange{Kind: ItemizedChangeNew, Path: item(rel, false), FileType: "f", Flags: flag + "+++++++++", Size: src.Size}
+		if inDest && !deleting[rel] {
+			change.Kind = ItemizedChangeUpdated
+			change.Flags = flag + ".st......"
+		}
+		transfers = append(transfers, change)
+	}
+
+	// 目录中有保留的内容时不能删除该目录
+	for rel := range dest {
+		if deleting[rel] {
+			continue
+		}
+		for parent := path.Dir(rel); rel != "" && parent != "."; parent = path.Dir(parent) {
+			delete(deleting, parent)
+		}
+	}
+	var deletes []ItemizedChange
+	transfers = lo.Filter(transfers, func(change ItemizedChange, _ int) bool {
+		rel := TransferRel(change.Path, opts.Prefix)
+		return !replacing[rel] || deleting[rel]
+	})
+	for rel := range deleting {
+		isDir := dest[rel].IsDir
+		fileType := "f"
+		if isDir {
+			fileType = "d"
+		}
+		deletes = append(deletes, ItemizedChange{Kind: ItemizedChangeDeleted, Path: item(rel, isDir), FileType: fileType, Flags: "*deleting"})
+	}
+	sort.Slice(deletes, func(i, j int) bool { return deletes[i].Path > deletes[j].Path })
+	return append(deletes, transfers...)
+}
+
+// TransferRel 逐项变更的路径去掉前缀和目录结尾的 / 后，相对于源和目标目录的路径
+func TransferRel(changePath, prefix string) string {
+	if prefix != "" {
+		changePath = strings.TrimPrefix(strings.TrimPrefix(changePath, prefix), "/")
+	}
+	return strings.TrimSuffix(changePath, "/")
+}
+
+// blockedBy rel 是否在 blocked 中的某个路径之下
+func blockedBy(blocked []string, rel string) bool {
+	for _, b := range blocked {
+		if strings.HasPrefix(rel, b+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sftpBatchArgs 以批处理模式执行 sftp 的公共参数，批处理命令从标准输入读取
+// -b 会让 ssh 以 BatchMode 执行、不询问密码；ssh 以第一次出现的选项为准，使用密码的连接在 -b 之前设置 BatchMode=no，由 ssh 在终端中询问
+func sftpBatchArgs(sshConnection *SSHConnection) []string {
+	cmd := []string{"sftp"}
+	if sshConnection.PasswordType == PasswordTypePassword {
+		cmd = append(cmd, "-o", "BatchMode=no")
+	}
+	cmd = append(cmd, "-b", "-", "-P", strconv.Itoa(sshConnection.Port))
+	if sshConnection.PasswordType == PasswordTypeKeyPath && sshConnection.KeyPath != "" {
+		cmd = append(cmd, "-i", sshConnection.KeyPath)
+	}
+	return cmd
+}
+
+// BuildSFTPCommand 生成以批处理模式执行 sftp 的命令，批处理命令从标准输入读取
+// sftp 没有 rsync 的 --timeout，用 ssh 的保活检测代替：超过 Timeout 秒服务器没有响应时断开
+func BuildSFTPCommand(sshConnection *SSHConnection, config *RsyncConfig) []string {
+	cmd := sftpBatchArgs(sshConnection)
+	if config.Compress {
+		cmd = append(cmd, "-C")
+	}
+	if config.BandwidthLimit > 0 {
+		// sftp -l 的单位是 Kbit/s
+		cmd = append(cmd, "-l", strconv.Itoa(config.BandwidthLimit*8))
+	}
+	if config.Timeout > 0 {
+		cmd = append(cmd, "-o", fmt.Sprintf("ServerAliveInterval=%d", config.Timeout), "-o", "ServerAliveCountMax=1")
+	}
+	return append(cmd, sshConnection.Destination())
+}
+
+// SFTPBatchLine 一条 sftp 批处理命令及其对应的变更
+type SFTPBatchLine struct {
+	Command string
+	Change  ItemizedChange
+}
+
+// BuildSFTPBatch 把传输计划转换为 sftp 批处理命令
+// 上传时删除、新建目录和传输都在服务器上执行；下载时只生成 get，删除和新建目录由调用方在本地完成
+// preserve 为 true 时使用 -p 保持修改时间和权限
+func BuildSFTPBatch(changes []ItemizedChange, localRoot, remoteRoot, prefix string, upload, preserve bool) ([]SFTPBatchLine, error) {
+	flags := ""
+	if preserve {
+		flags = " -p"
+	}
+
+	var lines []SFTPBatchLine
+	for _, change := range changes {
+		if strings.ContainsAny(change.Path, "\n\r") {
+			return nil, fmt.Errorf("文件名 %q 包含换行符，sftp 引擎无法处理", change.Path)
+		}
+		rel := TransferRel(change.Path, prefix)
+		local := SFTPQuote(filepath.Join(localRoot, filepath.FromSlash(rel)))
+		remote := SFTPQuote(sftpRemotePath(remoteRoot, rel))
+
+		var command string
+		switch {
+		case !upload && change.FileType == "f" && change.Kind != ItemizedChangeDeleted:
+			command = "get" + flags + " " + remote + " " + local
+		case !upload:
+			continue
+		case change.Kind == ItemizedChangeDeleted && change.FileType == "d":
+			command = "rmdir " + remote
+		case change.Kind == ItemizedChangeDeleted:
+			command = "rm " + remote
+		case change.FileType == "d":
+			command = "mkdir " + remote
+		default:
+			command = "put" + flags + " " + local + " " + remote
+		}
+		lines = append(lines, SFTPBatchLine{Command: command, Change: change})
+	}
+	return lines, nil
+}
+
+// sftpRemotePath 服务器上的路径，sftp 从用户目录开始且不展开 ~，因此去掉开头的 ~/
+func sftpRemotePath(root, rel string) string {
+	switch {
+	case root == "~":
+		root = ""
+	case strings.HasPrefix(root, "~/"):
+		root = root[2:]
+	}
+	p := path.Join(root, rel)
+	if p == "" {
+		return "."
+	}
+	// 以 - 开头的参数会被 sftp 当作选项
+	if strings.HasPrefix(p, "-") {
+		p = "./" + p
+	}
+	return p
+}
+
+// sftpSpecialChars sftp 批处理命令中需要转义的字符：分隔符、引号、转义符和通配符
+const sftpSpecialChars = " \t\"'\\*?[]#"
+
+// SFTPQuote 为 sftp 批处理命令转义单个参数，sftp 会对 get、put 等命令的参数做通配符匹配
+func SFTPQuote(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(sftpSpecialChars, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
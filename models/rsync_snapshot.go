@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SnapshotTimeLayout       = "2006-01-02_150405" // 快照目录名称的时间格式
+	SnapshotLatestLink       = "latest"            // 指向最新快照的符号链接
+	SnapshotInProgressSuffix = ".inprogress"       // 尚未完成的快照目录后缀，失败后下一次执行会继续使用
+)
+
+// RsyncSnapshot 快照备份中的一个快照目录
+type RsyncSnapshot struct {
+	Name   string    `json:"name"`
+	Path   string    `json:"path"`
+	Time   time.Time `json:"time"`
+	Latest bool      `json:"latest"` // latest 链接指向该快照
+}
+
+// SnapshotName 快照目录名称
+func SnapshotName(t time.Time) string {
+	return t.Format(SnapshotTimeLayout)
+}
+
+// ParseSnapshotName 解析快照目录名称中的时间，不是快照目录时返回 false
+func ParseSnapshotName(name string) (time.Time, bool) {
+	t, err := time.ParseInLocation(SnapshotTimeLayout, name, time.Local)
+	return t, err == nil
+}
+
+// BuildSnapshotCommand 生成快照备份的rsync命令，下载到 snapshotDir，
+// 与 linkDest（上一个快照的绝对路径，为空表示第一个快照）相同的文件使用硬链接
+func (r *RsyncConfig) BuildSnapshotCommand(sshConnection *SSHConnection, snapshotDir, linkDest string) []string {
+	cmd := r.buildCommand(sshConnection, snapshotDir, nil)
+	if linkDest == "" {
+		return cmd
+	}
+
+	// --link-dest 放在源路径和目标路径之前
+	paths := len(cmd) - 2
+	result := make([]string, 0, len(cmd)+1)
+	result = append(result, cmd[:paths]...)
+	result = append(result, "--link-dest="+linkDest)
+	return append(result, cmd[paths:]...)
+}
+
+// ValidateSnapshot 检查快照备份设置
+func (r *RsyncConfig) ValidateSnapshot() error {
+	if !r.SnapshotMode {
+		return nil
+	}
+	if r.Direction != RsyncDirectionDownload {
+		return fmt.Errorf("快照备份只能用于下载配置")
+	}
+	if r.SnapshotKeepDaily < 0 || r.SnapshotKeepWeekly < 0 || r.SnapshotKeepMonthly < 0 {
+		return fmt.Errorf("快照保留数量不能为负数")
+	}
+	return nil
+}
+
+// SnapshotRetention 保留策略的简短描述
+func (r *RsyncConfig) SnapshotRetention() string {
+	var parts []string
+	if r.SnapshotKeepDaily > 0 {
+		parts = append(parts, fmt.Sprintf("%d 天", r.SnapshotKeepDaily))
+	}
+	if r.SnapshotKeepWeekly > 0 {
+		parts = append(parts, fmt.Sprintf("%d 周", r.SnapshotKeepWeekly))
+	}
+	if r.SnapshotKeepMonthly > 0 {
+		parts = append(parts, fmt.Sprintf("%d 个月", r.SnapshotKeepMonthly))
+	}
+	if len(parts) == 0 {
+		return "保留全部"
+	}
+	return "保留 " + strings.Join(parts, "、")
+}
+
+// SnapshotsToPrune 按保留策略返回需要删除的快照
+// 每天、每周、每月分别保留最近 N 个时间段中最新的快照，最新的快照总是保留；没有设置保留数量时保留全部
+func (r *RsyncConfig) SnapshotsToPrune(snapshots []RsyncSnapshot) []RsyncSnapshot {
+	if r.SnapshotKeepDaily <= 0 && r.SnapshotKeepWeekly <= 0 && r.SnapshotKeepMonthly <= 0 {
+		return nil
+	}
+
+	sorted := append([]RsyncSnapshot(nil), snapshots...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.After(sorted[j].Time) })
+
+	keep := make(map[string]bool)
+	if len(sorted) > 0 {
+		keep[sorted[0].Name] = true
+	}
+
+	policies := []struct {
+		count  int
+		bucket func(time.Time) string
+	}{
+		{r.SnapshotKeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{r.SnapshotKeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{r.SnapshotKeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, policy := range policies {
+		seen := make(map[string]bool)
+		for _, snapshot := range sorted {
+			if len(seen) >= policy.count {
+				break
+			}
+			bucket := policy.bucket(snapshot.Time)
+			if !seen[bucket] {
+				seen[bucket] = true
+				keep[snapshot.Name] = true
+			}
+		}
+	}
+
+	var prune []RsyncSnapshot
+	for _, snapshot := range sorted {
+		if !keep[snapshot.Name] {
+			prune = append(prune, snapshot)
+		}
+	}
+	return prune
+}
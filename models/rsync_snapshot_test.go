@@ -0,0 +1,64 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotsToPrune(t *testing.T) {
+	var snapshots []RsyncSnapshot
+	// 2024-01-01 到 2024-03-31 每天两个快照
+	for day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local); day.Month() <= 3 && day.Year() == 2024; day = day.AddDate(0, 0, 1) {
+		for _, hour := range []int{3, 15} {
+			at := day.Add(time.Duration(hour) * time.Hour)
+			snapshots = append(snapshots, RsyncSnapshot{Name: SnapshotName(at), Time: at})
+		}
+	}
+
+	config := RsyncConfig{SnapshotKeepDaily: 3, SnapshotKeepWeekly: 2, SnapshotKeepMonthly: 3}
+	prune := config.SnapshotsToPrune(snapshots)
+
+	pruned := make(map[string]bool)
+	for _, s := range prune {
+		pruned[s.Name] = true
+	}
+	var kept []string
+	for _, s := range snapshots {
+		if !pruned[s.Name] {
+			kept = append(kept, s.Name)
+		}
+	}
+
+	want := []string{
+		"2024-01-31_150000", // 1 月最新
+		"2024-02-29_150000", // 2 月最新
+		"2024-03-24_150000", // 上一周（周日）最新
+		"2024-03-29_150000",
+		"2024-03-30_150000",
+		"2024-03-31_150000", // 最新，也是本周和本月最新
+	}
+	if !reflect.DeepEqual(kept, want) {
+		t.Errorf("保留的快照 = %v, 期望 %v", kept, want)
+	}
+
+	if prune := (&RsyncConfig{}).SnapshotsToPrune(snapshots); prune != nil {
+		t.Errorf("没有保留策略时不应删除快照，得到 %d 个", len(prune))
+	}
+}
+
+func TestBuildSnapshotCommand(t *testing.T) {
+	config := RsyncConfig{Direction: RsyncDirectionDownload, Archive: true, LocalPath: "/backup/web", RemotePath: "/var/www"}
+	conn := &SSHConnection{Username: "u", Address: "h", Port: 22}
+
+	got := config.BuildSnapshotCommand(conn, "/backup/web/2024-03-01_030000.inprogress", "/backup/web/2024-02-29_030000")
+	want := []string{"rsync", "-a", "-e", "ssh -p 22", "--link-dest=/backup/web/2024-02-29_030000",
+		"u@h:/var/www", "/backup/web/2024-03-01_030000.inprogress"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildSnapshotCommand = %q, 期望 %q", got, want)
+	}
+
+	if got := config.BuildSnapshotCommand(conn, "/backup/web/2024-03-01_030000", ""); got[len(got)-3] != "ssh -p 22" {
+		t.Errorf("第一个快照不应包含 --link-dest: %q", got)
+	}
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 变量值的来源，优先级从低到高
+const (
+	TemplateSourceBuiltin = "内置"
+	TemplateSourceDefault = "默认值"
+	TemplateSourceEnv     = "环境变量"
+	TemplateSourceFlag    = "--var"
+)
+
+// templateVariablePattern 匹配 {{name}}，名称中可以有 . 和 -，如 {{ssh.username}}
+var templateVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// templateVariableName 用户定义的变量名称，不能包含 . 以免与连接字段混淆
+var templateVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// TemplateVariable 渲染时使用的一个变量
+type TemplateVariable struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// BuiltinTemplateVariableNames 内置变量，用户定义的变量不能使用这些名称
+var BuiltinTemplateVariableNames = []string{
+	"name", "date", "time", "datetime", "year", "month", "day", "timestamp", "home",
+	"ssh.name", "ssh.address", "ssh.port", "ssh.username",
+	"target.name", "target.address", "target.port", "target.username",
+}
+
+// BuiltinTemplateVariables 内置变量的值，target 为服务器之间传输的目标连接，可以为空
+func BuiltinTemplateVariables(config *RsyncConfig, sshConnection, target *SSHConnection, now time.Time, home string) map[string]string {
+	vars := map[string]string{
+		"name":      config.Name,
+		"date":      now.Format("2006-01-02"),
+		"time":      now.Format("150405"),
+		"datetime":  now.Format("2006-01-02_150405"),
+		"year":      now.Format("2006"),
+		"month":     now.Format("01"),
+		"day":       now.Format("02"),
+		"timestamp": strconv.FormatInt(now.Unix(), 10),
+		"home":      home,
+	}
+	for prefix, conn := range map[string]*SSHConnection{"ssh": sshConnection, "target": target} {
+		if conn == nil {
+			continue
+		}
+		vars[prefix+".name"] = conn.Name
+		vars[prefix+".address"] = conn.Address
+		vars[prefix+".port"] = strconv.Itoa(conn.Port)
+		vars[prefix+".username"] = conn.Username
+	}
+	return vars
+}
+
+// ParseTemplateAssignment 解析 名称=值
+func ParseTemplateAssignment(text string) (string, string, error) {
+	name, value, ok := strings.Cut(text, "=")
+	name = strings.TrimSpace(name)
+	if !ok || !templateVariableName.MatchString(name) {
+		return "", "", fmt.Errorf("变量 '%s' 格式错误，应为 名称=值，名称只能包含字母、数字、_ 和 -", text)
+	}
+	for _, builtin := range BuiltinTemplateVariableNames {
+		if name == builtin {
+			return "", "", fmt.Errorf("变量 '%s' 是内置变量，不能重新定义", name)
+		}
+	}
+	return name, value, nil
+}
+
+// ParseTemplateVariables 解析每行一个 名称=值 的变量默认值，# 开头的行为注释
+func ParseTemplateVariables(text string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, err := ParseTemplateAssignment(line)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行%v", i+1, err)
+		}
+		vars[name] = strings.TrimSpace(value)
+	}
+	return vars, nil
+}
+
+type templateField struct {
+	label string
+	value *string
+}
+
+// templateFields 可以使用变量的字段
+func (r *RsyncConfig) templateFields() []templateField {
+	return []templateField{
+		{"本地路径", &r.LocalPath},
+		{"远程路径", &r.RemotePath},
+		{"目标路径", &r.TargetPath},
+		{"排除规则", &r.ExcludeRules},
+		{"过滤规则", &r.FilterRules},
+		{"额外选项", &r.Options},
+		{"备份目录", &r.BackupDir},
+	}
+}
+
+// TemplateVariableNames 配置中用到的变量名称，按名称排序
+func (r *RsyncConfig) TemplateVariableNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, field := range r.templateFields() {
+		for _, match := range templateVariablePattern.FindAllStringSubmatch(*field.value, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				names = append(names, match[1])
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateTemplates 检查变量的写法和默认值
+func (r *RsyncConfig) ValidateTemplates() error {
+	if _, err := ParseTemplateVariables(r.Variables); err != nil {
+		return fmt.Errorf("变量默认值%v", err)
+	}
+	for _, field := range r.templateFields() {
+		rest := templateVariablePattern.ReplaceAllString(*field.value, "")
+		if strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+			return fmt.Errorf("%s中的变量格式错误，应为 {{名称}}", field.label)
+		}
+	}
+	if r.SnapshotMode && templateVariablePattern.MatchString(r.LocalPath) {
+		return fmt.Errorf("快照备份的本地路径中保存所有快照，不能使用变量")
+	}
+	return nil
+}
+
+// Render 用 vars 替换配置中的变量，返回新的配置，有未定义的变量时返回错误
+func (r *RsyncConfig) Render(vars map[string]string) (*RsyncConfig, error) {
+	rendered := *r
+	missing := make(map[string]bool)
+	for _, field := range rendered.templateFields() {
+		*field.value = templateVariablePattern.ReplaceAllStringFunc(*field.value, func(s string) string {
+			name := templateVariablePattern.FindStringSubmatch(s)[1]
+			value, ok := vars[name]
+			if !ok {
+				missing[name] = true
+			}
+			return value
+		})
+	}
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("未定义的变量: %s（可在配置中设置默认值，或使用 --var 名称=值）", strings.Join(names, ", "))
+	}
+	return &rendered, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRsyncConfigRender(t *testing.T) {
+	config := RsyncConfig{
+		Name:         "app",
+		LocalPath:    "{{home}}/backups/{{ env }}/{{date}}",
+		RemotePath:   "/home/{{ssh.username}}/{{project}}/",
+		ExcludeRules: "*.log\n{{env}}.secret",
+		Options:      "--backup-dir=old-{{datetime}}",
+	}
+	conn := &SSHConnection{Name: "web", Username: "deploy", Address: "h", Port: 22}
+	vars := BuiltinTemplateVariables(&config, conn, nil, time.Date(2024, 3, 1, 15, 4, 5, 0, time.UTC), "/Users/me")
+	vars["env"] = "prod"
+	vars["project"] = "shop"
+
+	rendered, err := config.Render(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.LocalPath != "/Users/me/backups/prod/2024-03-01" {
+		t.Errorf("LocalPath = %s", rendered.LocalPath)
+	}
+	if rendered.RemotePath != "/home/deploy/shop/" {
+		t.Errorf("RemotePath = %s", rendered.RemotePath)
+	}
+	if rendered.ExcludeRules != "*.log\nprod.secret" || rendered.Options != "--backup-dir=old-2024-03-01_150405" {
+		t.Errorf("规则和选项替换错误: %q %q", rendered.ExcludeRules, rendered.Options)
+	}
+	if config.LocalPath != "{{home}}/backups/{{ env }}/{{date}}" {
+		t.Error("Render 不应修改原配置")
+	}
+
+	if names := config.TemplateVariableNames(); strings.Join(names, ",") != "date,datetime,env,home,project,ssh.username" {
+		t.Errorf("TemplateVariableNames = %v", names)
+	}
+
+	delete(vars, "project")
+	delete(vars, "env")
+	if _, err := config.Render(vars); err == nil || !strings.Contains(err.Error(), "env, project") {
+		t.Errorf("未定义的变量应该全部列出: %v", err)
+	}
+}
+
+func TestParseTemplateVariables(t *testing.T) {
+	vars, err := ParseTemplateVariables("# 默认环境\nenv = staging\nurl=https://a?b=c\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["env"] != "staging" || vars["url"] != "https://a?b=c" {
+		t.Errorf("ParseTemplateVariables = %v", vars)
+	}
+
+	for _, text := range []string{"env", "date=2024", "ssh.username=u", "bad name=x"} {
+		if _, err := ParseTemplateVariables(text); err == nil {
+			t.Errorf("ParseTemplateVariables(%q) 应该返回错误", text)
+		}
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RsyncConfig
+		wantErr bool
+	}{
+		{"正常", RsyncConfig{LocalPath: "/data/{{env}}", Variables: "env=dev"}, false},
+		{"未闭合", RsyncConfig{RemotePath: "/srv/{{env"}, true},
+		{"多余的括号", RsyncConfig{Options: "--x=}}"}, true},
+		{"快照路径", RsyncConfig{LocalPath: "/backup/{{env}}", SnapshotMode: true}, true},
+		{"快照远程路径", RsyncConfig{LocalPath: "/backup", RemotePath: "/srv/{{env}}", SnapshotMode: true}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.config.ValidateTemplates(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, 期望错误 %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VerifyDiffKind 校验发现的差异类型
+type VerifyDiffKind string
+
+const (
+	VerifyOnlyLocal  VerifyDiffKind = "only_local"  // 只在本地存在
+	VerifyOnlyRemote VerifyDiffKind = "only_remote" // 只在服务器上存在
+	VerifyType       VerifyDiffKind = "type"        // 一端是文件，另一端是目录
+	VerifySize       VerifyDiffKind = "size"        // 大小不同
+	VerifyModTime    VerifyDiffKind = "mtime"       // 大小相同，修改时间不同
+	VerifyChecksum   VerifyDiffKind = "checksum"    // 大小和修改时间相同，内容不同
+)
+
+// VerifyFileInfo 差异中一端的文件信息
+type VerifyFileInfo struct {
+	IsDir    bool   `json:"is_dir,omitempty"`
+	Size     int64  `json:"size"`
+	ModTime  int64  `json:"mtime"`              // Unix 秒
+	Checksum string `json:"checksum,omitempty"` // SHA-256
+}
+
+// VerifyDiff 本地和服务器上的一处差异
+type VerifyDiff struct {
+	Kind    VerifyDiffKind  `json:"kind"`
+	Path    string          `json:"path"` // 相对于比较的目录
+	Local   *VerifyFileInfo `json:"local,omitempty"`
+	Remote  *VerifyFileInfo `json:"remote,omitempty"`
+	Ignored bool            `json:"ignored,omitempty"` // 不影响校验结果，如没有启用删除时目标中多余的文件
+}
+
+// VerifyReport 比较本地和服务器上的文件得到的校验报告
+type VerifyReport struct {
+	ConfigName string       `json:"config_name"`
+	LocalPath  string       `json:"local_path"`
+	RemotePath string       `json:"remote_path"`
+	Checksum   bool         `json:"checksum"` // 是否比较了内容的校验和
+	CheckedAt  time.Time    `json:"checked_at"`
+	Files      int          `json:"files"`   // 比较的文件数
+	Matched    int          `json:"matched"` // 两端一致的文件数
+	Diffs      []VerifyDiff `json:"diffs"`
+	Passed     bool         `json:"passed"`
+
+	matched map[string]TransferEntry // 大小和修改时间一致的文件，比较校验和时用于填写差异
+}
+
+// VerifySummary 校验结果摘要，随执行结果保存
+type VerifySummary struct {
+	Passed     bool   `json:"passed"`
+	Files      int    `json:"files"`
+	Matched    int    `json:"matched"`
+	Failures   int    `json:"failures"`              // 导致校验失败的差异数
+	Ignored    int    `json:"ignored"`               // 不影响结果的差异数
+	Error      string `json:"error,omitempty"`       // 没能完成校验的原因
+	ReportPath string `json:"report_path,omitempty"` // 完整报告的路径
+}
+
+// VerifyOptions 比较两端文件的选项
+type VerifyOptions struct {
+	Prefix       string                            // 传给 Excluded 和 Protected 的路径前缀
+	Excluded     func(rel string, isDir bool) bool // 被排除、不会传输的路径
+	Protected    func(rel string, isDir bool) bool // 接收端被保护、不会删除的路径
+	ModTime      bool                              // 是否比较修改时间，传输时保持了修改时间才有意义
+	MaxSize      int64                             // 大于该大小的文件不会传输，不比较
+	IgnoreLocal  bool                              // 只在本地存在的文件不影响结果
+	IgnoreRemote bool                              // 只在服务器上存在的文件不影响结果
+}
+
+// ValidateVerify 检查配置能否校验以及执行后校验的设置
+func (r *RsyncConfig) ValidateVerify() error {
+	if r.VerifyChecksum && !r.VerifyAfterRun {
+		return fmt.Errorf("校验内容需要启用执行后校验")
+	}
+	if !r.VerifyAfterRun {
+		return nil
+	}
+	return r.verifiable()
+}
+
+// verifiable 只能校验本地和服务器之间同步的目录
+func (r *RsyncConfig) verifiable() error {
+	switch {
+	case r.Direction == RsyncDirectionRemote:
+		return fmt.Errorf("服务器之间传输不能校验，两端都不在本地")
+	case r.SnapshotMode:
+		return fmt.Errorf("快照备份每次写入新的目录，不能校验，请使用 rsync snapshots 查看快照")
+	}
+	return nil
+}
+
+// VerifyRoots 校验时比较的本地目录、服务器目录和过滤规则路径的前缀，与传输时的目录相同
+func (r *RsyncConfig) VerifyRoots() (localRoot, remoteRoot, prefix string, err error) {
+	if err := r.verifiable(); err != nil {
+		return "", "", "", err
+	}
+	if r.Direction == RsyncDirectionBidirectional {
+		remoteRoot = strings.TrimRight(r.RemotePath, "/")
+		if remoteRoot == "" {
+			remoteRoot = "/"
+		}
+		return filepath.Clean(r.LocalPath), remoteRoot, "", nil
+	}
+	localRoot, remoteRoot, prefix = r.SFTPTransferRoots()
+	return localRoot, remoteRoot, prefix, nil
+}
+
+// VerifyIgnoredSides 目标中多余的文件在没有启用删除时不影响结果，双向同步两端都应一致
+func (r *RsyncConfig) VerifyIgnoredSides() (ignoreLocal, ignoreRemote bool) {
+	switch r.Direction {
+	case RsyncDirectionUpload:
+		return false, !r.Delete
+	case RsyncDirectionDownload:
+		return !r.Delete, false
+	}
+	return false, false
+}
+
+// CompareTransferTrees 按路径比较本地和服务器上的文件，返回报告和大小、修改时间一致、可以再比较内容的文件
+func CompareTransferTrees(local, remote map[string]TransferEntry, opts VerifyOptions) (*VerifyReport, []string) {
+	skip := func(rel string, isDir bool) bool {
+		return opts.Excluded != nil && opts.Excluded(path.Join(opts.Prefix, rel), isDir)
+	}
+	protected := func(rel string, isDir bool) bool {
+		return opts.Protected != nil && opts.Protected(path.Join(opts.Prefix, rel), isDir)
+	}
+	info := func(e TransferEntry) *VerifyFileInfo {
+		return &VerifyFileInfo{IsDir: e.IsDir, Size: e.Size, ModTime: e.ModTime}
+	}
+
+	var paths []string
+	for rel := range local {
+		paths = append(paths, rel)
+	}
+	for rel := range remote {
+		if _, ok := local[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+
+	report := &VerifyReport{Diffs: []VerifyDiff{}, matched: make(map[string]TransferEntry)}
+	var matched []string
+	var missing []string // 只在一端存在的目录，其下的内容不再逐个列出
+	for _, rel := range paths {
+		if rel == "" || blockedBy(missing, rel) {
+			continue
+		}
+		l, inLocal := local[rel]
+		r, inRemote := remote[rel]
+		entry := l
+		if !inLocal {
+			entry = r
+		}
+		if skip(rel, entry.IsDir) || (!entry.IsDir && opts.MaxSize > 0 && entry.Size > opts.MaxSize) {
+			continue
+		}
+		if !entry.IsDir {
+			report.Files++
+		}
+
+		switch {
+		case !inRemote:
+			report.Diffs = append(report.Diffs, VerifyDiff{Kind: VerifyOnlyLocal, Path: rel, Local: info(l), Ignored: opts.IgnoreLocal || protected(rel, l.IsDir)})
+		case !inLocal:
+			report.Diffs = append(report.Diffs, VerifyDiff{Kind: VerifyOnlyRemote, Path: rel, Remote: info(r), Ignored: opts.IgnoreRemote || protected(rel, r.IsDir)})
+		case l.IsDir != r.IsDir:
+			report.Diffs = append(report.Diffs, VerifyDiff{Kind: VerifyType, Path: rel, Local: info(l), Remote: info(r)})
+		case l.IsDir:
+			continue
+		case l.Size != r.Size:
+			report.Diffs = append(report.Diffs, VerifyDiff{Kind: VerifySize, Path: rel, Local: info(l), Remote: info(r)})
+		case opts.ModTime && !l.SameModTime(r):
+			report.Diffs = append(report.Diffs, VerifyDiff{Kind: VerifyModTime, Path: rel, Local: info(l), Remote: info(r)})
+		default:
+			report.Matched++
+			report.matched[rel] = l
+			matched = append(matched, rel)
+			continue
+		}
+		if entry.IsDir && (!inLocal || !inRemote || l.IsDir != r.IsDir) {
+			missing = append(missing, rel)
+		}
+	}
+	report.finish()
+	return report, matched
+}
+
+// ApplyChecksums 比较 CompareTransferTrees 返回的文件的校验和，内容不同的文件记为差异
+func (r *VerifyReport) ApplyChecksums(files []string, local, remote map[string]string) {
+	r.Checksum = true
+	for _, rel := range files {
+		if local[rel] == remote[rel] && local[rel] != "" {
+			continue
+		}
+		r.Matched--
+		entry := r.matched[rel]
+		r.Diffs = append(r.Diffs, VerifyDiff{
+			Kind:   VerifyChecksum,
+			Path:   rel,
+			Local:  &VerifyFileInfo{Size: entry.Size, ModTime: entry.ModTime, Checksum: local[rel]},
+			Remote: &VerifyFileInfo{Size: entry.Size, ModTime: entry.ModTime, Checksum: remote[rel]},
+		})
+	}
+	sort.SliceStable(r.Diffs, func(i, j int) bool { return r.Diffs[i].Path < r.Diffs[j].Path })
+	r.finish()
+}
+
+// finish 没有影响结果的差异时校验通过
+func (r *VerifyReport) finish() {
+	r.Passed = r.Summary().Failures == 0
+}
+
+// Summary 报告的摘要
+func (r *VerifyReport) Summary() VerifySummary {
+	summary := VerifySummary{Files: r.Files, Matched: r.Matched}
+	for _, diff := range r.Diffs {
+		if diff.Ignored {
+			summary.Ignored++
+		} else {
+			summary.Failures++
+		}
+	}
+	summary.Passed = summary.Failures == 0
+	return summary
+}
+
+// String 摘要的简短说明
+func (s VerifySummary) String() string {
+	if s.Error != "" {
+		return "校验失败: " + s.Error
+	}
+	text := fmt.Sprintf("%d 个文件中 %d 个一致", s.Files, s.Matched)
+	if s.Failures > 0 {
+		text += fmt.Sprintf("，%d 处差异", s.Failures)
+	}
+	if s.Ignored > 0 {
+		text += fmt.Sprintf("，%d 处可忽略的差异", s.Ignored)
+	}
+	if s.Passed {
+		return "校验通过: " + text
+	}
+	return "校验未通过: " + text
+}
+
+// remoteChecksumScript 逐行从标准输入读取相对路径并计算 SHA-256，没有 sha256sum 时（macOS、BSD）使用 shasum
+const remoteChecksumScript = `if command -v sha256sum >/dev/null 2>&1; then sum="sha256sum"; else sum="shasum -a 256"; fi; ` +
+	`while IFS= read -r f; do $sum -- "$f"; done`
+
+// BuildRemoteChecksumCommand 生成在服务器上计算 dir 中文件 SHA-256 的 ssh 命令，
+// 文件的相对路径每行一个从标准输入读取（见 ChecksumInput），输出为 "校验和  路径"，每行一个
+func BuildRemoteChecksumCommand(sshConnection *SSHConnection, dir string) []string {
+	return sshConnection.RemoteCommand(fmt.Sprintf("cd %s && { %s; }", remoteShellPath(dir), remoteChecksumScript))
+}
+
+// ChecksumInput BuildRemoteChecksumCommand 的标准输入，包含换行符的路径无法逐行传递
+func ChecksumInput(files []string) (string, error) {
+	var b strings.Builder
+	for _, f := range files {
+		if strings.ContainsAny(f, "\n\r") {
+			return "", fmt.Errorf("文件名 %q 包含换行符，无法在服务器上计算校验和", f)
+		}
+		b.WriteString(f + "\n")
+	}
+	return b.String(), nil
+}
+
+// ParseChecksumList 解析 sha256sum 或 shasum 的输出，键为路径，不存在或不能读取的文件没有输出
+func ParseChecksumList(output string) (map[string]string, error) {
+	sums := make(map[string]string)
+	for _, record := range strings.Split(output, "\n") {
+		record = strings.TrimRight(record, "\r")
+		if record == "" {
+			continue
+		}
+		sum, file, ok := strings.Cut(record, "  ")
+		if !ok {
+			sum, file, ok = strings.Cut(record, " *")
+		}
+		if !ok || len(sum) != 64 {
+			return nil, fmt.Errorf("无法解析服务器上的校验和: %q", record)
+		}
+		sums[file] = sum
+	}
+	return sums, nil
+}
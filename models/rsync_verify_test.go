@@ -0,0 +1,117 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareTransferTrees(t *testing.T) {
+	dir := TransferEntry{IsDir: true}
+	local := map[string]TransferEntry{
+		"":          dir,
+		"same.txt":  {Size: 10, ModTime: 100},
+		"size.txt":  {Size: 10, ModTime: 100},
+		"time.txt":  {Size: 10, ModTime: 100},
+		"missing":   dir,
+		"missing/a": {Size: 1, ModTime: 1},
+		"cache":     dir,
+		"cache/x":   {Size: 1, ModTime: 1},
+		"big.iso":   {Size: 1000, ModTime: 1},
+		"kind":      dir,
+		"keep.log":  {Size: 1, ModTime: 1},
+	}
+	remote := map[string]TransferEntry{
+		"":         dir,
+		"same.txt": {Size: 10, ModTime: 100},
+		"size.txt": {Size: 11, ModTime: 100},
+		"time.txt": {Size: 10, ModTime: 200},
+		"extra":    {Size: 5, ModTime: 5},
+		"kind":     {Size: 3, ModTime: 3},
+	}
+	opts := VerifyOptions{
+		Excluded:     func(rel string, isDir bool) bool { return rel == "cache" || strings.HasPrefix(rel, "cache/") },
+		ModTime:      true,
+		MaxSize:      500,
+		IgnoreRemote: true,
+	}
+	report, matched := CompareTransferTrees(local, remote, opts)
+
+	var got []string
+	for _, diff := range report.Diffs {
+		text := string(diff.Kind) + " " + diff.Path
+		if diff.Ignored {
+			text += " (ignored)"
+		}
+		got = append(got, text)
+	}
+	want := "only_remote extra (ignored), only_local keep.log, type kind, only_local missing, size size.txt, mtime time.txt"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("差异 = %s\n期望 %s", strings.Join(got, ", "), want)
+	}
+	if report.Passed || report.Files != 5 || report.Matched != 1 || len(matched) != 1 || matched[0] != "same.txt" {
+		t.Errorf("报告 = %+v, matched = %v", report, matched)
+	}
+
+	// 不比较修改时间，并只看被忽略的差异
+	local = map[string]TransferEntry{"": dir, "a": {Size: 1, ModTime: 1}}
+	remote = map[string]TransferEntry{"": dir, "a": {Size: 1, ModTime: 2}, "b": {Size: 1}}
+	report, matched = CompareTransferTrees(local, remote, VerifyOptions{IgnoreRemote: true})
+	if !report.Passed || report.Summary().Ignored != 1 || len(matched) != 1 {
+		t.Errorf("报告 = %+v", report)
+	}
+
+	report.ApplyChecksums(matched, map[string]string{"a": "1"}, map[string]string{"a": "2"})
+	if report.Passed || report.Matched != 0 || report.Diffs[0].Kind != VerifyChecksum || !report.Checksum {
+		t.Errorf("校验和不同时 = %+v", report)
+	}
+	if !strings.HasPrefix(report.Summary().String(), "校验未通过") {
+		t.Errorf("摘要 = %s", report.Summary())
+	}
+}
+
+func TestParseChecksumList(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	sums, err := ParseChecksumList(sum + "  a b.txt\n" + sum + " *dir/c\r\n")
+	if err != nil || len(sums) != 2 || sums["a b.txt"] != sum || sums["dir/c"] != sum {
+		t.Errorf("ParseChecksumList = %v, %v", sums, err)
+	}
+	if _, err := ParseChecksumList("sha256sum: x: No such file\n"); err == nil {
+		t.Error("无法解析的输出应该返回错误")
+	}
+
+	if input, err := ChecksumInput([]string{"a b.txt", "dir/c"}); err != nil || input != "a b.txt\ndir/c\n" {
+		t.Errorf("ChecksumInput = %q, %v", input, err)
+	}
+	if _, err := ChecksumInput([]string{"a\nb"}); err == nil {
+		t.Error("ChecksumInput() 路径包含换行符时应返回错误")
+	}
+}
+
+func TestValidateVerify(t *testing.T) {
+	valid := []RsyncConfig{
+		{Direction: RsyncDirectionDownload, VerifyAfterRun: true, VerifyChecksum: true},
+		{Direction: RsyncDirectionBidirectional, VerifyAfterRun: true},
+		{Direction: RsyncDirectionRemote},
+	}
+	for _, config := range valid {
+		if err := config.ValidateVerify(); err != nil {
+			t.Errorf("%+v: %v", config, err)
+		}
+	}
+	invalid := []RsyncConfig{
+		{Direction: RsyncDirectionUpload, VerifyChecksum: true},
+		{Direction: RsyncDirectionRemote, VerifyAfterRun: true},
+		{Direction: RsyncDirectionDownload, SnapshotMode: true, VerifyAfterRun: true},
+	}
+	for _, config := range invalid {
+		if err := config.ValidateVerify(); err == nil {
+			t.Errorf("%+v 应该返回错误", config)
+		}
+	}
+
+	config := RsyncConfig{Direction: RsyncDirectionBidirectional, LocalPath: "/data/", RemotePath: "~/data/"}
+	local, remote, prefix, err := config.VerifyRoots()
+	if err != nil || local != "/data" || remote != "~/data" || prefix != "" {
+		t.Errorf("VerifyRoots = %s, %s, %s, %v", local, remote, prefix, err)
+	}
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// SplitShellWords 按 POSIX shell 规则把字符串拆分为参数
+// 支持单引号、双引号和反斜杠转义，不做变量展开和通配符匹配
+func SplitShellWords(s string) ([]string, error) {
+	var (
+		words   []string
+		current strings.Builder
+		inWord  bool
+	)
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+
+		case c == '\\':
+			if i+1 >= len(runes) {
+				return nil, errors.New("末尾存在未转义的反斜杠")
+			}
+			i++
+			// 反斜杠加换行是续行，不产生字符
+			if runes[i] != '\n' {
+				current.WriteRune(runes[i])
+			}
+			inWord = true
+
+		case c == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, errors.New("单引号未闭合")
+			}
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord = true
+
+		case c == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				// 双引号内反斜杠只转义 $ ` " \ 和换行
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("双引号未闭合")
+			}
+			inWord = true
+
+		default:
+			current.WriteRune(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+// shellSafeChars 不需要加引号的字符
+const shellSafeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./-_"
+
+// ShellQuote 为 POSIX shell 引用单个参数，不含特殊字符时原样返回
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, c := range s {
+		if !strings.ContainsRune(shellSafeChars, c) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ShellJoin 把参数列表拼接为可以直接粘贴到 shell 执行的命令
+func ShellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// RemoteShellQuote 为 rsync -e 的命令字符串引用单个参数
+// rsync 自己拆分 -e 的命令：以空格分隔，单引号内两个连续单引号表示一个单引号，不支持反斜杠转义
+func RemoteShellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// needsProtectArgs 远程路径是否包含会被远程 shell 拆分或解释的字符
+// 通配符和 ~ 不在其中，它们通常就是希望由远程展开的
+func needsProtectArgs(path string) bool {
+	return strings.ContainsAny(path, " \t\n'\"\\$`;&|<>()!#")
+}
+
+// safeLocalPath 避免本地路径被 rsync 误认为选项（以 - 开头）或远程路径（第一个 / 之前有 :）
+func safeLocalPath(path string) string {
+	if strings.HasPrefix(path, "-") {
+		return "./" + path
+	}
+	if colon := strings.Index(path, ":"); colon >= 0 {
+		if slash := strings.Index(path, "/"); slash < 0 || colon < slash {
+			return "./" + path
+		}
+	}
+	return path
+}
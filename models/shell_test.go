@@ -0,0 +1,142 @@
+package models
+
+import (
+	"math/rand"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// nastyChars 路径中容易出问题的字符
+var nastyChars = []rune(" \t\n'\"\\$`;&|<>()[]{}*?!#~=:-@%,.^/aZ9中文é")
+
+// nastyString 由 nastyChars 组成的随机字符串
+type nastyString string
+
+func (nastyString) Generate(r *rand.Rand, size int) reflect.Value {
+	n := r.Intn(size + 1)
+	runes := make([]rune, n)
+	for i := range runes {
+		runes[i] = nastyChars[r.Intn(len(nastyChars))]
+	}
+	return reflect.ValueOf(nastyString(runes))
+}
+
+// nastyArgs 随机参数列表
+type nastyArgs []string
+
+func (nastyArgs) Generate(r *rand.Rand, size int) reflect.Value {
+	args := make([]string, r.Intn(5)+1)
+	for i := range args {
+		args[i] = string(nastyString("").Generate(r, size).Interface().(nastyString))
+	}
+	return reflect.ValueOf(nastyArgs(args))
+}
+
+// splitRsyncRemoteShell 按 rsync do_cmd 的规则拆分 -e 的命令字符串
+func splitRsyncRemoteShell(cmd string) []string {
+	var args []string
+	f := []rune(cmd)
+	for i := 0; i < len(f); i++ {
+		if f[i] == ' ' {
+			continue
+		}
+		var arg []rune
+		var inQuote rune
+		for i < len(f) && (f[i] != ' ' || inQuote != 0) {
+			if f[i] == '\'' || f[i] == '"' {
+				if inQuote == 0 {
+					inQuote = f[i]
+					i++
+					continue
+				}
+				if f[i] == inQuote {
+					i++
+					if i >= len(f) || f[i] != inQuote {
+						inQuote = 0
+						continue
+					}
+				}
+			}
+			arg = append(arg, f[i])
+			i++
+		}
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+func TestShellJoinRoundTrip(t *testing.T) {
+	property := func(args nastyArgs) bool {
+		words, err := SplitShellWords(ShellJoin(args))
+		return err == nil && reflect.DeepEqual(words, []string(args))
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShellJoinWithSh(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("没有找到 sh")
+	}
+	property := func(args nastyArgs) bool {
+		out, err := exec.Command(sh, "-c", `printf '%s\0' `+ShellJoin(args)).Output()
+		if err != nil {
+			return false
+		}
+		got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+		return reflect.DeepEqual(got, []string(args))
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRemoteShellQuoteRoundTrip(t *testing.T) {
+	property := func(args nastyArgs) bool {
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = RemoteShellQuote(arg)
+		}
+		return reflect.DeepEqual(splitRsyncRemoteShell(strings.Join(quoted, " ")), []string(args))
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{``, nil},
+		{`  -a   -v `, []string{"-a", "-v"}},
+		{`--rsync-path="sudo rsync" --chmod=D755`, []string{"--rsync-path=sudo rsync", "--chmod=D755"}},
+		{`--filter='- *.tmp'`, []string{"--filter=- *.tmp"}},
+		{`a\ b "c\"d" 'e\f'`, []string{"a b", `c"d`, `e\f`}},
+		{`"$HOME" "a\b"`, []string{"$HOME", `a\b`}},
+		{`''`, []string{""}},
+		{"a\\\nb", []string{"ab"}},
+	}
+	for _, tt := range tests {
+		got, err := SplitShellWords(tt.input)
+		if err != nil {
+			t.Errorf("SplitShellWords(%q) 错误: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitShellWords(%q) = %q, 期望 %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{`"abc`, `'abc`, `abc\`} {
+		if _, err := SplitShellWords(input); err == nil {
+			t.Errorf("SplitShellWords(%q) 应该返回错误", input)
+		}
+	}
+}
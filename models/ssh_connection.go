@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"strings"
+
 	"gorm.io/gorm"
 )
 
@@ -14,7 +16,7 @@ const (
 
 type SSHConnection struct {
 	gorm.Model
-	Name         string       `gorm:"uniqueIndex;not null" json:"name"`
+	Name         string       `gorm:"uniqueIndex:idx_ssh_connections_active_name,where:deleted_at IS NULL;not null" json:"name"`
 	Address      string       `gorm:"not null" json:"address"`
 	Port         int          `gorm:"default:22" json:"port"`
 	Username     string       `gorm:"not null" json:"username"`
@@ -23,6 +25,7 @@ type SSHConnection struct {
 	KeyPath      string       `json:"key_path,omitempty"`
 	LocalIP      string       `json:"local_ip"`
 	Description  string       `json:"description"`
+	Tags         string       `json:"tags"` // 标签，以逗号或空格分隔，如 web, prod
 	UsageCount   int          `gorm:"default:0" json:"usage_count"`
 }
 
@@ -43,5 +46,56 @@ func (s *SSHConnection) GetVariables() map[string]string {
 		"ssh_key_path": s.KeyPath,
 		"ssh_local_ip": s.LocalIP,
 		"ssh_desc":     s.Description,
+		"ssh_tags":     strings.Join(s.TagList(), ","),
+	}
+}
+
+// TagList 连接的标签，去掉空白和重复的标签
+func (s *SSHConnection) TagList() []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(s.Tags, func(r rune) bool { return r == ',' || r == '，' || r == ' ' }) {
+		if !containsTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// HasTag 连接是否有指定的标签，不区分大小写
+func (s *SSHConnection) HasTag(tag string) bool {
+	return containsTag(s.TagList(), tag)
+}
+
+// NormalizeTags 整理输入的标签，去掉重复和空白，以 ", " 连接
+func NormalizeTags(tags string) string {
+	return strings.Join((&SSHConnection{Tags: tags}).TagList(), ", ")
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// SSHArgs 连接所需的 ssh 选项，不含 ssh 本身和目标主机
+func (s *SSHConnection) SSHArgs() []string {
+	args := []string{"-p", fmt.Sprintf("%d", s.Port)}
+	if s.PasswordType == PasswordTypeKeyPath && s.KeyPath != "" {
+		args = append(args, "-i", s.KeyPath)
 	}
+	return args
+}
+
+// Destination ssh 的目标主机 user@host
+func (s *SSHConnection) Destination() string {
+	return s.Username + "@" + s.Address
+}
+
+// RemoteCommand 生成在服务器上执行 command 的 ssh 命令，command 由远程用户的 shell 解释
+func (s *SSHConnection) RemoteCommand(command string) []string {
+	cmd := append([]string{"ssh"}, s.SSHArgs()...)
+	return append(cmd, s.Destination(), command)
 }
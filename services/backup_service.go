@@ -0,0 +1,476 @@
+package services
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	backupPrefix      = "connections-"
+	backupTimeLayout  = "20060102-150405"
+	defaultBackupKeep = 10
+)
+
+// BackupOptions 备份选项
+type BackupOptions struct {
+	Compress bool   // 使用 gzip 压缩备份文件
+	Keep     int    // 保留的备份数量，<=0 表示不轮转
+	Reason   string // 备份原因，会附加在文件名中，如 delete、restore
+}
+
+// BackupFile 备份文件信息
+type BackupFile struct {
+	Name       string
+	Path       string
+	Size       int64
+	ModTime    time.Time
+	Compressed bool
+}
+
+// EntityDiff 某类数据在恢复前后的差异
+type EntityDiff struct {
+	Entity  string
+	Added   []string // 备份中有、当前没有
+	Removed []string // 当前有、备份中没有
+	Changed []string // 两边都有但更新时间不同
+}
+
+// HasChanges 是否存在差异
+func (d EntityDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// RestorePreview 恢复预览
+type RestorePreview struct {
+	File  string
+	Diffs []EntityDiff
+}
+
+// BackupDatabase 使用 SQLite 的 VACUUM INTO 生成一致性的在线备份
+func BackupDatabase(opts BackupOptions) (string, error) {
+	db := database.GetDB()
+	if db == nil {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	backupDir := database.GetBackupDir()
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", fmt.Errorf("无法创建备份目录: %v", err)
+	}
+
+	dbFile := filepath.Join(backupDir, backupName(backupDir, opts.Reason, time.Now())+".db")
+
+	if err := db.Exec("VACUUM INTO ?", dbFile).Error; err != nil {
+		return "", fmt.Errorf("备份数据库失败: %v", err)
+	}
+
+	result := dbFile
+	if opts.Compress {
+		gzFile := dbFile + ".gz"
+		if err := gzipFile(dbFile, gzFile); err != nil {
+			os.Remove(dbFile)
+			return "", fmt.Errorf("压缩备份失败: %v", err)
+		}
+		os.Remove(dbFile)
+		result = gzFile
+	}
+
+	if opts.Keep > 0 {
+		if _, err := RotateBackups(opts.Keep); err != nil {
+			return result, fmt.Errorf("备份已创建，但轮转失败: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// backupName 备份文件名（不含扩展名），时间精确到毫秒
+// VACUUM INTO 不能覆盖已有文件，同一毫秒内已有备份时顺延，保证文件名唯一且按名称排序即为时间顺序
+func backupName(dir, reason string, now time.Time) string {
+	for {
+		stamp := backupPrefix + now.Format(backupTimeLayout) + fmt.Sprintf("-%03d", now.Nanosecond()/int(time.Millisecond))
+		if !backupExists(dir, stamp) {
+			if reason != "" {
+				return stamp + "-" + reason
+			}
+			return stamp
+		}
+		now = now.Add(time.Millisecond)
+	}
+}
+
+// backupExists 目录中是否已有以 stamp 开头的备份（不论原因和是否压缩）
+func backupExists(dir, stamp string) bool {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), stamp) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListBackups 列出所有备份，按时间从新到旧排序
+func ListBackups() ([]BackupFile, error) {
+	entries, err := os.ReadDir(database.GetBackupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []BackupFile{}, nil
+		}
+		return nil, fmt.Errorf("读取备份目录失败: %v", err)
+	}
+
+	var backups []BackupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) {
+			continue
+		}
+		if !strings.HasSuffix(name, ".db") && !strings.HasSuffix(name, ".db.gz") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupFile{
+			Name:       name,
+			Path:       filepath.Join(database.GetBackupDir(), name),
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			Compressed: strings.HasSuffix(name, ".gz"),
+		})
+	}
+
+	// 文件名以时间戳开头，按名称倒序即为从新到旧
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name > backups[j].Name
+	})
+	return backups, nil
+}
+
+// RotateBackups 只保留最新的 keep 个备份，返回被删除的文件
+func RotateBackups(keep int) ([]string, error) {
+	backups, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	if keep <= 0 || len(backups) <= keep {
+		return []string{}, nil
+	}
+
+	var removed []string
+	for _, backup := range backups[keep:] {
+		if err := os.Remove(backup.Path); err != nil {
+			return removed, fmt.Errorf("删除旧备份 %s 失败: %v", backup.Name, err)
+		}
+		removed = append(removed, backup.Name)
+	}
+	return removed, nil
+}
+
+// AutoBackup 在破坏性操作前自动备份
+// 通过环境变量 ALFRED_AUTO_BACKUP=1 开启，ALFRED_BACKUP_KEEP 控制保留数量（默认10）
+func AutoBackup(reason string) error {
+	enabled, _ := strconv.ParseBool(os.Getenv("ALFRED_AUTO_BACKUP"))
+	if !enabled {
+		return nil
+	}
+
+	keep := defaultBackupKeep
+	if value := os.Getenv("ALFRED_BACKUP_KEEP"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			keep = n
+		}
+	}
+
+	if _, err := BackupDatabase(BackupOptions{Compress: true, Keep: keep, Reason: reason}); err != nil {
+		return fmt.Errorf("自动备份失败: %v", err)
+	}
+	return nil
+}
+
+// PreviewRestore 校验备份文件并预览恢复后会发生的变化
+func PreviewRestore(file string) (*RestorePreview, error) {
+	dbFile, cleanup, err := prepareBackupFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	backupDB, err := openBackupDB(dbFile)
+	if err != nil {
+		return nil, err
+	}
+	defer closeGormDB(backupDB)
+
+	if err := checkIntegrity(backupDB); err != nil {
+		return nil, err
+	}
+
+	current, err := collectEntityVersions(database.GetDB())
+	if err != nil {
+		return nil, fmt.Errorf("读取当前数据失败: %v", err)
+	}
+	backup, err := collectEntityVersions(backupDB)
+	if err != nil {
+		return nil, fmt.Errorf("读取备份数据失败: %v", err)
+	}
+
+	preview := &RestorePreview{File: file}
+	for _, entity := range []string{"ssh", "rsync", "service"} {
+		preview.Diffs = append(preview.Diffs, diffEntityVersions(entity, current[entity], backup[entity]))
+	}
+	return preview, nil
+}
+
+// RestoreDatabase 用备份文件替换当前数据库，替换前会先备份当前数据库
+func RestoreDatabase(file string) (string, error) {
+	dbFile, cleanup, err := prepareBackupFile(file)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	backupDB, err := openBackupDB(dbFile)
+	if err != nil {
+		return "", err
+	}
+	err = checkIntegrity(backupDB)
+	closeGormDB(backupDB)
+	if err != nil {
+		return "", err
+	}
+
+	safetyBackup, err := BackupDatabase(BackupOptions{Compress: true, Reason: "pre-restore"})
+	if err != nil {
+		return "", fmt.Errorf("恢复前备份当前数据库失败: %v", err)
+	}
+
+	if err := database.CloseDB(); err != nil {
+		return safetyBackup, fmt.Errorf("关闭数据库失败: %v", err)
+	}
+
+	// 先写入临时文件再重命名，避免复制中断导致数据库损坏
+	tmpFile := database.DBPath + ".restoring"
+	if err := copyFile(dbFile, tmpFile); err != nil {
+		os.Remove(tmpFile)
+		database.InitDB()
+		return safetyBackup, fmt.Errorf("复制备份文件失败: %v", err)
+	}
+	os.Remove(database.DBPath + "-wal")
+	os.Remove(database.DBPath + "-shm")
+	if err := os.Rename(tmpFile, database.DBPath); err != nil {
+		os.Remove(tmpFile)
+		database.InitDB()
+		return safetyBackup, fmt.Errorf("替换数据库文件失败: %v", err)
+	}
+
+	database.InitDB()
+	return safetyBackup, nil
+}
+
+// ResolveBackupFile 支持传入完整路径或备份目录中的文件名
+func ResolveBackupFile(file string) (string, error) {
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+	candidate := filepath.Join(database.GetBackupDir(), file)
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate, nil
+	}
+	return "", fmt.Errorf("备份文件不存在: %s", file)
+}
+
+// prepareBackupFile 返回可直接打开的 SQLite 文件路径，压缩备份会解压到临时文件
+func prepareBackupFile(file string) (string, func(), error) {
+	path, err := ResolveBackupFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return path, func() {}, nil
+	}
+
+	tmp, err := os.CreateTemp("", "alfred-tool-restore-*.db")
+	if err != nil {
+		return "", nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmp.Close()
+	cleanup := func() { os.Remove(tmp.Name()) }
+
+	if err := gunzipFile(path, tmp.Name()); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("解压备份失败: %v", err)
+	}
+	return tmp.Name(), cleanup, nil
+}
+
+func openBackupDB(path string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("打开备份文件失败: %v", err)
+	}
+	return db, nil
+}
+
+func closeGormDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+func checkIntegrity(db *gorm.DB) error {
+	var results []string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+		return fmt.Errorf("完整性检查失败: %v", err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("备份文件已损坏: %s", strings.Join(results, "; "))
+	}
+	return nil
+}
+
+// collectEntityVersions 收集各类数据的 名称 -> 更新时间
+func collectEntityVersions(db *gorm.DB) (map[string]map[string]time.Time, error) {
+	result := map[string]map[string]time.Time{
+		"ssh":     {},
+		"rsync":   {},
+		"service": {},
+	}
+
+	if db.Migrator().HasTable(&models.SSHConnection{}) {
+		var connections []models.SSHConnection
+		if err := db.Find(&connections).Error; err != nil {
+			return nil, err
+		}
+		for _, conn := range connections {
+			result["ssh"][conn.Name] = conn.UpdatedAt
+		}
+	}
+
+	if db.Migrator().HasTable(&models.RsyncConfig{}) {
+		var configs []models.RsyncConfig
+		if err := db.Find(&configs).Error; err != nil {
+			return nil, err
+		}
+		for _, config := range configs {
+			result["rsync"][config.Name] = config.UpdatedAt
+		}
+	}
+
+	if db.Migrator().HasTable(&models.Service{}) {
+		var serviceList []models.Service
+		if err := db.Find(&serviceList).Error; err != nil {
+			return nil, err
+		}
+		for _, service := range serviceList {
+			result["service"][service.Name] = service.UpdatedAt
+		}
+	}
+
+	return result, nil
+}
+
+func diffEntityVersions(entity string, current, backup map[string]time.Time) EntityDiff {
+	diff := EntityDiff{Entity: entity}
+	for name, updatedAt := range backup {
+		currentUpdatedAt, ok := current[name]
+		if !ok {
+			diff.Added = append(diff.Added, name)
+		} else if !currentUpdatedAt.Equal(updatedAt) {
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range current {
+		if _, ok := backup[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		gz.Close()
+		out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func gunzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, gz); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
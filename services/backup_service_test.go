@@ -0,0 +1,152 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+)
+
+func TestBackupNameUnique(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 123*int(time.Millisecond), time.Local)
+
+	first := backupName(dir, "", now)
+	if first != "connections-20240101-120000-123" {
+		t.Errorf("backupName() = %s", first)
+	}
+	if err := os.WriteFile(filepath.Join(dir, first+".db.gz"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一毫秒内已有备份（不论原因和是否压缩）时顺延
+	second := backupName(dir, "delete", now)
+	if second != "connections-20240101-120000-124-delete" {
+		t.Errorf("同一毫秒的第二个备份 = %s", second)
+	}
+	if second <= first {
+		t.Errorf("顺延后的备份 %s 应排在 %s 之后", second, first)
+	}
+}
+
+func TestBackupDatabaseTwice(t *testing.T) {
+	setupTestDB(t)
+
+	first, err := BackupDatabase(BackupOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := BackupDatabase(BackupOptions{})
+	if err != nil {
+		t.Fatalf("连续备份失败: %v", err)
+	}
+	if first == second {
+		t.Errorf("连续备份的文件名相同: %s", first)
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	setupTestDB(t)
+	dir := database.GetBackupDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{
+		"connections-20240101-120000-000.db",
+		"connections-20240102-120000-000-delete.db.gz",
+		"connections-20240103-120000-000.db.gz",
+		"notes.txt",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := RotateBackups(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"connections-20240101-120000-000.db"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("RotateBackups() 删除了 %v，期望 %v", removed, want)
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, backup := range backups {
+		kept = append(kept, backup.Name)
+	}
+	if want := []string{"connections-20240103-120000-000.db.gz", "connections-20240102-120000-000-delete.db.gz"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("保留的备份 = %v，期望 %v", kept, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("轮转不应删除其他文件")
+	}
+
+	if removed, _ := RotateBackups(0); len(removed) != 0 {
+		t.Errorf("keep <= 0 时不应删除备份: %v", removed)
+	}
+}
+
+func TestRestoreDatabase(t *testing.T) {
+	setupTestDB(t)
+	db := database.GetDB()
+	if err := db.Create(&models.SSHConnection{Name: "web", Address: "10.0.0.1", Port: 22, Username: "root"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := BackupDatabase(BackupOptions{Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteConnection("web", DeleteConnectionOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.GetDB().Create(&models.SSHConnection{Name: "db", Address: "10.0.0.2", Port: 22, Username: "root"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	preview, err := PreviewRestore(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := preview.Diffs[0]; !reflect.DeepEqual(diff.Added, []string{"web"}) || !reflect.DeepEqual(diff.Removed, []string{"db"}) {
+		t.Errorf("恢复预览 = %+v，期望新增 web、移除 db", diff)
+	}
+
+	safetyBackup, err := RestoreDatabase(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(safetyBackup); err != nil {
+		t.Errorf("恢复前应备份当前数据库: %v", err)
+	}
+	if _, err := GetConnectionByName("web"); err != nil {
+		t.Errorf("恢复后应有连接 web: %v", err)
+	}
+	if _, err := GetConnectionByName("db"); err == nil {
+		t.Error("恢复后不应有备份之后新建的连接 db")
+	}
+}
+
+func TestRestoreDatabaseCorrupted(t *testing.T) {
+	setupTestDB(t)
+	corrupted := filepath.Join(t.TempDir(), "connections-20240101-120000-000.db")
+	if err := os.WriteFile(corrupted, []byte("not a database"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestoreDatabase(corrupted); err == nil {
+		t.Error("损坏的备份不应恢复")
+	}
+	if backups, _ := ListBackups(); len(backups) != 0 {
+		t.Errorf("校验失败时不应备份当前数据库: %v", backups)
+	}
+}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"alfred-tool/database"
+	"alfred-tool/models"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// bisyncState 一次双向同步所需的两端状态和计划
+type bisyncState struct {
+	config      *models.RsyncConfig
+	sshConn     *models.SSHConnection
+	ignoreRules []models.FilterRule
+	matcher     *models.FilterMatcher
+	manifest    *models.SyncManifest
+	local       map[string]models.SyncFileState
+	remote      map[string]models.SyncFileState
+	plan        models.SyncPlan
+}
+
+// PreviewBidirectionalSync 列出两端的变化，返回双向同步的计划，不修改任何文件
+func PreviewBidirectionalSync(configName string, vars map[string]string) (*models.SyncPlan, error) {
+	config, sshConn, err := loadRsyncConfigWithConnection(configName, vars)
+	if err != nil {
+		return nil, err
+	}
+	if config.Direction != models.RsyncDirectionBidirectional {
+		return nil, fmt.Errorf("rsync配置 '%s' 不是双向同步配置", configName)
+	}
+	state, err := planBidirectionalSync(config, sshConn)
+	if err != nil {
+		return nil, err
+	}
+	return &state.plan, nil
+}
+
+// planBidirectionalSync 读取上一次同步的状态，列出两端的文件并生成计划
+func planBidirectionalSync(config *models.RsyncConfig, sshConn *models.SSHConnection) (*bisyncState, error) {
+	if err := config.ValidateFilters(); err != nil {
+		return nil, err
+	}
+	if err := config.ValidateBidirectional(); err != nil {
+		return nil, err
+	}
+	policy, _ := models.ParseConflictPolicy(string(config.ConflictPolicy))
+
+	state := &bisyncState{config: config, sshConn: sshConn}
+	var err error
+	if state.ignoreRules, err = RsyncIgnoreRules(config); err != nil {
+		return nil, err
+	}
+	if state.matcher, err = RsyncFilterMatcher(config); err != nil {
+		return nil, err
+	}
+	if state.manifest, err = loadSyncManifest(config); err != nil {
+		return nil, err
+	}
+	if err := state.list(); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateSyncSides(state.manifest.Files, state.local, state.remote); err != nil {
+		return nil, err
+	}
+	state.plan = models.PlanBidirectionalSync(state.manifest.Files, state.local, state.remote, policy)
+
+	// 大小相同而修改时间不同的冲突，比较两端内容，相同的不算冲突
+	var candidates []string
+	for _, c := range state.plan.Conflicts {
+		if c.Local != nil && c.Remote != nil && c.Local.Size == c.Remote.Size {
+			candidates = append(candidates, c.Path)
+		}
+	}
+	if len(candidates) > 0 {
+		remoteHashes, err := remoteFileHashes(sshConn, config.RemotePath, candidates)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range candidates {
+			l := state.local[p]
+			if l.Hash == "" {
+				if l.Hash, err = fileHash(filepath.Join(config.LocalPath, filepath.FromSlash(p))); err != nil {
+					return nil, err
+				}
+				state.local[p] = l
+			}
+			r := state.remote[p]
+			r.Hash = remoteHashes[p]
+			state.remote[p] = r
+		}
+		state.plan = models.PlanBidirectionalSync(state.manifest.Files, state.local, state.remote, policy)
+	}
+	return state, nil
+}
+
+// list 列出两端当前的文件
+func (s *bisyncState) list() error {
+	var err error
+	if s.local, err = listLocalFiles(s.config, s.matcher, s.manifest.Files); err != nil {
+		return err
+	}
+	s.remote, err = listRemoteFiles(s.config, s.sshConn, s.ignoreRules)
+	return err
+}
+
+// runBidirectionalSync 执行双向同步：先下载服务器的变化（包括删除本地文件），再上传本地的变化，
+// 成功后重新列出两端的文件保存为下一次同步的基准，调用方需持有配置的执行锁
+func runBidirectionalSync(config *models.RsyncConfig, sshConn *models.SSHConnection, opts RsyncRunOptions) (*models.RsyncResult, error) {
+	state, err := planBidirectionalSync(config, sshConn)
+	if err != nil {
+		return nil, err
+	}
+
+	listDir, err := os.MkdirTemp("", "alfred-tool-bisync")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(listDir)
+
+	// 保留两个版本时，本地版本先改名为冲突副本，再作为新文件上传
+	now := time.Now()
+	var pull, push []string
+	renames := make(map[string]string)
+	for _, action := range state.plan.Actions {
+		switch action.Type {
+		case models.SyncActionPull, models.SyncActionDeleteLocal:
+			pull = append(pull, action.Path)
+		case models.SyncActionPush, models.SyncActionDeleteRemote:
+			push = append(push, action.Path)
+		case models.SyncActionKeepBoth:
+			copyName := models.ConflictCopyName(action.Path, now)
+			renames[action.Path] = copyName
+			pull = append(pull, action.Path)
+			push = append(push, copyName)
+		}
+	}
+	pullList, pushList := filepath.Join(listDir, "pull"), filepath.Join(listDir, "push")
+	if err := writeFileList(pullList, pull); err != nil {
+		return nil, err
+	}
+	if err := writeFileList(pushList, push); err != nil {
+		return nil, err
+	}
+	pullArgs := localRsyncArgs(config.BuildBisyncCommand(sshConn, models.RsyncDirectionDownload, pullList, state.ignoreRules), sshConn)
+	pushArgs := localRsyncArgs(config.BuildBisyncCommand(sshConn, models.RsyncDirectionUpload, pushList, state.ignoreRules), sshConn)
+
+	// 执行记录中的命令为第一条需要执行的rsync命令，第二条写在日志中
+	steps := [][]string{}
+	if len(pull) > 0 {
+		steps = append(steps, pullArgs)
+	}
+	if len(push) > 0 {
+		steps = append(steps, pushArgs)
+	}
+	firstArgs := pullArgs
+	if len(steps) > 0 {
+		firstArgs = steps[0]
+	}
+
+	execution, err := startRsyncExecution(config, sshConn, firstArgs, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer execution.close()
+
+	for i := range state.plan.Conflicts {
+		execution.handler(models.RsyncEvent{Type: models.RsyncEventConflict, Conflict: &state.plan.Conflicts[i]})
+	}
+
+	if err := execution.runHooks(models.RsyncHookPre, nil); err != nil {
+		return execution.finish(models.RsyncResult{}, models.RsyncRunExitCodeHookFailed, err)
+	}
+
+	// 计划中没有删除时不需要检查；一端为空已在生成计划时拒绝
+	if counts := state.plan.DeleteGuardCounts(state.local, state.remote); config.DeleteGuardEnabled() && counts.Deleted > 0 {
+		if err := execution.checkDeleteGuard(counts, opts.Force); err != nil {
+			return execution.finish(models.RsyncResult{}, models.RsyncRunExitCodeDeleteGuard, err)
+		}
+	}
+
+	if err := os.MkdirAll(config.LocalPath, 0755); err != nil {
+		return execution.finish(models.RsyncResult{}, models.RsyncRunExitCodeNotStarted, fmt.Errorf("创建本地路径失败: %v", err))
+	}
+	for from, to := range renames {
+		if err := os.Rename(filepath.Join(config.LocalPath, from), filepath.Join(config.LocalPath, to)); err != nil {
+			return execution.finish(models.RsyncResult{}, models.RsyncRunExitCodeNotStarted, fmt.Errorf("保存冲突副本失败: %v", err))
+		}
+	}
+
+	var result models.RsyncResult
+	for i, args := range steps {
+		if i > 0 {
+			execution.cmdArgs = withOutputOptions(args)
+			fmt.Fprintf(execution.logFile, "$ %s\n", models.ShellJoin(execution.cmdArgs))
+		}
+		stepResult, exitCode, err := execution.rsync()
+		result.Add(stepResult)
+		if err != nil {
+			result.Error = stepResult.Error
+			return execution.finish(result, exitCode, err)
+		}
+	}
+
+	if err := state.saveManifest(now); err != nil {
+		return execution.finish(result, 0, err)
+	}
+	result.Conflicts = len(state.plan.Unresolved())
+
+	if err := execution.runHooks(models.RsyncHookPost, &result); err != nil {
+		return execution.finish(result, models.RsyncRunExitCodeHookFailed, err)
+	}
+	if config.VerifyAfterRun && !config.DryRun {
+		result.Verify = execution.verify()
+	}
+	final, err := execution.finish(result, 0, nil)
+	if err != nil {
+		return final, err
+	}
+	return final, incrementRsyncUsage(config, sshConn)
+}
+
+// saveManifest 同步后重新列出两端的文件，两端一致的文件保存为下一次同步的基准，没有解决的冲突保留原来的基准
+func (s *bisyncState) saveManifest(syncedAt time.Time) error {
+	unresolved := make(map[string]bool)
+	for _, c := range s.plan.Unresolved() {
+		unresolved[c.Path] = true
+	}
+
+	base := s.manifest.Files
+	if err := s.list(); err != nil {
+		return fmt.Errorf("同步后列出文件失败: %v", err)
+	}
+
+	files := make(map[string]models.SyncManifestEntry)
+	for p, l := range s.local {
+		if unresolved[p] {
+			continue
+		}
+		r, ok := s.remote[p]
+		if !ok || r.Size != l.Size {
+			continue
+		}
+		entry := models.SyncManifestEntry{Size: l.Size, LocalModTime: l.ModTime, RemoteModTime: r.ModTime, Hash: l.Hash}
+		if old, ok := base[p]; ok && old.Size == l.Size && old.LocalModTime == l.ModTime && entry.Hash == "" {
+			entry.Hash = old.Hash
+		}
+		if entry.Hash == "" {
+			hash, err := fileHash(filepath.Join(s.config.LocalPath, filepath.FromSlash(p)))
+			if err != nil {
+				return err
+			}
+			entry.Hash = hash
+		}
+		files[p] = entry
+	}
+	for p := range unresolved {
+		if entry, ok := base[p]; ok {
+			files[p] = entry
+		}
+	}
+
+	manifest := &models.SyncManifest{
+		ConfigID:   s.config.ID,
+		SSHName:    s.config.SSHName,
+		LocalPath:  s.config.LocalPath,
+		RemotePath: s.config.RemotePath,
+		SyncedAt:   syncedAt,
+		Files:      files,
+	}
+	return saveSyncManifest(manifest)
+}
+
+// syncManifestPath 配置的双向同步状态文件
+func syncManifestPath(config *models.RsyncConfig) string {
+	return filepath.Join(database.GetStateDir(), fmt.Sprintf("rsync-%d.json", config.ID))
+}
+
+// loadSyncManifest 读取上一次同步的状态，没有同步过或两端路径已变化时返回空的状态
+func loadSyncManifest(config *models.RsyncConfig) (*models.SyncManifest, error) {
+	empty := &models.SyncManifest{Files: map[string]models.SyncManifestEntry{}}
+	data, err := os.ReadFile(syncManifestPath(config))
+	if os.IsNotExist(err) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取同步状态失败: %v", err)
+	}
+
+	var manifest models.SyncManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("同步状态文件已损坏: %v", err)
+	}
+	if !manifest.Matches(config) || manifest.Files == nil {
+		return empty, nil
+	}
+	return &manifest, nil
+}
+
+// saveSyncManifest 先写入临时文件再替换，避免中断时留下不完整的状态
+func saveSyncManifest(manifest *models.SyncManifest) error {
+	dir := database.GetStateDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建同步状态目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化同步状态失败: %v", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("rsync-%d.json", manifest.ConfigID))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("保存同步状态失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("保存同步状态失败: %v", err)
+	}
+	return nil
+}
+
+// listLocalFiles 列出本地路径中没有被排除的普通文件，修改时间变化而大小不变的文件计算哈希
+func listLocalFiles(config *models.RsyncConfig, matcher *models.FilterMatcher, base map[string]models.SyncManifestEntry) (map[string]models.SyncFileState, error) {
+	files := make(map[string]models.SyncFileState)
+	root := filepath.Clean(config.LocalPath)
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return files, nil
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("读取 '%s' 失败: %v", path, err)
+		}
+		rel, ok := config.TransferPath(path)
+		if !ok || rel == "" {
+			return nil
+		}
+		if matcher.Excluded(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("读取 '%s' 失败: %v", path, err)
+		}
+		state := models.SyncFileState{Size: info.Size(), ModTime: info.ModTime().Unix()}
+		if entry, ok := base[rel]; ok && entry.Size == state.Size && entry.LocalModTime != state.ModTime && entry.Hash != "" {
+			if state.Hash, err = fileHash(path); err != nil {
+				return err
+			}
+		}
+		files[rel] = state
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// listRemoteFiles 用 rsync --list-only 列出远程路径中的普通文件，远程路径不存在时返回空
+func listRemoteFiles(config *models.RsyncConfig, sshConn *models.SSHConnection, ignoreRules []models.FilterRule) (map[string]models.SyncFileState, error) {
+	cmdArgs := localRsyncArgs(config.BuildListCommand(sshConn, ignoreRules), sshConn)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "No such file or directory") {
+			return map[string]models.SyncFileState{}, nil
+		}
+		return nil, fmt.Errorf("列出远程文件失败: %v\n%s", err, msg)
+	}
+	return models.ParseListOnly(stdout.String(), time.Local), nil
+}
+
+// remoteFileHashes 在服务器上计算 dir 中文件的 sha256，键为相对路径，不存在的文件没有结果
+func remoteFileHashes(sshConn *models.SSHConnection, dir string, paths []string) (map[string]string, error) {
+	input, err := models.ChecksumInput(paths)
+	if err != nil {
+		return nil, err
+	}
+	cmdArgs := models.BuildRemoteChecksumCommand(sshConn, dir)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// 有文件不存在时退出码不为 0，以输出为准
+	if err := cmd.Run(); err != nil && stdout.Len() == 0 && len(paths) > 0 {
+		return nil, fmt.Errorf("计算服务器上的校验和失败: %v\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	return models.ParseChecksumList(stdout.String())
+}
+
+// fileHash 计算本地文件的 sha256
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("读取 '%s' 失败: %v", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("读取 '%s' 失败: %v", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeFileList 写入 --files-from 使用的文件列表，以 NUL 分隔（--from0）
+func writeFileList(path string, files []string) error {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f)
+		b.WriteByte(0)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("写入文件列表失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"alfred-tool/database"
+	"alfred-tool/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GetAllConfigTemplates 获取所有配置模板
+func GetAllConfigTemplates() ([]models.RsyncConfigTemplate, error) {
+	var templates []models.RsyncConfigTemplate
+	db := database.GetDB()
+	err := db.Order("usage_count DESC, name").Find(&templates).Error
+	return templates, err
+}
+
+// GetConfigTemplateByName 根据名称获取配置模板
+func GetConfigTemplateByName(name string) (*models.RsyncConfigTemplate, error) {
+	var template models.RsyncConfigTemplate
+	db := database.GetDB()
+	if err := db.Where("name = ?", name).First(&template).Error; err != nil {
+		return nil, fmt.Errorf("配置模板 '%s' 不存在", name)
+	}
+	return &template, nil
+}
+
+// ValidateConfigTemplate 验证配置模板的名称、内容和生成配置的名称
+func ValidateConfigTemplate(template *models.RsyncConfigTemplate) error {
+	if strings.TrimSpace(template.Name) == "" {
+		return errors.New("模板名称不能为空")
+	}
+	if err := models.ValidateConfigTemplate(template.Config); err != nil {
+		return err
+	}
+	if template.NamePattern != "" {
+		example := models.GenerateNameVariables(template.Name, &models.SSHConnection{Name: "example"})
+		if _, err := models.RenderNamePattern(template.NamePattern, example); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveConfigTemplate 保存配置模板，同名的模板已存在时覆盖其内容
+func SaveConfigTemplate(template *models.RsyncConfigTemplate) error {
+	if err := ValidateConfigTemplate(template); err != nil {
+		return err
+	}
+	db := database.GetDB()
+	existing, err := GetConfigTemplateByName(template.Name)
+	if err != nil {
+		return db.Create(template).Error
+	}
+	template.ID = existing.ID
+	template.CreatedAt = existing.CreatedAt
+	template.UsageCount = existing.UsageCount
+	return db.Save(template).Error
+}
+
+// CreateConfigTemplateFromConfig 以已有的rsync配置为基础保存模板，名称和SSH连接不保存在模板中
+func CreateConfigTemplateFromConfig(configName string, template *models.RsyncConfigTemplate) error {
+	config, err := GetRsyncConfigByName(configName)
+	if err != nil {
+		return fmt.Errorf("rsync配置 '%s' 不存在", configName)
+	}
+	if template.Config, err = models.NewRsyncConfigTemplateJSON(config); err != nil {
+		return err
+	}
+	return SaveConfigTemplate(template)
+}
+
+// DeleteConfigTemplate 删除配置模板，已生成的配置不受影响
+func DeleteConfigTemplate(name string) error {
+	template, err := GetConfigTemplateByName(name)
+	if err != nil {
+		return err
+	}
+	db := database.GetDB()
+	return db.Delete(template).Error
+}
+
+// CloneRsyncConfig 复制rsync配置，sshName 不为空时新配置使用该SSH连接
+// 执行记录、快照、双向同步的状态和所属分组不会复制，新配置的定时执行处于暂停状态以免与原配置同时执行
+func CloneRsyncConfig(name, newName, sshName string) (*models.RsyncConfig, error) {
+	config, err := GetRsyncConfigByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("rsync配置 '%s' 不存在", name)
+	}
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return nil, errors.New("新配置名称不能为空")
+	}
+	if _, err := GetRsyncConfigByName(newName); err == nil {
+		return nil, fmt.Errorf("rsync配置 '%s' 已存在", newName)
+	}
+
+	clone := *config
+	clone.Model = gorm.Model{}
+	clone.Name = newName
+	clone.UsageCount = 0
+	clone.SchedulePaused = clone.Schedule != ""
+	if sshName = strings.TrimSpace(sshName); sshName != "" {
+		clone.SSHName = sshName
+	}
+	if err := ValidateRsyncConfig(&clone); err != nil {
+		return nil, err
+	}
+	if err := CreateRsyncConfig(&clone); err != nil {
+		return nil, fmt.Errorf("保存rsync配置失败: %v", err)
+	}
+	return &clone, nil
+}
+
+// GeneratedRsyncConfig 批量生成的一个配置
+type GeneratedRsyncConfig struct {
+	Config *models.RsyncConfig `json:"config"`
+	Exists bool                `json:"exists"`          // 同名配置已存在，不会覆盖
+	Error  string              `json:"error,omitempty"` // 配置无效，不会创建
+}
+
+// GeneratePlan 按模板为匹配的SSH连接生成配置的预览
+type GeneratePlan struct {
+	Template    string                 `json:"template"`
+	Hosts       string                 `json:"hosts"`
+	NamePattern string                 `json:"name_pattern"`
+	Configs     []GeneratedRsyncConfig `json:"configs"`
+}
+
+// Creatable 将要创建的配置数
+func (p *GeneratePlan) Creatable() int {
+	count := 0
+	for _, generated := range p.Configs {
+		if !generated.Exists && generated.Error == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// PlanRsyncGenerate 为每个匹配 hosts 的SSH连接按模板生成一个配置，只返回预览，不写入数据库
+// namePattern 为空时使用模板中的名称，模板中也没有时使用 DefaultGenerateNamePattern
+func PlanRsyncGenerate(templateName, hosts, namePattern string) (*GeneratePlan, error) {
+	template, err := GetConfigTemplateByName(templateName)
+	if err != nil {
+		return nil, err
+	}
+	if namePattern == "" {
+		namePattern = template.NamePattern
+	}
+	if namePattern == "" {
+		namePattern = models.DefaultGenerateNamePattern
+	}
+
+	connections, err := ListAllConnections()
+	if err != nil {
+		return nil, err
+	}
+	matched, err := models.MatchHosts(connections, hosts)
+	if err != nil {
+		return nil, err
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("没有匹配 '%s' 的SSH连接", hosts)
+	}
+
+	plan := &GeneratePlan{Template: template.Name, Hosts: hosts, NamePattern: namePattern}
+	names := make(map[string]bool)
+	for i := range matched {
+		conn := &matched[i]
+		name, err := models.RenderNamePattern(namePattern, models.GenerateNameVariables(template.Name, conn))
+		if err != nil {
+			return nil, err
+		}
+
+		config := &models.RsyncConfig{Name: name, SSHName: conn.Name}
+		if err := template.Apply(config); err != nil {
+			return nil, fmt.Errorf("模板 '%s' 错误: %v", template.Name, err)
+		}
+		config.Name, config.SSHName = name, conn.Name
+
+		generated := GeneratedRsyncConfig{Config: config}
+		_, err = GetRsyncConfigByName(name)
+		generated.Exists = err == nil
+		switch {
+		case names[name]:
+			generated.Error = "与前面生成的配置重名，请在名称中使用 {{ssh.name}}"
+		case name == "":
+			generated.Error = "名称为空"
+		case config.RemotePath == "" || (config.LocalPath == "" && config.Direction != models.RsyncDirectionRemote):
+			generated.Error = "模板中没有设置本地路径或远程路径"
+		default:
+			if err := ValidateRsyncConfig(config); err != nil {
+				generated.Error = err.Error()
+			}
+		}
+		names[name] = true
+		plan.Configs = append(plan.Configs, generated)
+	}
+	return plan, nil
+}
+
+// ApplyRsyncGenerate 在同一事务中创建预览中可以创建的配置，返回创建的配置数
+func ApplyRsyncGenerate(plan *GeneratePlan) (int, error) {
+	created := 0
+	db := database.GetDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, generated := range plan.Configs {
+			if generated.Exists || generated.Error != "" {
+				continue
+			}
+			if err := tx.Create(generated.Config).Error; err != nil {
+				return fmt.Errorf("保存rsync配置 '%s' 失败: %v", generated.Config.Name, err)
+			}
+			created++
+		}
+		if created == 0 {
+			return nil
+		}
+		return tx.Model(&models.RsyncConfigTemplate{}).Where("name = ?", plan.Template).
+			UpdateColumn("usage_count", gorm.Expr("usage_count + 1")).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
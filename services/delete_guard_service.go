@@ -0,0 +1,79 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// guardDeletes 启用删除时在传输前试运行，统计将要删除的文件，超过上限且没有 force 时返回错误
+// 源目录为空或不存在时无论 force 都拒绝执行；检查结果记录在执行记录和日志中，并发送删除保护事件
+func (e *rsyncExecution) guardDeletes(force bool) error {
+	if !e.config.DeleteGuardEnabled() {
+		return nil
+	}
+
+	counts, err := e.countDeletes()
+	if err != nil {
+		e.run.DeleteGuard = "已中止: " + err.Error()
+		fmt.Fprintf(e.logFile, "删除保护: %s\n", e.run.DeleteGuard)
+		return fmt.Errorf("删除保护: %v", err)
+	}
+
+	return e.checkDeleteGuard(counts, force)
+}
+
+// checkDeleteGuard 按配置的上限判断统计结果，记录在执行记录和日志中，并发送删除保护事件
+func (e *rsyncExecution) checkDeleteGuard(counts models.DeleteGuardCounts, force bool) error {
+	maxCount, maxPercent, percentMinDeletes := e.config.DeleteGuardLimits()
+	decision := models.EvaluateDeleteGuard(counts, maxCount, maxPercent, percentMinDeletes, force)
+	e.run.DeleteGuard = decision.String()
+	fmt.Fprintf(e.logFile, "删除保护: %s\n", decision)
+	e.handler(models.RsyncEvent{Type: models.RsyncEventDeleteGuard, DeleteGuard: &decision})
+
+	switch {
+	case decision.Allowed:
+		return nil
+	case counts.Source == 0:
+		return fmt.Errorf("删除保护: %s", decision.Reason)
+	}
+	return fmt.Errorf("删除保护: %s，确认无误后使用 --force 执行", decision.Reason)
+}
+
+// countDeletes 试运行本次传输，统计源和目标中的文件数和将要删除的数量，不会修改任何文件
+func (e *rsyncExecution) countDeletes() (models.DeleteGuardCounts, error) {
+	if e.config.Direction == models.RsyncDirectionUpload {
+		info, err := os.Stat(e.config.LocalPath)
+		if err != nil {
+			return models.DeleteGuardCounts{}, fmt.Errorf("源目录 '%s' 不存在", e.config.LocalPath)
+		}
+		if !info.IsDir() {
+			return models.DeleteGuardCounts{}, fmt.Errorf("源路径 '%s' 不是目录", e.config.LocalPath)
+		}
+	}
+
+	if e.engine.Engine == models.TransferEngineSFTP {
+		t, err := planSFTPTransfer(e.config, e.sshConn)
+		if err != nil {
+			return models.DeleteGuardCounts{}, err
+		}
+		return t.deleteCounts, nil
+	}
+
+	cmdArgs := models.InsertRsyncOptions(e.cmdArgs, "--dry-run", "-ii")
+	fmt.Fprintf(e.logFile, "$ %s\n", models.ShellJoin(cmdArgs))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		fmt.Fprintln(e.logFile, msg)
+		return models.DeleteGuardCounts{}, fmt.Errorf("试运行失败，无法确认源目录: %v %s", err, msg)
+	}
+	return models.CountDeleteGuardItems(stdout.String()), nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// mergeFileMaxDepth 合并文件中还可以合并其他文件，限制层数避免循环
+const mergeFileMaxDepth = 5
+
+// RsyncFilterCheck 判断一个路径是否会被传输的结果
+type RsyncFilterCheck struct {
+	Path      string              // 相对传输根目录的路径
+	IsDir     bool                // 路径是否为目录
+	Transfer  models.FilterResult // 发送端的判断结果
+	Protected models.FilterResult // 接收端的判断结果，被排除或保护时不会被 --delete 删除
+}
+
+// RsyncIgnoreRules 开启忽略文件时，读取本地路径中各级目录的 .gitignore 和 .rsyncignore 并转换为过滤规则
+func RsyncIgnoreRules(config *models.RsyncConfig) ([]models.FilterRule, error) {
+	if !config.UseIgnoreFiles {
+		return nil, nil
+	}
+	base, err := expandMergeRules(config, config.OrderedFilterRules(nil), 0)
+	if err != nil {
+		return nil, err
+	}
+	return collectIgnoreRules(config, base)
+}
+
+// RsyncFilterMatcher 编译配置的全部规则（包括合并文件和忽略文件中的规则）
+func RsyncFilterMatcher(config *models.RsyncConfig) (*models.FilterMatcher, error) {
+	if err := config.ValidateFilters(); err != nil {
+		return nil, err
+	}
+	rules, err := expandMergeRules(config, config.OrderedFilterRules(nil), 0)
+	if err != nil {
+		return nil, err
+	}
+	if config.UseIgnoreFiles {
+		ignore, err := collectIgnoreRules(config, rules)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, ignore...)
+	}
+	return models.NewFilterMatcher(rules)
+}
+
+// TestRsyncFilter 判断配置是否会传输给定的路径
+// 路径可以是本地路径中的文件，也可以是相对于本地路径的路径，以 / 结尾表示目录
+func TestRsyncFilter(configName, target string) (*RsyncFilterCheck, error) {
+	config, _, err := loadRsyncConfigWithConnection(configName, nil)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := RsyncFilterMatcher(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// 服务器之间传输时路径相对于源服务器上的远程路径，只能按结尾的 / 判断是否为目录
+	remote := config.Direction == models.RsyncDirectionRemote
+	if remote {
+		source := *config
+		source.LocalPath = config.RemotePath
+		config = &source
+	}
+
+	local := target
+	if !filepath.IsAbs(local) {
+		local = filepath.Join(config.LocalPath, target)
+	}
+	rel, ok := config.TransferPath(local)
+	if !ok || rel == "" {
+		if remote {
+			return nil, fmt.Errorf("路径 '%s' 不在源路径 '%s' 中", target, config.LocalPath)
+		}
+		return nil, fmt.Errorf("路径 '%s' 不在本地路径 '%s' 中", target, config.LocalPath)
+	}
+
+	check := &RsyncFilterCheck{Path: rel, IsDir: strings.HasSuffix(target, "/")}
+	if info, err := os.Stat(local); err == nil && !remote {
+		check.IsDir = info.IsDir()
+	}
+	check.Transfer = matcher.Check(rel, check.IsDir)
+	check.Protected = matcher.Protected(rel, check.IsDir)
+	return check, nil
+}
+
+// expandMergeRules 把合并规则替换为文件中的规则
+func expandMergeRules(config *models.RsyncConfig, rules []models.FilterRule, depth int) ([]models.FilterRule, error) {
+	var expanded []models.FilterRule
+	for _, rule := range rules {
+		if rule.Action != models.FilterMerge {
+			expanded = append(expanded, rule)
+			continue
+		}
+		if depth >= mergeFileMaxDepth {
+			return nil, fmt.Errorf("合并文件 '%s' 嵌套超过 %d 层", rule.Pattern, mergeFileMaxDepth)
+		}
+
+		data, err := os.ReadFile(config.MergeFilePath(rule.Pattern))
+		if err != nil {
+			return nil, fmt.Errorf("读取合并文件 '%s' 失败: %v", rule.Pattern, err)
+		}
+		merged, err := models.ParseFilterRules(string(data), rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("合并文件 '%s' %v", rule.Pattern, err)
+		}
+		merged, err = expandMergeRules(config, merged, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, merged...)
+	}
+	return expanded, nil
+}
+
+// collectIgnoreRules 遍历本地路径读取忽略文件，深层目录中的规则优先，被排除的目录不会进入
+func collectIgnoreRules(config *models.RsyncConfig, base []models.FilterRule) ([]models.FilterRule, error) {
+	root := filepath.Clean(config.LocalPath)
+	info, err := os.Stat(root)
+	if os.IsNotExist(err) || (err == nil && !info.IsDir()) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("本地路径 '%s' 无法访问: %v", config.LocalPath, err)
+	}
+
+	type ignoreGroup struct {
+		depth int
+		rules []models.FilterRule
+	}
+	var groups []ignoreGroup
+	ordered := func() []models.FilterRule {
+		sort.SliceStable(groups, func(i, j int) bool { return groups[i].depth > groups[j].depth })
+		var rules []models.FilterRule
+		for _, group := range groups {
+			rules = append(rules, group.rules...)
+		}
+		return rules
+	}
+
+	matcher, err := models.NewFilterMatcher(base)
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return fmt.Errorf("读取目录 '%s' 失败: %v", path, err)
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" && path != root {
+			return filepath.SkipDir
+		}
+		rel, ok := config.TransferPath(path)
+		if !ok {
+			return nil
+		}
+		if rel != "" && matcher.Excluded(rel, true) {
+			return filepath.SkipDir
+		}
+
+		// 同一目录中后面的忽略文件优先，因此倒序加入
+		var rules []models.FilterRule
+		for i := len(models.IgnoreFileNames) - 1; i >= 0; i-- {
+			file := filepath.Join(path, models.IgnoreFileNames[i])
+			data, err := os.ReadFile(file)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("读取忽略文件 '%s' 失败: %v", file, err)
+			}
+			source, _ := filepath.Rel(root, file)
+			rules = append(rules, models.ConvertIgnoreFile(string(data), rel, filepath.ToSlash(source))...)
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+
+		depth := 0
+		if rel != "" {
+			depth = strings.Count(rel, "/") + 1
+		}
+		groups = append(groups, ignoreGroup{depth: depth, rules: rules})
+		matcher, err = models.NewFilterMatcher(append(append([]models.FilterRule{}, base...), ordered()...))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ordered(), nil
+}
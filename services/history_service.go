@@ -0,0 +1,158 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// revertModel 用快照覆盖记录的字段，快照中已隐藏的敏感字段保留当前值
+func revertModel(model any, snapshot map[string]any) error {
+	currentJSON, err := json.Marshal(model)
+	if err != nil {
+		return err
+	}
+	var current map[string]any
+	if err := json.Unmarshal(currentJSON, &current); err != nil {
+		return err
+	}
+	revertedJSON, err := json.Marshal(models.RevertFields(current, snapshot))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(revertedJSON, model)
+}
+
+// resolveHistoryEntityID 根据名称或ID找到记录ID，同名记录有多条时取最近变更的那条
+func resolveHistoryEntityID(entity, key string) (uint, error) {
+	if _, err := entityModel(entity); err != nil {
+		return 0, err
+	}
+
+	db := database.GetDB()
+	var record models.ChangeRecord
+	err := db.Where("entity = ? AND entity_name = ?", entity, key).Order("id DESC").First(&record).Error
+	if err == nil {
+		return record.EntityID, nil
+	}
+
+	if id, parseErr := strconv.ParseUint(key, 10, 32); parseErr == nil {
+		err = db.Where("entity = ? AND entity_id = ?", entity, id).First(&record).Error
+		if err == nil {
+			return record.EntityID, nil
+		}
+	}
+	return 0, fmt.Errorf("没有找到 %s '%s' 的变更历史", entity, key)
+}
+
+// GetHistory 获取记录的全部变更历史，按版本从旧到新排序
+func GetHistory(entity, key string) ([]models.ChangeRecord, error) {
+	id, err := resolveHistoryEntityID(entity, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.ChangeRecord
+	err = database.GetDB().Where("entity = ? AND entity_id = ?", entity, id).Order("version").Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("读取变更历史失败: %v", err)
+	}
+	return records, nil
+}
+
+// GetHistoryVersion 获取记录的指定版本，version <= 0 表示最新版本
+func GetHistoryVersion(entity, key string, version int) (*models.ChangeRecord, error) {
+	records, err := GetHistory(entity, key)
+	if err != nil {
+		return nil, err
+	}
+	if version <= 0 {
+		return &records[len(records)-1], nil
+	}
+	for _, record := range records {
+		if record.Version == version {
+			return &record, nil
+		}
+	}
+	return nil, fmt.Errorf("版本 %d 不存在（共 %d 个版本）", version, len(records))
+}
+
+// DiffHistoryVersions 比较同一记录两个版本的快照，to <= 0 表示最新版本
+func DiffHistoryVersions(entity, key string, from, to int) ([]models.FieldChange, error) {
+	fromRecord, err := GetHistoryVersion(entity, key, from)
+	if err != nil {
+		return nil, err
+	}
+	toRecord, err := GetHistoryVersion(entity, key, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fromFields, err := fromRecord.SnapshotFields()
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := toRecord.SnapshotFields()
+	if err != nil {
+		return nil, err
+	}
+	return models.DiffFields(fromFields, toFields), nil
+}
+
+// RevertToVersion 将记录恢复到指定版本的内容，被删除的记录会同时恢复
+// 历史中的敏感字段已被隐藏，回滚时保留当前值
+func RevertToVersion(entity, key string, version int) (*models.ChangeRecord, error) {
+	record, err := GetHistoryVersion(entity, key, version)
+	if err != nil {
+		return nil, err
+	}
+	if record.Action == models.ChangeActionPurge {
+		return nil, fmt.Errorf("版本 %d 是永久删除记录，无法回滚到该版本", record.Version)
+	}
+
+	snapshot, err := record.SnapshotFields()
+	if err != nil {
+		return nil, err
+	}
+
+	// 回滚和普通修改走同样的逻辑：连接改名时同步rsync配置、服务检查同名、回收站中的记录检查同名后恢复
+	db := database.GetDB().Set(database.AuditActionKey, models.ChangeActionRevert)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		model, _ := entityModel(entity)
+		if err := tx.Unscoped().First(model, record.EntityID).Error; err != nil {
+			return fmt.Errorf("记录已被永久删除，无法回滚")
+		}
+		deleted := reflect.ValueOf(model).Elem().FieldByName("DeletedAt").Interface().(gorm.DeletedAt).Valid
+
+		if err := revertModel(model, snapshot); err != nil {
+			return err
+		}
+		if deleted {
+			name := reflect.ValueOf(model).Elem().FieldByName("Name").String()
+			if err := checkRestoreName(tx, entity, name); err != nil {
+				return err
+			}
+			reflect.ValueOf(model).Elem().FieldByName("DeletedAt").Set(reflect.ValueOf(gorm.DeletedAt{}))
+		}
+
+		switch m := model.(type) {
+		case *models.SSHConnection:
+			return updateConnection(tx, m)
+		case *models.Service:
+			return updateService(tx, m)
+		default:
+			return tx.Unscoped().Omit(clause.Associations).Save(model).Error
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("回滚失败: %v", err)
+	}
+	return record, nil
+}
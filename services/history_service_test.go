@@ -0,0 +1,95 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+)
+
+// setupTestDB 在临时目录中初始化数据库
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	oldPath := database.DBPath
+	database.DBPath = filepath.Join(t.TempDir(), "connections.db")
+	database.InitDB()
+	t.Cleanup(func() {
+		closeGormDB(database.DB)
+		database.DBPath = oldPath
+	})
+}
+
+func TestRevertRenamedConnection(t *testing.T) {
+	setupTestDB(t)
+	db := database.GetDB()
+
+	conn := &models.SSHConnection{Name: "web", Address: "10.0.0.1", Port: 22, Username: "root"}
+	if err := db.Create(conn).Error; err != nil {
+		t.Fatal(err)
+	}
+	config := &models.RsyncConfig{Name: "site", SSHName: "web", Direction: models.RsyncDirectionUpload, LocalPath: "/tmp/site", RemotePath: "/srv/site"}
+	if err := db.Create(config).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Name = "web-new"
+	if err := UpdateConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := RevertToVersion("ssh", "web-new", 1)
+	if err != nil {
+		t.Fatalf("回滚失败: %v", err)
+	}
+	if record.Version != 1 {
+		t.Errorf("回滚的版本 = %d，期望 1", record.Version)
+	}
+
+	if _, err := GetConnectionByName("web"); err != nil {
+		t.Errorf("回滚后应该恢复原名称: %v", err)
+	}
+	var reverted models.RsyncConfig
+	if err := db.First(&reverted, config.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reverted.SSHName != "web" {
+		t.Errorf("rsync配置的连接名称 = %q，应随回滚同步为 %q", reverted.SSHName, "web")
+	}
+
+	history, err := GetHistory("ssh", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := history[len(history)-1]; last.Action != models.ChangeActionRevert || last.Version != 3 {
+		t.Errorf("最新的历史记录 = 版本 %d %s，期望版本 3 revert", last.Version, last.Action)
+	}
+}
+
+func TestRevertDeletedConnectionNameConflict(t *testing.T) {
+	setupTestDB(t)
+	db := database.GetDB()
+
+	conn := &models.SSHConnection{Name: "web", Address: "10.0.0.1", Port: 22, Username: "root"}
+	if err := db.Create(conn).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteConnection("web", DeleteConnectionOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.SSHConnection{Name: "web", Address: "10.0.0.2", Port: 22, Username: "root"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RevertToVersion("ssh", "1", 1); err == nil {
+		t.Fatal("已存在同名连接时回滚被删除的连接应该失败")
+	}
+
+	var deleted models.SSHConnection
+	if err := db.Unscoped().First(&deleted, conn.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !deleted.DeletedAt.Valid {
+		t.Error("回滚失败时被删除的连接应该仍在回收站中")
+	}
+}
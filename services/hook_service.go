@@ -0,0 +1,116 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runHooks 按顺序执行指定阶段的钩子，遇到失败的钩子立即停止；result 为执行后钩子提供本次的统计结果
+func (e *rsyncExecution) runHooks(stage models.RsyncHookStage, result *models.RsyncResult) error {
+	hooks := e.config.GetHooks(stage)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	env := e.hookEnv(stage, result)
+	for _, hook := range hooks {
+		hookResult := e.runHook(hook, env)
+		e.handler(models.RsyncEvent{Type: models.RsyncEventHook, Hook: &hookResult})
+		if hookResult.Error != "" {
+			return fmt.Errorf("钩子 %s 执行失败: %s", hook, hookResult.Error)
+		}
+	}
+	return nil
+}
+
+// runHook 执行一个钩子，输出同时写入终端（标准错误，避免混入 JSON 事件）和执行日志
+func (e *rsyncExecution) runHook(hook models.RsyncHook, env map[string]string) models.RsyncHookResult {
+	fmt.Fprintf(e.logFile, "\n$ %s\n", hook)
+
+	var cmd *exec.Cmd
+	if hook.Remote {
+		// 远程服务器的 sshd 通常不接受自定义环境变量，在命令前导出
+		cmdArgs := e.sshConn.RemoteCommand(remoteHookCommand(hook.Command, env))
+		cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		cmd.Stdin = e.stdin
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", hook.Command)
+		cmd.Env = os.Environ()
+		for _, key := range sortedKeys(env) {
+			cmd.Env = append(cmd.Env, key+"="+env[key])
+		}
+		// 本地钩子在本地路径下执行，方便直接运行构建命令
+		if info, err := os.Stat(e.config.LocalPath); err == nil && info.IsDir() {
+			cmd.Dir = e.config.LocalPath
+		}
+	}
+	output := io.MultiWriter(e.stderr, e.logFile)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	start := time.Now()
+	err := cmd.Run()
+	result := models.RsyncHookResult{RsyncHook: hook, DurationSeconds: time.Since(start).Seconds()}
+	if err != nil {
+		result.ExitCode = models.RsyncRunExitCodeNotStarted
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		}
+		result.Error = err.Error()
+		fmt.Fprintf(e.logFile, "钩子执行失败: %v\n", err)
+	}
+	return result
+}
+
+// hookEnv 描述本次执行的环境变量
+func (e *rsyncExecution) hookEnv(stage models.RsyncHookStage, result *models.RsyncResult) map[string]string {
+	env := map[string]string{
+		"ALFRED_RSYNC_NAME":        e.config.Name,
+		"ALFRED_RSYNC_DIRECTION":   string(e.config.Direction),
+		"ALFRED_RSYNC_LOCAL_PATH":  e.config.LocalPath,
+		"ALFRED_RSYNC_REMOTE_PATH": e.config.RemotePath,
+		"ALFRED_RSYNC_SSH_NAME":    e.sshConn.Name,
+		"ALFRED_RSYNC_HOST":        e.sshConn.Address,
+		"ALFRED_RSYNC_PORT":        strconv.Itoa(e.sshConn.Port),
+		"ALFRED_RSYNC_USER":        e.sshConn.Username,
+		"ALFRED_RSYNC_TRIGGER":     e.run.Trigger,
+		"ALFRED_RSYNC_RUN_ID":      strconv.FormatUint(uint64(e.run.ID), 10),
+		"ALFRED_RSYNC_LOG":         e.run.LogPath,
+		"ALFRED_RSYNC_HOOK_STAGE":  string(stage),
+	}
+	if result != nil {
+		env["ALFRED_RSYNC_FILES_TRANSFERRED"] = strconv.Itoa(result.FilesTransferred)
+		env["ALFRED_RSYNC_TRANSFERRED_SIZE"] = strconv.FormatInt(result.TransferredSize, 10)
+	}
+	if e.run.Snapshot != "" {
+		env["ALFRED_RSYNC_SNAPSHOT"] = e.run.Snapshot
+	}
+	return env
+}
+
+// remoteHookCommand 在远程命令前导出环境变量
+func remoteHookCommand(command string, env map[string]string) string {
+	var exports []string
+	for _, key := range sortedKeys(env) {
+		exports = append(exports, key+"="+models.ShellQuote(env[key]))
+	}
+	return "export " + strings.Join(exports, " ") + "; " + command
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
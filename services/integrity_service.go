@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+)
+
+// DanglingReference 指向不存在（或已删除）SSH连接的引用
+type DanglingReference struct {
+	Entity    string // rsync 或 service
+	ID        uint
+	Name      string
+	Reference string // 引用的连接名称或ID
+}
+
+// FindDanglingReferences 查找所有引用了不存在SSH连接的rsync配置和服务
+func FindDanglingReferences() ([]DanglingReference, error) {
+	db := database.GetDB()
+
+	var configs []models.RsyncConfig
+	err := db.Where("ssh_name NOT IN (?)", db.Model(&models.SSHConnection{}).Select("name")).
+		Find(&configs).Error
+	if err != nil {
+		return nil, fmt.Errorf("检查rsync配置失败: %v", err)
+	}
+
+	// 服务器之间传输的目标连接
+	var targetConfigs []models.RsyncConfig
+	err = db.Where("target_ssh_name <> '' AND target_ssh_name NOT IN (?)", db.Model(&models.SSHConnection{}).Select("name")).
+		Find(&targetConfigs).Error
+	if err != nil {
+		return nil, fmt.Errorf("检查rsync配置失败: %v", err)
+	}
+
+	var serviceList []models.Service
+	err = db.Where("ssh_connection_id > 0 AND ssh_connection_id NOT IN (?)", db.Model(&models.SSHConnection{}).Select("id")).
+		Find(&serviceList).Error
+	if err != nil {
+		return nil, fmt.Errorf("检查服务失败: %v", err)
+	}
+
+	var references []DanglingReference
+	for _, config := range configs {
+		references = append(references, DanglingReference{
+			Entity:    "rsync",
+			ID:        config.ID,
+			Name:      config.Name,
+			Reference: config.SSHName,
+		})
+	}
+	for _, config := range targetConfigs {
+		references = append(references, DanglingReference{
+			Entity:    "rsync",
+			ID:        config.ID,
+			Name:      config.Name,
+			Reference: config.TargetSSHName,
+		})
+	}
+	for _, service := range serviceList {
+		references = append(references, DanglingReference{
+			Entity:    "service",
+			ID:        service.ID,
+			Name:      service.Name,
+			Reference: fmt.Sprintf("ID %d", service.SSHConnectionID),
+		})
+	}
+	return references, nil
+}
+
+// GetConnectionNameSet 返回所有有效SSH连接名称的集合
+func GetConnectionNameSet() (map[string]bool, error) {
+	var names []string
+	if err := database.GetDB().Model(&models.SSHConnection{}).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set, nil
+}
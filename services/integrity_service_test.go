@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+)
+
+func TestFindDanglingReferences(t *testing.T) {
+	setupTestDB(t)
+	conn := createConnectionWithDependents(t)
+
+	// 绕过删除时的引用检查，直接软删除连接
+	if err := database.GetDB().Delete(conn).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	references, err := FindDanglingReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, reference := range references {
+		got[reference.Entity+"/"+reference.Name] = reference.Reference
+	}
+	// mirror 的源连接 db 不存在，目标连接 web 已删除，各算一条
+	want := map[string]string{"rsync/site": "web", "rsync/mirror": "web", "service/nginx": "ID 1"}
+	if len(references) != 4 {
+		t.Errorf("FindDanglingReferences() = %+v，期望 4 条", references)
+	}
+	for key, reference := range want {
+		if got[key] != reference {
+			t.Errorf("%s 的失效引用 = %q，期望 %q", key, got[key], reference)
+		}
+	}
+
+	if err := database.GetDB().Create(&models.SSHConnection{Name: "db", Address: "10.0.0.2", Port: 22, Username: "root"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if references, _ := FindDanglingReferences(); len(references) != 3 {
+		t.Errorf("创建连接 db 后应剩 3 条失效引用: %+v", references)
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+
+	"gorm.io/gorm"
+)
+
+// MergeConflict 两边都修改过的记录，按更新时间决定保留哪一边
+type MergeConflict struct {
+	Name           string
+	Winner         string // local 或 other
+	LocalUpdatedAt time.Time
+	OtherUpdatedAt time.Time
+}
+
+// MergeReport 某类数据的合并结果
+type MergeReport struct {
+	Entity    string
+	Added     []string // 只存在于另一个数据库，已新增
+	Updated   []string // 另一个数据库中的版本更新，已覆盖本地
+	Conflicts []MergeConflict
+}
+
+// MergeDatabase 将另一个数据库（如 iCloud 冲突副本）合并到当前数据库
+// 以名称匹配记录，两边都存在且内容不同时保留 UpdatedAt 较新的版本；dryRun 时只生成报告
+func MergeDatabase(otherPath string, dryRun bool) ([]MergeReport, error) {
+	if _, err := os.Stat(otherPath); err != nil {
+		return nil, fmt.Errorf("数据库文件不存在: %s", otherPath)
+	}
+
+	otherDB, err := openBackupDB(otherPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeGormDB(otherDB)
+
+	if err := checkIntegrity(otherDB); err != nil {
+		return nil, err
+	}
+
+	var otherConnections []models.SSHConnection
+	var otherConfigs []models.RsyncConfig
+	var otherServices []models.Service
+	if err := otherDB.Find(&otherConnections).Error; err != nil {
+		return nil, fmt.Errorf("读取SSH连接失败: %v", err)
+	}
+	if err := otherDB.Find(&otherConfigs).Error; err != nil {
+		return nil, fmt.Errorf("读取rsync配置失败: %v", err)
+	}
+	if otherDB.Migrator().HasTable(&models.Service{}) {
+		if err := otherDB.Preload("SSHConnection").Find(&otherServices).Error; err != nil {
+			return nil, fmt.Errorf("读取服务失败: %v", err)
+		}
+	}
+
+	var reports []MergeReport
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		report, err := mergeConnections(tx, otherConnections)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+
+		report, err = mergeRsyncConfigs(tx, otherConfigs)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+
+		report, err = mergeServices(tx, otherServices)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+
+		if dryRun {
+			return errDryRunRollback
+		}
+		return nil
+	})
+	if err == errDryRunRollback {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("合并失败: %v", err)
+	}
+	return reports, nil
+}
+
+// errDryRunRollback 预览模式下用于回滚事务
+var errDryRunRollback = errors.New("dry run")
+
+// resolveMerge 记录两边内容不同的冲突，返回是否需要用另一边覆盖本地
+func resolveMerge(report *MergeReport, name string, localUpdatedAt, otherUpdatedAt time.Time, same bool) bool {
+	if same {
+		return false
+	}
+	conflict := MergeConflict{
+		Name:           name,
+		Winner:         "local",
+		LocalUpdatedAt: localUpdatedAt,
+		OtherUpdatedAt: otherUpdatedAt,
+	}
+	if otherUpdatedAt.After(localUpdatedAt) {
+		conflict.Winner = "other"
+		report.Updated = append(report.Updated, name)
+	}
+	report.Conflicts = append(report.Conflicts, conflict)
+	return conflict.Winner == "other"
+}
+
+func mergeConnections(tx *gorm.DB, others []models.SSHConnection) (MergeReport, error) {
+	report := MergeReport{Entity: "ssh"}
+	for _, other := range others {
+		var local models.SSHConnection
+		err := tx.Where("name = ?", other.Name).First(&local).Error
+		if err == gorm.ErrRecordNotFound {
+			other.Model = gorm.Model{CreatedAt: other.CreatedAt, UpdatedAt: other.UpdatedAt}
+			if err := tx.Create(&other).Error; err != nil {
+				return report, err
+			}
+			report.Added = append(report.Added, other.Name)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if !resolveMerge(&report, other.Name, local.UpdatedAt, other.UpdatedAt, sameConnection(local, other)) {
+			continue
+		}
+		otherUpdatedAt := other.UpdatedAt
+		other.Model = local.Model
+		if other.UsageCount < local.UsageCount {
+			other.UsageCount = local.UsageCount
+		}
+		if err := tx.Save(&other).Error; err != nil {
+			return report, err
+		}
+		if err := keepUpdatedAt(tx, &other, otherUpdatedAt); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func mergeRsyncConfigs(tx *gorm.DB, others []models.RsyncConfig) (MergeReport, error) {
+	report := MergeReport{Entity: "rsync"}
+	for _, other := range others {
+		var local models.RsyncConfig
+		err := tx.Where("name = ?", other.Name).First(&local).Error
+		if err == gorm.ErrRecordNotFound {
+			other.Model = gorm.Model{CreatedAt: other.CreatedAt, UpdatedAt: other.UpdatedAt}
+			if err := tx.Create(&other).Error; err != nil {
+				return report, err
+			}
+			report.Added = append(report.Added, other.Name)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if !resolveMerge(&report, other.Name, local.UpdatedAt, other.UpdatedAt, sameRsyncConfig(local, other)) {
+			continue
+		}
+		otherUpdatedAt := other.UpdatedAt
+		other.Model = local.Model
+		if other.UsageCount < local.UsageCount {
+			other.UsageCount = local.UsageCount
+		}
+		if err := tx.Save(&other).Error; err != nil {
+			return report, err
+		}
+		if err := keepUpdatedAt(tx, &other, otherUpdatedAt); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func mergeServices(tx *gorm.DB, others []models.Service) (MergeReport, error) {
+	report := MergeReport{Entity: "service"}
+	for _, other := range others {
+		// 另一个数据库中的连接ID在本地没有意义，按连接名称重新映射
+		var sshConnectionID uint
+		if other.SSHConnectionID > 0 && other.SSHConnection.Name != "" {
+			var conn models.SSHConnection
+			if err := tx.Where("name = ?", other.SSHConnection.Name).First(&conn).Error; err == nil {
+				sshConnectionID = conn.ID
+			}
+		}
+		other.SSHConnectionID = sshConnectionID
+		other.SSHConnection = models.SSHConnection{}
+
+		var local models.Service
+		err := tx.Where("name = ?", other.Name).First(&local).Error
+		if err == gorm.ErrRecordNotFound {
+			other.Model = gorm.Model{CreatedAt: other.CreatedAt, UpdatedAt: other.UpdatedAt}
+			if err := tx.Omit("SSHConnection").Create(&other).Error; err != nil {
+				return report, err
+			}
+			report.Added = append(report.Added, other.Name)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if !resolveMerge(&report, other.Name, local.UpdatedAt, other.UpdatedAt, sameService(local, other)) {
+			continue
+		}
+		otherUpdatedAt := other.UpdatedAt
+		other.Model = local.Model
+		if other.UsageCount < local.UsageCount {
+			other.UsageCount = local.UsageCount
+		}
+		if err := tx.Omit("SSHConnection").Save(&other).Error; err != nil {
+			return report, err
+		}
+		if err := keepUpdatedAt(tx, &other, otherUpdatedAt); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// keepUpdatedAt Save 会把 updated_at 改成当前时间，合并后恢复为另一边的更新时间
+func keepUpdatedAt(tx *gorm.DB, model any, updatedAt time.Time) error {
+	return tx.Model(model).UpdateColumn("updated_at", updatedAt).Error
+}
+
+// sameConnection 比较参与变更历史的业务字段，忽略ID、时间和使用次数
+func sameConnection(a, b models.SSHConnection) bool {
+	return sameFields(&a, &b)
+}
+
+func sameRsyncConfig(a, b models.RsyncConfig) bool {
+	return sameFields(&a, &b)
+}
+
+func sameService(a, b models.Service) bool {
+	return sameFields(&a, &b)
+}
+
+func sameFields(a, b any) bool {
+	return len(models.DiffFields(database.AuditFields(a), database.AuditFields(b))) == 0
+}
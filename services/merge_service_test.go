@@ -0,0 +1,115 @@
+package services
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// createOtherDB 在临时目录中创建另一个数据库（如冲突副本）并写入连接，返回文件路径
+func createOtherDB(t *testing.T, conns ...models.SSHConnection) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "connections 2.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeGormDB(db)
+	if err := db.AutoMigrate(&models.SSHConnection{}, &models.RsyncConfig{}, &models.Service{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range conns {
+		if err := db.Create(&conns[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestMergeDatabase(t *testing.T) {
+	setupTestDB(t)
+	db := database.GetDB()
+
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	local := []models.SSHConnection{
+		{Name: "same", Address: "10.0.0.1", Port: 22, Username: "root", UsageCount: 5},
+		{Name: "other-newer", Address: "10.0.0.2", Port: 22, Username: "root"},
+		{Name: "local-newer", Address: "10.0.0.3", Port: 22, Username: "root"},
+	}
+	localUpdatedAt := []time.Time{older, older, newer}
+	for i := range local {
+		if err := db.Create(&local[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(&local[i]).UpdateColumn("updated_at", localUpdatedAt[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	otherPath := createOtherDB(t,
+		// 内容相同，只有时间和使用次数不同，不算冲突
+		models.SSHConnection{Name: "same", Address: "10.0.0.1", Port: 22, Username: "root", UsageCount: 1, Model: gormModelAt(newer)},
+		models.SSHConnection{Name: "other-newer", Address: "10.1.0.2", Port: 22, Username: "root", Model: gormModelAt(newer)},
+		models.SSHConnection{Name: "local-newer", Address: "10.1.0.3", Port: 22, Username: "root", Model: gormModelAt(older)},
+		models.SSHConnection{Name: "added", Address: "10.1.0.4", Port: 22, Username: "root", Model: gormModelAt(older)},
+	)
+
+	reports, err := MergeDatabase(otherPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := reports[0]
+	if !reflect.DeepEqual(report.Added, []string{"added"}) {
+		t.Errorf("新增 = %v，期望 [added]", report.Added)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"other-newer"}) {
+		t.Errorf("覆盖 = %v，期望 [other-newer]", report.Updated)
+	}
+	if len(report.Conflicts) != 2 {
+		t.Errorf("冲突 = %+v，期望 other-newer 和 local-newer", report.Conflicts)
+	}
+
+	want := map[string]string{"same": "10.0.0.1", "other-newer": "10.1.0.2", "local-newer": "10.0.0.3", "added": "10.1.0.4"}
+	for name, address := range want {
+		conn, err := GetConnectionByName(name)
+		if err != nil {
+			t.Errorf("合并后应存在连接 %s: %v", name, err)
+			continue
+		}
+		if conn.Address != address {
+			t.Errorf("连接 %s 的地址 = %s，期望 %s", name, conn.Address, address)
+		}
+	}
+	merged, _ := GetConnectionByName("other-newer")
+	if !merged.UpdatedAt.Equal(newer) {
+		t.Errorf("覆盖后的更新时间 = %v，应保留另一边的 %v", merged.UpdatedAt, newer)
+	}
+}
+
+func TestMergeDatabaseDryRun(t *testing.T) {
+	setupTestDB(t)
+	otherPath := createOtherDB(t, models.SSHConnection{Name: "added", Address: "10.1.0.4", Port: 22, Username: "root"})
+
+	reports, err := MergeDatabase(otherPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports[0].Added) != 1 {
+		t.Errorf("预览应报告新增的连接: %+v", reports[0])
+	}
+	if _, err := GetConnectionByName("added"); err == nil {
+		t.Error("预览不应写入数据库")
+	}
+}
+
+func gormModelAt(updatedAt time.Time) gorm.Model {
+	return gorm.Model{CreatedAt: updatedAt, UpdatedAt: updatedAt}
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"fmt"
+)
+
+// ApplyRsyncPreset 把预设应用到已保存的配置，只修改预设涉及的选项
+func ApplyRsyncPreset(configName, presetName string) (*models.RsyncConfig, error) {
+	preset, err := models.GetRsyncPreset(presetName)
+	if err != nil {
+		return nil, err
+	}
+	config, err := GetRsyncConfigByName(configName)
+	if err != nil {
+		return nil, fmt.Errorf("获取rsync配置失败: %v", err)
+	}
+
+	preset.Apply(config)
+	if err := config.ValidateLimits(); err != nil {
+		return nil, err
+	}
+	if err := UpdateRsyncConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// GetRemoteFile 通过 sftp 把服务器上的文件或目录下载到本地，local 为空时下载到当前目录
+// stdout 为终端时 sftp 会在其中显示进度
+func GetRemoteFile(remote models.RemoteFileSpec, local string, opts models.FileTransferOptions, stdout io.Writer) error {
+	sshConn, err := GetConnectionByName(remote.Connection)
+	if err != nil {
+		return fmt.Errorf("SSH连接 '%s' 不存在", remote.Connection)
+	}
+	batch, err := models.BuildFileTransferBatch(false, local, remote.Path, opts)
+	if err != nil {
+		return err
+	}
+	if err := runFileTransfer(sshConn, batch, stdout); err != nil {
+		return fmt.Errorf("下载 '%s' 失败: %v", remote, err)
+	}
+	return nil
+}
+
+// PutRemoteFile 通过 sftp 把本地文件或目录上传到服务器，remote.Path 为空时上传到用户目录
+func PutRemoteFile(local string, remote models.RemoteFileSpec, opts models.FileTransferOptions, stdout io.Writer) error {
+	info, err := os.Stat(local)
+	if err != nil {
+		return fmt.Errorf("本地路径 '%s' 不存在", local)
+	}
+	if info.IsDir() && !opts.Recursive {
+		return fmt.Errorf("'%s' 是目录，使用 -r 递归上传", local)
+	}
+
+	sshConn, err := GetConnectionByName(remote.Connection)
+	if err != nil {
+		return fmt.Errorf("SSH连接 '%s' 不存在", remote.Connection)
+	}
+	batch, err := models.BuildFileTransferBatch(true, local, remote.Path, opts)
+	if err != nil {
+		return err
+	}
+	if err := runFileTransfer(sshConn, batch, stdout); err != nil {
+		return fmt.Errorf("上传到 '%s' 失败: %v", remote, err)
+	}
+	return nil
+}
+
+// runFileTransfer 执行 sftp 批处理命令，失败时返回 sftp 的错误输出
+func runFileTransfer(sshConn *models.SSHConnection, batch string, stdout io.Writer) error {
+	if err := checkPasswordPrompt(sshConn); err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmdArgs := models.BuildFileTransferCommand(sshConn)
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdin = strings.NewReader(batch)
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// RemoteEditSession ssh edit 下载到临时目录编辑的服务器文件
+type RemoteEditSession struct {
+	Remote    models.RemoteFileSpec
+	Dir       string // 临时目录
+	LocalPath string // 编辑的本地副本
+	hash      string // 下载或上次上传时的内容 sha256，用于检查两端是否修改
+}
+
+// StartRemoteEdit 把服务器上的文件下载到临时目录，本地副本保持原文件名以便编辑器识别文件类型
+func StartRemoteEdit(remote models.RemoteFileSpec) (*RemoteEditSession, error) {
+	if strings.TrimSpace(remote.Path) == "" {
+		return nil, fmt.Errorf("请指定要编辑的文件路径")
+	}
+	dir, err := os.MkdirTemp("", "alfred-tool-edit-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+
+	session := &RemoteEditSession{
+		Remote:    remote,
+		Dir:       dir,
+		LocalPath: filepath.Join(dir, path.Base(remote.Path)),
+	}
+	if err := GetRemoteFile(remote, session.LocalPath, models.FileTransferOptions{}, io.Discard); err != nil {
+		session.Cleanup()
+		return nil, err
+	}
+	if info, err := os.Stat(session.LocalPath); err != nil || info.IsDir() {
+		session.Cleanup()
+		return nil, fmt.Errorf("'%s' 不是文件", remote)
+	}
+	if session.hash, err = fileHash(session.LocalPath); err != nil {
+		session.Cleanup()
+		return nil, err
+	}
+	return session, nil
+}
+
+// LocalChanged 本地副本是否已修改
+func (s *RemoteEditSession) LocalChanged() (bool, error) {
+	hash, err := fileHash(s.LocalPath)
+	if err != nil {
+		return false, err
+	}
+	return hash != s.hash, nil
+}
+
+// RemoteChanged 重新下载服务器上的文件，检查下载后服务器上是否被修改，返回下载的副本路径
+func (s *RemoteEditSession) RemoteChanged() (string, bool, error) {
+	remoteDir := filepath.Join(s.Dir, "remote")
+	if err := os.MkdirAll(remoteDir, 0755); err != nil {
+		return "", false, fmt.Errorf("创建目录 '%s' 失败: %v", remoteDir, err)
+	}
+	remoteCopy := filepath.Join(remoteDir, filepath.Base(s.LocalPath))
+	if err := GetRemoteFile(s.Remote, remoteCopy, models.FileTransferOptions{}, io.Discard); err != nil {
+		return "", false, err
+	}
+	hash, err := fileHash(remoteCopy)
+	if err != nil {
+		return "", false, err
+	}
+	return remoteCopy, hash != s.hash, nil
+}
+
+// Upload 把本地副本上传回服务器
+func (s *RemoteEditSession) Upload() error {
+	hash, err := fileHash(s.LocalPath)
+	if err != nil {
+		return err
+	}
+	if err := PutRemoteFile(s.LocalPath, s.Remote, models.FileTransferOptions{}, io.Discard); err != nil {
+		return err
+	}
+	s.hash = hash
+	return nil
+}
+
+// RemoteEditEvent 编辑过程中一次保存的处理结果
+type RemoteEditEvent struct {
+	Ready    bool  // 开始监听，之后的保存都会被检测到
+	Uploaded bool  // 已上传
+	Conflict bool  // 服务器上的文件在编辑期间被修改，没有上传，留到编辑器退出后处理
+	Err      error // 检查或上传失败
+}
+
+// remoteEditDebounce 保存后等待多久再上传，合并编辑器保存时的多次写入
+const remoteEditDebounce = 300 * time.Millisecond
+
+// WatchSaves 监听本地副本，每次保存后检查服务器上的文件是否被修改（force 为 true 时不检查）并上传，直到 ctx 结束
+// 监听临时目录而不是文件本身，编辑器以重命名方式保存时同样能检测到；
+// 编辑器占用终端时无法询问，出现冲突后不再上传，由编辑器退出后的检查处理
+func (s *RemoteEditSession) WatchSaves(ctx context.Context, force bool, handler func(RemoteEditEvent)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("监听 '%s' 失败: %v", s.LocalPath, err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(s.Dir); err != nil {
+		return fmt.Errorf("监听 '%s' 失败: %v", s.LocalPath, err)
+	}
+	handler(RemoteEditEvent{Ready: true})
+
+	var debounce <-chan time.Time
+	conflict := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Name == s.LocalPath && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				debounce = time.After(remoteEditDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			handler(RemoteEditEvent{Err: err})
+		case <-debounce:
+			debounce = nil
+			if conflict {
+				continue
+			}
+			event := s.uploadSaved(force)
+			conflict = event.Conflict
+			if event != (RemoteEditEvent{}) {
+				handler(event)
+			}
+		}
+	}
+}
+
+// uploadSaved 本地副本有修改且服务器上的文件没有被修改时上传
+func (s *RemoteEditSession) uploadSaved(force bool) RemoteEditEvent {
+	changed, err := s.LocalChanged()
+	if err != nil || !changed {
+		return RemoteEditEvent{Err: err}
+	}
+	if !force {
+		_, conflict, err := s.RemoteChanged()
+		if err != nil || conflict {
+			return RemoteEditEvent{Conflict: conflict, Err: err}
+		}
+	}
+	if err := s.Upload(); err != nil {
+		return RemoteEditEvent{Err: err}
+	}
+	return RemoteEditEvent{Uploaded: true}
+}
+
+// Cleanup 删除临时目录
+func (s *RemoteEditSession) Cleanup() {
+	os.RemoveAll(s.Dir)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// RemoteListOptions 列出服务器目录的选项
+type RemoteListOptions struct {
+	All      bool // 包括以 . 开头的隐藏文件
+	DirsOnly bool // 只列出目录和符号链接
+}
+
+// ListRemoteDir 通过 sftp 列出SSH连接服务器上的目录，dir 为空或 ~ 时为用户目录
+func ListRemoteDir(connName, dir string, opts RemoteListOptions) (*models.RemoteListing, error) {
+	sshConn, err := GetConnectionByName(connName)
+	if err != nil {
+		return nil, fmt.Errorf("SSH连接 '%s' 不存在", connName)
+	}
+	if err := checkPasswordPrompt(sshConn); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmdArgs := models.BuildRemoteLsCommand(sshConn)
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdin = strings.NewReader(models.BuildRemoteLsBatch(dir))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("列出 '%s' 失败: %s", dir, msg)
+		}
+		return nil, fmt.Errorf("列出 '%s' 失败: %v", dir, err)
+	}
+
+	path, entries, err := models.ParseRemoteLs(stdout.String())
+	if err != nil {
+		return nil, err
+	}
+	listing := &models.RemoteListing{
+		Connection: sshConn.Name,
+		Path:       path,
+		Parent:     models.RemoteParent(path),
+		Entries:    []models.RemoteEntry{},
+	}
+	for _, entry := range entries {
+		if (!opts.All && strings.HasPrefix(entry.Name, ".")) || (opts.DirsOnly && !entry.Browsable()) {
+			continue
+		}
+		listing.Entries = append(listing.Entries, entry)
+	}
+	return listing, nil
+}
+
+// RemoteBrowseCommand 对话框中服务器目录选择器列出目录的命令（见 field.NewRemoteFolderField），
+// 以 JSON 格式只列出目录，要列出的目录由对话框作为最后一个参数追加
+func RemoteBrowseCommand(connName string) []string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "alfred-tool"
+	}
+	return []string{exe, "ssh", "ls", connName, "--dirs", "-f", "json"}
+}
+
+// checkPasswordPrompt 使用密码的连接由 ssh 在终端中询问密码，没有终端时（如在 Alfred、界面或定时任务中执行）
+// ssh 只会返回 Permission denied，提前返回说明原因的错误
+func checkPasswordPrompt(sshConn *models.SSHConnection) error {
+	if sshConn.PasswordType != models.PasswordTypePassword {
+		return nil
+	}
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		tty.Close()
+		return nil
+	}
+	return fmt.Errorf("SSH连接 '%s' 使用密码认证，没有终端可以输入密码，请在终端中执行或改用密钥", sshConn.Name)
+}
+
+// listRemoteTree 用 sftp ls 逐层列出服务器上 root 中的文件和目录，每一层执行一次 sftp，cmdArgs 为批处理模式的 sftp 命令
+// 键为相对路径，root 本身为空字符串；root 不存在时返回空列表，跳过符号链接和特殊文件
+// 只依赖服务器的 SFTP 子系统，修改时间只精确到分钟（半年以前的文件精确到日期）
+func listRemoteTree(cmdArgs []string, root string) (map[string]models.TransferEntry, error) {
+	entries := make(map[string]models.TransferEntry)
+	now := time.Now()
+	for level := []string{""}; len(level) > 0; {
+		batch, err := models.BuildRemoteTreeBatch(root, level)
+		if err != nil {
+			return nil, err
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		cmd.Stdin = strings.NewReader(batch)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("列出服务器上的文件失败: %v\n%s", err, strings.TrimSpace(stderr.String()))
+		}
+
+		var next []string
+		for i, listing := range models.ParseRemoteTreeBatch(stdout.String(), len(level)) {
+			dir := level[i]
+			if dir == "" {
+				if listing.Self == nil {
+					return entries, nil
+				}
+				root, err := listing.Self.TransferEntry(now)
+				if err != nil {
+					return nil, err
+				}
+				entries[""] = root
+			}
+			for _, child := range listing.Entries {
+				if !child.IsDir && !child.IsRegular() {
+					continue
+				}
+				entry, err := child.TransferEntry(now)
+				if err != nil {
+					return nil, err
+				}
+				rel := path.Join(dir, child.Name)
+				entries[rel] = entry
+				if entry.IsDir {
+					next = append(next, rel)
+				}
+			}
+		}
+		level = next
+	}
+	return entries, nil
+}
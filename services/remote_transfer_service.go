@@ -0,0 +1,25 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"fmt"
+	"math/rand"
+)
+
+// buildRemoteTransferArgs 生成服务器之间传输的命令，中转时随机选择源服务器上反向隧道的端口，
+// 端口被占用时 ssh 会因 ExitOnForwardFailure 直接失败
+func buildRemoteTransferArgs(config *models.RsyncConfig, sshConn *models.SSHConnection) ([]string, *snapshotPlan, error) {
+	if err := config.ValidateRemoteTransfer(); err != nil {
+		return nil, nil, err
+	}
+	target, err := GetConnectionByName(config.TargetSSHName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取目标SSH连接失败: %v", err)
+	}
+
+	relayPort := 0
+	if mode, _ := models.ParseRemoteTransferMode(string(config.RemoteTransferMode)); mode == models.RemoteTransferRelay {
+		relayPort = models.RemoteRelayPortMin + rand.Intn(models.RemoteRelayPortMax-models.RemoteRelayPortMin)
+	}
+	return config.BuildRemoteTransferCommand(sshConn, target, relayPort), nil, nil
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"alfred-tool/database"
+	"alfred-tool/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetAllRsyncGroups 获取所有rsync分组
+func GetAllRsyncGroups() ([]models.RsyncGroup, error) {
+	var groups []models.RsyncGroup
+	db := database.GetDB()
+	err := db.Order("name").Find(&groups).Error
+	return groups, err
+}
+
+// SearchRsyncGroups 按名称和描述搜索rsync分组
+func SearchRsyncGroups(query string) ([]models.RsyncGroup, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return GetAllRsyncGroups()
+	}
+
+	var groups []models.RsyncGroup
+	db := database.GetDB()
+	err := db.Where("name LIKE ? OR description LIKE ?", "%"+query+"%", "%"+query+"%").
+		Order("name").Find(&groups).Error
+	return groups, err
+}
+
+// GetRsyncGroupByName 根据名称获取rsync分组
+func GetRsyncGroupByName(name string) (*models.RsyncGroup, error) {
+	var group models.RsyncGroup
+	db := database.GetDB()
+	if err := db.Where("name = ?", name).First(&group).Error; err != nil {
+		return nil, fmt.Errorf("rsync分组 '%s' 不存在", name)
+	}
+	return &group, nil
+}
+
+// GetRsyncGroupConfigs 按顺序获取分组中的rsync配置，missing 为已被删除的配置数
+func GetRsyncGroupConfigs(group *models.RsyncGroup) (configs []models.RsyncConfig, missing int, err error) {
+	var members []models.RsyncGroupMember
+	db := database.GetDB()
+	if err := db.Where("group_id = ?", group.ID).Order("position").Find(&members).Error; err != nil {
+		return nil, 0, fmt.Errorf("读取分组成员失败: %v", err)
+	}
+
+	for _, member := range members {
+		var config models.RsyncConfig
+		err := db.First(&config, member.RsyncConfigID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			missing++
+			continue
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("读取rsync配置失败: %v", err)
+		}
+		configs = append(configs, config)
+	}
+	return configs, missing, nil
+}
+
+// ValidateRsyncGroup 验证rsync分组
+func ValidateRsyncGroup(group *models.RsyncGroup) error {
+	if strings.TrimSpace(group.Name) == "" {
+		return errors.New("分组名称不能为空")
+	}
+	if _, err := models.ParseRsyncGroupMode(string(group.Mode)); err != nil {
+		return err
+	}
+	if group.Concurrency < 0 {
+		return errors.New("并行数量不能为负数")
+	}
+
+	existing, err := GetRsyncGroupByName(group.Name)
+	if err == nil && existing.ID != group.ID {
+		return fmt.Errorf("分组名称 '%s' 已存在", group.Name)
+	}
+	return nil
+}
+
+// SaveRsyncGroup 创建或更新rsync分组，configNames 不为 nil 时按给定顺序替换分组中的配置
+func SaveRsyncGroup(group *models.RsyncGroup, configNames []string) error {
+	if err := ValidateRsyncGroup(group); err != nil {
+		return err
+	}
+
+	var configIDs []uint
+	seen := make(map[uint]bool)
+	for _, name := range configNames {
+		config, err := GetRsyncConfigByName(name)
+		if err != nil {
+			return fmt.Errorf("rsync配置 '%s' 不存在", name)
+		}
+		if seen[config.ID] {
+			return fmt.Errorf("rsync配置 '%s' 重复", name)
+		}
+		seen[config.ID] = true
+		configIDs = append(configIDs, config.ID)
+	}
+	if configNames != nil && len(configIDs) == 0 {
+		return errors.New("分组至少需要包含一个rsync配置")
+	}
+
+	db := database.GetDB()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(group).Error; err != nil {
+			return fmt.Errorf("保存rsync分组失败: %v", err)
+		}
+		if configNames == nil {
+			return nil
+		}
+
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.RsyncGroupMember{}).Error; err != nil {
+			return fmt.Errorf("更新分组成员失败: %v", err)
+		}
+		for i, id := range configIDs {
+			member := models.RsyncGroupMember{GroupID: group.ID, RsyncConfigID: id, Position: i}
+			if err := tx.Create(&member).Error; err != nil {
+				return fmt.Errorf("更新分组成员失败: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRsyncGroup 删除rsync分组，分组中的rsync配置不受影响
+func DeleteRsyncGroup(name string) error {
+	group, err := GetRsyncGroupByName(name)
+	if err != nil {
+		return err
+	}
+
+	db := database.GetDB()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.RsyncGroupMember{}).Error; err != nil {
+			return fmt.Errorf("删除分组成员失败: %v", err)
+		}
+		if err := tx.Delete(group).Error; err != nil {
+			return fmt.Errorf("删除rsync分组失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// RsyncGroupProgress 分组的整体进度
+type RsyncGroupProgress struct {
+	Total   int      `json:"total"`
+	Done    int      `json:"done"`
+	Running []string `json:"running"` // 正在执行的配置
+	Percent int      `json:"percent"` // 各配置完成百分比的平均值
+}
+
+// RsyncGroupEvent 分组执行过程中某个配置的事件，附带整体进度
+type RsyncGroupEvent struct {
+	Config   string             `json:"config"`
+	Event    models.RsyncEvent  `json:"event"`
+	Progress RsyncGroupProgress `json:"progress"`
+}
+
+// RsyncGroupEventHandler 接收分组执行过程中的事件
+type RsyncGroupEventHandler func(event RsyncGroupEvent)
+
+// groupRun 一次分组执行的共享状态
+type groupRun struct {
+	mu       sync.Mutex
+	parallel bool       // 同时执行多个配置，不连接标准输入，错误输出逐行加上配置名称
+	outputMu sync.Mutex // 同时执行时保证各配置的错误输出按整行写入
+	handler  RsyncGroupEventHandler
+	result   models.RsyncGroupResult
+	percents []int
+	running  map[int]bool
+	done     int
+	failed   bool
+}
+
+// RunRsyncGroup 执行分组中的所有配置，顺序执行时逐个执行，并行执行时同时执行的数量不超过 Concurrency
+// 设置了 StopOnFailure 时，有配置失败后不再开始执行其余配置（并行时已经开始的会执行完）
+func RunRsyncGroup(name string, handler RsyncGroupEventHandler) (*models.RsyncGroupResult, error) {
+	if handler == nil {
+		handler = func(RsyncGroupEvent) {}
+	}
+
+	group, err := GetRsyncGroupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	configs, missing, err := GetRsyncGroupConfigs(group)
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("rsync分组 '%s' 中没有可执行的配置", name)
+	}
+	run := &groupRun{
+		handler:  handler,
+		result:   models.RsyncGroupResult{GroupName: group.Name, Items: make([]models.RsyncGroupItemResult, len(configs)), Missing: missing},
+		percents: make([]int, len(configs)),
+		running:  make(map[int]bool),
+	}
+	for i, config := range configs {
+		run.result.Items[i] = models.RsyncGroupItemResult{ConfigName: config.Name, Status: models.RsyncGroupItemSkipped}
+	}
+
+	start := time.Now()
+	limit := group.Limit()
+	if limit <= 0 {
+		limit = len(configs)
+	}
+	run.parallel = limit > 1 && len(configs) > 1
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		slots <- struct{}{}
+		if group.StopOnFailure && run.hasFailed() {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(index int, config models.RsyncConfig) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			run.runConfig(index, config)
+		}(i, config)
+	}
+	wg.Wait()
+
+	run.result.Summarize(time.Since(start))
+	if err := incrementRsyncGroupUsage(group); err != nil {
+		return &run.result, err
+	}
+	if run.result.Failed > 0 {
+		return &run.result, fmt.Errorf("%d 个配置执行失败", run.result.Failed)
+	}
+	return &run.result, nil
+}
+
+// runConfig 执行分组中的一个配置并记录结果
+func (g *groupRun) runConfig(index int, config models.RsyncConfig) {
+	g.mu.Lock()
+	g.running[index] = true
+	g.mu.Unlock()
+
+	opts := RsyncRunOptions{
+		Trigger: models.RsyncTriggerGroup,
+		Handler: func(event models.RsyncEvent) {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if event.Type == models.RsyncEventProgress {
+				g.percents[index] = event.Progress.Percent
+			}
+			// 结果事件在下面记录完成状态后再发送
+			if event.Type != models.RsyncEventResult {
+				g.emit(config.Name, event)
+			}
+		},
+	}
+	// 并行时多个 rsync 不能共用终端的标准输入，错误输出按配置名称区分
+	var stderr *prefixWriter
+	if g.parallel {
+		stderr = &prefixWriter{w: os.Stderr, mu: &g.outputMu, prefix: "[" + config.Name + "] "}
+		opts.Detached = true
+		opts.Stderr = stderr
+	}
+	result, err := RunRsyncConfig(config.Name, opts)
+	if stderr != nil {
+		stderr.Flush()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	item := &g.result.Items[index]
+	item.Result = result
+	if err != nil {
+		item.Status = models.RsyncGroupItemFailed
+		item.Error = err.Error()
+		g.failed = true
+	} else {
+		item.Status = models.RsyncGroupItemSuccess
+	}
+	delete(g.running, index)
+	g.percents[index] = 100
+	g.done++
+
+	if result == nil {
+		result = &models.RsyncResult{Error: item.Error}
+	}
+	g.emit(config.Name, models.RsyncEvent{Type: models.RsyncEventResult, Result: result})
+}
+
+// emit 发送带整体进度的事件，调用时需持有 g.mu
+func (g *groupRun) emit(configName string, event models.RsyncEvent) {
+	progress := RsyncGroupProgress{Total: len(g.result.Items), Done: g.done}
+	sum := 0
+	for i, percent := range g.percents {
+		sum += percent
+		if g.running[i] {
+			progress.Running = append(progress.Running, g.result.Items[i].ConfigName)
+		}
+	}
+	progress.Percent = sum / len(g.percents)
+	g.handler(RsyncGroupEvent{Config: configName, Event: event, Progress: progress})
+}
+
+func (g *groupRun) hasFailed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.failed
+}
+
+// incrementRsyncGroupUsage 增加分组的使用次数
+func incrementRsyncGroupUsage(group *models.RsyncGroup) error {
+	db := database.GetDB()
+	if err := db.Model(group).UpdateColumn("usage_count", gorm.Expr("usage_count + 1")).Error; err != nil {
+		return fmt.Errorf("更新分组使用次数失败: %v", err)
+	}
+	return nil
+}
+
+// prefixWriter 在每一行前加上前缀后写入 w，不完整的行保留到换行或 Flush 时写入
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex // 多个 prefixWriter 写入同一个 w 时共享
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	end := bytes.LastIndexByte(p.buf, '\n')
+	if end < 0 {
+		return len(data), nil
+	}
+	lines := p.buf[:end+1]
+	p.buf = append([]byte{}, p.buf[end+1:]...)
+	if err := p.writeLines(lines); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush 写入最后不以换行结尾的内容
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	lines := append(p.buf, '\n')
+	p.buf = nil
+	return p.writeLines(lines)
+}
+
+func (p *prefixWriter) writeLines(lines []byte) error {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			out.WriteString(p.prefix)
+			out.Write(line)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.w.Write(out.Bytes())
+	return err
+}
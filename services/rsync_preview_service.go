@@ -0,0 +1,109 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RsyncPreview 实际执行 rsync --dry-run 得到的变更预览
+type RsyncPreview struct {
+	ConfigName string                    `json:"config_name"`
+	Command    []string                  `json:"command"`
+	Changes    []models.ItemizedChange   `json:"changes"`
+	Summary    models.ItemizedSummary    `json:"summary"`
+	Engine     models.TransferEngineInfo `json:"engine"`
+}
+
+// PreviewRsyncConfig 以 --dry-run 执行rsync配置，解析逐项变更输出，不会修改任何文件
+// 使用 sftp 引擎时预览的是比较两端文件列表得到的传输计划，engine 不为空时代替配置中的传输引擎
+func PreviewRsyncConfig(configName string, vars map[string]string, engine models.TransferEngine) (*RsyncPreview, error) {
+	config, sshConn, err := loadRsyncConfigWithConnection(configName, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := selectTransferEngine(config, sshConn, engine)
+	if err != nil {
+		return nil, err
+	}
+	if info.Engine == models.TransferEngineSFTP {
+		t, err := planSFTPTransfer(config, sshConn)
+		if err != nil {
+			return nil, err
+		}
+		return &RsyncPreview{
+			ConfigName: config.Name,
+			Command:    t.listCommand,
+			Changes:    t.changes,
+			Summary:    models.SummarizeItemizedChanges(t.changes),
+			Engine:     info,
+		}, nil
+	}
+
+	cmdArgs, _, err := buildRsyncArgs(config, sshConn, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	preview, err := previewRsyncCommand(config, cmdArgs)
+	if err != nil {
+		return nil, err
+	}
+	preview.Engine = info
+	return preview, nil
+}
+
+// previewRsyncCommand 以 --dry-run 执行rsync命令并解析逐项变更
+func previewRsyncCommand(config *models.RsyncConfig, cmdArgs []string) (*RsyncPreview, error) {
+	cmdArgs = models.InsertRsyncOptions(cmdArgs, "--dry-run", models.ItemizeOutFormat)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("rsync预览失败: %v\n%s", err, msg)
+		}
+		return nil, fmt.Errorf("rsync预览失败: %v", err)
+	}
+
+	changes := models.ParseItemizedChanges(stdout.String())
+	return &RsyncPreview{
+		ConfigName: config.Name,
+		Command:    cmdArgs,
+		Changes:    changes,
+		Summary:    models.SummarizeItemizedChanges(changes),
+		Engine:     rsyncEngineInfo(config),
+	}, nil
+}
+
+// loadRsyncConfigWithConnection 获取替换变量后的rsync配置及其关联的SSH连接，vars 为 --var 指定的变量
+func loadRsyncConfigWithConnection(configName string, vars map[string]string) (*models.RsyncConfig, *models.SSHConnection, error) {
+	config, sshConn, err := loadRawRsyncConfig(configName)
+	if err != nil {
+		return nil, nil, err
+	}
+	rendered, _, err := renderRsyncConfig(config, sshConn, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rendered, sshConn, nil
+}
+
+// loadRawRsyncConfig 获取rsync配置（不替换变量）及其关联的SSH连接
+func loadRawRsyncConfig(configName string) (*models.RsyncConfig, *models.SSHConnection, error) {
+	config, err := GetRsyncConfigByName(configName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取rsync配置失败: %v", err)
+	}
+
+	sshConn, err := GetConnectionByName(config.SSHName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取SSH连接失败: %v", err)
+	}
+	return config, sshConn, nil
+}
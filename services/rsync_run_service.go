@@ -0,0 +1,149 @@
+package services
+
+import (
+	"alfred-tool/database"
+	"alfred-tool/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// ErrRsyncRunning 同一配置已经在执行
+var ErrRsyncRunning = errors.New("该rsync配置正在执行中")
+
+// lockRsyncConfig 获取配置的执行锁，防止定时任务和手动执行同时运行同一配置
+func lockRsyncConfig(config *models.RsyncConfig) (unlock func(), err error) {
+	unlock, err = acquireFileLock(fmt.Sprintf("rsync-%d.lock", config.ID))
+	if errors.Is(err, errLocked) {
+		return nil, fmt.Errorf("%w: %s", ErrRsyncRunning, config.Name)
+	}
+	return unlock, err
+}
+
+// errLocked 锁已被其他进程持有
+var errLocked = errors.New("locked")
+
+// acquireFileLock 获取本机的排他文件锁，进程退出时由系统自动释放
+func acquireFileLock(name string) (unlock func(), err error) {
+	lockDir := filepath.Join(os.TempDir(), "alfred-tool-locks")
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建锁目录失败: %v", err)
+	}
+
+	lockFile, err := os.OpenFile(filepath.Join(lockDir, name), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("创建锁文件失败: %v", err)
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		lockFile.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLocked
+		}
+		return nil, fmt.Errorf("获取锁失败: %v", err)
+	}
+
+	return func() {
+		syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+		lockFile.Close()
+	}, nil
+}
+
+// startRsyncRun 创建执行记录和日志文件
+func startRsyncRun(config *models.RsyncConfig, cmdArgs []string, trigger string, engine models.TransferEngine) (*models.RsyncRun, *os.File, error) {
+	run := &models.RsyncRun{
+		RsyncConfigID: config.ID,
+		ConfigName:    config.Name,
+		Trigger:       trigger,
+		Engine:        engine,
+		StartedAt:     time.Now(),
+		Command:       models.ShellJoin(cmdArgs),
+	}
+
+	db := database.GetDB()
+	if err := db.Create(run).Error; err != nil {
+		return nil, nil, fmt.Errorf("创建执行记录失败: %v", err)
+	}
+
+	logDir := database.GetLogDir()
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("创建日志目录失败: %v", err)
+	}
+	run.LogPath = filepath.Join(logDir, fmt.Sprintf("rsync-%d.log", run.ID))
+	logFile, err := os.Create(run.LogPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建日志文件失败: %v", err)
+	}
+	fmt.Fprintf(logFile, "$ %s\n", run.Command)
+
+	if err := db.Model(run).Update("log_path", run.LogPath).Error; err != nil {
+		logFile.Close()
+		return nil, nil, fmt.Errorf("更新执行记录失败: %v", err)
+	}
+	return run, logFile, nil
+}
+
+// finishRsyncRun 记录执行结束时间、退出码和统计信息
+func finishRsyncRun(run *models.RsyncRun, result models.RsyncResult, exitCode int) error {
+	endedAt := time.Now()
+	run.EndedAt = &endedAt
+	run.ExitCode = exitCode
+	run.ApplyResult(result)
+
+	db := database.GetDB()
+	if err := db.Save(run).Error; err != nil {
+		return fmt.Errorf("更新执行记录失败: %v", err)
+	}
+	return nil
+}
+
+// GetRsyncRuns 获取执行记录，从新到旧排列；configName 为空时返回所有配置的记录，limit <= 0 时不限制数量
+func GetRsyncRuns(configName string, limit int) ([]models.RsyncRun, error) {
+	db := database.GetDB()
+	query := db.Order("started_at DESC, id DESC")
+	if configName != "" {
+		config, err := GetRsyncConfigByName(configName)
+		if err != nil {
+			return nil, fmt.Errorf("获取rsync配置失败: %v", err)
+		}
+		query = query.Where("rsync_config_id = ?", config.ID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var runs []models.RsyncRun
+	if err := query.Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("读取执行记录失败: %v", err)
+	}
+	return runs, nil
+}
+
+// GetRsyncRun 按ID获取执行记录
+func GetRsyncRun(id uint) (*models.RsyncRun, error) {
+	var run models.RsyncRun
+	db := database.GetDB()
+	if err := db.First(&run, id).Error; err != nil {
+		return nil, fmt.Errorf("执行记录 %d 不存在", id)
+	}
+	return &run, nil
+}
+
+// GetLastRsyncRuns 获取每个rsync配置最近一次的执行记录，以配置ID为键
+func GetLastRsyncRuns() (map[uint]models.RsyncRun, error) {
+	var runs []models.RsyncRun
+	db := database.GetDB()
+	err := db.Where("id IN (?)", db.Model(&models.RsyncRun{}).Select("MAX(id)").Group("rsync_config_id")).
+		Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("读取执行记录失败: %v", err)
+	}
+
+	lastRuns := make(map[uint]models.RsyncRun, len(runs))
+	for _, run := range runs {
+		lastRuns[run.RsyncConfigID] = run
+	}
+	return lastRuns, nil
+}
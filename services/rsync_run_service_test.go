@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+)
+
+func TestLockRsyncConfig(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	config := &models.RsyncConfig{Name: "site"}
+	config.ID = 1
+
+	unlock, err := lockRsyncConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockRsyncConfig(config); !errors.Is(err, ErrRsyncRunning) {
+		t.Errorf("同一配置正在执行时应返回 ErrRsyncRunning，得到 %v", err)
+	}
+
+	other := &models.RsyncConfig{Name: "other"}
+	other.ID = 2
+	unlockOther, err := lockRsyncConfig(other)
+	if err != nil {
+		t.Errorf("不同配置可以同时执行: %v", err)
+	} else {
+		unlockOther()
+	}
+
+	unlock()
+	unlock, err = lockRsyncConfig(config)
+	if err != nil {
+		t.Fatalf("释放后应能再次获取锁: %v", err)
+	}
+	unlock()
+}
+
+func TestRsyncRunRecord(t *testing.T) {
+	setupTestDB(t)
+	config := &models.RsyncConfig{Name: "site", SSHName: "web", Direction: models.RsyncDirectionUpload, LocalPath: "/tmp/site", RemotePath: "/srv/site"}
+	if err := database.GetDB().Create(config).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	run, logFile, err := startRsyncRun(config, []string{"rsync", "-a", "/tmp/site/", "web:/srv/site"}, "manual", models.TransferEngineRsync)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logFile.Close()
+	if err := finishRsyncRun(run, models.RsyncResult{FilesTransferred: 3, TransferredSize: 1024}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := GetRsyncRuns("site", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("执行记录 = %d 条，期望 1 条", len(runs))
+	}
+	got := runs[0]
+	if got.EndedAt == nil || got.ExitCode != 0 || got.FilesTransferred != 3 || got.TransferredSize != 1024 {
+		t.Errorf("执行记录 = %+v", got)
+	}
+	data, err := os.ReadFile(got.LogPath)
+	if err != nil || !strings.HasPrefix(string(data), "$ rsync -a /tmp/site/ web:/srv/site") {
+		t.Errorf("日志应以执行的命令开头: %q, %v", data, err)
+	}
+
+	lastRuns, err := GetLastRsyncRuns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastRuns[config.ID].ID != run.ID {
+		t.Errorf("最近一次执行记录 = %d，期望 %d", lastRuns[config.ID].ID, run.ID)
+	}
+}
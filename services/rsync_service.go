@@ -3,10 +3,16 @@ package services
 import (
 	"alfred-tool/database"
 	"alfred-tool/models"
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // GetAllRsyncConfigs 获取所有rsync配置
@@ -61,68 +67,306 @@ func SearchRsyncConfigs(query string) ([]models.RsyncConfig, error) {
 	return configs, err
 }
 
-// ExecuteRsyncConfig 执行rsync配置
-func ExecuteRsyncConfig(configName string) error {
-	// 获取rsync配置
-	config, err := GetRsyncConfigByName(configName)
+// RsyncEventHandler 接收rsync执行过程中的事件
+type RsyncEventHandler func(event models.RsyncEvent)
+
+// RsyncRunOptions 执行rsync配置的选项
+type RsyncRunOptions struct {
+	Trigger   string                // 触发方式，默认为手动执行
+	Handler   RsyncEventHandler     // 接收执行事件，可以为空
+	Variables map[string]string     // 替换配置中的变量，优先于环境变量和配置中的默认值
+	Engine    models.TransferEngine // 不为空时代替配置中的传输引擎
+	Force     bool                  // 将要删除的文件超过删除保护的上限时仍然执行
+	Detached  bool                  // 不连接标准输入，与其他配置同时执行时避免争抢终端
+	Stderr    io.Writer             // 命令和钩子输出到终端的内容，为空时为标准错误
+}
+
+// RunRsyncConfig 执行rsync配置，把输出解析为文件、进度事件交给 Handler，结束时发送结果事件并返回最终结果
+// 执行前的钩子失败时不执行rsync，rsync 成功后才执行执行后的钩子，钩子的输出记录在执行日志中
+// 启用删除时执行前钩子之后先经过删除保护检查，源目录为空或删除超过上限时不执行
+// 同一配置正在执行时返回 ErrRsyncRunning
+func RunRsyncConfig(configName string, opts RsyncRunOptions) (*models.RsyncResult, error) {
+	config, sshConn, err := loadRsyncConfigWithConnection(configName, opts.Variables)
 	if err != nil {
-		return fmt.Errorf("获取rsync配置失败: %v", err)
+		return nil, err
 	}
 
-	// 获取SSH连接信息
-	sshConn, err := GetConnectionByName(config.SSHName)
+	unlock, err := lockRsyncConfig(config)
 	if err != nil {
-		return fmt.Errorf("获取SSH连接失败: %v", err)
+		return nil, err
 	}
+	defer unlock()
 
-	// 构建rsync命令
-	cmdArgs := config.BuildRsyncCommand(sshConn)
+	if config.Direction == models.RsyncDirectionBidirectional {
+		return runBidirectionalSync(config, sshConn, opts)
+	}
+
+	engine, err := selectTransferEngine(config, sshConn, opts.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmdArgs []string
+	var snapshot *snapshotPlan
+	if engine.Engine == models.TransferEngineSFTP {
+		cmdArgs = models.BuildSFTPCommand(sshConn, config)
+	} else if cmdArgs, snapshot, err = buildRsyncArgs(config, sshConn, time.Now()); err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		if err := snapshot.prepare(); err != nil {
+			return nil, err
+		}
+	}
 
-	fmt.Printf("执行命令: %s\n", strings.Join(cmdArgs, " "))
+	execution, err := startTransferExecution(config, sshConn, cmdArgs, engine, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer execution.close()
 
-	// 执行命令
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	if err := execution.runHooks(models.RsyncHookPre, nil); err != nil {
+		return execution.finish(models.RsyncResult{}, models.RsyncRunExitCodeHookFailed, err)
+	}
 
-	fmt.Println("开始执行rsync...")
-	err = cmd.Run()
-	if err == nil {
-		fmt.Println("rsync执行完成")
+	if err := execution.guardDeletes(opts.Force); err != nil {
+		return execution.finish(models.RsyncResult{}, models.RsyncRunExitCodeDeleteGuard, err)
+	}
+
+	result, exitCode, err := execution.transfer()
+	if err != nil {
+		return execution.finish(result, exitCode, err)
+	}
+
+	if snapshot != nil {
+		if err := snapshot.commit(); err != nil {
+			return execution.finish(result, exitCode, err)
+		}
+		execution.run.Snapshot = snapshot.name
+	}
+
+	if err := execution.runHooks(models.RsyncHookPost, &result); err != nil {
+		return execution.finish(result, models.RsyncRunExitCodeHookFailed, err)
 	}
+
+	if config.VerifyAfterRun && !config.DryRun {
+		result.Verify = execution.verify()
+	}
+
+	final, err := execution.finish(result, exitCode, nil)
 	if err != nil {
-		return fmt.Errorf("rsync执行失败: %v", err)
+		return final, err
+	}
+
+	if snapshot != nil {
+		if _, err := PruneRsyncSnapshots(config.Name, false); err != nil {
+			return final, fmt.Errorf("清理旧快照失败: %v", err)
+		}
 	}
 
 	// 更新使用次数
-	db := database.GetDB()
-	db.Model(config).Update("usage_count", config.UsageCount+1)
-	db.Model(sshConn).Update("usage_count", sshConn.UsageCount+1)
-	return nil
+	return final, incrementRsyncUsage(config, sshConn)
+}
+
+// buildRsyncArgs 生成执行配置的rsync命令，快照备份时同时返回本次快照的计划（尚未创建目录）
+func buildRsyncArgs(config *models.RsyncConfig, sshConn *models.SSHConnection, now time.Time) ([]string, *snapshotPlan, error) {
+	if err := config.ValidateFilters(); err != nil {
+		return nil, nil, err
+	}
+	if config.Direction == models.RsyncDirectionBidirectional {
+		return nil, nil, fmt.Errorf("双向同步由多条rsync命令完成，请使用 rsync run --dry-run 预览")
+	}
+	if config.Direction == models.RsyncDirectionRemote {
+		return buildRemoteTransferArgs(config, sshConn)
+	}
+	if !config.SnapshotMode {
+		ignoreRules, err := RsyncIgnoreRules(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		return localRsyncArgs(config.BuildRsyncCommand(sshConn, ignoreRules), sshConn), nil, nil
+	}
+	if err := config.ValidateSnapshot(); err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := planRsyncSnapshot(config, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return localRsyncArgs(config.BuildSnapshotCommand(sshConn, snapshot.dir, snapshot.linkDest), sshConn), snapshot, nil
+}
+
+// rsyncExecution 一次执行的执行记录、日志文件和事件处理
+type rsyncExecution struct {
+	config  *models.RsyncConfig
+	sshConn *models.SSHConnection
+	cmdArgs []string
+	run     *models.RsyncRun
+	logFile *os.File
+	handler RsyncEventHandler
+	engine  models.TransferEngineInfo
+	stdin   io.Reader // 需要交互的命令（如询问密码）的标准输入，分离执行时为空
+	stderr  io.Writer // 命令和钩子输出到终端的内容
+}
+
+// startRsyncExecution 以 rsync 执行时创建执行记录和日志文件并发送开始事件，调用方需持有配置的执行锁
+func startRsyncExecution(config *models.RsyncConfig, sshConn *models.SSHConnection, cmdArgs []string, opts RsyncRunOptions) (*rsyncExecution, error) {
+	return startTransferExecution(config, sshConn, cmdArgs, rsyncEngineInfo(config), opts)
+}
+
+// startTransferExecution 以指定的传输引擎创建执行记录和日志文件并发送开始事件，调用方需持有配置的执行锁
+func startTransferExecution(config *models.RsyncConfig, sshConn *models.SSHConnection, cmdArgs []string, engine models.TransferEngineInfo, opts RsyncRunOptions) (*rsyncExecution, error) {
+	handler := opts.Handler
+	if handler == nil {
+		handler = func(models.RsyncEvent) {}
+	}
+	if opts.Trigger == "" {
+		opts.Trigger = models.RsyncTriggerManual
+	}
+
+	if engine.Engine == models.TransferEngineRsync {
+		cmdArgs = withOutputOptions(cmdArgs)
+	}
+
+	// 记录本次执行，完整输出写入日志文件
+	run, logFile, err := startRsyncRun(config, cmdArgs, opts.Trigger, engine.Engine)
+	if err != nil {
+		return nil, err
+	}
+	handler(models.RsyncEvent{Type: models.RsyncEventStart, Command: cmdArgs, Engine: &engine})
+
+	execution := &rsyncExecution{
+		config:  config,
+		sshConn: sshConn,
+		cmdArgs: cmdArgs,
+		run:     run,
+		logFile: logFile,
+		handler: handler,
+		engine:  engine,
+		stdin:   os.Stdin,
+		stderr:  opts.Stderr,
+	}
+	if opts.Detached {
+		execution.stdin = nil
+	}
+	if execution.stderr == nil {
+		execution.stderr = os.Stderr
+	}
+	return execution, nil
 }
 
-// DryRunRsyncConfig 预览rsync命令（不执行）
-func DryRunRsyncConfig(configName string) (string, error) {
-	// 获取rsync配置
-	config, err := GetRsyncConfigByName(configName)
+// withOutputOptions 加上解析所需的逐项输出、进度和统计选项
+// 服务器之间传输时rsync在源服务器上执行，版本未知，使用所有版本都支持的 --progress
+func withOutputOptions(cmdArgs []string) []string {
+	progress := "--progress"
+	if cmdArgs[0] == "rsync" {
+		progress = rsyncProgressOption()
+	}
+	return models.InsertRsyncOptions(cmdArgs, models.ItemizeOutFormat, progress, "--stats")
+}
+
+// transfer 按本次执行的传输引擎执行传输
+func (e *rsyncExecution) transfer() (models.RsyncResult, int, error) {
+	if e.engine.Engine == models.TransferEngineSFTP {
+		return e.sftp()
+	}
+	return e.rsync()
+}
+
+// rsync 执行rsync命令，把输出解析为事件，返回统计结果和退出码
+func (e *rsyncExecution) rsync() (models.RsyncResult, int, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(e.cmdArgs[0], e.cmdArgs[1:]...)
+	cmd.Stderr = io.MultiWriter(e.stderr, &stderr, e.logFile)
+	cmd.Stdin = e.stdin
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
 	if err != nil {
-		return "", fmt.Errorf("获取rsync配置失败: %v", err)
+		return models.RsyncResult{}, models.RsyncRunExitCodeNotStarted, fmt.Errorf("rsync执行失败: %v", err)
+	}
+
+	var parser models.RsyncOutputParser
+	scanner := bufio.NewScanner(io.TeeReader(stdout, e.logFile))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(models.ScanRsyncLines)
+	for scanner.Scan() {
+		if event := parser.ParseLine(scanner.Text()); event != nil {
+			e.handler(*event)
+		}
+	}
+	err = cmd.Wait()
+
+	result := parser.Result()
+	if err == nil {
+		return result, 0, nil
+	}
+
+	exitCode := models.RsyncRunExitCodeNotStarted
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	result.Error = err.Error()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		result.Error += ": " + msg
 	}
+	return result, exitCode, fmt.Errorf("rsync执行失败: %v", err)
+}
+
+// finish 发送结果事件并记录执行结束，err 不为空表示本次执行失败
+func (e *rsyncExecution) finish(result models.RsyncResult, exitCode int, err error) (*models.RsyncResult, error) {
+	result.RunID = e.run.ID
+	result.Duration = time.Since(e.run.StartedAt)
+	result.DurationSeconds = result.Duration.Seconds()
+	result.Success = err == nil
+	if err != nil && result.Error == "" {
+		result.Error = err.Error()
+	}
+	e.handler(models.RsyncEvent{Type: models.RsyncEventResult, Result: &result})
+
+	if recordErr := finishRsyncRun(e.run, result, exitCode); recordErr != nil {
+		return &result, recordErr
+	}
+	return &result, err
+}
+
+// close 关闭日志文件
+func (e *rsyncExecution) close() {
+	e.logFile.Close()
+}
+
+// incrementRsyncUsage 在同一事务中增加rsync配置和SSH连接的使用次数
+func incrementRsyncUsage(config *models.RsyncConfig, sshConn *models.SSHConnection) error {
+	db := database.GetDB()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(config).UpdateColumn("usage_count", gorm.Expr("usage_count + 1")).Error; err != nil {
+			return fmt.Errorf("更新rsync使用次数失败: %v", err)
+		}
+		if err := tx.Model(sshConn).UpdateColumn("usage_count", gorm.Expr("usage_count + 1")).Error; err != nil {
+			return fmt.Errorf("更新SSH使用次数失败: %v", err)
+		}
+		return nil
+	})
+}
 
-	// 获取SSH连接信息
-	sshConn, err := GetConnectionByName(config.SSHName)
+// DryRunRsyncConfig 生成带 --dry-run 的rsync命令（不执行）
+func DryRunRsyncConfig(configName string, vars map[string]string) (string, error) {
+	config, sshConn, err := loadRsyncConfigWithConnection(configName, vars)
 	if err != nil {
-		return "", fmt.Errorf("获取SSH连接失败: %v", err)
+		return "", err
 	}
 
 	// 构建rsync命令
-	cmdArgs := config.BuildRsyncCommand(sshConn)
+	cmdArgs, _, err := buildRsyncArgs(config, sshConn, time.Now())
+	if err != nil {
+		return "", err
+	}
 
 	// 添加 --dry-run 参数进行预览
-	cmdArgs = append(cmdArgs[:1], append([]string{"--dry-run"}, cmdArgs[1:]...)...)
+	cmdArgs = models.InsertRsyncOptions(cmdArgs, "--dry-run")
 
-	return strings.Join(cmdArgs, " "), nil
+	return models.ShellJoin(cmdArgs), nil
 }
 
 // ValidateRsyncConfig 验证rsync配置
@@ -133,8 +377,54 @@ func ValidateRsyncConfig(config *models.RsyncConfig) error {
 		return fmt.Errorf("SSH连接 '%s' 不存在", config.SSHName)
 	}
 
-	// 检查本地路径
-	if config.Direction == models.RsyncDirectionUpload {
+	// 检查额外选项的引号是否匹配
+	if _, err := config.ParseOptions(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateSnapshot(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateFilters(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateBidirectional(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateRemoteTransfer(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateTemplates(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateLimits(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateEngine(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateVerify(); err != nil {
+		return err
+	}
+
+	if err := config.ValidateDeleteGuard(); err != nil {
+		return err
+	}
+	if config.Direction == models.RsyncDirectionRemote {
+		if _, err := GetConnectionByName(config.TargetSSHName); err != nil {
+			return fmt.Errorf("目标SSH连接 '%s' 不存在", config.TargetSSHName)
+		}
+	}
+
+	// 检查本地路径，使用变量的路径在执行时才能确定
+	if config.Direction == models.RsyncDirectionUpload && len(config.TemplateVariableNames()) == 0 {
 		if _, err := os.Stat(config.LocalPath); os.IsNotExist(err) {
 			return fmt.Errorf("本地路径 '%s' 不存在", config.LocalPath)
 		}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"alfred-tool/database"
+	"alfred-tool/models"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ScheduledRsyncConfig 设置了定时执行的rsync配置
+type ScheduledRsyncConfig struct {
+	Config   models.RsyncConfig
+	Schedule *models.CronSchedule
+	LastRun  *models.RsyncRun // 最近一次执行，可能为空
+	NextRun  time.Time        // 下一次应执行的时间，早于当前时间表示错过了执行需要补执行
+}
+
+// Due 是否已经到了执行时间
+func (s *ScheduledRsyncConfig) Due(now time.Time) bool {
+	return !s.Config.SchedulePaused && !s.NextRun.IsZero() && !s.NextRun.After(now)
+}
+
+// GetScheduledRsyncConfigs 获取所有设置了定时执行的rsync配置
+// 下一次执行时间从最近一次执行（或配置最后修改）之后计算，因此守护进程停止期间错过的执行只会补一次
+func GetScheduledRsyncConfigs() ([]ScheduledRsyncConfig, error) {
+	var configs []models.RsyncConfig
+	db := database.GetDB()
+	if err := db.Where("schedule <> ''").Order("name").Find(&configs).Error; err != nil {
+		return nil, fmt.Errorf("读取rsync配置失败: %v", err)
+	}
+
+	lastRuns, err := GetLastRsyncRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []ScheduledRsyncConfig
+	for _, config := range configs {
+		schedule, err := models.ParseCron(config.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("rsync配置 '%s' 的定时设置无效: %v", config.Name, err)
+		}
+
+		item := ScheduledRsyncConfig{Config: config, Schedule: schedule}
+		baseline := config.UpdatedAt
+		if run, ok := lastRuns[config.ID]; ok {
+			item.LastRun = &run
+			if run.StartedAt.After(baseline) {
+				baseline = run.StartedAt
+			}
+		}
+		item.NextRun = schedule.Next(baseline)
+		scheduled = append(scheduled, item)
+	}
+	return scheduled, nil
+}
+
+// SetRsyncSchedule 设置rsync配置的定时执行，expr 为空时取消定时执行
+func SetRsyncSchedule(name, expr string) error {
+	expr = strings.TrimSpace(expr)
+	if expr != "" {
+		if _, err := models.ParseCron(expr); err != nil {
+			return err
+		}
+	}
+
+	config, err := GetRsyncConfigByName(name)
+	if err != nil {
+		return fmt.Errorf("获取rsync配置失败: %v", err)
+	}
+	return updateScheduleColumn(config, "schedule", expr)
+}
+
+// PauseRsyncSchedule 暂停rsync配置的定时执行
+func PauseRsyncSchedule(name string) error {
+	return setSchedulePaused(name, true)
+}
+
+// ResumeRsyncSchedule 恢复rsync配置的定时执行，暂停期间错过的执行不会补执行
+func ResumeRsyncSchedule(name string) error {
+	return setSchedulePaused(name, false)
+}
+
+func setSchedulePaused(name string, paused bool) error {
+	config, err := GetRsyncConfigByName(name)
+	if err != nil {
+		return fmt.Errorf("获取rsync配置失败: %v", err)
+	}
+	if config.Schedule == "" {
+		return fmt.Errorf("rsync配置 '%s' 没有设置定时执行", name)
+	}
+	return updateScheduleColumn(config, "schedule_paused", paused)
+}
+
+// updateScheduleColumn 更新定时设置，同时更新 updated_at，下一次执行时间从此刻重新计算
+func updateScheduleColumn(config *models.RsyncConfig, column string, value any) error {
+	db := database.GetDB()
+	if err := db.Model(config).Update(column, value).Error; err != nil {
+		return fmt.Errorf("更新定时设置失败: %v", err)
+	}
+	return nil
+}
+
+// ExportCrontab 把定时设置导出为 crontab 条目，暂停的配置会被注释掉
+func ExportCrontab() (string, error) {
+	scheduled, err := GetScheduledRsyncConfigs()
+	if err != nil {
+		return "", err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("获取程序路径失败: %v", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("# alfred-tool rsync 定时任务\n")
+	for _, item := range scheduled {
+		command := models.ShellJoin([]string{executable, "rsync", "run", item.Config.Name, "--trigger", models.RsyncTriggerSchedule})
+		// crontab 中 % 表示换行，需要转义
+		command = strings.ReplaceAll(command, "%", `\%`)
+		line := fmt.Sprintf("%s %s\n", item.Schedule.Expr, command)
+		if item.Config.SchedulePaused {
+			line = "# 已暂停: " + line
+		}
+		b.WriteString(line)
+	}
+	return b.String(), nil
+}
+
+// SystemdUnit 一个 systemd 单元文件
+type SystemdUnit struct {
+	Name    string
+	Content string
+}
+
+// ExportSystemdUnits 把定时设置导出为 systemd service 和 timer 单元，暂停的配置不导出
+// timer 设置了 Persistent=true，关机期间错过的执行会在开机后补执行
+func ExportSystemdUnits() ([]SystemdUnit, error) {
+	scheduled, err := GetScheduledRsyncConfigs()
+	if err != nil {
+		return nil, err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("获取程序路径失败: %v", err)
+	}
+
+	var units []SystemdUnit
+	for _, item := range scheduled {
+		if item.Config.SchedulePaused {
+			continue
+		}
+		unitName := fmt.Sprintf("alfred-tool-rsync-%d", item.Config.ID)
+		description := fmt.Sprintf("alfred-tool rsync: %s", strings.ReplaceAll(item.Config.Name, "%", "%%"))
+
+		var execStart []string
+		for _, arg := range []string{executable, "rsync", "run", item.Config.Name, "--trigger", models.RsyncTriggerSchedule} {
+			execStart = append(execStart, systemdQuote(arg))
+		}
+
+		service := fmt.Sprintf("[Unit]\nDescription=%s\n\n[Service]\nType=oneshot\nExecStart=%s\n",
+			description, strings.Join(execStart, " "))
+
+		var timer strings.Builder
+		fmt.Fprintf(&timer, "[Unit]\nDescription=%s (%s)\n\n[Timer]\n", description, item.Schedule.Expr)
+		for _, calendar := range item.Schedule.SystemdOnCalendar() {
+			fmt.Fprintf(&timer, "OnCalendar=%s\n", calendar)
+		}
+		timer.WriteString("Persistent=true\n\n[Install]\nWantedBy=timers.target\n")
+
+		units = append(units,
+			SystemdUnit{Name: unitName + ".service", Content: service},
+			SystemdUnit{Name: unitName + ".timer", Content: timer.String()},
+		)
+	}
+	return units, nil
+}
+
+// systemdQuote 按 systemd ExecStart 的规则引用参数，% 和 $ 需要重复以避免被展开
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(arg) + `"`
+}
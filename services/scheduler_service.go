@@ -0,0 +1,182 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// SchedulerOptions 定时任务守护进程的选项
+type SchedulerOptions struct {
+	Retries int           // 失败后最多重试次数
+	Backoff time.Duration // 第一次重试的等待时间，之后每次翻倍
+}
+
+// schedulerPollInterval 最长多久重新读取一次配置，使新增或修改的定时设置生效
+const schedulerPollInterval = time.Minute
+
+// retryState 等待重试的任务
+type retryState struct {
+	attempt int
+	at      time.Time
+}
+
+// Scheduler 执行到期的rsync定时任务
+// 同一配置不会重叠执行，失败后按指数退避重试
+type Scheduler struct {
+	opts    SchedulerOptions
+	mu      sync.Mutex
+	running map[uint]bool
+	started map[uint]time.Time // 本进程中最近一次启动的时间，没能生成执行记录时也不会重复执行
+	retries map[uint]retryState
+	wg      sync.WaitGroup
+}
+
+// NewScheduler 创建调度器
+func NewScheduler(opts SchedulerOptions) *Scheduler {
+	return &Scheduler{
+		opts:    opts,
+		running: make(map[uint]bool),
+		started: make(map[uint]time.Time),
+		retries: make(map[uint]retryState),
+	}
+}
+
+// Run 持续执行到期的任务，直到 ctx 结束，之后等待正在执行的任务完成
+func (s *Scheduler) Run(ctx context.Context) error {
+	unlock, err := lockDaemon()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	log.Println("定时任务守护进程已启动")
+	for {
+		next, err := s.RunDue(time.Now())
+		if err != nil {
+			log.Printf("检查定时任务失败: %v", err)
+		}
+
+		wait := schedulerPollInterval
+		if !next.IsZero() {
+			if d := time.Until(next); d < wait {
+				wait = d
+			}
+		}
+		if wait < time.Second {
+			wait = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("正在等待执行中的任务结束...")
+			s.Wait()
+			log.Println("定时任务守护进程已退出")
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RunDue 在后台启动所有到期的任务（包括错过后需要补执行的和等待重试的），返回最近一个未到期任务的时间
+func (s *Scheduler) RunDue(now time.Time) (time.Time, error) {
+	scheduled, err := GetScheduledRsyncConfigs()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	earliest := func(t time.Time) {
+		if !t.IsZero() && t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	for _, item := range scheduled {
+		config := item.Config
+		if config.SchedulePaused || s.running[config.ID] {
+			continue
+		}
+
+		nextRun := item.NextRun
+		if started, ok := s.started[config.ID]; ok {
+			if n := item.Schedule.Next(started); n.After(nextRun) {
+				nextRun = n
+			}
+		}
+
+		retry, retrying := s.retries[config.ID]
+		switch {
+		case !nextRun.IsZero() && !nextRun.After(now):
+			if nextRun.Before(now.Add(-schedulerPollInterval)) {
+				log.Printf("补执行错过的定时任务 %s（应在 %s 执行）", config.Name, nextRun.Format("2006-01-02 15:04"))
+			}
+			delete(s.retries, config.ID)
+			s.start(config, models.RsyncTriggerSchedule, 0)
+		case retrying && !retry.at.After(now):
+			s.start(config, models.RsyncTriggerRetry, retry.attempt)
+		default:
+			earliest(nextRun)
+			if retrying {
+				earliest(retry.at)
+			}
+		}
+	}
+	return next, nil
+}
+
+// Wait 等待所有正在执行的任务结束
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// start 在后台执行任务，调用时需持有 s.mu
+func (s *Scheduler) start(config models.RsyncConfig, trigger string, attempt int) {
+	s.running[config.ID] = true
+	s.started[config.ID] = time.Now()
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		log.Printf("开始执行 %s（%s）", config.Name, trigger)
+		result, err := RunRsyncConfig(config.Name, RsyncRunOptions{Trigger: trigger})
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.running, config.ID)
+
+		switch {
+		case errors.Is(err, ErrRsyncRunning):
+			log.Printf("跳过 %s: 上一次执行尚未结束", config.Name)
+		case err != nil:
+			if attempt < s.opts.Retries {
+				delay := s.opts.Backoff << attempt
+				s.retries[config.ID] = retryState{attempt: attempt + 1, at: time.Now().Add(delay)}
+				log.Printf("%s 执行失败: %v，%s 后第 %d 次重试", config.Name, err, delay, attempt+1)
+			} else {
+				delete(s.retries, config.ID)
+				log.Printf("%s 执行失败: %v", config.Name, err)
+			}
+		default:
+			delete(s.retries, config.ID)
+			log.Printf("%s 执行完成: 传输 %d 个文件（%s），用时 %s",
+				config.Name, result.FilesTransferred, models.FormatBytes(result.TransferredSize), result.Duration.Round(time.Second))
+		}
+	}()
+}
+
+// lockDaemon 保证同一时间只有一个守护进程在运行
+func lockDaemon() (unlock func(), err error) {
+	unlock, err = acquireFileLock("daemon.lock")
+	if errors.Is(err, errLocked) {
+		return nil, errors.New("已有守护进程在运行")
+	}
+	return unlock, err
+}
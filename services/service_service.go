@@ -5,6 +5,9 @@ import (
 	"alfred-tool/models"
 	"errors"
 	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ServiceService struct{}
@@ -64,6 +67,11 @@ func (s *ServiceService) SearchServices(keyword string) ([]models.Service, error
 }
 
 func (s *ServiceService) UpdateService(service *models.Service) error {
+	return updateService(database.GetDB(), service)
+}
+
+// updateService 在 db 上（可以是外层事务）更新服务，检查是否与其他服务同名
+func updateService(db *gorm.DB, service *models.Service) error {
 	if service.ID == 0 {
 		return errors.New("服务ID不能为空")
 	}
@@ -72,11 +80,11 @@ func (s *ServiceService) UpdateService(service *models.Service) error {
 	}
 
 	var existingService models.Service
-	if err := database.GetDB().Where("name = ? AND id != ?", service.Name, service.ID).First(&existingService).Error; err == nil {
+	if err := db.Where("name = ? AND id != ?", service.Name, service.ID).First(&existingService).Error; err == nil {
 		return errors.New("已存在同名服务")
 	}
 
-	return database.GetDB().Save(service).Error
+	return db.Unscoped().Omit(clause.Associations).Save(service).Error
 }
 
 func (s *ServiceService) DeleteService(id uint) error {
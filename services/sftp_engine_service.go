@@ -0,0 +1,342 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sftpTransfer sftp 引擎一次传输的计划
+type sftpTransfer struct {
+	localRoot   string
+	remoteRoot  string
+	prefix      string
+	upload      bool
+	destExists  bool // 目标目录是否已存在
+	totalSize   int64
+	listCommand []string // 列出服务器上文件的 sftp 命令
+	changes     []models.ItemizedChange
+
+	deleteCounts models.DeleteGuardCounts // 删除保护使用的文件数
+}
+
+// planSFTPTransfer 列出本地和服务器上的文件，按大小和修改时间比较生成传输计划，不会修改任何文件
+func planSFTPTransfer(config *models.RsyncConfig, sshConn *models.SSHConnection) (*sftpTransfer, error) {
+	matcher, err := RsyncFilterMatcher(config)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &sftpTransfer{upload: config.Direction == models.RsyncDirectionUpload}
+	t.localRoot, t.remoteRoot, t.prefix = config.SFTPTransferRoots()
+	t.listCommand = models.BuildSFTPCommand(sshConn, config)
+
+	remote, err := listRemoteTree(t.listCommand, t.remoteRoot)
+	if err != nil {
+		return nil, err
+	}
+	var localExcluded func(rel string, isDir bool) bool
+	if t.upload {
+		localExcluded = func(rel string, isDir bool) bool {
+			return matcher.Excluded(path.Join(t.prefix, rel), isDir)
+		}
+	}
+	local, err := listLocalEntries(t.localRoot, localExcluded)
+	if err != nil {
+		return nil, err
+	}
+
+	source, dest := local, remote
+	if !t.upload {
+		source, dest = remote, local
+	}
+	if root, ok := source[""]; !ok || !root.IsDir {
+		if t.upload {
+			return nil, fmt.Errorf("本地路径 '%s' 不存在或不是目录", t.localRoot)
+		}
+		return nil, fmt.Errorf("服务器路径 '%s' 不存在或不是目录", t.remoteRoot)
+	}
+	_, t.destExists = dest[""]
+	for _, entry := range source {
+		if !entry.IsDir {
+			t.totalSize += entry.Size
+		}
+	}
+	t.deleteCounts.Source = len(source) - 1
+	if t.destExists {
+		t.deleteCounts.Dest = len(dest) - 1
+	}
+
+	t.changes = models.PlanFileTransfer(source, dest, models.TransferPlanOptions{
+		Upload:   t.upload,
+		Prefix:   t.prefix,
+		Delete:   config.Delete,
+		MaxSize:  config.MaxSize,
+		Excluded: matcher.Excluded,
+		Protected: func(rel string, isDir bool) bool {
+			return matcher.Protected(rel, isDir).Excluded
+		},
+	})
+	for _, change := range t.changes {
+		if change.Kind == models.ItemizedChangeDeleted {
+			t.deleteCounts.Deleted++
+		}
+	}
+	return t, nil
+}
+
+// listLocalEntries 列出本地目录中的文件和目录，键为相对路径，根目录为空字符串；目录不存在时返回空列表
+// 跳过符号链接和特殊文件，excluded 不为空时跳过被排除的路径
+func listLocalEntries(root string, excluded func(rel string, isDir bool) bool) (map[string]models.TransferEntry, error) {
+	entries := make(map[string]models.TransferEntry)
+	resolved, err := filepath.EvalSymlinks(root)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取本地路径 '%s' 失败: %v", root, err)
+	}
+
+	err = filepath.WalkDir(resolved, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("读取 '%s' 失败: %v", p, err)
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(resolved, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		} else if excluded != nil && excluded(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("读取 '%s' 失败: %v", p, err)
+		}
+		entry := models.TransferEntry{IsDir: d.IsDir(), ModTime: info.ModTime().Unix(), Mode: uint32(info.Mode().Perm())}
+		if !entry.IsDir {
+			entry.Size = info.Size()
+		}
+		entries[rel] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// sftp 以 sftp 引擎执行传输：生成传输计划，下载时在本地删除和新建目录，其余操作以 sftp 批处理命令执行
+// 试运行时只按计划发送文件事件，不修改任何文件
+func (e *rsyncExecution) sftp() (models.RsyncResult, int, error) {
+	t, err := planSFTPTransfer(e.config, e.sshConn)
+	if err != nil {
+		return models.RsyncResult{}, models.RsyncRunExitCodeNotStarted, err
+	}
+	fmt.Fprintf(e.logFile, "$ %s\n", models.ShellJoin(t.listCommand))
+
+	result := models.RsyncResult{TotalSize: t.totalSize}
+	if e.config.DryRun {
+		for i := range t.changes {
+			e.sftpChange(&t.changes[i])
+		}
+		return result, 0, nil
+	}
+
+	if err := t.prepareDest(e.sshConn); err != nil {
+		return result, models.RsyncRunExitCodeNotStarted, err
+	}
+
+	preserve := e.config.Archive || e.config.Times || e.config.Perms
+	lines, err := models.BuildSFTPBatch(t.changes, t.localRoot, t.remoteRoot, t.prefix, t.upload, preserve)
+	if err != nil {
+		return result, models.RsyncRunExitCodeNotStarted, err
+	}
+	if !t.upload {
+		// 下载时 sftp 只执行 get，删除和新建目录在本地完成
+		if err := e.applyLocalChanges(t); err != nil {
+			return result, models.RsyncRunExitCodeNotStarted, err
+		}
+	}
+	if len(lines) == 0 {
+		return result, 0, nil
+	}
+	return e.runSFTPBatch(t, lines, result)
+}
+
+// prepareDest 目标目录不存在时创建它及其上级目录
+func (t *sftpTransfer) prepareDest(sshConn *models.SSHConnection) error {
+	if t.destExists {
+		return nil
+	}
+	if !t.upload {
+		base := t.localRoot
+		if t.prefix != "" {
+			base = filepath.Dir(base)
+		}
+		if err := os.MkdirAll(base, 0755); err != nil {
+			return fmt.Errorf("创建本地目录 '%s' 失败: %v", base, err)
+		}
+		return nil
+	}
+	base := t.remoteRoot
+	if t.prefix != "" {
+		base = path.Dir(base)
+	}
+	cmdArgs := models.BuildRemoteMkdirCommand(sshConn, base)
+	if output, err := exec.Command(cmdArgs[0], cmdArgs[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("创建服务器目录 '%s' 失败: %v\n%s", base, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// applyLocalChanges 下载时在本地执行计划中的删除和新建目录
+func (e *rsyncExecution) applyLocalChanges(t *sftpTransfer) error {
+	for i := range t.changes {
+		change := &t.changes[i]
+		local := filepath.Join(t.localRoot, filepath.FromSlash(models.TransferRel(change.Path, t.prefix)))
+		switch {
+		case change.Kind == models.ItemizedChangeDeleted:
+			e.sftpChange(change)
+			if err := os.Remove(local); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("删除 '%s' 失败: %v", local, err)
+			}
+		case change.FileType == "d":
+			e.sftpChange(change)
+			if err := os.MkdirAll(local, 0755); err != nil {
+				return fmt.Errorf("创建目录 '%s' 失败: %v", local, err)
+			}
+		}
+	}
+	return nil
+}
+
+// runSFTPBatch 执行 sftp 批处理命令，sftp 执行每条命令前会回显 "sftp> 命令"，据此发送文件和进度事件
+func (e *rsyncExecution) runSFTPBatch(t *sftpTransfer, lines []models.SFTPBatchLine, result models.RsyncResult) (models.RsyncResult, int, error) {
+	var batch strings.Builder
+	totalFiles := 0
+	var totalBytes int64 // 需要传输的文件总大小
+	for _, line := range lines {
+		batch.WriteString(line.Command + "\n")
+		if line.Change.FileType == "f" && line.Change.Kind != models.ItemizedChangeDeleted {
+			totalFiles++
+			totalBytes += line.Change.Size
+		}
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(e.cmdArgs[0], e.cmdArgs[1:]...)
+	cmd.Stdin = strings.NewReader(batch.String())
+	cmd.Stderr = io.MultiWriter(e.stderr, &stderr, e.logFile)
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return result, models.RsyncRunExitCodeNotStarted, fmt.Errorf("sftp执行失败: %v", err)
+	}
+
+	started := time.Now()
+	next := 0 // 下一条回显对应的命令
+	done := 0 // 已完成的文件数
+	var doneBytes int64
+	// complete 记录一条命令完成，是文件传输时返回 true
+	complete := func(line models.SFTPBatchLine) bool {
+		if line.Change.FileType != "f" || line.Change.Kind == models.ItemizedChangeDeleted {
+			return false
+		}
+		done++
+		doneBytes += line.Change.Size
+		return true
+	}
+	progress := func() {
+		p := models.RsyncProgress{
+			Bytes:       doneBytes,
+			Percent:     100,
+			Transferred: done,
+			ToCheck:     totalFiles - done,
+			TotalFiles:  totalFiles,
+		}
+		if totalBytes > 0 && doneBytes < totalBytes {
+			p.Percent = int(doneBytes * 100 / totalBytes)
+		}
+		if elapsed := time.Since(started).Seconds(); elapsed > 0 {
+			p.Rate = float64(doneBytes) / elapsed
+		}
+		if p.Rate > 0 {
+			p.ETASeconds = int64(float64(totalBytes-doneBytes) / p.Rate)
+		}
+		e.handler(models.RsyncEvent{Type: models.RsyncEventProgress, Progress: &p})
+	}
+
+	scanner := bufio.NewScanner(io.TeeReader(stdout, e.logFile))
+	for scanner.Scan() {
+		if !strings.HasPrefix(scanner.Text(), "sftp> ") || next >= len(lines) {
+			continue
+		}
+		if next > 0 && complete(lines[next-1]) {
+			progress()
+		}
+		e.handler(models.RsyncEvent{Type: models.RsyncEventFile, File: &lines[next].Change})
+		next++
+	}
+	err = cmd.Wait()
+
+	if err == nil {
+		// 没有回显时（如 sftp 版本不同）按全部完成处理
+		for i := max(next-1, 0); i < len(lines); i++ {
+			if i >= next {
+				e.handler(models.RsyncEvent{Type: models.RsyncEventFile, File: &lines[i].Change})
+			}
+			complete(lines[i])
+		}
+		progress()
+	}
+
+	result.FilesTransferred = done
+	result.TransferredSize = doneBytes
+	if t.upload {
+		result.BytesSent = doneBytes
+	} else {
+		result.BytesReceived = doneBytes
+	}
+	if doneBytes > 0 {
+		result.Speedup = float64(result.TotalSize) / float64(doneBytes)
+	}
+	if err == nil {
+		return result, 0, nil
+	}
+
+	exitCode := models.RsyncRunExitCodeNotStarted
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	result.Error = err.Error()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		result.Error += ": " + msg
+	}
+	return result, exitCode, fmt.Errorf("sftp执行失败: %v", err)
+}
+
+// sftpChange 发送一项变更的文件事件并按 rsync 逐项输出的格式写入日志
+func (e *rsyncExecution) sftpChange(change *models.ItemizedChange) {
+	fmt.Fprintf(e.logFile, "%s %s\n", change.Flags, change.Path)
+	e.handler(models.RsyncEvent{Type: models.RsyncEventFile, File: change})
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotPlan 一次快照备份的目录安排
+type snapshotPlan struct {
+	root     string // 快照所在目录（配置的 LocalPath）
+	name     string // 完成后的快照目录名称
+	dir      string // rsync 写入的目录，完成后重命名为 name
+	linkDest string // 上一个快照的绝对路径，为空表示第一个快照
+	resume   string // 上一次没有完成的快照目录，继续在其基础上同步
+}
+
+// planRsyncSnapshot 安排本次快照：新目录以当前时间命名，硬链接到 latest 指向的快照
+func planRsyncSnapshot(config *models.RsyncConfig, now time.Time) (*snapshotPlan, error) {
+	root, err := filepath.Abs(config.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("本地路径 '%s' 无效: %v", config.LocalPath, err)
+	}
+
+	plan := &snapshotPlan{root: root, name: models.SnapshotName(now)}
+	plan.dir = filepath.Join(root, plan.name+models.SnapshotInProgressSuffix)
+
+	snapshots, err := listSnapshots(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Name == plan.name {
+			return nil, fmt.Errorf("快照 '%s' 已存在，请稍后再执行", plan.name)
+		}
+		if snapshot.Latest {
+			plan.linkDest = snapshot.Path
+		}
+	}
+	// latest 链接丢失时使用最新的快照
+	if plan.linkDest == "" && len(snapshots) > 0 {
+		plan.linkDest = snapshots[0].Path
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取快照目录失败: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasSuffix(entry.Name(), models.SnapshotInProgressSuffix) {
+			plan.resume = filepath.Join(root, entry.Name())
+			break
+		}
+	}
+	return plan, nil
+}
+
+// prepare 创建快照所在目录，上一次没有完成的快照目录改名后继续使用，已传输的文件不需要重新下载
+func (p *snapshotPlan) prepare() error {
+	if err := os.MkdirAll(p.root, 0755); err != nil {
+		return fmt.Errorf("创建快照目录失败: %v", err)
+	}
+	if p.resume != "" && p.resume != p.dir {
+		if err := os.Rename(p.resume, p.dir); err != nil {
+			return fmt.Errorf("继续使用未完成的快照 '%s' 失败: %v", filepath.Base(p.resume), err)
+		}
+	}
+	return nil
+}
+
+// commit 把完成的快照改为正式名称，并更新 latest 链接
+func (p *snapshotPlan) commit() error {
+	final := filepath.Join(p.root, p.name)
+	if err := os.Rename(p.dir, final); err != nil {
+		return fmt.Errorf("保存快照失败: %v", err)
+	}
+
+	// 先创建临时链接再替换，避免 latest 短暂不存在
+	link := filepath.Join(p.root, models.SnapshotLatestLink)
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(p.name, tmp); err != nil {
+		return fmt.Errorf("创建 latest 链接失败: %v", err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("更新 latest 链接失败: %v", err)
+	}
+	return nil
+}
+
+// listSnapshots 列出目录中的快照，从新到旧排列
+func listSnapshots(root string) ([]models.RsyncSnapshot, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取快照目录失败: %v", err)
+	}
+
+	latest, _ := os.Readlink(filepath.Join(root, models.SnapshotLatestLink))
+	latest = filepath.Base(latest)
+
+	var snapshots []models.RsyncSnapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		t, ok := models.ParseSnapshotName(entry.Name())
+		if !ok {
+			continue
+		}
+		snapshots = append(snapshots, models.RsyncSnapshot{
+			Name:   entry.Name(),
+			Path:   filepath.Join(root, entry.Name()),
+			Time:   t,
+			Latest: entry.Name() == latest,
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.After(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// GetRsyncSnapshots 获取快照备份配置的所有快照，从新到旧排列
+func GetRsyncSnapshots(configName string) (*models.RsyncConfig, []models.RsyncSnapshot, error) {
+	config, err := getSnapshotConfig(configName)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := filepath.Abs(config.LocalPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("本地路径 '%s' 无效: %v", config.LocalPath, err)
+	}
+	snapshots, err := listSnapshots(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, snapshots, nil
+}
+
+// PruneRsyncSnapshots 按保留策略删除旧快照，dryRun 时只返回将要删除的快照
+func PruneRsyncSnapshots(configName string, dryRun bool) ([]models.RsyncSnapshot, error) {
+	config, snapshots, err := GetRsyncSnapshots(configName)
+	if err != nil {
+		return nil, err
+	}
+
+	// latest 指向的快照总是保留
+	var prune []models.RsyncSnapshot
+	for _, snapshot := range config.SnapshotsToPrune(snapshots) {
+		if !snapshot.Latest {
+			prune = append(prune, snapshot)
+		}
+	}
+	if dryRun {
+		return prune, nil
+	}
+	for i, snapshot := range prune {
+		if err := os.RemoveAll(snapshot.Path); err != nil {
+			return prune[:i], fmt.Errorf("删除快照 '%s' 失败: %v", snapshot.Name, err)
+		}
+	}
+	return prune, nil
+}
+
+// getSnapshotConfig 获取开启了快照备份的配置，远程路径中的变量使用环境变量和默认值
+func getSnapshotConfig(configName string) (*models.RsyncConfig, error) {
+	config, _, err := loadRsyncConfigWithConnection(configName, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !config.SnapshotMode {
+		return nil, fmt.Errorf("rsync配置 '%s' 没有开启快照备份", configName)
+	}
+	return config, nil
+}
+
+// buildRestoreArgs 生成把快照上传回服务器的rsync命令
+func buildRestoreArgs(configName, snapshotName string) (*models.RsyncConfig, *models.SSHConnection, []string, error) {
+	config, snapshots, err := GetRsyncSnapshots(configName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sshConn, err := GetConnectionByName(config.SSHName)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("获取SSH连接失败: %v", err)
+	}
+
+	var snapshot *models.RsyncSnapshot
+	for i := range snapshots {
+		if snapshots[i].Name == snapshotName || (snapshotName == models.SnapshotLatestLink && snapshots[i].Latest) {
+			snapshot = &snapshots[i]
+			break
+		}
+	}
+	if snapshot == nil {
+		return nil, nil, nil, fmt.Errorf("快照 '%s' 不存在", snapshotName)
+	}
+
+	// 远程路径不以 / 结尾时，下载的是目录本身，快照中对应的是同名子目录
+	restore := *config
+	restore.Direction = models.RsyncDirectionUpload
+	restore.SnapshotMode = false
+	if strings.HasSuffix(config.RemotePath, "/") {
+		restore.LocalPath = snapshot.Path + "/"
+	} else {
+		restore.LocalPath = filepath.Join(snapshot.Path, path.Base(config.RemotePath)) + "/"
+		restore.RemotePath = config.RemotePath + "/"
+	}
+	if _, err := os.Stat(restore.LocalPath); err != nil {
+		return nil, nil, nil, fmt.Errorf("快照内容 '%s' 无法访问: %v", restore.LocalPath, err)
+	}
+	return config, sshConn, localRsyncArgs(restore.BuildRsyncCommand(sshConn, nil), sshConn), nil
+}
+
+// PreviewRsyncRestore 预览把快照恢复到服务器时将要发生的变更
+func PreviewRsyncRestore(configName, snapshotName string) (*RsyncPreview, error) {
+	config, _, cmdArgs, err := buildRestoreArgs(configName, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	return previewRsyncCommand(config, cmdArgs)
+}
+
+// RestoreRsyncSnapshot 把快照上传回服务器的远程路径，记录为配置的一次执行
+func RestoreRsyncSnapshot(configName, snapshotName string, opts RsyncRunOptions) (*models.RsyncResult, error) {
+	config, sshConn, cmdArgs, err := buildRestoreArgs(configName, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := lockRsyncConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	opts.Trigger = models.RsyncTriggerRestore
+	execution, err := startRsyncExecution(config, sshConn, cmdArgs, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer execution.close()
+
+	result, exitCode, err := execution.rsync()
+	return execution.finish(result, exitCode, err)
+}
@@ -7,6 +7,8 @@ import (
 
 	"alfred-tool/database"
 	"alfred-tool/models"
+
+	"gorm.io/gorm"
 )
 
 func SearchConnections(query string) ([]models.SSHConnection, error) {
@@ -20,7 +22,7 @@ func SearchConnections(query string) ([]models.SSHConnection, error) {
 	query = strings.TrimSpace(query)
 	searchPattern := "%" + query + "%"
 
-	err := db.Where("name LIKE ? OR address LIKE ?", searchPattern, searchPattern).
+	err := db.Where("name LIKE ? OR address LIKE ? OR tags LIKE ?", searchPattern, searchPattern, searchPattern).
 		Find(&connections).Error
 
 	if err != nil {
@@ -54,16 +56,85 @@ func GetConnectionByName(name string) (*models.SSHConnection, error) {
 	return &connection, nil
 }
 
+// UpdateConnection 更新SSH连接，连接改名时同步更新引用它的rsync配置（包括回收站中的）
 func UpdateConnection(conn *models.SSHConnection) error {
-	db := database.GetDB()
-	err := db.Save(conn).Error
-	if err != nil {
+	if err := updateConnection(database.GetDB(), conn); err != nil {
 		return fmt.Errorf("更新连接失败: %v", err)
 	}
 	return nil
 }
 
-func DeleteConnection(name string) error {
+// updateConnection 在 db 上（可以是外层事务）更新SSH连接并同步引用它的rsync配置
+// 回收站中的连接同样可以更新，回滚历史版本时由调用方清除 DeletedAt 一并恢复
+func updateConnection(db *gorm.DB, conn *models.SSHConnection) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var old models.SSHConnection
+		if err := tx.Unscoped().First(&old, conn.ID).Error; err != nil {
+			return fmt.Errorf("未找到连接: %d", conn.ID)
+		}
+		conn.CreatedAt = old.CreatedAt
+		conn.UsageCount = old.UsageCount
+
+		if err := tx.Unscoped().Save(conn).Error; err != nil {
+			return err
+		}
+
+		// 同步引用时不更新 updated_at，定时执行按它计算下次执行时间
+		if old.Name != conn.Name {
+			err := tx.Unscoped().Model(&models.RsyncConfig{}).
+				Where("ssh_name = ?", old.Name).
+				UpdateColumn("ssh_name", conn.Name).Error
+			if err == nil {
+				err = tx.Unscoped().Model(&models.RsyncConfig{}).
+					Where("target_ssh_name = ?", old.Name).
+					UpdateColumn("target_ssh_name", conn.Name).Error
+			}
+			if err != nil {
+				return fmt.Errorf("同步rsync配置失败: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ConnectionDependents 引用某个SSH连接的rsync配置和服务
+type ConnectionDependents struct {
+	RsyncConfigs []models.RsyncConfig
+	Services     []models.Service
+}
+
+// IsEmpty 是否没有任何引用
+func (d *ConnectionDependents) IsEmpty() bool {
+	return len(d.RsyncConfigs) == 0 && len(d.Services) == 0
+}
+
+// GetConnectionDependents 获取引用指定连接的rsync配置（包括作为服务器之间传输的目标）和服务
+func GetConnectionDependents(name string) (*ConnectionDependents, error) {
+	connection, err := GetConnectionByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.GetDB()
+	dependents := &ConnectionDependents{}
+	if err := db.Where("ssh_name = ? OR target_ssh_name = ?", connection.Name, connection.Name).Find(&dependents.RsyncConfigs).Error; err != nil {
+		return nil, fmt.Errorf("查询rsync配置失败: %v", err)
+	}
+	if err := db.Where("ssh_connection_id = ?", connection.ID).Find(&dependents.Services).Error; err != nil {
+		return nil, fmt.Errorf("查询服务失败: %v", err)
+	}
+	return dependents, nil
+}
+
+// DeleteConnectionOptions 删除仍被引用的SSH连接时如何处理引用
+type DeleteConnectionOptions struct {
+	Cascade    bool   // 一并将引用的rsync配置和服务移入回收站
+	ReassignTo string // 将引用转移到另一个连接
+}
+
+// DeleteConnection 删除SSH连接
+// 连接仍被rsync配置或服务引用时，必须指定 Cascade 或 ReassignTo，否则拒绝删除
+func DeleteConnection(name string, opts DeleteConnectionOptions) error {
 	db := database.GetDB()
 	var connection models.SSHConnection
 
@@ -72,7 +143,40 @@ func DeleteConnection(name string) error {
 		return fmt.Errorf("未找到连接: %s", name)
 	}
 
-	err = db.Delete(&connection).Error
+	dependents, err := GetConnectionDependents(name)
+	if err != nil {
+		return err
+	}
+	if !dependents.IsEmpty() && !opts.Cascade && opts.ReassignTo == "" {
+		return fmt.Errorf("连接仍被 %d 个rsync配置和 %d 个服务引用", len(dependents.RsyncConfigs), len(dependents.Services))
+	}
+
+	var target *models.SSHConnection
+	if opts.ReassignTo != "" {
+		if opts.ReassignTo == name {
+			return errors.New("不能转移到被删除的连接本身")
+		}
+		target, err = GetConnectionByName(opts.ReassignTo)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if target != nil {
+			if err := reassignConnection(tx, &connection, target); err != nil {
+				return err
+			}
+		} else if opts.Cascade {
+			if err := tx.Where("ssh_name = ? OR target_ssh_name = ?", connection.Name, connection.Name).Delete(&models.RsyncConfig{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("ssh_connection_id = ?", connection.ID).Delete(&models.Service{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&connection).Error
+	})
 	if err != nil {
 		return fmt.Errorf("删除连接失败: %v", err)
 	}
@@ -80,6 +184,22 @@ func DeleteConnection(name string) error {
 	return nil
 }
 
+// reassignConnection 将引用 from 的rsync配置和服务转移到 to，不更新它们的 updated_at
+func reassignConnection(tx *gorm.DB, from, to *models.SSHConnection) error {
+	err := tx.Model(&models.RsyncConfig{}).Where("ssh_name = ?", from.Name).UpdateColumn("ssh_name", to.Name).Error
+	if err == nil {
+		err = tx.Model(&models.RsyncConfig{}).Where("target_ssh_name = ?", from.Name).UpdateColumn("target_ssh_name", to.Name).Error
+	}
+	if err != nil {
+		return fmt.Errorf("转移rsync配置失败: %v", err)
+	}
+	err = tx.Model(&models.Service{}).Where("ssh_connection_id = ?", from.ID).UpdateColumn("ssh_connection_id", to.ID).Error
+	if err != nil {
+		return fmt.Errorf("转移服务失败: %v", err)
+	}
+	return nil
+}
+
 func IncrementUsageCount(name string) error {
 	db := database.GetDB()
 	var connection models.SSHConnection
@@ -89,8 +209,8 @@ func IncrementUsageCount(name string) error {
 		return nil
 	}
 
-	connection.UsageCount++
-	err = db.Save(&connection).Error
+	// 使用原子自增，避免并发实例读-改-写覆盖彼此的计数
+	err = db.Model(&connection).UpdateColumn("usage_count", gorm.Expr("usage_count + 1")).Error
 	if err != nil {
 		return fmt.Errorf("更新使用次数失败: %v", err)
 	}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+)
+
+func TestUpdateConnectionRenameKeepsScheduleBaseline(t *testing.T) {
+	setupTestDB(t)
+	db := database.GetDB()
+
+	conn := &models.SSHConnection{Name: "web", Address: "10.0.0.1", Port: 22, Username: "root"}
+	if err := db.Create(conn).Error; err != nil {
+		t.Fatal(err)
+	}
+	config := &models.RsyncConfig{Name: "site", SSHName: "web", Direction: models.RsyncDirectionUpload, LocalPath: "/tmp/site", RemotePath: "/srv/site"}
+	if err := db.Create(config).Error; err != nil {
+		t.Fatal(err)
+	}
+	baseline := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.Model(config).UpdateColumn("updated_at", baseline).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Name = "web-new"
+	if err := UpdateConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	var renamed models.RsyncConfig
+	if err := db.First(&renamed, config.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if renamed.SSHName != "web-new" {
+		t.Errorf("rsync配置的连接名称 = %q，期望 %q", renamed.SSHName, "web-new")
+	}
+	if !renamed.UpdatedAt.Equal(baseline) {
+		t.Errorf("连接改名不应更新rsync配置的 updated_at: %v", renamed.UpdatedAt)
+	}
+}
+
+func TestUpdateConnectionRenamePropagates(t *testing.T) {
+	setupTestDB(t)
+	conn := createConnectionWithDependents(t)
+
+	conn.Name = "web-new"
+	if err := UpdateConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	dependents, err := GetConnectionDependents("web-new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dependents.RsyncConfigs) != 2 || len(dependents.Services) != 1 {
+		t.Errorf("改名后的引用 = %d 个rsync配置、%d 个服务，期望 2 和 1", len(dependents.RsyncConfigs), len(dependents.Services))
+	}
+	var mirror models.RsyncConfig
+	database.GetDB().Where("name = ?", "mirror").First(&mirror)
+	if mirror.SSHName != "db" || mirror.TargetSSHName != "web-new" {
+		t.Errorf("服务器之间传输的配置 = %s -> %s，期望 db -> web-new", mirror.SSHName, mirror.TargetSSHName)
+	}
+}
+
+func TestDeleteConnectionReassign(t *testing.T) {
+	setupTestDB(t)
+	db := database.GetDB()
+	createConnectionWithDependents(t)
+	target := &models.SSHConnection{Name: "web2", Address: "10.0.0.2", Port: 22, Username: "root"}
+	if err := db.Create(target).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteConnection("web", DeleteConnectionOptions{ReassignTo: "web"}); err == nil {
+		t.Error("不能转移到被删除的连接本身")
+	}
+	if err := DeleteConnection("web", DeleteConnectionOptions{ReassignTo: "missing"}); err == nil {
+		t.Error("转移到不存在的连接应该失败")
+	}
+
+	if err := DeleteConnection("web", DeleteConnectionOptions{ReassignTo: "web2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetConnectionByName("web"); err == nil {
+		t.Error("连接应已移入回收站")
+	}
+	dependents, err := GetConnectionDependents("web2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dependents.RsyncConfigs) != 2 || len(dependents.Services) != 1 {
+		t.Errorf("转移后的引用 = %d 个rsync配置、%d 个服务，期望 2 和 1", len(dependents.RsyncConfigs), len(dependents.Services))
+	}
+	if names := trashNames(t, "rsync"); len(names) != 0 {
+		t.Errorf("转移引用时不应删除rsync配置: %v", names)
+	}
+
+	references, err := FindDanglingReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reference := range references {
+		if reference.Reference == "web" || reference.Entity == "service" {
+			t.Errorf("转移后不应有指向 web 的失效引用: %+v", reference)
+		}
+	}
+}
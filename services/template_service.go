@@ -0,0 +1,101 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"os"
+	"time"
+)
+
+// RsyncRender 替换变量后的配置和将要执行的命令
+type RsyncRender struct {
+	ConfigName string                    `json:"config_name"`
+	Variables  []models.TemplateVariable `json:"variables"`
+	Config     *models.RsyncConfig       `json:"config"`
+	Commands   [][]string                `json:"commands"` // 双向同步时依次为列出服务器文件、下载、上传的命令
+}
+
+// RenderRsyncConfig 替换配置中的变量，返回用到的变量及其来源和将要执行的命令，不执行任何命令
+func RenderRsyncConfig(configName string, vars map[string]string) (*RsyncRender, error) {
+	config, sshConn, err := loadRawRsyncConfig(configName)
+	if err != nil {
+		return nil, err
+	}
+	rendered, used, err := renderRsyncConfig(config, sshConn, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	render := &RsyncRender{ConfigName: config.Name, Variables: used, Config: rendered}
+	if rendered.Direction == models.RsyncDirectionBidirectional {
+		if err := rendered.ValidateFilters(); err != nil {
+			return nil, err
+		}
+		ignoreRules, err := RsyncIgnoreRules(rendered)
+		if err != nil {
+			return nil, err
+		}
+		render.Commands = [][]string{
+			rendered.BuildListCommand(sshConn, ignoreRules),
+			rendered.BuildBisyncCommand(sshConn, models.RsyncDirectionDownload, "<下载列表>", ignoreRules),
+			rendered.BuildBisyncCommand(sshConn, models.RsyncDirectionUpload, "<上传列表>", ignoreRules),
+		}
+		return render, nil
+	}
+
+	cmdArgs, _, err := buildRsyncArgs(rendered, sshConn, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	render.Commands = [][]string{cmdArgs}
+	return render, nil
+}
+
+// RenderRsyncTemplate 用内置变量、环境变量和默认值替换尚未保存的配置中的变量，用于编辑时预览
+func RenderRsyncTemplate(config *models.RsyncConfig, sshConn *models.SSHConnection) (*models.RsyncConfig, error) {
+	rendered, _, err := renderRsyncConfig(config, sshConn, nil)
+	return rendered, err
+}
+
+// renderRsyncConfig 按优先级取得配置用到的变量并替换：内置变量、--var、环境变量（Alfred 工作流变量）、配置中的默认值
+func renderRsyncConfig(config *models.RsyncConfig, sshConn *models.SSHConnection, vars map[string]string) (*models.RsyncConfig, []models.TemplateVariable, error) {
+	names := config.TemplateVariableNames()
+	if len(names) == 0 {
+		return config, nil, nil
+	}
+	defaults, err := models.ParseTemplateVariables(config.Variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var target *models.SSHConnection
+	if config.TargetSSHName != "" {
+		target, _ = GetConnectionByName(config.TargetSSHName)
+	}
+	home, _ := os.UserHomeDir()
+	builtins := models.BuiltinTemplateVariables(config, sshConn, target, time.Now(), home)
+
+	values := make(map[string]string)
+	var used []models.TemplateVariable
+	for _, name := range names {
+		variable := models.TemplateVariable{Name: name}
+		if value, ok := builtins[name]; ok {
+			variable.Value, variable.Source = value, models.TemplateSourceBuiltin
+		} else if value, ok := vars[name]; ok {
+			variable.Value, variable.Source = value, models.TemplateSourceFlag
+		} else if value, ok := os.LookupEnv(name); ok {
+			variable.Value, variable.Source = value, models.TemplateSourceEnv
+		} else if value, ok := defaults[name]; ok {
+			variable.Value, variable.Source = value, models.TemplateSourceDefault
+		} else {
+			continue
+		}
+		values[name] = variable.Value
+		used = append(used, variable)
+	}
+
+	rendered, err := config.Render(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rendered, used, nil
+}
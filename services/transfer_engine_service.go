@@ -0,0 +1,120 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"fmt"
+	"os/exec"
+	"sync"
+)
+
+var (
+	localRsyncOnce    sync.Once
+	localRsyncVersion models.RsyncVersion
+	localRsyncErr     error
+)
+
+// detectLocalRsync 检测本地的 rsync 及其版本，结果在进程内缓存
+// 能执行但无法识别版本时按最旧的版本处理
+func detectLocalRsync() (models.RsyncVersion, error) {
+	localRsyncOnce.Do(func() {
+		output, err := exec.Command("rsync", "--version").Output()
+		if err != nil {
+			localRsyncErr = fmt.Errorf("本地没有可用的 rsync: %v", err)
+			return
+		}
+		localRsyncVersion, _ = models.ParseRsyncVersion(string(output))
+	})
+	return localRsyncVersion, localRsyncErr
+}
+
+// rsyncProgressOption 返回本地 rsync 支持的进度输出选项
+func rsyncProgressOption() string {
+	if version, err := detectLocalRsync(); err == nil && version.SupportsInfoProgress() {
+		return "--info=progress2"
+	}
+	return "--progress"
+}
+
+// localRsyncArgs 本地 rsync 早于 3.0 时不支持 --protect-args，改为由远程 shell 去掉远程路径上的引号
+func localRsyncArgs(cmdArgs []string, sshConn *models.SSHConnection) []string {
+	if version, err := detectLocalRsync(); err == nil && !version.SupportsProtectArgs() {
+		return models.QuoteRemotePathArgs(cmdArgs, sshConn)
+	}
+	return cmdArgs
+}
+
+// rsyncEngineInfo 使用 rsync 执行时的传输引擎说明，用于快照恢复、双向同步等只能使用 rsync 的执行
+func rsyncEngineInfo(config *models.RsyncConfig) models.TransferEngineInfo {
+	info := models.TransferEngineInfo{Engine: models.TransferEngineRsync}
+	if config.Direction == models.RsyncDirectionRemote {
+		info.Reason = "在源服务器上执行"
+		return info
+	}
+	if version, err := detectLocalRsync(); err == nil {
+		info.Version = version.String()
+	}
+	return info
+}
+
+// selectTransferEngine 选择执行配置的传输引擎，requested 不为空时优先于配置中的设置
+// 自动选择时本地有 rsync 就使用 rsync，否则在配置和连接支持时使用 sftp
+func selectTransferEngine(config *models.RsyncConfig, sshConn *models.SSHConnection, requested models.TransferEngine) (models.TransferEngineInfo, error) {
+	engine := config.Engine
+	if requested != "" {
+		engine = requested
+	}
+	engine, err := models.ParseTransferEngine(string(engine))
+	if err != nil {
+		return models.TransferEngineInfo{}, err
+	}
+
+	// 服务器之间传输在源服务器上执行 rsync，与本地是否安装无关
+	if config.Direction == models.RsyncDirectionRemote {
+		if engine == models.TransferEngineSFTP {
+			return models.TransferEngineInfo{}, config.ValidateSFTPEngine()
+		}
+		return rsyncEngineInfo(config), nil
+	}
+
+	switch engine {
+	case models.TransferEngineRsync:
+		if _, err := detectLocalRsync(); err != nil {
+			return models.TransferEngineInfo{}, err
+		}
+		return rsyncEngineInfo(config), nil
+	case models.TransferEngineSFTP:
+		return sftpEngineInfo(config, sshConn, "")
+	}
+
+	version, rsyncErr := detectLocalRsync()
+	if rsyncErr == nil {
+		info := rsyncEngineInfo(config)
+		if !version.SupportsInfoProgress() {
+			info.Reason = "版本低于 3.1，只显示逐文件进度"
+		}
+		return info, nil
+	}
+	info, err := sftpEngineInfo(config, sshConn, "本地没有 rsync")
+	if err != nil {
+		return models.TransferEngineInfo{}, fmt.Errorf("%v，也不能使用 sftp 引擎: %v", rsyncErr, err)
+	}
+	return info, nil
+}
+
+// sftpEngineInfo 检查能否使用 sftp 引擎：配置只在本地和服务器之间同步，本地有 sftp，使用密码的连接有终端可以输入密码
+func sftpEngineInfo(config *models.RsyncConfig, sshConn *models.SSHConnection, reason string) (models.TransferEngineInfo, error) {
+	if err := config.ValidateSFTPEngine(); err != nil {
+		return models.TransferEngineInfo{}, err
+	}
+	if _, err := exec.LookPath("sftp"); err != nil {
+		return models.TransferEngineInfo{}, fmt.Errorf("本地没有可用的 sftp: %v", err)
+	}
+	if err := checkPasswordPrompt(sshConn); err != nil {
+		return models.TransferEngineInfo{}, err
+	}
+	return models.TransferEngineInfo{
+		Engine:  models.TransferEngineSFTP,
+		Reason:  reason,
+		Ignored: config.SFTPIgnoredOptions(),
+	}, nil
+}
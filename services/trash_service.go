@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+
+	"gorm.io/gorm"
+)
+
+// TrashEntities 回收站支持的数据类型
+var TrashEntities = []string{"ssh", "rsync", "service"}
+
+// TrashItem 回收站中的一条记录
+type TrashItem struct {
+	Entity    string
+	ID        uint
+	Name      string
+	DeletedAt time.Time
+}
+
+// entityModel 返回数据类型对应的模型
+func entityModel(entity string) (any, error) {
+	switch entity {
+	case "ssh":
+		return &models.SSHConnection{}, nil
+	case "rsync":
+		return &models.RsyncConfig{}, nil
+	case "service":
+		return &models.Service{}, nil
+	}
+	return nil, fmt.Errorf("未知的数据类型: %s（可选: ssh, rsync, service）", entity)
+}
+
+// ListTrash 列出回收站中的记录，entity 为空时列出所有类型
+func ListTrash(entity string) ([]TrashItem, error) {
+	entities := TrashEntities
+	if entity != "" {
+		entities = []string{entity}
+	}
+
+	db := database.GetDB()
+	var items []TrashItem
+	for _, e := range entities {
+		model, err := entityModel(e)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []TrashItem
+		err = db.Unscoped().Model(model).
+			Select("id, name, deleted_at").
+			Where("deleted_at IS NOT NULL").
+			Order("deleted_at DESC").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("读取回收站失败: %v", err)
+		}
+		for i := range rows {
+			rows[i].Entity = e
+		}
+		items = append(items, rows...)
+	}
+	return items, nil
+}
+
+// findTrashItem 按ID或名称查找回收站中的记录，同名时取最近删除的一条
+func findTrashItem(entity, key string) (*TrashItem, error) {
+	items, err := ListTrash(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	id, idErr := strconv.ParseUint(key, 10, 32)
+	for _, item := range items {
+		if item.Name == key || (idErr == nil && item.ID == uint(id)) {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("回收站中没有找到 %s: %s", entity, key)
+}
+
+// RestoreTrash 从回收站恢复记录，key 可以是名称或ID
+func RestoreTrash(entity, key string) (*TrashItem, error) {
+	item, err := findTrashItem(entity, key)
+	if err != nil {
+		return nil, err
+	}
+	model, _ := entityModel(entity)
+
+	db := database.GetDB()
+	if err := checkRestoreName(db, entity, item.Name); err != nil {
+		return nil, err
+	}
+
+	err = db.Unscoped().Model(model).Where("id = ?", item.ID).Update("deleted_at", nil).Error
+	if err != nil {
+		return nil, fmt.Errorf("恢复失败: %v", err)
+	}
+	return item, nil
+}
+
+// checkRestoreName 恢复回收站中的记录前检查是否已存在同名的记录
+func checkRestoreName(db *gorm.DB, entity, name string) error {
+	model, err := entityModel(entity)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := db.Model(model).Where("name = ?", name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("已存在同名的 %s '%s'，请先重命名或删除后再恢复", entity, name)
+	}
+	return nil
+}
+
+// PurgeTrash 永久删除回收站中的记录
+// key 为空时清空 entity 类型的回收站，entity 也为空时清空整个回收站；返回删除的记录
+func PurgeTrash(entity, key string) ([]TrashItem, error) {
+	var items []TrashItem
+	if key != "" {
+		item, err := findTrashItem(entity, key)
+		if err != nil {
+			return nil, err
+		}
+		items = []TrashItem{*item}
+	} else {
+		var err error
+		items, err = ListTrash(entity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db := database.GetDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			model, err := entityModel(item.Entity)
+			if err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", item.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("清理回收站失败: %v", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"testing"
+
+	"alfred-tool/database"
+	"alfred-tool/models"
+)
+
+// createConnectionWithDependents 创建连接 web 以及引用它的rsync配置和服务
+func createConnectionWithDependents(t *testing.T) *models.SSHConnection {
+	t.Helper()
+	db := database.GetDB()
+	conn := &models.SSHConnection{Name: "web", Address: "10.0.0.1", Port: 22, Username: "root"}
+	if err := db.Create(conn).Error; err != nil {
+		t.Fatal(err)
+	}
+	configs := []models.RsyncConfig{
+		{Name: "site", SSHName: "web", Direction: models.RsyncDirectionUpload, LocalPath: "/tmp/site", RemotePath: "/srv/site"},
+		{Name: "mirror", SSHName: "db", TargetSSHName: "web", Direction: models.RsyncDirectionRemote, RemotePath: "/srv/a", TargetPath: "/srv/b"},
+	}
+	if err := db.Create(&configs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Omit("SSHConnection").Create(&models.Service{Name: "nginx", SSHConnectionID: conn.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func trashNames(t *testing.T, entity string) []string {
+	t.Helper()
+	items, err := ListTrash(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+func TestDeleteConnectionWithDependents(t *testing.T) {
+	setupTestDB(t)
+	createConnectionWithDependents(t)
+
+	if err := DeleteConnection("web", DeleteConnectionOptions{}); err == nil {
+		t.Fatal("连接仍被引用时应拒绝删除")
+	}
+	if _, err := GetConnectionByName("web"); err != nil {
+		t.Errorf("拒绝删除后连接应仍然存在: %v", err)
+	}
+
+	if err := DeleteConnection("web", DeleteConnectionOptions{Cascade: true}); err != nil {
+		t.Fatal(err)
+	}
+	if names := trashNames(t, "ssh"); len(names) != 1 || names[0] != "web" {
+		t.Errorf("回收站中的连接 = %v，期望 [web]", names)
+	}
+	if names := trashNames(t, "rsync"); len(names) != 2 {
+		t.Errorf("回收站中的rsync配置 = %v，期望 site 和 mirror", names)
+	}
+	if names := trashNames(t, "service"); len(names) != 1 || names[0] != "nginx" {
+		t.Errorf("回收站中的服务 = %v，期望 [nginx]", names)
+	}
+}
+
+func TestRestoreTrash(t *testing.T) {
+	setupTestDB(t)
+	db := database.GetDB()
+	conn := &models.SSHConnection{Name: "web", Address: "10.0.0.1", Port: 22, Username: "root"}
+	if err := db.Create(conn).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteConnection("web", DeleteConnectionOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除后可以新建同名连接，此时不能恢复
+	other := &models.SSHConnection{Name: "web", Address: "10.0.0.2", Port: 22, Username: "root"}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("删除后应能新建同名连接: %v", err)
+	}
+	if _, err := RestoreTrash("ssh", "web"); err == nil {
+		t.Fatal("已存在同名连接时应拒绝恢复")
+	}
+
+	if err := db.Delete(other).Error; err != nil {
+		t.Fatal(err)
+	}
+	item, err := RestoreTrash("ssh", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != other.ID {
+		t.Errorf("同名时应恢复最近删除的一条: 恢复了 %d，期望 %d", item.ID, other.ID)
+	}
+	if names := trashNames(t, "ssh"); len(names) != 1 {
+		t.Errorf("恢复后回收站中应剩一条: %v", names)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	setupTestDB(t)
+	db := database.GetDB()
+	for _, name := range []string{"web", "db"} {
+		if err := db.Create(&models.SSHConnection{Name: name, Address: "10.0.0.1", Port: 22, Username: "root"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := DeleteConnection("web", DeleteConnectionOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := PurgeTrash("ssh", "db"); err == nil {
+		t.Error("不在回收站中的记录不能永久删除")
+	}
+	purged, err := PurgeTrash("ssh", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purged) != 1 || purged[0].Name != "web" {
+		t.Errorf("PurgeTrash() = %+v，期望只删除 web", purged)
+	}
+
+	var count int64
+	db.Unscoped().Model(&models.SSHConnection{}).Count(&count)
+	if count != 1 {
+		t.Errorf("永久删除后应剩 1 条连接，实际 %d", count)
+	}
+}
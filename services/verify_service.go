@@ -0,0 +1,115 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VerifyRsyncConfig 比较本地和服务器上的文件（大小、修改时间，checksum 为 true 时还比较 SHA-256），
+// 遵循配置的排除和过滤规则，不会修改任何文件
+func VerifyRsyncConfig(configName string, vars map[string]string, checksum bool) (*models.VerifyReport, error) {
+	config, sshConn, err := loadRsyncConfigWithConnection(configName, vars)
+	if err != nil {
+		return nil, err
+	}
+	return verifyRsyncConfig(config, sshConn, checksum)
+}
+
+func verifyRsyncConfig(config *models.RsyncConfig, sshConn *models.SSHConnection, checksum bool) (*models.VerifyReport, error) {
+	localRoot, remoteRoot, prefix, err := config.VerifyRoots()
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := RsyncFilterMatcher(config)
+	if err != nil {
+		return nil, err
+	}
+	excluded := func(rel string, isDir bool) bool {
+		return matcher.Excluded(path.Join(prefix, rel), isDir)
+	}
+
+	if err := checkPasswordPrompt(sshConn); err != nil {
+		return nil, err
+	}
+	remote, err := listRemoteTree(models.BuildSFTPCommand(sshConn, config), remoteRoot)
+	if err != nil {
+		return nil, err
+	}
+	local, err := listLocalEntries(localRoot, excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreLocal, ignoreRemote := config.VerifyIgnoredSides()
+	report, matched := models.CompareTransferTrees(local, remote, models.VerifyOptions{
+		Prefix:   prefix,
+		Excluded: matcher.Excluded,
+		Protected: func(rel string, isDir bool) bool {
+			return matcher.Protected(rel, isDir).Excluded
+		},
+		ModTime:      config.Archive || config.Times,
+		MaxSize:      config.MaxSize,
+		IgnoreLocal:  ignoreLocal,
+		IgnoreRemote: ignoreRemote,
+	})
+	report.ConfigName = config.Name
+	report.LocalPath = localRoot
+	report.RemotePath = remoteRoot
+	report.CheckedAt = time.Now()
+
+	if checksum && len(matched) > 0 {
+		localSums, err := localChecksums(localRoot, matched)
+		if err != nil {
+			return nil, err
+		}
+		remoteSums, err := remoteFileHashes(sshConn, remoteRoot, matched)
+		if err != nil {
+			return nil, err
+		}
+		report.ApplyChecksums(matched, localSums, remoteSums)
+	}
+	return report, nil
+}
+
+// localChecksums 计算本地文件的 SHA-256
+func localChecksums(root string, files []string) (map[string]string, error) {
+	sums := make(map[string]string, len(files))
+	for _, rel := range files {
+		sum, err := fileHash(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		sums[rel] = sum
+	}
+	return sums, nil
+}
+
+// verify 执行成功后按配置校验，完整报告保存在日志目录中，校验失败不影响执行结果
+func (e *rsyncExecution) verify() *models.VerifySummary {
+	report, err := verifyRsyncConfig(e.config, e.sshConn, e.config.VerifyChecksum)
+	if err != nil {
+		summary := &models.VerifySummary{Error: err.Error()}
+		fmt.Fprintf(e.logFile, "%s\n", summary)
+		return summary
+	}
+
+	summary := report.Summary()
+	reportPath := strings.TrimSuffix(e.run.LogPath, ".log") + "-verify.json"
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = os.WriteFile(reportPath, data, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(e.logFile, "保存校验报告失败: %v\n", err)
+	} else {
+		summary.ReportPath = reportPath
+	}
+	fmt.Fprintf(e.logFile, "%s\n", summary)
+	return &summary
+}
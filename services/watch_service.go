@@ -0,0 +1,232 @@
+package services
+
+import (
+	"alfred-tool/models"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchEventType 监听过程中的事件类型
+type WatchEventType string
+
+const (
+	WatchEventReady     WatchEventType = "ready"      // 开始监听
+	WatchEventChange    WatchEventType = "change"     // 检测到文件变化，等待合并后同步
+	WatchEventSyncStart WatchEventType = "sync_start" // 开始同步
+	WatchEventSyncDone  WatchEventType = "sync_done"  // 同步结束，Err 不为空表示失败
+	WatchEventError     WatchEventType = "error"      // 监听出错，不影响继续监听
+)
+
+// WatchEvent 监听过程中的事件
+type WatchEvent struct {
+	Type    WatchEventType
+	Dirs    int                 // 开始监听时的目录数
+	Paths   []string            // 本次同步包含的变化（相对传输根目录）
+	Result  *models.RsyncResult // 同步结果
+	Err     error
+	Skipped bool // 同一配置正在由其他进程执行，本次同步推迟
+}
+
+// WatchOptions 监听选项
+type WatchOptions struct {
+	Debounce    time.Duration     // 最后一次变化后等待多久再同步，合并连续保存等突发变化
+	InitialSync bool              // 开始监听前先同步一次
+	Handler     func(WatchEvent)  // 接收监听事件，可以为空
+	RunHandler  RsyncEventHandler // 接收每次同步的执行事件，可以为空
+}
+
+// watchRetryDelay 同一配置正在执行时，多久后重新尝试同步
+const watchRetryDelay = 5 * time.Second
+
+// WatchRsyncConfig 递归监听上传配置的本地路径，变化（排除规则匹配的除外）合并后执行同步，直到 ctx 结束
+func WatchRsyncConfig(ctx context.Context, configName string, opts WatchOptions) error {
+	handler := opts.Handler
+	if handler == nil {
+		handler = func(WatchEvent) {}
+	}
+
+	config, _, err := loadRsyncConfigWithConnection(configName, nil)
+	if err != nil {
+		return err
+	}
+	if config.Direction != models.RsyncDirectionUpload {
+		return fmt.Errorf("rsync配置 '%s' 不是上传配置，只能监听上传配置", configName)
+	}
+	info, err := os.Stat(config.LocalPath)
+	if err != nil {
+		return fmt.Errorf("本地路径 '%s' 无法访问: %v", config.LocalPath, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("本地路径 '%s' 不是目录", config.LocalPath)
+	}
+	matcher, err := RsyncFilterMatcher(config)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建文件监听失败: %v", err)
+	}
+	defer watcher.Close()
+
+	w := &rsyncWatch{config: config, matcher: matcher, watcher: watcher, handler: handler}
+	if err := w.addTree(config.LocalPath); err != nil {
+		return err
+	}
+
+	syncChanges := func(paths []string) (retry bool) {
+		handler(WatchEvent{Type: WatchEventSyncStart, Paths: paths})
+		result, err := RunRsyncConfig(configName, RsyncRunOptions{Trigger: models.RsyncTriggerWatch, Handler: opts.RunHandler})
+		if errors.Is(err, ErrRsyncRunning) {
+			handler(WatchEvent{Type: WatchEventSyncDone, Paths: paths, Err: err, Skipped: true})
+			return true
+		}
+		handler(WatchEvent{Type: WatchEventSyncDone, Paths: paths, Result: result, Err: err})
+		return false
+	}
+
+	if opts.InitialSync {
+		syncChanges(nil)
+	}
+	handler(WatchEvent{Type: WatchEventReady, Dirs: w.dirs})
+
+	// 变化先收集起来，最后一次变化后 Debounce 时间内没有新的变化才同步
+	pending := make(map[string]bool)
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			rel, relevant := w.handle(event)
+			if !relevant {
+				continue
+			}
+			if !pending[rel] {
+				pending[rel] = true
+				handler(WatchEvent{Type: WatchEventChange, Paths: []string{rel}})
+			}
+			resetTimer(timer, opts.Debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			handler(WatchEvent{Type: WatchEventError, Err: err})
+
+		case <-timer.C:
+			if len(pending) == 0 {
+				continue
+			}
+			paths := make([]string, 0, len(pending))
+			for p := range pending {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+
+			// 同步期间发生的变化会留在 Events 中，同步结束后再合并处理
+			if syncChanges(paths) {
+				resetTimer(timer, watchRetryDelay)
+				continue
+			}
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+// resetTimer 重置计时器，并丢弃已经触发但还没有读取的时间，避免提前同步
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// rsyncWatch 监听一个配置的本地目录树
+type rsyncWatch struct {
+	config  *models.RsyncConfig
+	matcher *models.FilterMatcher
+	watcher *fsnotify.Watcher
+	handler func(WatchEvent)
+	dirs    int
+}
+
+// addTree 递归监听目录，跳过被排除的目录
+func (w *rsyncWatch) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 子目录可能在遍历过程中被删除或没有权限，跳过即可
+			if path == root {
+				return fmt.Errorf("读取目录 '%s' 失败: %v", path, err)
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if rel, ok := w.config.TransferPath(path); ok && w.matcher.Excluded(rel, true) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			if path == root {
+				return fmt.Errorf("监听目录 '%s' 失败: %v", path, err)
+			}
+			w.handler(WatchEvent{Type: WatchEventError, Err: fmt.Errorf("监听目录 '%s' 失败: %v", path, err)})
+			return nil
+		}
+		w.dirs++
+		return nil
+	})
+}
+
+// handle 处理一个文件系统事件，返回相对路径以及是否需要同步
+func (w *rsyncWatch) handle(event fsnotify.Event) (string, bool) {
+	// 只修改权限的事件（如 Spotlight 索引）不需要同步，除非配置保持权限
+	if event.Op == fsnotify.Chmod && !w.config.Archive && !w.config.Perms {
+		return "", false
+	}
+
+	rel, ok := w.config.TransferPath(event.Name)
+	if !ok {
+		return "", false
+	}
+
+	isDir := false
+	if event.Op.Has(fsnotify.Create) {
+		if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+			isDir = true
+		}
+	}
+	if w.matcher.Excluded(rel, isDir) {
+		return "", false
+	}
+
+	// 新建的目录需要加入监听，其中已有的文件由下一次同步带上
+	if isDir {
+		if err := w.addTree(event.Name); err != nil {
+			w.handler(WatchEvent{Type: WatchEventError, Err: err})
+		}
+	}
+	return rel, true
+}
@@ -0,0 +1,90 @@
+package component
+
+import (
+	"alfred-tool/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// RemoteFolderLister 列出服务器上的目录，dir 为空时为用户目录
+type RemoteFolderLister func(dir string) (*models.RemoteListing, error)
+
+// ShowRemoteFolderDialog 显示服务器目录选择对话框，点击目录进入，点击“选择”时以当前目录调用 onSelect
+func ShowRemoteFolderDialog(title, start string, list RemoteFolderLister, onSelect func(path string), parent fyne.Window) {
+	var listing *models.RemoteListing
+	var folders []models.RemoteEntry
+
+	pathLabel := widget.NewLabel(start)
+	pathLabel.Truncation = fyne.TextTruncateEllipsis
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	upButton := widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil)
+	upButton.Disable()
+
+	folderList := widget.NewList(
+		func() int { return len(folders) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(widget.NewIcon(theme.FolderIcon()), widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			name := folders[id].Name
+			if folders[id].IsLink {
+				name += " (链接)"
+			}
+			item.(*fyne.Container).Objects[1].(*widget.Label).SetText(name)
+		},
+	)
+
+	load := func(dir string) {
+		statusLabel.SetText("正在加载...")
+		go func() {
+			result, err := list(dir)
+			if err != nil {
+				statusLabel.SetText(err.Error())
+				return
+			}
+			listing = result
+			folders = folders[:0]
+			for _, entry := range result.Entries {
+				if entry.Browsable() {
+					folders = append(folders, entry)
+				}
+			}
+			pathLabel.SetText(result.Path)
+			statusLabel.SetText("")
+			if result.Parent == "" {
+				upButton.Disable()
+			} else {
+				upButton.Enable()
+			}
+			folderList.UnselectAll()
+			folderList.Refresh()
+		}()
+	}
+	folderList.OnSelected = func(id widget.ListItemID) {
+		load(folders[id].Path)
+	}
+	upButton.OnTapped = func() {
+		if listing != nil && listing.Parent != "" {
+			load(listing.Parent)
+		}
+	}
+
+	content := container.NewBorder(
+		container.NewVBox(container.NewBorder(nil, nil, upButton, nil, pathLabel), statusLabel),
+		nil, nil, nil,
+		folderList,
+	)
+	d := dialog.NewCustomConfirm(title, "选择", "取消", content, func(ok bool) {
+		if ok && listing != nil {
+			onSelect(listing.Path)
+		}
+	}, parent)
+	d.Resize(fyne.NewSize(480, 420))
+	d.Show()
+	load(start)
+}
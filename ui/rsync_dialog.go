@@ -6,7 +6,10 @@ import (
 	"alfred-tool/ui/xtheme"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"alfred-tool/database"
 	"alfred-tool/models"
@@ -17,6 +20,7 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/samber/lo"
 )
 
 // ShowRsyncDialog 显示rsync配置对话框
@@ -76,8 +80,25 @@ func ShowRsyncDialog(configName string) error {
 	sshSelect.SetSelected(sshNames[0])
 
 	// 传输方向选择
-	directionSelect := widget.NewSelect([]string{"上传 (本地→服务器)", "下载 (服务器→本地)"}, nil)
-	directionSelect.SetSelected("上传 (本地→服务器)")
+	directionSelect := widget.NewSelect([]string{directionOptionUpload, directionOptionDownload, directionOptionBidirectional, directionOptionRemote}, nil)
+	directionSelect.SetSelected(directionOptionUpload)
+
+	// 双向同步的冲突处理方式
+	conflictSelect := widget.NewSelect(lo.Map(conflictPolicyOptions, func(o conflictPolicyOption, _ int) string { return o.label }), nil)
+	conflictSelect.SetSelected(conflictPolicyOptions[0].label)
+	bisyncForm := func() bisyncSettings {
+		return bisyncSettings{conflictPolicy: conflictSelect.Selected}
+	}
+
+	// 服务器之间传输的目标
+	targetSSHSelect := widget.NewSelect(sshNames, nil)
+	targetPathEntry := widget.NewEntry()
+	targetPathEntry.SetPlaceHolder("输入目标服务器上的路径...")
+	transferModeSelect := widget.NewSelect([]string{transferModeOptionDirect, transferModeOptionRelay}, nil)
+	transferModeSelect.SetSelected(transferModeOptionDirect)
+	remoteForm := func() remoteSettings {
+		return remoteSettings{targetSSH: targetSSHSelect.Selected, targetPath: targetPathEntry.Text, mode: transferModeSelect.Selected}
+	}
 
 	// 路径输入
 	localPathEntry := widget.NewEntry()
@@ -94,11 +115,37 @@ func ShowRsyncDialog(configName string) error {
 	remotePathEntry := widget.NewEntry()
 	remotePathEntry.SetPlaceHolder("输入远程路径...")
 
+	// 通过SFTP浏览所选连接上的目录
+	remoteBrowseButton := func(connSelect *widget.Select, entry *widget.Entry) *widget.Button {
+		button := widget.NewButton("", func() {
+			if connSelect.Selected == "" {
+				dialog.ShowError(errors.New("请先选择SSH连接"), myWindow)
+				return
+			}
+			list := func(dir string) (*models.RemoteListing, error) {
+				return services.ListRemoteDir(connSelect.Selected, dir, services.RemoteListOptions{DirsOnly: true})
+			}
+			component.ShowRemoteFolderDialog("选择远程路径", entry.Text, list, entry.SetText, myWindow)
+		})
+		button.SetIcon(icons.Icon_Ellipsis)
+		return button
+	}
+	remotePathBrowseButton := remoteBrowseButton(sshSelect, remotePathEntry)
+	targetPathBrowseButton := remoteBrowseButton(targetSSHSelect, targetPathEntry)
+
 	// 排除规则
 	excludeEntry := widget.NewMultiLineEntry()
 	excludeEntry.SetPlaceHolder("输入排除规则，每行一个:\n*.log\n*.tmp\n.DS_Store")
 	excludeEntry.Resize(fyne.NewSize(0, 80))
 
+	// 过滤规则
+	filterEntry := widget.NewMultiLineEntry()
+	filterEntry.SetPlaceHolder("有序的过滤规则，每行一条，第一条匹配的规则生效:\n+ keep.log\n- *.log\nP /uploads/\n. .rsync-filter")
+	ignoreFilesCheck := widget.NewCheck("读取本地路径中的 .gitignore 和 .rsyncignore", nil)
+	filterForm := func() filterSettings {
+		return filterSettings{rules: filterEntry.Text, ignoreFiles: ignoreFilesCheck.Checked}
+	}
+
 	// 常用rsync选项复选框
 	verboseCheck := widget.NewCheck("详细输出 (-v)", nil)
 	recursiveCheck := widget.NewCheck("递归 (-r)", nil)
@@ -129,10 +176,108 @@ func ShowRsyncDialog(configName string) error {
 	optionsContainer4 := container.NewGridWithColumns(3,
 		permsCheck, ownerCheck, groupCheck)
 
+	// 传输限制
+	bandwidthEntry := widget.NewEntry()
+	bandwidthEntry.SetPlaceHolder("带宽限制，如 500K、2M")
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetPlaceHolder("超时秒数")
+	maxSizeEntry := widget.NewEntry()
+	maxSizeEntry.SetPlaceHolder("最大文件大小，如 100M")
+	compressLevelEntry := widget.NewEntry()
+	compressLevelEntry.SetPlaceHolder("压缩级别 1-9")
+	resumeSelect := widget.NewSelect(lo.Map(resumeModes, func(mode models.ResumeMode, _ int) string { return mode.String() }), nil)
+	resumeSelect.SetSelected(models.ResumeNone.String())
+	backupCheck := widget.NewCheck("备份被覆盖或删除的文件 (--backup)", nil)
+	backupDirEntry := widget.NewEntry()
+	backupDirEntry.SetPlaceHolder("备份目录，相对路径在目标路径下，如 .rsync-backup/{{date}}，留空时加 ~ 后缀")
+	limitForm := func() limitSettings {
+		return limitSettings{
+			bandwidth:     bandwidthEntry.Text,
+			timeout:       timeoutEntry.Text,
+			maxSize:       maxSizeEntry.Text,
+			compressLevel: compressLevelEntry.Text,
+			resume:        resumeSelect.Selected,
+			backup:        backupCheck.Checked,
+			backupDir:     backupDirEntry.Text,
+		}
+	}
+	setLimitForm := func(config *models.RsyncConfig) {
+		bandwidthEntry.SetText(models.FormatBandwidth(config.BandwidthLimit))
+		timeoutEntry.SetText(formatCount(config.Timeout))
+		maxSizeEntry.SetText(models.FormatByteSize(config.MaxSize))
+		compressLevelEntry.SetText(formatCount(config.CompressLevel))
+		resumeSelect.SetSelected(config.ResumeMode.String())
+		backupCheck.SetChecked(config.Backup)
+		backupDirEntry.SetText(config.BackupDir)
+	}
+
+	// 传输引擎
+	engineSelect := widget.NewSelect(lo.Map(transferEngines, func(engine models.TransferEngine, _ int) string { return engineOption(engine) }), nil)
+	engineSelect.SetSelected(engineOption(models.TransferEngineAuto))
+	engineForm := func() engineSettings {
+		return engineSettings{engine: engineSelect.Selected}
+	}
+
+	// 执行后校验
+	verifyCheck := widget.NewCheck("执行成功后比较本地和服务器上的文件", nil)
+	verifyChecksumCheck := widget.NewCheck("同时比较内容 (SHA-256，较慢)", nil)
+	verifyForm := func() verifySettings {
+		return verifySettings{afterRun: verifyCheck.Checked, checksum: verifyChecksumCheck.Checked}
+	}
+
+	// 删除保护
+	deleteMaxCountEntry := widget.NewEntry()
+	deleteMaxCountEntry.SetPlaceHolder("最多删除的文件数")
+	deleteMaxPercentEntry := widget.NewEntry()
+	deleteMaxPercentEntry.SetPlaceHolder(fmt.Sprintf("最多删除目标中文件的百分比，默认 %d", models.DefaultDeleteMaxPercent))
+	deleteGuardForm := func() deleteGuardSettings {
+		return deleteGuardSettings{maxCount: deleteMaxCountEntry.Text, maxPercent: deleteMaxPercentEntry.Text}
+	}
+
+	// 预设：把常见场景的选项填入表单，只修改预设涉及的选项
+	presetSelect := widget.NewSelect(lo.Map(models.RsyncPresets, func(preset models.RsyncPreset, _ int) string { return preset.Label }), nil)
+	presetSelect.PlaceHolder = "选择预设填入下方的选项..."
+	presetDescription := widget.NewLabel("")
+	presetDescription.Wrapping = fyne.TextWrapWord
+
 	// 额外选项
 	optionsEntry := widget.NewEntry()
 	optionsEntry.SetPlaceHolder("输入额外rsync选项，如: --backup")
 
+	// 定时执行
+	scheduleEntry := widget.NewEntry()
+	scheduleEntry.SetPlaceHolder("cron 表达式，如: 0 3 * * *（每天 3 点），留空表示不定时执行")
+
+	// 执行前后的钩子
+	preHooksEntry := widget.NewMultiLineEntry()
+	preHooksEntry.SetPlaceHolder("执行前的命令，每行一个，失败时不执行同步:\nnpm run build\nremote: mkdir -p /var/www/app")
+	postHooksEntry := widget.NewMultiLineEntry()
+	postHooksEntry.SetPlaceHolder("同步成功后的命令，每行一个，remote: 开头的在服务器上执行:\nremote: systemctl restart app")
+
+	// 快照备份
+	snapshotCheck := widget.NewCheck("每次执行保存为新的快照 (仅下载配置，--link-dest)", nil)
+	keepDailyEntry := widget.NewEntry()
+	keepDailyEntry.SetPlaceHolder("保留天数")
+	keepWeeklyEntry := widget.NewEntry()
+	keepWeeklyEntry.SetPlaceHolder("保留周数")
+	keepMonthlyEntry := widget.NewEntry()
+	keepMonthlyEntry.SetPlaceHolder("保留月数")
+	snapshotForm := func() snapshotSettings {
+		return snapshotSettings{
+			enabled:     snapshotCheck.Checked,
+			keepDaily:   keepDailyEntry.Text,
+			keepWeekly:  keepWeeklyEntry.Text,
+			keepMonthly: keepMonthlyEntry.Text,
+		}
+	}
+
+	// 变量默认值
+	variablesEntry := widget.NewMultiLineEntry()
+	variablesEntry.SetPlaceHolder("变量默认值，每行一个 名称=值，在路径、规则和选项中以 {{名称}} 引用:\nenv=staging\nproject=web")
+	templateForm := func() templateSettings {
+		return templateSettings{variables: variablesEntry.Text}
+	}
+
 	// 描述
 	descEntry := widget.NewMultiLineEntry()
 	descEntry.SetPlaceHolder("输入描述信息...")
@@ -147,7 +292,8 @@ func ShowRsyncDialog(configName string) error {
 	// 更新预览的函数
 	updatePreview := func() {
 		// 检查必要字段是否为空
-		if sshSelect.Selected == "" || localPathEntry.Text == "" || remotePathEntry.Text == "" {
+		remote := directionFromOption(directionSelect.Selected) == models.RsyncDirectionRemote
+		if sshSelect.Selected == "" || (localPathEntry.Text == "" && !remote) || remotePathEntry.Text == "" {
 			previewEntry.SetText("请先填写SSH连接、本地路径和远程路径")
 			return
 		}
@@ -160,15 +306,8 @@ func ShowRsyncDialog(configName string) error {
 		}
 
 		// 创建临时配置用于生成预览
-		var dir models.RsyncDirection
-		if directionSelect.Selected == "上传 (本地→服务器)" {
-			dir = models.RsyncDirectionUpload
-		} else {
-			dir = models.RsyncDirectionDownload
-		}
-
 		tempConfig := &models.RsyncConfig{
-			Direction:    dir,
+			Direction:    directionFromOption(directionSelect.Selected),
 			LocalPath:    localPathEntry.Text,
 			RemotePath:   remotePathEntry.Text,
 			ExcludeRules: excludeEntry.Text,
@@ -187,9 +326,95 @@ func ShowRsyncDialog(configName string) error {
 			Group:        groupCheck.Checked,
 		}
 
-		// 生成rsync命令
-		cmdArgs := tempConfig.BuildRsyncCommand(sshConn)
-		previewEntry.SetText(strings.Join(cmdArgs, " "))
+		if _, err := tempConfig.ParseOptions(); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+
+		if err := snapshotForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		if err := filterForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		if err := bisyncForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		if err := remoteForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		if err := templateForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		if err := limitForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		if err := engineForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		if err := verifyForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		if err := deleteGuardForm().apply(tempConfig); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+
+		// 变量使用环境变量和默认值替换，执行时还可以用 --var 指定
+		tempConfig, err = services.RenderRsyncTemplate(tempConfig, sshConn)
+		if err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+
+		// 服务器之间传输在源服务器上执行rsync，中转的端口在执行时随机选择
+		if remote {
+			target, err := services.GetConnectionByName(tempConfig.TargetSSHName)
+			if err != nil {
+				previewEntry.SetText("获取目标SSH连接失败: " + err.Error())
+				return
+			}
+			relayPort := 0
+			if tempConfig.RemoteTransferMode == models.RemoteTransferRelay {
+				relayPort = models.RemoteRelayPortMin
+			}
+			previewEntry.SetText(models.ShellJoin(tempConfig.BuildRemoteTransferCommand(sshConn, target, relayPort)))
+			return
+		}
+		ignoreRules, err := services.RsyncIgnoreRules(tempConfig)
+		if err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+
+		// 双向同步先比较两端的文件，再分别下载和上传有变化的文件
+		if tempConfig.Direction == models.RsyncDirectionBidirectional {
+			previewEntry.SetText(strings.Join([]string{
+				"# 列出服务器文件",
+				models.ShellJoin(tempConfig.BuildListCommand(sshConn, ignoreRules)),
+				"# 下载服务器端的变化",
+				models.ShellJoin(tempConfig.BuildBisyncCommand(sshConn, models.RsyncDirectionDownload, "<下载列表>", ignoreRules)),
+				"# 上传本地的变化",
+				models.ShellJoin(tempConfig.BuildBisyncCommand(sshConn, models.RsyncDirectionUpload, "<上传列表>", ignoreRules)),
+			}, "\n"))
+			return
+		}
+
+		// 生成rsync命令，快照备份时显示本次执行会写入的目录
+		cmdArgs := tempConfig.BuildRsyncCommand(sshConn, ignoreRules)
+		if tempConfig.SnapshotMode {
+			snapshotDir := filepath.Join(tempConfig.LocalPath, models.SnapshotName(time.Now())+models.SnapshotInProgressSuffix)
+			cmdArgs = tempConfig.BuildSnapshotCommand(sshConn, snapshotDir, filepath.Join(tempConfig.LocalPath, models.SnapshotLatestLink))
+		}
+		previewEntry.SetText(models.ShellJoin(cmdArgs))
 	}
 
 	// 如果是修改模式，填充现有数据
@@ -197,17 +422,34 @@ func ShowRsyncDialog(configName string) error {
 		nameEntry.SetText(config.Name)
 		sshSelect.SetSelected(config.SSHName)
 
-		if config.Direction == models.RsyncDirectionUpload {
-			directionSelect.SetSelected("上传 (本地→服务器)")
-		} else {
-			directionSelect.SetSelected("下载 (服务器→本地)")
-		}
+		directionSelect.SetSelected(directionOption(config.Direction))
+		conflictSelect.SetSelected(conflictPolicyLabel(config.ConflictPolicy))
+		targetSSHSelect.SetSelected(config.TargetSSHName)
+		targetPathEntry.SetText(config.TargetPath)
+		transferModeSelect.SetSelected(transferModeOption(config.RemoteTransferMode))
 
 		localPathEntry.SetText(config.LocalPath)
 		remotePathEntry.SetText(config.RemotePath)
 		excludeEntry.SetText(config.ExcludeRules)
+		filterEntry.SetText(config.FilterRules)
+		ignoreFilesCheck.SetChecked(config.UseIgnoreFiles)
 		optionsEntry.SetText(config.Options)
+		scheduleEntry.SetText(config.Schedule)
+		preHooksEntry.SetText(config.PreHooks)
+		postHooksEntry.SetText(config.PostHooks)
 		descEntry.SetText(config.Description)
+		variablesEntry.SetText(config.Variables)
+		engineSelect.SetSelected(engineOption(config.Engine))
+		verifyCheck.SetChecked(config.VerifyAfterRun)
+		verifyChecksumCheck.SetChecked(config.VerifyChecksum)
+		deleteMaxCountEntry.SetText(formatCount(config.DeleteMaxCount))
+		deleteMaxPercentEntry.SetText(formatCount(config.DeleteMaxPercent))
+
+		snapshotCheck.SetChecked(config.SnapshotMode)
+		keepDailyEntry.SetText(formatCount(config.SnapshotKeepDaily))
+		keepWeeklyEntry.SetText(formatCount(config.SnapshotKeepWeekly))
+		keepMonthlyEntry.SetText(formatCount(config.SnapshotKeepMonthly))
+		setLimitForm(config)
 
 		// 设置复选框状态
 		verboseCheck.SetChecked(config.Verbose)
@@ -226,11 +468,40 @@ func ShowRsyncDialog(configName string) error {
 
 	// 为所有相关控件添加事件监听器，实时更新预览
 	sshSelect.OnChanged = func(string) { updatePreview() }
-	directionSelect.OnChanged = func(string) { updatePreview() }
+	directionSelect.OnChanged = func(selected string) {
+		if selected == directionOptionBidirectional {
+			conflictSelect.Enable()
+		} else {
+			conflictSelect.Disable()
+		}
+		if selected == directionOptionRemote {
+			localPathEntry.Disable()
+			localBrowseButton.Disable()
+			targetSSHSelect.Enable()
+			targetPathEntry.Enable()
+			targetPathBrowseButton.Enable()
+			transferModeSelect.Enable()
+		} else {
+			localPathEntry.Enable()
+			localBrowseButton.Enable()
+			targetSSHSelect.Disable()
+			targetPathEntry.Disable()
+			targetPathBrowseButton.Disable()
+			transferModeSelect.Disable()
+		}
+		updatePreview()
+	}
+	conflictSelect.OnChanged = func(string) { updatePreview() }
+	targetSSHSelect.OnChanged = func(string) { updatePreview() }
+	targetPathEntry.OnChanged = func(string) { updatePreview() }
+	transferModeSelect.OnChanged = func(string) { updatePreview() }
 	localPathEntry.OnChanged = func(string) { updatePreview() }
 	remotePathEntry.OnChanged = func(string) { updatePreview() }
 	excludeEntry.OnChanged = func(string) { updatePreview() }
+	filterEntry.OnChanged = func(string) { updatePreview() }
+	ignoreFilesCheck.OnChanged = func(bool) { updatePreview() }
 	optionsEntry.OnChanged = func(string) { updatePreview() }
+	variablesEntry.OnChanged = func(string) { updatePreview() }
 
 	verboseCheck.OnChanged = func(bool) { updatePreview() }
 	recursiveCheck.OnChanged = func(bool) { updatePreview() }
@@ -244,9 +515,48 @@ func ShowRsyncDialog(configName string) error {
 	permsCheck.OnChanged = func(bool) { updatePreview() }
 	ownerCheck.OnChanged = func(bool) { updatePreview() }
 	groupCheck.OnChanged = func(bool) { updatePreview() }
+	snapshotCheck.OnChanged = func(bool) { updatePreview() }
+	keepDailyEntry.OnChanged = func(string) { updatePreview() }
+	keepWeeklyEntry.OnChanged = func(string) { updatePreview() }
+	keepMonthlyEntry.OnChanged = func(string) { updatePreview() }
+	bandwidthEntry.OnChanged = func(string) { updatePreview() }
+	timeoutEntry.OnChanged = func(string) { updatePreview() }
+	maxSizeEntry.OnChanged = func(string) { updatePreview() }
+	compressLevelEntry.OnChanged = func(string) { updatePreview() }
+	resumeSelect.OnChanged = func(string) { updatePreview() }
+	backupCheck.OnChanged = func(bool) { updatePreview() }
+	backupDirEntry.OnChanged = func(string) { updatePreview() }
+	deleteMaxCountEntry.OnChanged = func(string) { updatePreview() }
+	deleteMaxPercentEntry.OnChanged = func(string) { updatePreview() }
+	presetSelect.OnChanged = func(label string) {
+		preset, err := models.GetRsyncPreset(label)
+		if err != nil {
+			return
+		}
+		presetDescription.SetText(preset.Description)
+
+		// 先读出表单中的选项，应用预设后再写回表单
+		config := &models.RsyncConfig{
+			Archive:  archiveCheck.Checked,
+			Compress: compressCheck.Checked,
+			Delete:   deleteCheck.Checked,
+			Checksum: checksumCheck.Checked,
+		}
+		if err := limitForm().parse(config); err != nil {
+			previewEntry.SetText(err.Error())
+			return
+		}
+		preset.Apply(config)
+		archiveCheck.SetChecked(config.Archive)
+		compressCheck.SetChecked(config.Compress)
+		deleteCheck.SetChecked(config.Delete)
+		checksumCheck.SetChecked(config.Checksum)
+		setLimitForm(config)
+		updatePreview()
+	}
 
 	// 初始预览更新
-	updatePreview()
+	directionSelect.OnChanged(directionSelect.Selected)
 
 	// 创建本地路径容器
 	localPathContainer := container.NewBorder(nil, nil, nil, localBrowseButton, localPathEntry)
@@ -256,16 +566,43 @@ func ShowRsyncDialog(configName string) error {
 		widget.NewFormItem("配置名称", nameEntry),
 		widget.NewFormItem("SSH连接", sshSelect),
 		widget.NewFormItem("传输方向", directionSelect),
+		widget.NewFormItem("冲突处理", conflictSelect),
 		widget.NewFormItem("本地路径", localPathContainer),
-		widget.NewFormItem("远程路径", remotePathEntry),
+		widget.NewFormItem("远程路径", container.NewBorder(nil, nil, nil, remotePathBrowseButton, remotePathEntry)),
+		widget.NewFormItem("目标SSH连接", targetSSHSelect),
+		widget.NewFormItem("目标路径", container.NewBorder(nil, nil, nil, targetPathBrowseButton, targetPathEntry)),
+		widget.NewFormItem("传输方式", transferModeSelect),
 		widget.NewFormItem("排除规则", excludeEntry),
+		widget.NewFormItem("过滤规则", container.NewVBox(filterEntry, ignoreFilesCheck)),
+		widget.NewFormItem("预设", container.NewVBox(presetSelect, presetDescription)),
 		widget.NewFormItem("常用选项", container.NewVBox(
 			optionsContainer1,
 			optionsContainer2,
 			optionsContainer3,
 			optionsContainer4,
 		)),
+		widget.NewFormItem("传输限制", container.NewVBox(
+			container.NewGridWithColumns(2, bandwidthEntry, timeoutEntry),
+			container.NewGridWithColumns(2, maxSizeEntry, compressLevelEntry),
+			resumeSelect,
+			backupCheck,
+			backupDirEntry,
+		)),
+		widget.NewFormItem("删除保护", container.NewVBox(
+			container.NewGridWithColumns(2, deleteMaxCountEntry, deleteMaxPercentEntry),
+			widget.NewLabel("启用删除时执行前先试运行，源目录为空或删除超过上限时中止执行"),
+		)),
+		widget.NewFormItem("传输引擎", engineSelect),
+		widget.NewFormItem("执行后校验", container.NewVBox(verifyCheck, verifyChecksumCheck)),
 		widget.NewFormItem("额外选项", optionsEntry),
+		widget.NewFormItem("变量", variablesEntry),
+		widget.NewFormItem("定时执行", scheduleEntry),
+		widget.NewFormItem("执行前钩子", preHooksEntry),
+		widget.NewFormItem("执行后钩子", postHooksEntry),
+		widget.NewFormItem("快照备份", container.NewVBox(
+			snapshotCheck,
+			container.NewGridWithColumns(3, keepDailyEntry, keepWeeklyEntry, keepMonthlyEntry),
+		)),
 		widget.NewFormItem("描述", descEntry),
 		widget.NewFormItem("命令预览", previewEntry),
 	)
@@ -275,16 +612,16 @@ func ShowRsyncDialog(configName string) error {
 		var err error
 		if isUpdateMode {
 			err = updateRsyncConfig(config.ID, nameEntry.Text, sshSelect.Selected, directionSelect.Selected,
-				localPathEntry.Text, remotePathEntry.Text, excludeEntry.Text, optionsEntry.Text, descEntry.Text,
+				localPathEntry.Text, remotePathEntry.Text, excludeEntry.Text, optionsEntry.Text, scheduleEntry.Text, preHooksEntry.Text, postHooksEntry.Text, descEntry.Text,
 				verboseCheck.Checked, recursiveCheck.Checked, archiveCheck.Checked, compressCheck.Checked,
 				timesCheck.Checked, progressCheck.Checked, deleteCheck.Checked, checksumCheck.Checked,
-				linksCheck.Checked, permsCheck.Checked, ownerCheck.Checked, groupCheck.Checked)
+				linksCheck.Checked, permsCheck.Checked, ownerCheck.Checked, groupCheck.Checked, snapshotForm(), filterForm(), bisyncForm(), remoteForm(), templateForm(), limitForm(), engineForm(), verifyForm(), deleteGuardForm())
 		} else {
 			err = saveRsyncConfig(nameEntry.Text, sshSelect.Selected, directionSelect.Selected,
-				localPathEntry.Text, remotePathEntry.Text, excludeEntry.Text, optionsEntry.Text, descEntry.Text,
+				localPathEntry.Text, remotePathEntry.Text, excludeEntry.Text, optionsEntry.Text, scheduleEntry.Text, preHooksEntry.Text, postHooksEntry.Text, descEntry.Text,
 				verboseCheck.Checked, recursiveCheck.Checked, archiveCheck.Checked, compressCheck.Checked,
 				timesCheck.Checked, progressCheck.Checked, deleteCheck.Checked, checksumCheck.Checked,
-				linksCheck.Checked, permsCheck.Checked, ownerCheck.Checked, groupCheck.Checked)
+				linksCheck.Checked, permsCheck.Checked, ownerCheck.Checked, groupCheck.Checked, snapshotForm(), filterForm(), bisyncForm(), remoteForm(), templateForm(), limitForm(), engineForm(), verifyForm(), deleteGuardForm())
 		}
 
 		if err != nil {
@@ -341,29 +678,24 @@ func ShowUpdateRsyncDialog(name string) error {
 	return ShowRsyncDialog(name)
 }
 
-func saveRsyncConfig(name, sshName, direction, localPath, remotePath, excludeRules, options, description string,
-	verbose, recursive, archive, compress, times, progress, delete, checksum, links, perms, owner, group bool) error {
-	if strings.TrimSpace(name) == "" || strings.TrimSpace(sshName) == "" ||
-		strings.TrimSpace(localPath) == "" || strings.TrimSpace(remotePath) == "" {
+func saveRsyncConfig(name, sshName, direction, localPath, remotePath, excludeRules, options, schedule, preHooks, postHooks, description string,
+	verbose, recursive, archive, compress, times, progress, delete, checksum, links, perms, owner, group bool, snapshot snapshotSettings, filter filterSettings, bisync bisyncSettings, remote remoteSettings, template templateSettings, limits limitSettings, engine engineSettings, verify verifySettings, deleteGuard deleteGuardSettings) error {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(sshName) == "" || strings.TrimSpace(remotePath) == "" ||
+		(strings.TrimSpace(localPath) == "" && directionFromOption(direction) != models.RsyncDirectionRemote) {
 		return errors.New("配置名称、SSH连接、本地路径和远程路径不能为空")
 	}
 
-	// 转换传输方向
-	var dir models.RsyncDirection
-	if direction == "上传 (本地→服务器)" {
-		dir = models.RsyncDirectionUpload
-	} else {
-		dir = models.RsyncDirectionDownload
-	}
-
 	config := models.RsyncConfig{
 		Name:         strings.TrimSpace(name),
 		SSHName:      strings.TrimSpace(sshName),
-		Direction:    dir,
+		Direction:    directionFromOption(direction),
 		LocalPath:    strings.TrimSpace(localPath),
 		RemotePath:   strings.TrimSpace(remotePath),
 		ExcludeRules: strings.TrimSpace(excludeRules),
 		Options:      strings.TrimSpace(options),
+		Schedule:     strings.TrimSpace(schedule),
+		PreHooks:     strings.TrimSpace(preHooks),
+		PostHooks:    strings.TrimSpace(postHooks),
 		Description:  strings.TrimSpace(description),
 		Verbose:      verbose,
 		Recursive:    recursive,
@@ -379,33 +711,64 @@ func saveRsyncConfig(name, sshName, direction, localPath, remotePath, excludeRul
 		Group:        group,
 	}
 
+	if _, err := config.ParseOptions(); err != nil {
+		return err
+	}
+	if err := snapshot.apply(&config); err != nil {
+		return err
+	}
+	if err := filter.apply(&config); err != nil {
+		return err
+	}
+	if err := bisync.apply(&config); err != nil {
+		return err
+	}
+	if err := remote.apply(&config); err != nil {
+		return err
+	}
+	if err := template.apply(&config); err != nil {
+		return err
+	}
+	if err := limits.apply(&config); err != nil {
+		return err
+	}
+	if err := engine.apply(&config); err != nil {
+		return err
+	}
+	if err := verify.apply(&config); err != nil {
+		return err
+	}
+	if err := deleteGuard.apply(&config); err != nil {
+		return err
+	}
+	if config.Schedule != "" {
+		if _, err := models.ParseCron(config.Schedule); err != nil {
+			return err
+		}
+	}
+
 	db := database.GetDB()
 	return db.Create(&config).Error
 }
 
-func updateRsyncConfig(id uint, name, sshName, direction, localPath, remotePath, excludeRules, options, description string,
-	verbose, recursive, archive, compress, times, progress, delete, checksum, links, perms, owner, group bool) error {
-	if strings.TrimSpace(name) == "" || strings.TrimSpace(sshName) == "" ||
-		strings.TrimSpace(localPath) == "" || strings.TrimSpace(remotePath) == "" {
+func updateRsyncConfig(id uint, name, sshName, direction, localPath, remotePath, excludeRules, options, schedule, preHooks, postHooks, description string,
+	verbose, recursive, archive, compress, times, progress, delete, checksum, links, perms, owner, group bool, snapshot snapshotSettings, filter filterSettings, bisync bisyncSettings, remote remoteSettings, template templateSettings, limits limitSettings, engine engineSettings, verify verifySettings, deleteGuard deleteGuardSettings) error {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(sshName) == "" || strings.TrimSpace(remotePath) == "" ||
+		(strings.TrimSpace(localPath) == "" && directionFromOption(direction) != models.RsyncDirectionRemote) {
 		return errors.New("配置名称、SSH连接、本地路径和远程路径不能为空")
 	}
 
-	// 转换传输方向
-	var dir models.RsyncDirection
-	if direction == "上传 (本地→服务器)" {
-		dir = models.RsyncDirectionUpload
-	} else {
-		dir = models.RsyncDirectionDownload
-	}
-
 	config := &models.RsyncConfig{
 		Name:         strings.TrimSpace(name),
 		SSHName:      strings.TrimSpace(sshName),
-		Direction:    dir,
+		Direction:    directionFromOption(direction),
 		LocalPath:    strings.TrimSpace(localPath),
 		RemotePath:   strings.TrimSpace(remotePath),
 		ExcludeRules: strings.TrimSpace(excludeRules),
 		Options:      strings.TrimSpace(options),
+		Schedule:     strings.TrimSpace(schedule),
+		PreHooks:     strings.TrimSpace(preHooks),
+		PostHooks:    strings.TrimSpace(postHooks),
 		Description:  strings.TrimSpace(description),
 		Verbose:      verbose,
 		Recursive:    recursive,
@@ -422,6 +785,51 @@ func updateRsyncConfig(id uint, name, sshName, direction, localPath, remotePath,
 	}
 	config.ID = id
 
+	// 保留表单中没有的字段
+	existing, err := getRsyncConfigByID(id)
+	if err != nil {
+		return err
+	}
+	config.CreatedAt = existing.CreatedAt
+	config.UsageCount = existing.UsageCount
+	config.SchedulePaused = existing.SchedulePaused
+
+	if _, err := config.ParseOptions(); err != nil {
+		return err
+	}
+	if err := snapshot.apply(config); err != nil {
+		return err
+	}
+	if err := filter.apply(config); err != nil {
+		return err
+	}
+	if err := bisync.apply(config); err != nil {
+		return err
+	}
+	if err := remote.apply(config); err != nil {
+		return err
+	}
+	if err := template.apply(config); err != nil {
+		return err
+	}
+	if err := limits.apply(config); err != nil {
+		return err
+	}
+	if err := engine.apply(config); err != nil {
+		return err
+	}
+	if err := verify.apply(config); err != nil {
+		return err
+	}
+	if err := deleteGuard.apply(config); err != nil {
+		return err
+	}
+	if config.Schedule != "" {
+		if _, err := models.ParseCron(config.Schedule); err != nil {
+			return err
+		}
+	}
+
 	db := database.GetDB()
 	return db.Save(config).Error
 }
@@ -435,3 +843,319 @@ func getRsyncConfigByName(name string) (*models.RsyncConfig, error) {
 	}
 	return &config, nil
 }
+
+func getRsyncConfigByID(id uint) (*models.RsyncConfig, error) {
+	var config models.RsyncConfig
+	db := database.GetDB()
+	if err := db.First(&config, id).Error; err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// snapshotSettings 表单中的快照备份设置
+type snapshotSettings struct {
+	enabled                            bool
+	keepDaily, keepWeekly, keepMonthly string
+}
+
+// apply 把快照备份设置写入配置，保留数量留空表示 0
+func (s snapshotSettings) apply(config *models.RsyncConfig) error {
+	counts := []struct {
+		text  string
+		name  string
+		value *int
+	}{
+		{s.keepDaily, "保留天数", &config.SnapshotKeepDaily},
+		{s.keepWeekly, "保留周数", &config.SnapshotKeepWeekly},
+		{s.keepMonthly, "保留月数", &config.SnapshotKeepMonthly},
+	}
+	for _, count := range counts {
+		text := strings.TrimSpace(count.text)
+		if text == "" {
+			*count.value = 0
+			continue
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%s必须是数字", count.name)
+		}
+		*count.value = n
+	}
+	config.SnapshotMode = s.enabled
+	return config.ValidateSnapshot()
+}
+
+// formatCount 数量为 0 时显示为空
+func formatCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// filterSettings 表单中的过滤规则设置
+type filterSettings struct {
+	rules       string
+	ignoreFiles bool
+}
+
+// apply 把过滤规则设置写入配置并检查格式，需要在快照备份设置之后调用
+func (f filterSettings) apply(config *models.RsyncConfig) error {
+	config.FilterRules = strings.TrimSpace(f.rules)
+	config.UseIgnoreFiles = f.ignoreFiles
+	return config.ValidateFilters()
+}
+
+// 传输方向选项
+const (
+	directionOptionUpload        = "上传 (本地→服务器)"
+	directionOptionDownload      = "下载 (服务器→本地)"
+	directionOptionBidirectional = "双向同步 (本地↔服务器)"
+	directionOptionRemote        = "服务器之间 (服务器→服务器)"
+)
+
+func directionFromOption(option string) models.RsyncDirection {
+	switch option {
+	case directionOptionDownload:
+		return models.RsyncDirectionDownload
+	case directionOptionBidirectional:
+		return models.RsyncDirectionBidirectional
+	case directionOptionRemote:
+		return models.RsyncDirectionRemote
+	}
+	return models.RsyncDirectionUpload
+}
+
+func directionOption(direction models.RsyncDirection) string {
+	switch direction {
+	case models.RsyncDirectionDownload:
+		return directionOptionDownload
+	case models.RsyncDirectionBidirectional:
+		return directionOptionBidirectional
+	case models.RsyncDirectionRemote:
+		return directionOptionRemote
+	}
+	return directionOptionUpload
+}
+
+type conflictPolicyOption struct {
+	label  string
+	policy models.ConflictPolicy
+}
+
+// conflictPolicyOptions 冲突处理方式选项，第一个为默认值
+var conflictPolicyOptions = []conflictPolicyOption{
+	{"只报告冲突，两端都不修改", models.ConflictPolicyReport},
+	{"以修改时间较新的一方为准", models.ConflictPolicyNewer},
+	{"保留两个版本，本地版本另存为冲突副本", models.ConflictPolicyKeepBoth},
+	{"以本地为准", models.ConflictPolicyLocal},
+	{"以服务器为准", models.ConflictPolicyRemote},
+}
+
+func conflictPolicyLabel(policy models.ConflictPolicy) string {
+	for _, option := range conflictPolicyOptions {
+		if option.policy == policy {
+			return option.label
+		}
+	}
+	return conflictPolicyOptions[0].label
+}
+
+// bisyncSettings 表单中的双向同步设置
+type bisyncSettings struct {
+	conflictPolicy string
+}
+
+// apply 把冲突处理方式写入配置，只有双向同步的配置保存冲突处理方式
+func (b bisyncSettings) apply(config *models.RsyncConfig) error {
+	config.ConflictPolicy = ""
+	if config.Direction == models.RsyncDirectionBidirectional {
+		for _, option := range conflictPolicyOptions {
+			if option.label == b.conflictPolicy {
+				config.ConflictPolicy = option.policy
+			}
+		}
+	}
+	return config.ValidateBidirectional()
+}
+
+// 服务器之间的传输方式选项
+const (
+	transferModeOptionDirect = "源服务器直接连接目标服务器"
+	transferModeOptionRelay  = "经本机中转 (服务器之间无法直接连接时)"
+)
+
+func transferModeOption(mode models.RemoteTransferMode) string {
+	if mode == models.RemoteTransferRelay {
+		return transferModeOptionRelay
+	}
+	return transferModeOptionDirect
+}
+
+// remoteSettings 表单中服务器之间传输的设置
+type remoteSettings struct {
+	targetSSH  string
+	targetPath string
+	mode       string
+}
+
+// apply 把目标写入配置，只有服务器之间传输的配置保存目标，且不使用本地路径
+func (s remoteSettings) apply(config *models.RsyncConfig) error {
+	config.TargetSSHName, config.TargetPath, config.RemoteTransferMode = "", "", ""
+	if config.Direction == models.RsyncDirectionRemote {
+		config.LocalPath = ""
+		config.TargetSSHName = strings.TrimSpace(s.targetSSH)
+		config.TargetPath = strings.TrimSpace(s.targetPath)
+		config.RemoteTransferMode = models.RemoteTransferDirect
+		if s.mode == transferModeOptionRelay {
+			config.RemoteTransferMode = models.RemoteTransferRelay
+		}
+	}
+	return config.ValidateRemoteTransfer()
+}
+
+// templateSettings 表单中的变量默认值
+type templateSettings struct {
+	variables string
+}
+
+// apply 把变量默认值写入配置并检查变量的写法
+func (t templateSettings) apply(config *models.RsyncConfig) error {
+	config.Variables = strings.TrimSpace(t.variables)
+	return config.ValidateTemplates()
+}
+
+// resumeModes 续传方式选项，第一个为默认值
+var resumeModes = []models.ResumeMode{models.ResumeNone, models.ResumePartial, models.ResumeAppendVerify}
+
+// limitSettings 表单中的传输限制
+type limitSettings struct {
+	bandwidth, timeout, maxSize, compressLevel string
+	resume                                     string
+	backup                                     bool
+	backupDir                                  string
+}
+
+// parse 把传输限制写入配置，留空表示不限制
+func (l limitSettings) parse(config *models.RsyncConfig) error {
+	bandwidth, err := models.ParseBandwidth(l.bandwidth)
+	if err != nil {
+		return fmt.Errorf("带宽限制格式错误: %v", err)
+	}
+	maxSize, err := models.ParseByteSize(l.maxSize)
+	if err != nil {
+		return fmt.Errorf("最大文件大小格式错误: %v", err)
+	}
+	counts := []struct {
+		text  string
+		name  string
+		value *int
+	}{
+		{l.timeout, "超时秒数", &config.Timeout},
+		{l.compressLevel, "压缩级别", &config.CompressLevel},
+	}
+	for _, count := range counts {
+		text := strings.TrimSpace(count.text)
+		if text == "" {
+			*count.value = 0
+			continue
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%s必须是数字", count.name)
+		}
+		*count.value = n
+	}
+
+	config.BandwidthLimit = bandwidth
+	config.MaxSize = maxSize
+	config.ResumeMode = models.ResumeNone
+	for _, mode := range resumeModes {
+		if mode.String() == l.resume {
+			config.ResumeMode = mode
+		}
+	}
+	config.Backup = l.backup
+	config.BackupDir = strings.TrimSpace(l.backupDir)
+	return nil
+}
+
+// apply 把传输限制写入配置并检查，需要在压缩选项和快照备份设置之后调用
+func (l limitSettings) apply(config *models.RsyncConfig) error {
+	if err := l.parse(config); err != nil {
+		return err
+	}
+	return config.ValidateLimits()
+}
+
+// transferEngines 传输引擎选项，第一个为默认值
+var transferEngines = []models.TransferEngine{models.TransferEngineAuto, models.TransferEngineRsync, models.TransferEngineSFTP}
+
+func engineOption(engine models.TransferEngine) string {
+	switch engine {
+	case models.TransferEngineRsync:
+		return "rsync"
+	case models.TransferEngineSFTP:
+		return "sftp (本地没有 rsync 时，整个文件传输)"
+	}
+	return "自动 (本地有 rsync 时使用 rsync，否则使用 sftp)"
+}
+
+// engineSettings 表单中的传输引擎
+type engineSettings struct {
+	engine string
+}
+
+// apply 把传输引擎写入配置并检查配置能否使用该引擎，需要在传输方向、快照备份和备份设置之后调用
+func (e engineSettings) apply(config *models.RsyncConfig) error {
+	config.Engine = models.TransferEngineAuto
+	for _, engine := range transferEngines {
+		if engineOption(engine) == e.engine {
+			config.Engine = engine
+		}
+	}
+	return config.ValidateEngine()
+}
+
+// verifySettings 表单中的执行后校验设置
+type verifySettings struct {
+	afterRun, checksum bool
+}
+
+// apply 把执行后校验写入配置并检查，只勾选比较内容时同时启用校验
+func (v verifySettings) apply(config *models.RsyncConfig) error {
+	config.VerifyAfterRun = v.afterRun || v.checksum
+	config.VerifyChecksum = v.checksum
+	return config.ValidateVerify()
+}
+
+// deleteGuardSettings 表单中的删除保护上限
+type deleteGuardSettings struct {
+	maxCount, maxPercent string
+}
+
+// apply 把删除数量和比例上限写入配置并检查，留空表示不限制，都留空时使用默认的比例上限
+func (d deleteGuardSettings) apply(config *models.RsyncConfig) error {
+	limits := []struct {
+		text  string
+		name  string
+		value *int
+	}{
+		{d.maxCount, "删除数量上限", &config.DeleteMaxCount},
+		{d.maxPercent, "删除比例上限", &config.DeleteMaxPercent},
+	}
+	for _, limit := range limits {
+		text := strings.TrimSuffix(strings.TrimSpace(limit.text), "%")
+		if text == "" {
+			*limit.value = 0
+			continue
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%s必须是数字", limit.name)
+		}
+		*limit.value = n
+	}
+	return config.ValidateDeleteGuard()
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"alfred-tool/ui/component"
 	"alfred-tool/ui/xtheme"
 	"errors"
 	"fmt"
@@ -97,12 +98,38 @@ func ShowServiceDialog(serviceID uint) error {
 		}
 	}
 
+	// 浏览关联服务器上的目录，选中的路径追加到服务详情
+	browseButton := widget.NewButton("浏览服务器...", func() {
+		var connName string
+		for _, conn := range sshConnections {
+			if sshSelect.Selected == fmt.Sprintf("%s (%s@%s)", conn.Name, conn.Username, conn.Address) {
+				connName = conn.Name
+				break
+			}
+		}
+		if connName == "" {
+			dialog.ShowError(errors.New("请先选择关联的SSH连接"), myWindow)
+			return
+		}
+		list := func(dir string) (*models.RemoteListing, error) {
+			return services.ListRemoteDir(connName, dir, services.RemoteListOptions{DirsOnly: true})
+		}
+		component.ShowRemoteFolderDialog("选择服务器路径", "", list, func(path string) {
+			details := strings.TrimRight(detailsEntry.Text, "\n")
+			if details != "" {
+				details += "\n"
+			}
+			detailsEntry.SetText(details + path)
+		}, myWindow)
+	})
+
 	// 创建表单
 	form := widget.NewForm(
 		widget.NewFormItem("服务名称", nameEntry),
 		widget.NewFormItem("关联SSH连接", sshSelect),
 		widget.NewFormItem("服务描述", descEntry),
 		widget.NewFormItem("服务详情", detailsEntry),
+		widget.NewFormItem("", browseButton),
 	)
 
 	// 设置表单提交和取消按钮